@@ -0,0 +1,84 @@
+package acli
+
+import (
+	"context"
+	"flag"
+	"io"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"reflect"
+	"time"
+)
+
+func (c *CLI) reconcile(ctx context.Context, args []string) error {
+	if _, err := c.parseFlags(flag.NewFlagSet("reconcile", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	report, err := c.app.Reconciler().Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+
+	output := ahmodel.CreateReconcileReport(report)
+
+	return c.print(output, func(w io.Writer) {
+		row(w, "STARTED AT", output.StartedAt.Format(time.RFC3339))
+		row(w, "FINISHED AT", output.FinishedAt.Format(time.RFC3339))
+		row(w, "SCANNED MEDIA", output.ScannedMedia)
+		row(w, "SCANNED METADATA", output.ScannedMetadata)
+		row(w, "DELETED ORPHANS", len(output.DeletedOrphans))
+		row(w, "MISSING MEDIA", len(output.MissingMedia))
+
+		for _, key := range output.DeletedOrphans {
+			row(w, "DELETED ORPHAN", key)
+		}
+
+		for _, id := range output.MissingMedia {
+			row(w, "MISSING MEDIA", id)
+		}
+	})
+}
+
+func (c *CLI) migrate(ctx context.Context, args []string) error {
+	if _, err := c.parseFlags(flag.NewFlagSet("migrate", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	return c.app.Migrate(ctx)
+}
+
+// printConfig prints the configuration as the server would use it. Durations are printed like `1h0m0s`, which is
+// also how they are configured.
+func (c *CLI) printConfig(ctx context.Context, args []string) error {
+	if _, err := c.parseFlags(flag.NewFlagSet("config print", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	configuration := reflect.ValueOf(c.configuration.Redacted())
+	fields := configuration.Type()
+
+	output := make(map[string]interface{}, fields.NumField())
+	for i := 0; i < fields.NumField(); i++ {
+		value := configuration.Field(i).Interface()
+		if duration, ok := value.(time.Duration); ok {
+			value = duration.String()
+		}
+
+		output[fields.Field(i).Name] = value
+	}
+
+	return c.print(output, func(w io.Writer) {
+		row(w, "NAME", "VALUE")
+		for i := 0; i < fields.NumField(); i++ {
+			row(w, fields.Field(i).Name, output[fields.Field(i).Name])
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package acli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"media-nexus/app"
+	"media-nexus/config"
+	"media-nexus/errortypes"
+	"media-nexus/logger"
+	"media-nexus/util"
+	"strings"
+	"time"
+)
+
+const usage = `usage: media-nexus [-config <file>] [-output table|json] [command]
+       media-nexus <config file>
+
+commands:
+  serve                  start the server, the default without a command
+  tags list              list all tags
+  tags create <name>     create a tag and print its ID
+  tags delete <id>...    delete tags
+  media get <id>         print a media item
+  media delete <id>      delete media and its blob
+  media find             find media by -tag <id> or -query <query>, see media find -h
+  reconcile              reconcile the media storage with the metadata and print the report
+  migrate                create indices and apply the schema migrations without starting the server
+  config print           print the configuration without secrets
+
+with a config file as only argument, the server is started with it
+`
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// commands are the first args run takes. Any other single arg is a config file, see ConfigFile.
+var commands = map[string]bool{
+	"serve":     true,
+	"tags":      true,
+	"media":     true,
+	"reconcile": true,
+	"migrate":   true,
+	"config":    true,
+	"help":      true,
+	"-h":        true,
+	"-help":     true,
+	"--help":    true,
+}
+
+// shutdownTimeout is the time spans not exported yet have to be flushed
+const shutdownTimeout = 5 * time.Second
+
+// CLI runs the admin commands against the same ports and services the server uses, so operators don't need to touch
+// MongoDB and S3 by hand.
+type CLI struct {
+	log           logger.Logger
+	app           app.App
+	configuration *config.Configuration
+	output        string
+	stdout        io.Writer
+	stderr        io.Writer
+	isSetUp       bool
+}
+
+// NewCLI sets up the app only for commands, that need it. Output is either OutputTable or OutputJSON.
+func NewCLI(
+	log logger.Logger,
+	application app.App,
+	configuration *config.Configuration,
+	output string,
+	stdout io.Writer,
+	stderr io.Writer,
+) (*CLI, error) {
+	if output != OutputTable && output != OutputJSON {
+		return nil, errortypes.NewBadUserInputf("unknown output '%v'. Use %v or %v.", output, OutputTable, OutputJSON)
+	}
+
+	return &CLI{log, application, configuration, output, stdout, stderr, false}, nil
+}
+
+// ConfigFile returns the config file and the args of the command. Before there were commands, the server was started
+// with the config file as only argument. So a single arg, that's no command, is taken as config file, if -config isn't
+// given.
+func ConfigFile(configFlag string, args []string) (string, []string) {
+	if configFlag == "" && len(args) == 1 && !commands[args[0]] {
+		return args[0], nil
+	}
+
+	return configFlag, args
+}
+
+// Run runs the command given by args. Without args, the server is started.
+func (c *CLI) Run(args []string) error {
+	err := c.run(args)
+	c.shutdown()
+
+	if errors.Is(err, flag.ErrHelp) {
+		// the usage was asked for and printed already
+		return nil
+	}
+
+	return err
+}
+
+func (c *CLI) run(args []string) error {
+	if len(args) < 1 {
+		return c.serve()
+	}
+
+	ctx := util.WithLogger(context.Background(), c.log)
+	command, args := args[0], args[1:]
+
+	switch command {
+	case "serve":
+		return c.serve()
+	case "tags":
+		return c.runSubcommand(ctx, command, args, map[string]func(context.Context, []string) error{
+			"list":   c.listTags,
+			"create": c.createTag,
+			"delete": c.deleteTags,
+		})
+	case "media":
+		return c.runSubcommand(ctx, command, args, map[string]func(context.Context, []string) error{
+			"get":    c.getMedia,
+			"delete": c.deleteMedia,
+			"find":   c.findMedia,
+		})
+	case "reconcile":
+		return c.reconcile(ctx, args)
+	case "migrate":
+		return c.migrate(ctx, args)
+	case "config":
+		return c.runSubcommand(ctx, command, args, map[string]func(context.Context, []string) error{
+			"print": c.printConfig,
+		})
+	case "help", "-h", "-help", "--help":
+		_, err := fmt.Fprint(c.stdout, usage)
+		return err
+	default:
+		return c.usageError("unknown command '%v'", command)
+	}
+}
+
+func (c *CLI) runSubcommand(
+	ctx context.Context,
+	command string,
+	args []string,
+	subcommands map[string]func(context.Context, []string) error,
+) error {
+	if len(args) < 1 {
+		return c.usageError("%v needs a subcommand", command)
+	}
+
+	subcommand, ok := subcommands[args[0]]
+	if !ok {
+		return c.usageError("unknown subcommand '%v %v'", command, args[0])
+	}
+
+	return subcommand(ctx, args[1:])
+}
+
+func (c *CLI) usageError(format string, args ...interface{}) error {
+	_, _ = fmt.Fprint(c.stderr, usage)
+	return errortypes.NewBadUserInputf(format, args...)
+}
+
+// setup connects to the storage once. Commands not needing it, like printing the configuration, work without it.
+func (c *CLI) setup() error {
+	if c.isSetUp {
+		return nil
+	}
+
+	if err := c.app.Setup(); err != nil {
+		return err
+	}
+
+	c.isSetUp = true
+
+	return nil
+}
+
+// shutdown flushes the spans of the command, before the process exits
+func (c *CLI) shutdown() {
+	if !c.isSetUp {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := c.app.Shutdown(ctx); err != nil {
+		c.log.Warnf("failed to flush traces: %v", err)
+	}
+}
+
+func (c *CLI) serve() error {
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	return c.app.Run()
+}
+
+// parseFlags parses the flags of a command. It fails, if there are more or less positional args than expected.
+func (c *CLI) parseFlags(flags *flag.FlagSet, args []string, positional ...string) ([]string, error) {
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(c.stderr, "usage: media-nexus %v", flags.Name())
+		for _, name := range positional {
+			_, _ = fmt.Fprintf(c.stderr, " <%v>", name)
+		}
+
+		_, _ = fmt.Fprintln(c.stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil, err
+	} else if err != nil {
+		return nil, errortypes.NewBadUserInputf("invalid arguments of %v: %v", flags.Name(), err)
+	}
+
+	// a trailing ... accepts any number of args, but at least one
+	variadic := len(positional) > 0 && strings.HasSuffix(positional[len(positional)-1], "...")
+	if (!variadic && flags.NArg() != len(positional)) || (variadic && flags.NArg() < len(positional)) {
+		flags.Usage()
+
+		if len(positional) < 1 {
+			return nil, errortypes.NewBadUserInputf("%v takes no arguments", flags.Name())
+		}
+
+		return nil, errortypes.NewBadUserInputf("%v expects %v", flags.Name(), strings.Join(positional, " "))
+	}
+
+	return flags.Args(), nil
+}
@@ -0,0 +1,122 @@
+package acli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"strings"
+	"time"
+)
+
+// findMediaOutput is the JSON output of media find. Pass NextAfter as -after to get the next page.
+type findMediaOutput struct {
+	Items     []*ahmodel.MediaItem `json:"items"`
+	NextAfter string               `json:"next_after,omitempty"`
+}
+
+func (c *CLI) getMedia(ctx context.Context, args []string) error {
+	args, err := c.parseFlags(flag.NewFlagSet("media get", flag.ContinueOnError), args, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	item, err := c.app.MediaService().GetMedia(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	output := ahmodel.MediaItemFromModel(item)
+
+	return c.print(output, func(w io.Writer) {
+		row(w, "ID", output.ID)
+		row(w, "NAME", output.Name)
+		row(w, "TAGS", strings.Join(output.TagIds, ","))
+		row(w, "MIME TYPE", output.MimeType)
+		row(w, "SIZE", output.Size)
+		row(w, "CHECKSUM", output.Checksum)
+		row(w, "STORED CHECKSUM", output.StoredChecksum)
+		row(w, "UPLOAD COMPLETE", output.UploadComplete)
+		row(w, "LAST UPDATE", output.LastUpdate.Format(time.RFC3339))
+		row(w, "FILE URL", output.FileURL)
+
+		if output.ImageInfo != nil {
+			row(w, "WIDTH", output.ImageInfo.Width)
+			row(w, "HEIGHT", output.ImageInfo.Height)
+		}
+	})
+}
+
+func (c *CLI) deleteMedia(ctx context.Context, args []string) error {
+	args, err := c.parseFlags(flag.NewFlagSet("media delete", flag.ContinueOnError), args, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	return c.app.MediaService().DeleteMedia(ctx, args[0])
+}
+
+func (c *CLI) findMedia(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("media find", flag.ContinueOnError)
+	tagID := flags.String("tag", "", "ID of the tag the media must have")
+	query := flags.String("query", "", "tag query like '(tagA AND tagB) OR NOT tagC' instead of -tag")
+	limit := flags.Int("limit", 100, "maximum number of media")
+	after := flags.String("after", "", "ID of the media to start after, to get the next page")
+
+	if _, err := c.parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if (*tagID == "") == (*query == "") {
+		flags.Usage()
+		return errortypes.NewBadUserInput("media find needs either -tag or -query")
+	}
+
+	if *limit < 1 {
+		return errortypes.NewBadUserInput("limit must be positive")
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	page := model.PageRequest{After: *after, Limit: *limit}
+
+	var items []model.MediaItem
+	var nextAfter string
+	var err error
+
+	if *tagID != "" {
+		items, nextAfter, err = c.app.MediaService().FindByTagID(ctx, *tagID, model.ImageFilter{}, page)
+	} else {
+		items, nextAfter, err = c.app.MediaService().FindByQuery(ctx, *query, model.ImageFilter{}, page)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	output := &findMediaOutput{ahmodel.CreateGetMediaResponse(items, "").Items, nextAfter}
+
+	return c.print(output, func(w io.Writer) {
+		row(w, "ID", "NAME", "MIME TYPE", "SIZE", "TAGS")
+		for _, item := range output.Items {
+			row(w, item.ID, item.Name, item.MimeType, item.Size, strings.Join(item.TagIds, ","))
+		}
+
+		if nextAfter != "" {
+			_, _ = fmt.Fprintf(c.stderr, "more media follows, continue with -after %v\n", nextAfter)
+		}
+	})
+}
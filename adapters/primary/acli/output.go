@@ -0,0 +1,35 @@
+package acli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// print writes value as indented JSON or lets writeTable write its rows separated by tabs, which are aligned to
+// columns.
+func (c *CLI) print(value interface{}, writeTable func(w io.Writer)) error {
+	if c.output == OutputJSON {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(value)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	writeTable(w)
+
+	return w.Flush()
+}
+
+// row writes the cells as one row of a table
+func row(w io.Writer, cells ...interface{}) {
+	values := make([]string, 0, len(cells))
+	for _, cell := range cells {
+		values = append(values, fmt.Sprint(cell))
+	}
+
+	_, _ = fmt.Fprintln(w, strings.Join(values, "\t"))
+}
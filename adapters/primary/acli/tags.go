@@ -0,0 +1,64 @@
+package acli
+
+import (
+	"context"
+	"flag"
+	"io"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"media-nexus/model"
+)
+
+func (c *CLI) listTags(ctx context.Context, args []string) error {
+	if _, err := c.parseFlags(flag.NewFlagSet("tags list", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	tags, err := c.app.TagRepo().ListTags(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.print(ahmodel.CreateGetTagsResponse(tags, "").Items, func(w io.Writer) {
+		row(w, "ID", "NAME")
+		for _, tag := range tags {
+			row(w, tag.ID, tag.Name)
+		}
+	})
+}
+
+func (c *CLI) createTag(ctx context.Context, args []string) error {
+	args, err := c.parseFlags(flag.NewFlagSet("tags create", flag.ContinueOnError), args, "name")
+	if err != nil {
+		return err
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	tagID, err := c.app.TagRepo().CreateTag(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.print(&ahmodel.PostTagsResponse{TagID: tagID}, func(w io.Writer) {
+		row(w, tagID)
+	})
+}
+
+func (c *CLI) deleteTags(ctx context.Context, args []string) error {
+	args, err := c.parseFlags(flag.NewFlagSet("tags delete", flag.ContinueOnError), args, "id...")
+	if err != nil {
+		return err
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	return c.app.TagRepo().DeleteTags(ctx, []model.TagID(args))
+}
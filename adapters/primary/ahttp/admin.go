@@ -0,0 +1,63 @@
+package ahttp
+
+import (
+	"context"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"media-nexus/httputils"
+	"media-nexus/logger"
+	"media-nexus/services"
+	"media-nexus/util"
+	"net/http"
+)
+
+type adminEndpoint struct {
+	reconciler services.Reconciler
+	log        logger.Logger
+}
+
+func (e *adminEndpoint) createContext(r *http.Request) context.Context {
+	return util.WithLogger(r.Context(), e.log)
+}
+
+// GetReconcileReport godoc
+//
+//	@Summary		Get reconciliation report
+//	@Description	retrieve the report of the last reconciliation of the media storage with the metadata run by the
+//	@Description	instance answering the request. Reconciliations run periodically on one of the instances.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	ahmodel.ReconcileReport
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/admin/reconcile [get]
+func (e *adminEndpoint) GetReconcileReport(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	report, err := e.reconciler.LastReport(ctx)
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	httputils.RespondWithJSON(http.StatusOK, ahmodel.CreateReconcileReport(report), w, e.log, true)
+}
+
+// Reconcile godoc
+//
+//	@Summary		Reconcile media storage with metadata
+//	@Description	delete stored media without metadata, that is older than the grace period, and report media with
+//	@Description	complete metadata, that isn't stored. Waits for the reconciliation to finish.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	ahmodel.ReconcileReport
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/admin/reconcile [post]
+func (e *adminEndpoint) Reconcile(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	report, err := e.reconciler.Reconcile(ctx)
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	httputils.RespondWithJSON(http.StatusOK, ahmodel.CreateReconcileReport(report), w, e.log, true)
+}
@@ -0,0 +1,39 @@
+package ahmodel
+
+import (
+	"media-nexus/model"
+	"time"
+)
+
+// ReconcileReport lists what a reconciliation of the media storage with the metadata found.
+type ReconcileReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// ScannedMedia counts the stored keys, including variants and media still being uploaded
+	ScannedMedia    int `json:"scanned_media"`
+	ScannedMetadata int `json:"scanned_metadata"`
+	// DeletedOrphans are the keys of stored media without metadata, that were older than the grace period
+	DeletedOrphans []string `json:"deleted_orphans"`
+	// MissingMedia are the IDs of media, that has complete metadata, but isn't stored
+	MissingMedia []string `json:"missing_media"`
+}
+
+func CreateReconcileReport(report *model.ReconcileReport) *ReconcileReport {
+	return &ReconcileReport{
+		StartedAt:       report.StartedAt,
+		FinishedAt:      report.FinishedAt,
+		ScannedMedia:    report.ScannedMedia,
+		ScannedMetadata: report.ScannedMetadata,
+		DeletedOrphans:  emptyIfNil(report.DeletedOrphans),
+		MissingMedia:    emptyIfNil(report.MissingMedia),
+	}
+}
+
+// emptyIfNil makes lists without entries show up as [] instead of null
+func emptyIfNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
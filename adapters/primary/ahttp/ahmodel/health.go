@@ -0,0 +1,38 @@
+package ahmodel
+
+import (
+	"media-nexus/model"
+	"time"
+)
+
+// HealthReport tells whether the dependencies can be used. It's healthy if all critical ones are.
+type HealthReport struct {
+	Healthy   bool                `json:"healthy"`
+	CheckedAt time.Time           `json:"checked_at"`
+	Checks    []HealthCheckResult `json:"checks"`
+}
+
+type HealthCheckResult struct {
+	Name       string `json:"name"     example:"mongodb"`
+	Critical   bool   `json:"critical"`
+	Healthy    bool   `json:"healthy"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+func CreateHealthReport(report *model.HealthReport) *HealthReport {
+	checks := make([]HealthCheckResult, 0, len(report.Checks))
+	for _, check := range report.Checks {
+		checks = append(checks, HealthCheckResult{
+			Name:       check.Name,
+			Critical:   check.Critical,
+			Healthy:    check.Healthy,
+			DurationMS: check.Duration.Milliseconds(),
+		})
+	}
+
+	return &HealthReport{
+		Healthy:   report.Healthy,
+		CheckedAt: report.CheckedAt,
+		Checks:    checks,
+	}
+}
@@ -1,34 +1,156 @@
 package ahmodel
 
-import "media-nexus/model"
+import (
+	"media-nexus/model"
+	"strconv"
+	"time"
+)
 
 type PostMediaResponse struct {
 	MediaID string `json:"media_id"`
 }
 
+type PostMediaUploadRequest struct {
+	Name   string   `json:"name"`
+	TagIDs []string `json:"tag_ids"`
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+	// Checksum is the hex encoded SHA-256 of the file
+	Checksum string `json:"checksum"`
+	// MimeType of the file like `image/png`. It is verified against the content on completion.
+	MimeType string `json:"mime_type"`
+}
+
+// PostMediaUploadResponse has no upload URL, if the media exists already.
+type PostMediaUploadResponse struct {
+	MediaID string `json:"media_id"`
+	// UploadURL takes the file with a PUT request
+	UploadURL string `json:"upload_url,omitempty"`
+	// UploadHeaders must be sent along with the PUT request
+	UploadHeaders map[string]string `json:"upload_headers,omitempty"`
+}
+
+func CreatePostMediaUploadResponse(mediaID model.MediaID, uploadURL *model.UploadURL) *PostMediaUploadResponse {
+	response := &PostMediaUploadResponse{MediaID: mediaID}
+	if uploadURL != nil {
+		response.UploadURL = uploadURL.URL
+		response.UploadHeaders = uploadURL.Headers
+	}
+
+	return response
+}
+
+// PatchMediaRequest changes the given fields only. Either replace all tag IDs with tag_ids or add and remove
+// single ones.
+type PatchMediaRequest struct {
+	Name         *string  `json:"name,omitempty"`
+	TagIDs       []string `json:"tag_ids,omitempty"`
+	AddTagIDs    []string `json:"add_tag_ids,omitempty"`
+	RemoveTagIDs []string `json:"remove_tag_ids,omitempty"`
+}
+
+func (r *PatchMediaRequest) ToModel() model.MediaMetadataUpdate {
+	return model.MediaMetadataUpdate{
+		Name:         r.Name,
+		TagIDs:       r.TagIDs,
+		AddTagIDs:    r.AddTagIDs,
+		RemoveTagIDs: r.RemoveTagIDs,
+	}
+}
+
 type GetMediaResponse struct {
 	Items []*MediaItem
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type MediaItem struct {
-	Name    string   `json:"name"`
-	TagIds  []string `json:"tag_ids"`
-	FileURL string   `json:"file_url"`
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	TagIds         []string   `json:"tag_ids"`
+	Checksum       string     `json:"checksum"`
+	StoredChecksum string     `json:"stored_checksum"`
+	Size           int64      `json:"size"`
+	MimeType       string     `json:"mime_type,omitempty"`
+	ImageInfo      *ImageInfo `json:"image_info,omitempty"`
+	UploadComplete bool       `json:"upload_complete"`
+	LastUpdate     time.Time  `json:"last_update"`
+	FileURL        string     `json:"file_url"`
+	// Variants maps the sizes of the scaled down variants of images to their URLs
+	Variants map[string]string `json:"variants,omitempty"`
+}
+
+// ImageInfo is read from the image headers on upload. Fields missing in the EXIF metadata are left out.
+type ImageInfo struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Orientation is the EXIF orientation from 1 to 8
+	Orientation int             `json:"orientation,omitempty"`
+	CaptureTime *time.Time      `json:"capture_time,omitempty"`
+	CameraModel string          `json:"camera_model,omitempty"`
+	GPS         *GPSCoordinates `json:"gps,omitempty"`
+}
+
+type GPSCoordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func ImageInfoFromModel(info *model.ImageInfo) *ImageInfo {
+	if info == nil {
+		return nil
+	}
+
+	result := &ImageInfo{
+		Width:       info.Width,
+		Height:      info.Height,
+		Orientation: info.Orientation,
+		CaptureTime: info.CaptureTime,
+		CameraModel: info.CameraModel,
+	}
+
+	if info.GPS != nil {
+		result.GPS = &GPSCoordinates{info.GPS.Latitude, info.GPS.Longitude}
+	}
+
+	return result
 }
 
 func MediaItemFromModel(item model.MediaItem) *MediaItem {
 	return &MediaItem{
-		Name:    item.Name(),
-		TagIds:  item.TagIDs(),
-		FileURL: item.FileURL(),
+		ID:             item.ID(),
+		Name:           item.Name(),
+		TagIds:         item.TagIDs(),
+		Checksum:       item.Checksum(),
+		StoredChecksum: item.StoredChecksum(),
+		Size:           item.Size(),
+		MimeType:       item.MimeType(),
+		ImageInfo:      ImageInfoFromModel(item.ImageInfo()),
+		UploadComplete: item.UploadComplete(),
+		LastUpdate:     item.LastUpdate(),
+		FileURL:        item.FileURL(),
+		Variants:       variantURLsFromModel(item.VariantURLs()),
+	}
+}
+
+func variantURLsFromModel(urls map[int]string) map[string]string {
+	if urls == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(urls))
+	for size, url := range urls {
+		result[strconv.Itoa(size)] = url
 	}
+
+	return result
 }
 
-func CreateGetMediaResponse(items []model.MediaItem) *GetMediaResponse {
+func CreateGetMediaResponse(items []model.MediaItem, nextCursor string) *GetMediaResponse {
 	oMediaItems := make([]*MediaItem, 0, len(items))
 	for _, mediaItem := range items {
 		oMediaItems = append(oMediaItems, MediaItemFromModel(mediaItem))
 	}
 
-	return &GetMediaResponse{oMediaItems}
+	return &GetMediaResponse{oMediaItems, nextCursor}
 }
@@ -15,13 +15,19 @@ type Tag struct {
 	Name string `json:"name"`
 }
 
-func CreateGetTagsResponse(tags []*model.Tag) []*Tag {
-	response := make([]*Tag, 0, len(tags))
+type GetTagsResponse struct {
+	Items []*Tag `json:"items"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func CreateGetTagsResponse(tags []*model.Tag, nextCursor string) *GetTagsResponse {
+	items := make([]*Tag, 0, len(tags))
 
 	for _, tag := range tags {
 		oTag := &Tag{tag.ID, tag.Name}
-		response = append(response, oTag)
+		items = append(items, oTag)
 	}
 
-	return response
+	return &GetTagsResponse{items, nextCursor}
 }
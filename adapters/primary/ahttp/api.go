@@ -3,7 +3,9 @@ package ahttp
 import (
 	"context"
 	"fmt"
+	"media-nexus/httputils"
 	"media-nexus/logger"
+	"media-nexus/metrics"
 	"media-nexus/ports"
 	"media-nexus/services"
 	"net/http"
@@ -16,6 +18,7 @@ import (
 
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //	@title			media-nexus API
@@ -32,30 +35,83 @@ import (
 // @BasePath	/api/v1
 func StartAPI(
 	log logger.Logger,
+	metrics metrics.Metrics,
+	tracerProvider trace.TracerProvider,
 	baseURL string,
 	port int,
 	mediaService services.MediaService,
+	uploadService services.UploadService,
+	reconciler services.Reconciler,
+	healthService services.HealthService,
 	tags ports.TagRepository,
+	mediaDownloader ports.SignedMediaDownloader,
+	mediaUploader ports.SignedMediaUploader,
+	cursorSigningKey []byte,
 ) error {
 	r := mux.NewRouter()
 
-	healthEndpoint := &healthEndpoint{log}
+	middlewares := []mux.MiddlewareFunc{traceRequests(tracerProvider), instrument(metrics)}
+	r.Use(middlewares...)
+
+	// not below /api/v1, where scrapers look for it by default
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+
+	healthEndpoint := &healthEndpoint{healthService, log}
 	r.HandleFunc("/api/v1/health/live", healthEndpoint.GetHealthLive).Methods(http.MethodGet)
 	r.HandleFunc("/api/v1/health/ready", healthEndpoint.GetHealthReady).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/health/startup", healthEndpoint.GetHealthStartup).Methods(http.MethodGet)
+
+	pagination := newPagination(cursorSigningKey)
+
+	mediaEndpoint := &mediaEndpoint{mediaService, log, metrics, 200, 500, 200, 200, 2000, pagination}
+
+	// registered before media/{id}, so uploads and tus aren't taken for media IDs
+	r.HandleFunc("/api/v1/media/uploads", mediaEndpoint.CreateDirectUpload).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/media/uploads/{id}/complete", mediaEndpoint.CompleteDirectUpload).Methods(http.MethodPost)
+
+	tusEndpoint := &tusEndpoint{uploadService, log, metrics, 10240, 500, 200, 200}
+	r.HandleFunc("/api/v1/media/tus", tusEndpoint.GetTusOptions).Methods(http.MethodOptions)
+	r.HandleFunc("/api/v1/media/tus", tusEndpoint.CreateUpload).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/media/tus/{id}", tusEndpoint.GetUpload).Methods(http.MethodHead)
+	r.HandleFunc("/api/v1/media/tus/{id}", tusEndpoint.PatchUpload).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/media/tus/{id}", tusEndpoint.DeleteUpload).Methods(http.MethodDelete)
 
-	mediaEndpoint := &mediaEndpoint{mediaService, log, 200, 500, 200}
 	r.HandleFunc("/api/v1/media", mediaEndpoint.GetMedia).Methods(http.MethodGet)
 	r.HandleFunc("/api/v1/media", mediaEndpoint.CreateMedia).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/media/{id}", mediaEndpoint.GetMediaByID).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/media/{id}", mediaEndpoint.PatchMedia).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/media/{id}", mediaEndpoint.DeleteMedia).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v1/media/{id}/content", mediaEndpoint.GetMediaContent).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/media/{id}/variants/{size}", mediaEndpoint.GetMediaVariant).Methods(http.MethodGet)
 
-	tagsEndpoint := &tagsEndpoint{tags, log, 500}
+	tagsEndpoint := &tagsEndpoint{tags, log, 500, pagination}
 	r.HandleFunc("/api/v1/tags", tagsEndpoint.ListTags).Methods(http.MethodGet)
 	r.HandleFunc("/api/v1/tags", tagsEndpoint.CreateTag).Methods(http.MethodPost)
 
+	adminEndpoint := &adminEndpoint{reconciler, log}
+	r.HandleFunc("/api/v1/admin/reconcile", adminEndpoint.GetReconcileReport).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/admin/reconcile", adminEndpoint.Reconcile).Methods(http.MethodPost)
+
+	if mediaDownloader != nil {
+		blobsEndpoint := &blobsEndpoint{mediaDownloader, mediaUploader, log, metrics, 200}
+		r.HandleFunc("/api/v1/blobs/{key}", blobsEndpoint.GetBlob).Methods(http.MethodGet)
+		r.HandleFunc("/api/v1/blobs/{key}", blobsEndpoint.PutBlob).Methods(http.MethodPut)
+	}
+
 	r.PathPrefix("/swagger").Handler(createSwaggerHandler(baseURL, port)).Methods(http.MethodGet)
 
+	// the middleware of the router doesn't run for requests matching no route
+	r.NotFoundHandler = withMiddlewares(middlewares, func(w http.ResponseWriter, r *http.Request) {
+		httputils.RespondWithError(w, http.StatusNotFound, "no route for %v", r.URL.Path)
+	})
+	r.MethodNotAllowedHandler = withMiddlewares(middlewares, func(w http.ResponseWriter, r *http.Request) {
+		httputils.RespondWithError(w, http.StatusMethodNotAllowed, "method %v not allowed for %v", r.Method, r.URL.Path)
+	})
+
 	srv := &http.Server{
-		Addr:              fmt.Sprintf(":%v", port),
-		Handler:           r,
+		Addr: fmt.Sprintf(":%v", port),
+		// wraps the router, so requests not matching any route get a request ID as well
+		Handler:           httputils.RequestID(r),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
@@ -78,6 +134,16 @@ func StartAPI(
 	return srv.Shutdown(ctx)
 }
 
+// withMiddlewares wraps the handler in the middlewares the way the router does, the first one is the outermost
+func withMiddlewares(middlewares []mux.MiddlewareFunc, handler http.HandlerFunc) http.Handler {
+	var wrapped http.Handler = handler
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		wrapped = middlewares[i](wrapped)
+	}
+
+	return wrapped
+}
+
 func createSwaggerHandler(baseURL string, port int) http.HandlerFunc {
 	url := fmt.Sprintf("%v:%v/swagger/doc.json", baseURL, port)
 	return httpSwagger.Handler(httpSwagger.URL(url))
@@ -0,0 +1,134 @@
+package ahttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"media-nexus/httputils"
+	"media-nexus/logger"
+	"media-nexus/metrics"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type blobsEndpoint struct {
+	downloader          ports.SignedMediaDownloader
+	uploader            ports.SignedMediaUploader
+	log                 logger.Logger
+	metrics             metrics.Metrics
+	maxUploadFileSizeMB int64
+}
+
+func (e *blobsEndpoint) createContext(r *http.Request) context.Context {
+	return util.WithLogger(r.Context(), e.log)
+}
+
+// GetBlob godoc
+//
+//	@Summary		Download media blob
+//	@Description	download the blob of a media item through a signed URL as returned in file_url.
+//	@Description	Only available when media is stored on the local file system.
+//	@Tags			media
+//	@Produce		octet-stream
+//	@Param			key			path		string	true	"key of the blob"
+//	@Param			expires		query		int		true	"expiry of the URL as unix timestamp"
+//	@Param			signature	query		string	true	"signature of the URL"
+//	@Success		200			{file}		binary
+//	@Failure		400			{object}	httputils.Problem
+//	@Failure		404			{object}	httputils.Problem
+//	@Failure		500			{object}	httputils.Problem
+//	@Router			/blobs/{key} [get]
+func (e *blobsEndpoint) GetBlob(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	key := mux.Vars(r)["key"]
+	query := r.URL.Query()
+
+	expiresUnix, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		httputils.RespondWithBadParameter(w, "expires", err)
+		return
+	}
+
+	blob, err := e.downloader.OpenSignedMedia(ctx, key, time.Unix(expiresUnix, 0), query.Get("signature"))
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+	defer blob.Close()
+
+	// the file system and the memory don't store the type. It was sniffed on upload, so sniffing again gives the same.
+	reader := bufio.NewReaderSize(blob, util.MimeTypeSniffLength)
+	head, _ := reader.Peek(util.MimeTypeSniffLength)
+
+	w.Header().Set(httputils.HeaderContentType, util.DetectMimeType(head))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		e.log.Errorf("failed to write blob %v: %v", key, err)
+	}
+}
+
+// PutBlob godoc
+//
+//	@Summary		Upload media blob
+//	@Description	upload the blob of a media item through a signed URL as returned in upload_url.
+//	@Description	Only available when media is stored on the local file system. Fails with 409, if the blob was
+//	@Description	uploaded already.
+//	@Tags			media
+//	@Accept			octet-stream
+//	@Param			key			path	string	true	"key of the blob"
+//	@Param			expires		query	int		true	"expiry of the URL as unix timestamp"
+//	@Param			signature	query	string	true	"signature of the URL"
+//	@Success		200
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		409	{object}	httputils.Problem
+//	@Failure		413	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/blobs/{key} [put]
+func (e *blobsEndpoint) PutBlob(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	key := mux.Vars(r)["key"]
+	query := r.URL.Query()
+
+	expiresUnix, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		httputils.RespondWithBadParameter(w, "expires", err)
+		return
+	}
+
+	started := time.Now()
+	body := &requestBodyReader{reader: http.MaxBytesReader(w, r.Body, e.maxUploadFileSizeMB<<20)}
+
+	err = e.uploader.CreateSignedMedia(ctx, key, time.Unix(expiresUnix, 0), query.Get("signature"), body)
+	e.metrics.ObserveUpload(metrics.UploadDirect, body.size, time.Since(started))
+
+	// if reading the request failed, it's on the client, whatever the repository made of it
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(body.err, &maxBytesErr) {
+		httputils.RespondWithError(
+			w,
+			http.StatusRequestEntityTooLarge,
+			"File is too large. Maximum is %v MB",
+			e.maxUploadFileSizeMB,
+		)
+		return
+	}
+
+	if body.err != nil {
+		httputils.RespondWithError(w, http.StatusBadRequest, "failed to read the file: %v", body.err)
+		return
+	}
+
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -1,18 +1,28 @@
 package ahttp
 
 import (
+	"context"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"media-nexus/httputils"
 	"media-nexus/logger"
+	"media-nexus/services"
+	"media-nexus/util"
 	"net/http"
 )
 
 type healthEndpoint struct {
-	log logger.Logger
+	healthService services.HealthService
+	log           logger.Logger
+}
+
+func (e *healthEndpoint) createContext(r *http.Request) context.Context {
+	return util.WithLogger(r.Context(), e.log)
 }
 
 // GetHealthLive godoc
 //
 //	@Summary	Retrieve live health status of server
-//	@Tags		tags
+//	@Tags		health
 //	@Produce	json
 //	@Success	204
 //	@Router		/health/live [get]
@@ -22,11 +32,41 @@ func (e *healthEndpoint) GetHealthLive(w http.ResponseWriter, r *http.Request) {
 
 // GetHealthReady godoc
 //
-//	@Summary	Retrieve ready health status of server
-//	@Tags		tags
-//	@Produce	json
-//	@Success	204
-//	@Router		/health/ready [get]
+//	@Summary		Retrieve ready health status of server
+//	@Description	check MongoDB and S3, depending on the configured backends. Fails with 503, if a critical one
+//	@Description	fails. Reports are cached for a few seconds.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	ahmodel.HealthReport
+//	@Failure		503	{object}	ahmodel.HealthReport
+//	@Router			/health/ready [get]
 func (e *healthEndpoint) GetHealthReady(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	report := e.healthService.Ready(ctx)
+
+	status := http.StatusOK
+	if !report.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	httputils.RespondWithJSON(status, ahmodel.CreateHealthReport(report), w, e.log, true)
+}
+
+// GetHealthStartup godoc
+//
+//	@Summary		Retrieve startup status of server
+//	@Description	succeeds once the storage is set up, e.g. the indices are created
+//	@Tags			health
+//	@Produce		json
+//	@Success		204
+//	@Failure		503	{object}	httputils.Problem
+//	@Router			/health/startup [get]
+func (e *healthEndpoint) GetHealthStartup(w http.ResponseWriter, r *http.Request) {
+	if !e.healthService.Started() {
+		httputils.RespondWithError(w, http.StatusServiceUnavailable, "the storage is still being set up")
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,92 @@
+package ahttp
+
+import (
+	"media-nexus/errortypes"
+	"media-nexus/httputils"
+	"media-nexus/model"
+	"net/url"
+	"strings"
+)
+
+var imageFilterParameters = []string{
+	"min_width",
+	"max_width",
+	"min_height",
+	"max_height",
+	"camera_model",
+	"captured_after",
+	"captured_before",
+	"has_gps",
+}
+
+// parseImageFilter reads the optional query parameters filtering media by their image info
+func parseImageFilter(query url.Values) (model.ImageFilter, error) {
+	var filter model.ImageFilter
+
+	dimensions := map[string]*int{
+		"min_width":  &filter.MinWidth,
+		"max_width":  &filter.MaxWidth,
+		"min_height": &filter.MinHeight,
+		"max_height": &filter.MaxHeight,
+	}
+
+	for name, value := range dimensions {
+		if !query.Has(name) {
+			continue
+		}
+
+		dimension, err := httputils.ParseInt32QueryParameter(query, name)
+		if err != nil {
+			return filter, err
+		}
+
+		if dimension < 1 {
+			return filter, errortypes.NewBadUserInputFieldf(name, "%v has to be positive", name)
+		}
+
+		*value = int(dimension)
+	}
+
+	filter.CameraModel = query.Get("camera_model")
+
+	if query.Has("captured_after") {
+		capturedAfter, err := httputils.ParseTimeQueryParameter(query, "captured_after")
+		if err != nil {
+			return filter, err
+		}
+
+		filter.CapturedAfter = &capturedAfter
+	}
+
+	if query.Has("captured_before") {
+		capturedBefore, err := httputils.ParseTimeQueryParameter(query, "captured_before")
+		if err != nil {
+			return filter, err
+		}
+
+		filter.CapturedBefore = &capturedBefore
+	}
+
+	if query.Has("has_gps") {
+		hasGPS, err := httputils.ParseBoolQueryParameter(query, "has_gps")
+		if err != nil {
+			return filter, err
+		}
+
+		filter.HasGPS = &hasGPS
+	}
+
+	return filter, nil
+}
+
+// imageFilterScope binds cursors to the image filter of the search
+func imageFilterScope(query url.Values) string {
+	var scope strings.Builder
+	for _, name := range imageFilterParameters {
+		if query.Has(name) {
+			scope.WriteString("\n" + name + "\n" + query.Get(name))
+		}
+	}
+
+	return scope.String()
+}
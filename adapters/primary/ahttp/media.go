@@ -2,22 +2,34 @@ package ahttp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"media-nexus/adapters/primary/ahttp/ahmodel"
 	"media-nexus/httputils"
 	"media-nexus/logger"
+	"media-nexus/metrics"
 	"media-nexus/model"
 	"media-nexus/services"
 	"media-nexus/util"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
 type mediaEndpoint struct {
 	mediaService        services.MediaService
 	log                 logger.Logger
+	metrics             metrics.Metrics
 	maxUploadFileSizeMB int64
 	mediaNameMaxLen     int
 	tagIDMaxLen         int
+	mediaIDMaxLen       int
+	queryMaxLen         int
+	pagination          *pagination
 }
 
 //nolint:unused,deadcode
@@ -35,65 +47,253 @@ func (e *mediaEndpoint) createContext(r *http.Request) context.Context {
 // CreateMedia godoc
 //
 //	@Summary		Create media
-//	@Description	create a new media with a list of tags and a name
+//	@Description	create a new media with a list of tags and a name. The file is streamed, so it has to be the last
+//	@Description	part of the form, after name and tag_ids[]. The type of the file is sniffed from its content
+//	@Description	and must be one of the types allowed by the deployment.
 //	@Tags			media
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Param			request	body		postMediaRequest	true	"media to be created"
 //	@Success		200		{object}	ahmodel.PostMediaResponse
-//	@Failure		400		{object}	string
+//	@Failure		400		{object}	httputils.Problem
+//	@Failure		413		{object}	httputils.Problem
+//	@Failure		415		{object}	httputils.Problem
+//	@Failure		500		{object}	httputils.Problem
 //	@Router			/media [post]
 func (e *mediaEndpoint) CreateMedia(w http.ResponseWriter, r *http.Request) {
 	ctx := e.createContext(r)
 
-	if err := r.ParseMultipartForm(e.maxUploadFileSizeMB << 20); err != nil {
-		http.Error(w, fmt.Sprintf("invalid multipart form: %v", err), http.StatusBadRequest)
+	r.Body = http.MaxBytesReader(w, r.Body, e.maxUploadFileSizeMB<<20)
+
+	multipartReader, err := r.MultipartReader()
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusBadRequest, "invalid multipart form: %v", err)
 		return
 	}
 
-	name := r.FormValue("name")
+	name := ""
+	tagIDList := make([]model.TagID, 0)
+
+	for {
+		part, err := multipartReader.NextPart()
+		if err == io.EOF {
+			httputils.RespondWithError(w, http.StatusBadRequest, "Error retrieving the file")
+			return
+		}
+
+		if err != nil {
+			httputils.RespondWithError(w, http.StatusBadRequest, "invalid multipart form: %v", err)
+			return
+		}
+
+		switch part.FormName() {
+		case "name":
+			value, ok := readFormValue(part, e.mediaNameMaxLen, "File name", w)
+			if !ok {
+				return
+			}
+
+			name = value
+		case "tag_ids[]":
+			value, ok := readFormValue(part, e.tagIDMaxLen, "Tag ID", w)
+			if !ok {
+				return
+			}
+
+			tagIDList = append(tagIDList, model.TagID(value))
+		case "file":
+			e.createMedia(ctx, name, tagIDList, part, w)
+			return
+		}
+	}
+}
+
+func (e *mediaEndpoint) createMedia(
+	ctx context.Context,
+	name string,
+	tagIDList []model.TagID,
+	file io.Reader,
+	w http.ResponseWriter,
+) {
 	if name == "" {
-		httputils.RespondWithError(w, http.StatusBadRequest, "File name is required")
+		httputils.RespondWithFieldError(w, "name", "File name is required")
+		return
+	}
+
+	started := time.Now()
+	body := &requestBodyReader{reader: file}
+
+	mediaID, err := e.mediaService.CreateMedia(ctx, name, tagIDList, body)
+	e.metrics.ObserveUpload(metrics.UploadForm, body.size, time.Since(started))
+
+	// if reading the request failed, it's on the client, whatever the service made of it
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(body.err, &maxBytesErr) {
+		httputils.RespondWithError(
+			w,
+			http.StatusRequestEntityTooLarge,
+			"File is too large. Maximum is %v MB",
+			e.maxUploadFileSizeMB,
+		)
 		return
 	}
 
-	if len(name) > e.mediaNameMaxLen {
-		httputils.RespondWithError(w, http.StatusBadRequest, "File name is too long. Maximum is %v", e.mediaNameMaxLen)
+	if body.err != nil {
+		httputils.RespondWithError(w, http.StatusBadRequest, "failed to read the file: %v", body.err)
 		return
 	}
 
-	rawTagIDs := r.Form["tag_ids[]"]
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
 
-	tagIDList := make([]model.TagID, 0, len(rawTagIDs))
-	for _, tagID := range rawTagIDs {
-		if !e.validateTagID(tagID, w) {
+	response := &ahmodel.PostMediaResponse{
+		MediaID: mediaID,
+	}
+
+	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, true)
+}
+
+// readFormValue reads at most maxLen bytes, so a client can't make us buffer arbitrary large form values.
+func readFormValue(part *multipart.Part, maxLen int, description string, w http.ResponseWriter) (string, bool) {
+	value, err := io.ReadAll(io.LimitReader(part, int64(maxLen)+1))
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusBadRequest, "invalid multipart form: %v", err)
+		return "", false
+	}
+
+	if len(value) > maxLen {
+		httputils.RespondWithFieldError(w, part.FormName(), "%v is too long. Maximum is %v", description, maxLen)
+		return "", false
+	}
+
+	return string(value), true
+}
+
+// requestBodyReader remembers read errors, so we can tell them apart from errors of the storage.
+type requestBodyReader struct {
+	reader io.Reader
+	err    error
+	// size counts the bytes read
+	size int64
+}
+
+func (r *requestBodyReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// CreateDirectUpload godoc
+//
+//	@Summary		Create direct media upload
+//	@Description	create a media, that the client uploads on its own with a PUT request to upload_url, sending
+//	@Description	upload_headers along. Then complete the upload. If the media exists already, there is no
+//	@Description	upload_url and nothing left to do.
+//	@Tags			media
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		ahmodel.PostMediaUploadRequest	true	"media to be uploaded"
+//	@Success		200		{object}	ahmodel.PostMediaUploadResponse
+//	@Failure		400		{object}	httputils.Problem
+//	@Failure		409		{object}	httputils.Problem
+//	@Failure		413		{object}	httputils.Problem
+//	@Failure		415		{object}	httputils.Problem
+//	@Failure		500		{object}	httputils.Problem
+//	@Router			/media/uploads [post]
+func (e *mediaEndpoint) CreateDirectUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	var data ahmodel.PostMediaUploadRequest
+	err := httputils.ParseJSONRequestBody(r.Body, &data)
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	if data.Name == "" {
+		httputils.RespondWithFieldError(w, "name", "File name is required")
+		return
+	}
+
+	if len(data.Name) > e.mediaNameMaxLen {
+		httputils.RespondWithFieldError(w, "name", "File name is too long. Maximum is %v", e.mediaNameMaxLen)
+		return
+	}
+
+	for _, tagID := range data.TagIDs {
+		if !e.validateTagID("tag_ids", tagID, w) {
 			return
 		}
-		tagIDList = append(tagIDList, model.TagID(tagID))
 	}
 
-	file, _, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "Error retrieving the file", http.StatusBadRequest)
+	if data.Size > e.maxUploadFileSizeMB<<20 {
+		httputils.RespondWithError(
+			w,
+			http.StatusRequestEntityTooLarge,
+			"File is too large. Maximum is %v MB",
+			e.maxUploadFileSizeMB,
+		)
 		return
 	}
-	defer file.Close()
 
-	mediaID, err := e.mediaService.CreateMedia(ctx, name, tagIDList, file)
+	mediaID, uploadURL, err := e.mediaService.CreateDirectUpload(
+		ctx,
+		data.Name,
+		data.TagIDs,
+		data.Size,
+		data.Checksum,
+		data.MimeType,
+	)
 	if httputils.HandleError(err, w, e.log) {
 		return
 	}
 
-	response := &ahmodel.PostMediaResponse{
-		MediaID: mediaID,
+	response := ahmodel.CreatePostMediaUploadResponse(mediaID, uploadURL)
+
+	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, false)
+}
+
+// CompleteDirectUpload godoc
+//
+//	@Summary		Complete direct media upload
+//	@Description	complete a media uploaded with the upload_url. Fails if the file doesn't match size, checksum
+//	@Description	and MIME type announced. Safe to retry.
+//	@Tags			media
+//	@Produce		json
+//	@Param			id	path		string	true	"media ID"
+//	@Success		200	{object}	ahmodel.MediaItem
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		409	{object}	httputils.Problem
+//	@Failure		415	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/uploads/{id}/complete [post]
+func (e *mediaEndpoint) CompleteDirectUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	mediaID := mux.Vars(r)["id"]
+	if !e.validateMediaID(mediaID, w) {
+		return
 	}
 
-	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, true)
+	mediaItem, err := e.mediaService.CompleteDirectUpload(ctx, model.MediaID(mediaID))
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	response := ahmodel.MediaItemFromModel(mediaItem)
+
+	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, false)
 }
 
-func (e *mediaEndpoint) validateTagID(tagID string, w http.ResponseWriter) bool {
+// validateTagID responds with a field error of field if the tag ID is invalid
+func (e *mediaEndpoint) validateTagID(field string, tagID string, w http.ResponseWriter) bool {
 	if len(tagID) > e.tagIDMaxLen {
-		httputils.RespondWithError(w, http.StatusBadRequest, "Tag ID is too long. Maximum is %v", e.tagIDMaxLen)
+		httputils.RespondWithFieldError(w, field, "Tag ID is too long. Maximum is %v", e.tagIDMaxLen)
 		return false
 	}
 
@@ -103,33 +303,299 @@ func (e *mediaEndpoint) validateTagID(tagID string, w http.ResponseWriter) bool
 // GetMedia godoc
 //
 //	@Summary		Query media items
-//	@Description	query media items based on some parameters
+//	@Description	query media items either by a single tag ID or by a query combining tag IDs with AND, OR, NOT
+//	@Description	and parentheses, e.g. `(tagA AND tagB) OR NOT tagC`. Items are returned in pages ordered by
+//	@Description	ID. Pass next_cursor as cursor to get the next page. The image filters only match images. Tag
+//	@Description	IDs with other characters than letters, digits, _ and -, or named like an operator, are put in
+//	@Description	double quotes, escaping " and \ with a backslash.
 //	@Tags			media
 //	@Produce		json
-//	@Param			tag_id	query		string	true	"tag ID to search for"
-//	@Success		200		{object}	ahmodel.GetMediaResponse
-//	@Failure		400		{object}	string
+//	@Param			tag_id			query		string	false	"tag ID to search for"
+//	@Param			q				query		string	false	"tag query to search for"
+//	@Param			min_width		query		int		false	"minimum image width in pixels"
+//	@Param			max_width		query		int		false	"maximum image width in pixels"
+//	@Param			min_height		query		int		false	"minimum image height in pixels"
+//	@Param			max_height		query		int		false	"maximum image height in pixels"
+//	@Param			camera_model	query		string	false	"camera model the image was taken with"
+//	@Param			captured_after	query		string	false	"RFC 3339 time the image was captured after"
+//	@Param			captured_before	query		string	false	"RFC 3339 time the image was captured before"
+//	@Param			has_gps			query		bool	false	"whether the image has GPS coordinates"
+//	@Param			limit			query		int		false	"maximum number of media items per page, 100 by default"
+//	@Param			cursor			query		string	false	"next_cursor of the previous page"
+//	@Success		200				{object}	ahmodel.GetMediaResponse
+//	@Failure		400				{object}	httputils.Problem
+//	@Failure		500				{object}	httputils.Problem
 //	@Router			/media [get]
 func (e *mediaEndpoint) GetMedia(w http.ResponseWriter, r *http.Request) {
 	ctx := e.createContext(r)
 
 	tagID := r.URL.Query().Get("tag_id")
+	q := r.URL.Query().Get("q")
+
+	if (tagID == "") == (q == "") {
+		httputils.RespondWithError(w, http.StatusBadRequest, "either tag_id or q parameter is required")
+		return
+	}
+
+	if tagID != "" && !e.validateTagID("tag_id", tagID, w) {
+		return
+	}
+
+	if len(q) > e.queryMaxLen {
+		httputils.RespondWithFieldError(w, "q", "Query is too long. Maximum is %v", e.queryMaxLen)
+		return
+	}
 
-	if tagID == "" {
-		httputils.RespondWithError(w, http.StatusBadRequest, "tag_id parameter is required")
+	filter, err := parseImageFilter(r.URL.Query())
+	if httputils.HandleError(err, w, e.log) {
 		return
 	}
 
-	if !e.validateTagID(tagID, w) {
+	// bind cursors to the search, so they can't be used to page through another one
+	scope := "media\ntag_id\n" + tagID
+	if q != "" {
+		scope = "media\nq\n" + q
+	}
+
+	scope += imageFilterScope(r.URL.Query())
+
+	page, err := e.pagination.parsePageRequest(r.URL.Query(), scope)
+	if httputils.HandleError(err, w, e.log) {
 		return
 	}
 
-	mediaItems, err := e.mediaService.FindByTagID(ctx, model.TagID(tagID))
+	var mediaItems []model.MediaItem
+	var nextAfter string
+
+	if tagID != "" {
+		mediaItems, nextAfter, err = e.mediaService.FindByTagID(ctx, model.TagID(tagID), filter, page)
+	} else {
+		mediaItems, nextAfter, err = e.mediaService.FindByQuery(ctx, q, filter, page)
+	}
+
 	if httputils.HandleError(err, w, e.log) {
 		return
 	}
 
-	response := ahmodel.CreateGetMediaResponse(mediaItems)
+	response := ahmodel.CreateGetMediaResponse(mediaItems, e.pagination.nextCursor(nextAfter, scope))
 
 	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, false)
 }
+
+func (e *mediaEndpoint) validateMediaID(mediaID string, w http.ResponseWriter) bool {
+	if len(mediaID) > e.mediaIDMaxLen {
+		httputils.RespondWithFieldError(w, "id", "Media ID is too long. Maximum is %v", e.mediaIDMaxLen)
+		return false
+	}
+
+	return true
+}
+
+// GetMediaByID godoc
+//
+//	@Summary		Get media item
+//	@Description	get a single media item by its ID
+//	@Tags			media
+//	@Produce		json
+//	@Param			id	path		string	true	"media ID"
+//	@Success		200	{object}	ahmodel.MediaItem
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/{id} [get]
+func (e *mediaEndpoint) GetMediaByID(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	mediaID := mux.Vars(r)["id"]
+	if !e.validateMediaID(mediaID, w) {
+		return
+	}
+
+	mediaItem, err := e.mediaService.GetMedia(ctx, model.MediaID(mediaID))
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	response := ahmodel.MediaItemFromModel(mediaItem)
+
+	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, false)
+}
+
+// GetMediaContent godoc
+//
+//	@Summary		Download media content
+//	@Description	download the file of a media item through this service. Supports Range requests as well as
+//	@Description	If-None-Match and If-Range with the stored checksum as ETag and If-Modified-Since with the time
+//	@Description	the upload completed. The content type is the MIME type sniffed on upload.
+//	@Tags			media
+//	@Produce		octet-stream
+//	@Param			id					path	string	true	"media ID"
+//	@Param			Range				header	string	false	"byte ranges to download, e.g. bytes=0-1023"
+//	@Param			If-None-Match		header	string	false	"ETag of a cached file"
+//	@Param			If-Modified-Since	header	string	false	"time a cached file was last modified"
+//	@Param			If-Range			header	string	false	"ETag the Range must match, else the whole file is sent"
+//	@Success		200					{file}	binary
+//	@Success		206					{file}	binary
+//	@Success		304
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		409	{object}	httputils.Problem
+//	@Failure		416	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/{id}/content [get]
+func (e *mediaEndpoint) GetMediaContent(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	mediaID := mux.Vars(r)["id"]
+	if !e.validateMediaID(mediaID, w) {
+		return
+	}
+
+	metadata, content, err := e.mediaService.OpenMediaContent(ctx, model.MediaID(mediaID))
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+	defer content.Close()
+
+	contentType := metadata.MimeType()
+	if contentType == "" {
+		contentType = httputils.ContentTypeOctetStream
+	}
+
+	w.Header().Set(httputils.HeaderContentType, contentType)
+	w.Header().Set(httputils.HeaderETag, fmt.Sprintf("%q", metadata.StoredChecksum()))
+	// the media ID is derived from the checksum, so the content behind it never changes
+	w.Header().Set(httputils.HeaderCacheControl, "public, max-age=31536000, immutable")
+
+	// handles Range, the conditional headers and Content-Length for us. The content was last modified, when the
+	// upload completed. Updates of the metadata leave it alone.
+	http.ServeContent(w, r, "", metadata.UploadCompletedAt(), content)
+}
+
+// GetMediaVariant godoc
+//
+//	@Summary		Get a scaled down variant of an image
+//	@Description	redirects to the variant of an image scaled down to fit into size x size pixels. Variants are
+//	@Description	generated in the background after upload. Missing ones are generated before redirecting. Images
+//	@Description	not larger than the size redirect to the original file.
+//	@Tags			media
+//	@Param			id		path	string	true	"media ID"
+//	@Param			size	path	int		true	"one of the configured variant sizes in pixels"
+//	@Success		302
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		409	{object}	httputils.Problem
+//	@Failure		415	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/{id}/variants/{size} [get]
+func (e *mediaEndpoint) GetMediaVariant(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	vars := mux.Vars(r)
+	mediaID := vars["id"]
+	if !e.validateMediaID(mediaID, w) {
+		return
+	}
+
+	size, err := strconv.Atoi(vars["size"])
+	if err != nil {
+		httputils.RespondWithBadParameter(w, "size", err)
+		return
+	}
+
+	url, err := e.mediaService.GetVariantURL(ctx, model.MediaID(mediaID), size)
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// PatchMedia godoc
+//
+//	@Summary		Update media item
+//	@Description	change the name or the tags of a media item. The media ID stays the same.
+//	@Tags			media
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string						true	"media ID"
+//	@Param			request	body		ahmodel.PatchMediaRequest	true	"fields to be changed"
+//	@Success		200		{object}	ahmodel.MediaItem
+//	@Failure		400		{object}	httputils.Problem
+//	@Failure		404		{object}	httputils.Problem
+//	@Failure		409		{object}	httputils.Problem
+//	@Failure		500		{object}	httputils.Problem
+//	@Router			/media/{id} [patch]
+func (e *mediaEndpoint) PatchMedia(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	mediaID := mux.Vars(r)["id"]
+	if !e.validateMediaID(mediaID, w) {
+		return
+	}
+
+	var data ahmodel.PatchMediaRequest
+	err := httputils.ParseJSONRequestBody(r.Body, &data)
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	if data.Name != nil && len(*data.Name) > e.mediaNameMaxLen {
+		httputils.RespondWithFieldError(w, "name", "File name is too long. Maximum is %v", e.mediaNameMaxLen)
+		return
+	}
+
+	tagIDFields := []struct {
+		name   string
+		tagIDs []string
+	}{
+		{"tag_ids", data.TagIDs},
+		{"add_tag_ids", data.AddTagIDs},
+		{"remove_tag_ids", data.RemoveTagIDs},
+	}
+
+	for _, field := range tagIDFields {
+		for _, tagID := range field.tagIDs {
+			if !e.validateTagID(field.name, tagID, w) {
+				return
+			}
+		}
+	}
+
+	mediaItem, err := e.mediaService.UpdateMedia(ctx, model.MediaID(mediaID), data.ToModel())
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	response := ahmodel.MediaItemFromModel(mediaItem)
+
+	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, false)
+}
+
+// DeleteMedia godoc
+//
+//	@Summary		Delete media item
+//	@Description	delete a media item with its file. Safe to retry if it failed.
+//	@Tags			media
+//	@Param			id	path	string	true	"media ID"
+//	@Success		204
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		409	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/{id} [delete]
+func (e *mediaEndpoint) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	mediaID := mux.Vars(r)["id"]
+	if !e.validateMediaID(mediaID, w) {
+		return
+	}
+
+	err := e.mediaService.DeleteMedia(ctx, model.MediaID(mediaID))
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package ahttp
+
+import (
+	"media-nexus/metrics"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// routeUnmatched is the route of requests matching no route, so unknown paths don't make up new series
+const routeUnmatched = "unmatched"
+
+// knownMethods are recorded as they are, others as OTHER, since clients can send any method
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// instrument records requests by the template of the route they matched. It's used as middleware of the router and
+// wraps the handlers of requests matching no route.
+func instrument(m metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(recorder, r)
+
+			m.ObserveHTTPRequest(routeTemplate(r), method(r), recorder.status, time.Since(started))
+		})
+	}
+}
+
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return routeUnmatched
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return routeUnmatched
+	}
+
+	return template
+}
+
+func method(r *http.Request) string {
+	if knownMethods[r.Method] {
+		return r.Method
+	}
+
+	return "OTHER"
+}
+
+// statusRecorder remembers the status written. Handlers not writing one respond with 200.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the writer of the server, e.g. to flush
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,97 @@
+package ahttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"media-nexus/errortypes"
+	"media-nexus/httputils"
+	"media-nexus/model"
+	"net/url"
+	"strings"
+)
+
+// pagination hands out opaque cursors. A cursor holds the ID the next page starts after and is signed together with
+// the scope it was created for, e.g. the query. So clients can neither forge cursors nor reuse them for another query.
+type pagination struct {
+	key          []byte
+	defaultLimit int
+	maxLimit     int
+}
+
+func newPagination(key []byte) *pagination {
+	return &pagination{key, 100, 1000}
+}
+
+// parsePageRequest reads the limit and cursor query parameters.
+func (p *pagination) parsePageRequest(query url.Values, scope string) (model.PageRequest, error) {
+	page := model.PageRequest{Limit: p.defaultLimit}
+
+	if query.Has("limit") {
+		limit, err := httputils.ParseInt32QueryParameter(query, "limit")
+		if err != nil {
+			return page, err
+		}
+
+		if limit < 1 || int(limit) > p.maxLimit {
+			return page, errortypes.NewBadUserInputFieldf("limit", "limit has to be between 1 and %v", p.maxLimit)
+		}
+
+		page.Limit = int(limit)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := p.decodeCursor(cursor, scope)
+		if err != nil {
+			return page, err
+		}
+
+		page.After = after
+	}
+
+	return page, nil
+}
+
+// nextCursor returns an empty cursor if there is no next page.
+func (p *pagination) nextCursor(after string, scope string) string {
+	if after == "" {
+		return ""
+	}
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString([]byte(after)) + "." + encoding.EncodeToString(p.sign(after, scope))
+}
+
+func (p *pagination) decodeCursor(cursor string, scope string) (string, error) {
+	encodedAfter, encodedSignature, found := strings.Cut(cursor, ".")
+	if !found {
+		return "", errortypes.NewBadUserInputFieldf("cursor", "invalid cursor")
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(encodedAfter)
+	if err != nil {
+		return "", errortypes.NewBadUserInputFieldf("cursor", "invalid cursor")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", errortypes.NewBadUserInputFieldf("cursor", "invalid cursor")
+	}
+
+	if !hmac.Equal(signature, p.sign(string(after), scope)) {
+		return "", errortypes.NewBadUserInputFieldf(
+			"cursor",
+			"invalid cursor. It has to be used with the same query it was returned for.",
+		)
+	}
+
+	return string(after), nil
+}
+
+func (p *pagination) sign(after string, scope string) []byte {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(fmt.Sprintf("%v\n%v", scope, after)))
+
+	return mac.Sum(nil)
+}
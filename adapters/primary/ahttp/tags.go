@@ -10,10 +10,13 @@ import (
 	"net/http"
 )
 
+const tagsCursorScope = "tags"
+
 type tagsEndpoint struct {
 	tags          ports.TagRepository
 	log           logger.Logger
 	tagNameMaxLen int
+	pagination    *pagination
 }
 
 func (e *tagsEndpoint) createContext(r *http.Request) context.Context {
@@ -29,7 +32,8 @@ func (e *tagsEndpoint) createContext(r *http.Request) context.Context {
 //	@Produce		json
 //	@Param			request	body		ahmodel.PostTagsRequest	true	"tag to be created"
 //	@Success		200		{object}	ahmodel.PostTagsResponse
-//	@Failure		400		{object}	string
+//	@Failure		400		{object}	httputils.Problem
+//	@Failure		500		{object}	httputils.Problem
 //	@Router			/tags [post]
 func (e *tagsEndpoint) CreateTag(w http.ResponseWriter, r *http.Request) {
 	ctx := e.createContext(r)
@@ -41,7 +45,7 @@ func (e *tagsEndpoint) CreateTag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(data.Name) > e.tagNameMaxLen {
-		httputils.RespondWithError(w, http.StatusBadRequest, "tag name is too long. Maximum is %v", e.tagNameMaxLen)
+		httputils.RespondWithFieldError(w, "name", "tag name is too long. Maximum is %v", e.tagNameMaxLen)
 		return
 	}
 
@@ -60,20 +64,34 @@ func (e *tagsEndpoint) CreateTag(w http.ResponseWriter, r *http.Request) {
 // ListTags godoc
 //
 //	@Summary		List tags
-//	@Description	retrieve all tags
+//	@Description	retrieve tags in pages ordered by ID. Pass next_cursor as cursor to get the next page.
 //	@Tags			tags
 //	@Produce		json
-//	@Success		200	{object}	[]ahmodel.Tag
+//	@Param			limit	query		int		false	"maximum number of tags per page, 100 by default"
+//	@Param			cursor	query		string	false	"next_cursor of the previous page"
+//	@Success		200		{object}	ahmodel.GetTagsResponse
+//	@Failure		400		{object}	httputils.Problem
+//	@Failure		500		{object}	httputils.Problem
 //	@Router			/tags [get]
 func (e *tagsEndpoint) ListTags(w http.ResponseWriter, r *http.Request) {
 	ctx := e.createContext(r)
 
-	tags, err := e.tags.ListTags(ctx)
+	page, err := e.pagination.parsePageRequest(r.URL.Query(), tagsCursorScope)
 	if httputils.HandleError(err, w, e.log) {
 		return
 	}
 
-	response := ahmodel.CreateGetTagsResponse(tags)
+	tags, hasMore, err := e.tags.ListTagsPage(ctx, page)
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	nextCursor := ""
+	if hasMore && len(tags) > 0 {
+		nextCursor = e.pagination.nextCursor(tags[len(tags)-1].ID, tagsCursorScope)
+	}
+
+	response := ahmodel.CreateGetTagsResponse(tags, nextCursor)
 
 	httputils.RespondWithJSON(http.StatusOK, response, w, e.log, true)
 }
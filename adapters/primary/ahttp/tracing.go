@@ -0,0 +1,59 @@
+package ahttp
+
+import (
+	"media-nexus/tracing"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceRequests starts a span for every request, that continues the trace of the caller given by the W3C traceparent
+// header. Like instrument, it's used as middleware of the router and wraps the handlers of requests matching no route.
+func traceRequests(tracerProvider trace.TracerProvider) mux.MiddlewareFunc {
+	tracer := tracerProvider.Tracer(tracing.TracerName)
+	propagator := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := routeTemplate(r)
+			method := method(r)
+
+			// spans are named by route, so paths with IDs don't make up new names
+			name := method
+			if route != routeUnmatched {
+				name = method + " " + route
+			}
+
+			ctx, span := tracer.Start(
+				ctx,
+				name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(method),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			if route != routeUnmatched {
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.status))
+
+			// client errors are the client's problem, not the one of this service
+			if recorder.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(recorder.status))
+			}
+		})
+	}
+}
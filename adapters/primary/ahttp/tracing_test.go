@@ -0,0 +1,102 @@
+package ahttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type traceRequestsTestSuite struct {
+	suite.Suite
+	recorder *tracetest.SpanRecorder
+	router   *mux.Router
+}
+
+func TestTraceRequests(t *testing.T) {
+	suite.Run(t, &traceRequestsTestSuite{})
+}
+
+func (s *traceRequestsTestSuite) SetupTest() {
+	s.recorder = tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder))
+
+	middlewares := []mux.MiddlewareFunc{traceRequests(tracerProvider)}
+
+	s.router = mux.NewRouter()
+	s.router.Use(middlewares...)
+	s.router.HandleFunc("/media/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// handlers get the span of the request
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodGet)
+	s.router.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}).Methods(http.MethodGet)
+	s.router.NotFoundHandler = withMiddlewares(middlewares, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+}
+
+func (s *traceRequestsTestSuite) serve(path string, traceparent string) sdktrace.ReadOnlySpan {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	if traceparent != "" {
+		request.Header.Set("traceparent", traceparent)
+	}
+
+	s.router.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+
+	return spans[0]
+}
+
+func (s *traceRequestsTestSuite) TestContinuesTraceOfCaller() {
+	span := s.serve("/media/abc", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	s.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	s.Equal("00f067aa0ba902b7", span.Parent().SpanID().String())
+	s.True(span.Parent().IsRemote())
+}
+
+func (s *traceRequestsTestSuite) TestStartsTraceWithoutCaller() {
+	span := s.serve("/media/abc", "")
+
+	s.True(span.SpanContext().IsValid())
+	s.False(span.Parent().IsValid())
+}
+
+func (s *traceRequestsTestSuite) TestNamesSpanByRoute() {
+	span := s.serve("/media/abc", "")
+
+	s.Equal("GET /media/{id}", span.Name())
+	s.Equal(trace.SpanKindServer, span.SpanKind())
+	s.Contains(span.Attributes(), semconv.HTTPRoute("/media/{id}"))
+	s.Contains(span.Attributes(), semconv.HTTPResponseStatusCode(http.StatusNoContent))
+	s.Equal(codes.Unset, span.Status().Code)
+}
+
+func (s *traceRequestsTestSuite) TestFailsSpanOfServerError() {
+	span := s.serve("/fail", "")
+
+	s.Equal(codes.Error, span.Status().Code)
+}
+
+func (s *traceRequestsTestSuite) TestSpanOfUnmatchedRoute() {
+	span := s.serve("/unknown", "")
+
+	s.Equal("GET", span.Name())
+	s.Contains(span.Attributes(), semconv.HTTPResponseStatusCode(http.StatusNotFound))
+}
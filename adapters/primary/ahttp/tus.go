@@ -0,0 +1,356 @@
+package ahttp
+
+import (
+	"context"
+	"encoding/base64"
+	"media-nexus/httputils"
+	"media-nexus/logger"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/services"
+	"media-nexus/util"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+
+	headerTusResumable   = "Tus-Resumable"
+	headerTusVersion     = "Tus-Version"
+	headerTusExtension   = "Tus-Extension"
+	headerTusMaxSize     = "Tus-Max-Size"
+	headerUploadLength   = "Upload-Length"
+	headerUploadOffset   = "Upload-Offset"
+	headerUploadMetadata = "Upload-Metadata"
+	headerUploadExpires  = "Upload-Expires"
+	headerLocation       = "Location"
+	// headerMediaID is not part of tus. It tells the client the ID of the media, once the upload is complete.
+	headerMediaID = "Media-Id"
+
+	contentTypeOffsetOctetStream = "application/offset+octet-stream"
+)
+
+// tusEndpoint implements the tus protocol (https://tus.io/protocols/resumable-upload) for uploading media in chunks.
+type tusEndpoint struct {
+	uploadService       services.UploadService
+	log                 logger.Logger
+	metrics             metrics.Metrics
+	maxUploadFileSizeMB int64
+	mediaNameMaxLen     int
+	tagIDMaxLen         int
+	uploadIDMaxLen      int
+}
+
+func (e *tusEndpoint) createContext(r *http.Request) context.Context {
+	return util.WithLogger(r.Context(), e.log)
+}
+
+// checkTusResumable responds with 412, if the client speaks another version of tus than we do.
+func (e *tusEndpoint) checkTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set(headerTusResumable, tusVersion)
+
+	if r.Header.Get(headerTusResumable) != tusVersion {
+		w.Header().Set(headerTusVersion, tusVersion)
+		httputils.RespondWithError(w, http.StatusPreconditionFailed, "only tus version %v is supported", tusVersion)
+		return false
+	}
+
+	return true
+}
+
+func (e *tusEndpoint) validateUploadID(uploadID string, w http.ResponseWriter) bool {
+	if len(uploadID) > e.uploadIDMaxLen {
+		httputils.RespondWithFieldError(w, "id", "Upload ID is too long. Maximum is %v", e.uploadIDMaxLen)
+		return false
+	}
+
+	return true
+}
+
+func setUploadHeaders(w http.ResponseWriter, upload *model.Upload) {
+	w.Header().Set(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	if upload.Complete() {
+		w.Header().Set(headerMediaID, upload.MediaID)
+	}
+}
+
+// GetTusOptions godoc
+//
+//	@Summary		Get tus capabilities
+//	@Description	get the tus version, extensions and maximum upload size supported for resumable uploads
+//	@Tags			media
+//	@Success		204
+//	@Header			204	{string}	Tus-Version		"supported tus versions"
+//	@Header			204	{string}	Tus-Extension	"supported tus extensions"
+//	@Header			204	{integer}	Tus-Max-Size	"maximum upload size in bytes"
+//	@Router			/media/tus [options]
+func (e *tusEndpoint) GetTusOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(headerTusResumable, tusVersion)
+	w.Header().Set(headerTusVersion, tusVersion)
+	w.Header().Set(headerTusExtension, tusExtensions)
+	w.Header().Set(headerTusMaxSize, strconv.FormatInt(e.maxUploadFileSizeMB<<20, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+//
+//	@Summary		Create resumable upload
+//	@Description	create a resumable upload for a media with the tus protocol. Upload-Metadata must contain a
+//	@Description	name and may contain tag_ids, a comma separated list of tag IDs. Both base64 encoded, as
+//	@Description	defined by tus. Once all bytes are sent with PATCH, the media is created.
+//	@Tags			media
+//	@Param			Tus-Resumable	header	string	true	"tus version, must be 1.0.0"
+//	@Param			Upload-Length	header	int		true	"size of the media in bytes"
+//	@Param			Upload-Metadata	header	string	true	"name and tag_ids of the media"
+//	@Success		201
+//	@Header			201	{string}	Location		"URL of the upload"
+//	@Header			201	{string}	Upload-Expires	"time the upload expires, if not continued"
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		412	{object}	httputils.Problem
+//	@Failure		413	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/tus [post]
+func (e *tusEndpoint) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	if !e.checkTusResumable(w, r) {
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get(headerUploadLength), 10, 64)
+	if err != nil || length < 0 {
+		httputils.RespondWithFieldError(w, headerUploadLength, "%v must be a non-negative integer", headerUploadLength)
+		return
+	}
+
+	if length > e.maxUploadFileSizeMB<<20 {
+		httputils.RespondWithError(
+			w,
+			http.StatusRequestEntityTooLarge,
+			"File is too large. Maximum is %v MB",
+			e.maxUploadFileSizeMB,
+		)
+		return
+	}
+
+	name, tagIDs, ok := e.parseUploadMetadata(r.Header.Get(headerUploadMetadata), w)
+	if !ok {
+		return
+	}
+
+	upload, err := e.uploadService.CreateUpload(ctx, name, tagIDs, length)
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set(headerLocation, "/api/v1/media/tus/"+upload.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// parseUploadMetadata parses the comma separated key value pairs of Upload-Metadata, values are base64 encoded.
+func (e *tusEndpoint) parseUploadMetadata(header string, w http.ResponseWriter) (string, []model.TagID, bool) {
+	name := ""
+	tagIDs := make([]model.TagID, 0)
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encodedValue, _ := strings.Cut(strings.TrimSpace(pair), " ")
+
+		value, err := base64.StdEncoding.DecodeString(encodedValue)
+		if err != nil {
+			httputils.RespondWithFieldError(w, headerUploadMetadata, "invalid %v %v: %v", headerUploadMetadata, key, err)
+			return "", nil, false
+		}
+
+		switch key {
+		case "name":
+			if len(value) > e.mediaNameMaxLen {
+				httputils.RespondWithFieldError(
+					w,
+					headerUploadMetadata,
+					"File name is too long. Maximum is %v",
+					e.mediaNameMaxLen,
+				)
+				return "", nil, false
+			}
+
+			name = string(value)
+		case "tag_ids":
+			for _, tagID := range strings.Split(string(value), ",") {
+				if tagID == "" {
+					continue
+				}
+
+				if len(tagID) > e.tagIDMaxLen {
+					httputils.RespondWithFieldError(
+						w,
+						headerUploadMetadata,
+						"Tag ID is too long. Maximum is %v",
+						e.tagIDMaxLen,
+					)
+					return "", nil, false
+				}
+
+				tagIDs = append(tagIDs, model.TagID(tagID))
+			}
+		}
+	}
+
+	if name == "" {
+		httputils.RespondWithFieldError(w, headerUploadMetadata, "File name is required")
+		return "", nil, false
+	}
+
+	return name, tagIDs, true
+}
+
+// GetUpload godoc
+//
+//	@Summary		Get resumable upload offset
+//	@Description	get the offset of a resumable upload, so the client knows where to continue
+//	@Tags			media
+//	@Param			id				path	string	true	"upload ID"
+//	@Param			Tus-Resumable	header	string	true	"tus version, must be 1.0.0"
+//	@Success		200
+//	@Header			200	{integer}	Upload-Offset	"number of bytes received"
+//	@Header			200	{integer}	Upload-Length	"size of the media in bytes"
+//	@Header			200	{string}	Upload-Expires	"time the upload expires, if not continued"
+//	@Header			200	{string}	Media-Id		"ID of the media, once the upload is complete"
+//	@Failure		400
+//	@Failure		404
+//	@Failure		412
+//	@Router			/media/tus/{id} [head]
+func (e *tusEndpoint) GetUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	if !e.checkTusResumable(w, r) {
+		return
+	}
+
+	uploadID := mux.Vars(r)["id"]
+	if !e.validateUploadID(uploadID, w) {
+		return
+	}
+
+	upload, err := e.uploadService.GetUpload(ctx, model.UploadID(uploadID))
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set(headerUploadLength, strconv.FormatInt(upload.Length, 10))
+	w.Header().Set(httputils.HeaderCacheControl, "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchUpload godoc
+//
+//	@Summary		Continue resumable upload
+//	@Description	append bytes to a resumable upload at the given offset. The media is created with the last
+//	@Description	bytes. If that failed, send an empty PATCH at the full length to retry. Media of a type not
+//	@Description	allowed is rejected with 415 and the upload is deleted.
+//	@Tags			media
+//	@Accept			application/offset+octet-stream
+//	@Param			id				path	string	true	"upload ID"
+//	@Param			Tus-Resumable	header	string	true	"tus version, must be 1.0.0"
+//	@Param			Upload-Offset	header	int		true	"offset of the bytes sent"
+//	@Success		204
+//	@Header			204	{integer}	Upload-Offset	"number of bytes received"
+//	@Header			204	{string}	Upload-Expires	"time the upload expires, if not continued"
+//	@Header			204	{string}	Media-Id		"ID of the media, once the upload is complete"
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		409	{object}	httputils.Problem
+//	@Failure		412	{object}	httputils.Problem
+//	@Failure		415	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/tus/{id} [patch]
+func (e *tusEndpoint) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	if !e.checkTusResumable(w, r) {
+		return
+	}
+
+	uploadID := mux.Vars(r)["id"]
+	if !e.validateUploadID(uploadID, w) {
+		return
+	}
+
+	if r.Header.Get(httputils.HeaderContentType) != contentTypeOffsetOctetStream {
+		httputils.RespondWithError(
+			w,
+			http.StatusUnsupportedMediaType,
+			"%v must be %v",
+			httputils.HeaderContentType,
+			contentTypeOffsetOctetStream,
+		)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		httputils.RespondWithFieldError(w, headerUploadOffset, "%v must be a non-negative integer", headerUploadOffset)
+		return
+	}
+
+	started := time.Now()
+	body := &requestBodyReader{reader: r.Body}
+
+	upload, err := e.uploadService.AppendChunk(ctx, model.UploadID(uploadID), offset, body)
+	e.metrics.ObserveUpload(metrics.UploadResumable, body.size, time.Since(started))
+
+	// if reading the request failed, it's on the client, whatever the service made of it
+	if body.err != nil && err != nil {
+		httputils.RespondWithError(w, http.StatusBadRequest, "failed to read the chunk: %v", body.err)
+		return
+	}
+
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUpload godoc
+//
+//	@Summary		Terminate resumable upload
+//	@Description	terminate a resumable upload and delete the bytes received so far
+//	@Tags			media
+//	@Param			id				path	string	true	"upload ID"
+//	@Param			Tus-Resumable	header	string	true	"tus version, must be 1.0.0"
+//	@Success		204
+//	@Failure		400	{object}	httputils.Problem
+//	@Failure		404	{object}	httputils.Problem
+//	@Failure		412	{object}	httputils.Problem
+//	@Failure		500	{object}	httputils.Problem
+//	@Router			/media/tus/{id} [delete]
+func (e *tusEndpoint) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := e.createContext(r)
+
+	if !e.checkTusResumable(w, r) {
+		return
+	}
+
+	uploadID := mux.Vars(r)["id"]
+	if !e.validateUploadID(uploadID, w) {
+		return
+	}
+
+	err := e.uploadService.DeleteUpload(ctx, model.UploadID(uploadID))
+	if httputils.HandleError(err, w, e.log) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
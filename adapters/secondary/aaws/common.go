@@ -1,9 +1,34 @@
 package aaws
 
-import "github.com/aws/aws-sdk-go-v2/service/s3"
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
 
 func WithRegion(region string) func(o *s3.Options) {
 	return func(o *s3.Options) {
 		o.Region = region
 	}
 }
+
+// isNotFound tells whether an object doesn't exist. HeadObject reports NotFound, other operations NoSuchKey.
+func isNotFound(err error) bool {
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) {
+		return apiError.ErrorCode() == "NoSuchKey" || apiError.ErrorCode() == "NotFound"
+	}
+
+	return false
+}
+
+// isInvalidRange tells whether a range starts after the end of an object.
+func isInvalidRange(err error) bool {
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) {
+		return apiError.ErrorCode() == "InvalidRange"
+	}
+
+	return false
+}
@@ -0,0 +1,28 @@
+package aaws
+
+import (
+	"context"
+	"media-nexus/ports"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type healthChecker struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewHealthChecker checks with HeadBucket, that S3 answers and we have access to the bucket
+func NewHealthChecker(client *s3.Client, bucket string) ports.HealthChecker {
+	return &healthChecker{client, bucket}
+}
+
+func (c *healthChecker) Name() string {
+	return "s3"
+}
+
+// Check passes if the bucket doesn't exist yet, it's created when the first media is stored
+func (c *healthChecker) Check(ctx context.Context) error {
+	_, err := bucketExists(ctx, c.client, c.bucket)
+	return err
+}
@@ -2,12 +2,21 @@ package aaws
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"media-nexus/errortypes"
+	"media-nexus/metrics"
+	"media-nexus/model"
 	"media-nexus/ports"
+	"media-nexus/util"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
@@ -15,14 +24,34 @@ import (
 type mediaRepository struct {
 	client        *s3.Client
 	presignClient *s3.PresignClient
+	uploader      *manager.Uploader
 	bucket        string
+	multipart     MultipartOptions
+	metrics       metrics.Metrics
 }
 
-func NewMediaRepository(client *s3.Client, presignClient *s3.PresignClient, bucket string) ports.MediaRepository {
-	return &mediaRepository{client, presignClient, bucket}
+// NewMediaRepository stores media in bucket. The returned runner aborts stale multipart uploads periodically.
+func NewMediaRepository(
+	client *s3.Client,
+	presignClient *s3.PresignClient,
+	bucket string,
+	multipart MultipartOptions,
+	metrics metrics.Metrics,
+) (ports.MediaRepository, util.Runner) {
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = multipart.PartSize
+		u.Concurrency = multipart.Concurrency
+		// the uploader would abort with the context of the upload, which might be canceled already. So we abort
+		// ourselves.
+		u.LeavePartsOnError = true
+	})
+
+	repo := &mediaRepository{client, presignClient, uploader, bucket, multipart, metrics}
+
+	return repo, repo.runAbortStaleUploads
 }
 
-func (r *mediaRepository) CreateMedia(ctx context.Context, key string, file io.Reader) error {
+func (r *mediaRepository) CreateMedia(ctx context.Context, key string, file io.Reader, mimeType string) error {
 	err := ensureBucketExists(ctx, r.client, r.bucket)
 	if err != nil {
 		return err
@@ -34,14 +63,127 @@ func (r *mediaRepository) CreateMedia(ctx context.Context, key string, file io.R
 		Body:   file,
 	}
 
-	_, err = r.client.PutObject(ctx, uploadInput)
+	// presigned downloads are served with the content type of the object
+	if mimeType != "" {
+		uploadInput.ContentType = aws.String(mimeType)
+	}
+
+	// the uploader reads the file sequentially and sends the parts in parallel. So we don't need to know the size
+	// upfront and the file is read only once.
+	_, err = r.uploader.Upload(ctx, uploadInput)
 	if err != nil {
+		r.abortFailedUpload(ctx, key, err)
 		return errortypes.NewInputOutputErrorf("failed to upload file to S3: %v", err)
 	}
 
 	return nil
 }
 
+func (r *mediaRepository) MoveMedia(ctx context.Context, fromKey string, toKey string) error {
+	err := ensureBucketExists(ctx, r.client, r.bucket)
+	if err != nil {
+		return err
+	}
+
+	head, err := r.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(fromKey),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return errortypes.NewResourceNotFound(fromKey)
+		}
+
+		return errortypes.NewInputOutputErrorf("failed to get %v from bucket: %v", fromKey, err)
+	}
+
+	if size := aws.ToInt64(head.ContentLength); size > maxCopyObjectSize {
+		err = r.copyObjectMultipart(ctx, fromKey, toKey, size, head.ContentType)
+	} else {
+		err = r.copyObject(ctx, fromKey, toKey)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(fromKey),
+	})
+	if err != nil {
+		return errortypes.NewInputOutputErrorf("failed to delete %v from bucket: %v", fromKey, err)
+	}
+
+	return nil
+}
+
+func (r *mediaRepository) copyObject(ctx context.Context, fromKey string, toKey string) error {
+	_, err := r.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(r.bucket),
+		Key:        aws.String(toKey),
+		CopySource: aws.String(r.copySource(fromKey)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return errortypes.NewResourceNotFound(fromKey)
+		}
+
+		return errortypes.NewInputOutputErrorf("failed to copy %v to %v in bucket: %v", fromKey, toKey, err)
+	}
+
+	return nil
+}
+
+func (r *mediaRepository) copySource(key string) string {
+	return r.bucket + "/" + url.PathEscape(key)
+}
+
+func (r *mediaRepository) OpenMedia(
+	ctx context.Context,
+	key string,
+	byteRange *model.ByteRange,
+) (io.ReadCloser, error) {
+	err := ensureBucketExists(ctx, r.client, r.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+	}
+
+	if byteRange != nil {
+		// S3 can't get empty ranges, but we still have to tell whether the media exists
+		if byteRange.Length < 1 {
+			if _, err := r.GetMediaAttributes(ctx, key); err != nil {
+				return nil, err
+			}
+
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+
+		input.Range = aws.String(fmt.Sprintf("bytes=%v-%v", byteRange.Offset, byteRange.Offset+byteRange.Length-1))
+	}
+
+	output, err := r.client.GetObject(ctx, input)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errortypes.NewResourceNotFound(key)
+		}
+
+		// the range starts after the end of the media
+		if isInvalidRange(err) {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+
+		return nil, errortypes.NewInputOutputErrorf("failed to get %v from bucket: %v", key, err)
+	}
+
+	return output.Body, nil
+}
+
 func (r *mediaRepository) GetMediaURL(ctx context.Context, key string, lifetime time.Duration) (string, error) {
 	err := ensureBucketExists(ctx, r.client, r.bucket)
 	if err != nil {
@@ -56,6 +198,7 @@ func (r *mediaRepository) GetMediaURL(ctx context.Context, key string, lifetime
 	})
 	if err != nil {
 		return "", errortypes.NewUpstreamCommunicationErrorf(
+			r.bucket,
 			"couldn't get a presigned request to get %v:%v: %v",
 			r.bucket,
 			key,
@@ -66,7 +209,116 @@ func (r *mediaRepository) GetMediaURL(ctx context.Context, key string, lifetime
 	return request.URL, err
 }
 
+// GetUploadURL presigns a PutObject with the size, the checksum and the content type, so S3 rejects any other content.
+func (r *mediaRepository) GetUploadURL(
+	ctx context.Context,
+	key string,
+	size int64,
+	checksum string,
+	mimeType string,
+	lifetime time.Duration,
+) (model.UploadURL, error) {
+	checksumBytes, err := hex.DecodeString(checksum)
+	if err != nil {
+		return model.UploadURL{}, errortypes.NewInvalidArgumentf("invalid checksum '%v': %v", checksum, err)
+	}
+
+	err = ensureBucketExists(ctx, r.client, r.bucket)
+	if err != nil {
+		return model.UploadURL{}, err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:         aws.String(r.bucket),
+		Key:            aws.String(key),
+		ContentLength:  aws.Int64(size),
+		ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(checksumBytes)),
+	}
+
+	if mimeType != "" {
+		input.ContentType = aws.String(mimeType)
+	}
+
+	request, err := r.presignClient.PresignPutObject(ctx, input, func(opts *s3.PresignOptions) {
+		opts.Expires = lifetime
+	})
+	if err != nil {
+		return model.UploadURL{}, errortypes.NewUpstreamCommunicationErrorf(
+			r.bucket,
+			"couldn't get a presigned request to put %v:%v: %v",
+			r.bucket,
+			key,
+			err,
+		)
+	}
+
+	headers := map[string]string{}
+	for name := range request.SignedHeader {
+		// clients set these on their own
+		if name == "Host" || name == "Content-Length" {
+			continue
+		}
+
+		headers[name] = request.SignedHeader.Get(name)
+	}
+
+	return model.UploadURL{URL: request.URL, Headers: headers}, nil
+}
+
+// GetMediaAttributes knows the checksum only for media uploaded in one part with a SHA-256 checksum, like through
+// GetUploadURL. Multipart uploads only have a checksum of the checksums of their parts.
+func (r *mediaRepository) GetMediaAttributes(ctx context.Context, key string) (model.MediaAttributes, error) {
+	err := ensureBucketExists(ctx, r.client, r.bucket)
+	if err != nil {
+		return model.MediaAttributes{}, err
+	}
+
+	output, err := r.client.GetObjectAttributes(ctx, &s3.GetObjectAttributesInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(key),
+		ObjectAttributes: []types.ObjectAttributes{
+			types.ObjectAttributesObjectSize,
+			types.ObjectAttributesChecksum,
+			types.ObjectAttributesObjectParts,
+		},
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return model.MediaAttributes{}, errortypes.NewResourceNotFound(key)
+		}
+
+		return model.MediaAttributes{}, errortypes.NewInputOutputErrorf(
+			"failed to get attributes of %v from bucket: %v",
+			key,
+			err,
+		)
+	}
+
+	attributes := model.MediaAttributes{Size: aws.ToInt64(output.ObjectSize)}
+
+	multipart := output.ObjectParts != nil && aws.ToInt32(output.ObjectParts.TotalPartsCount) > 0
+	if output.Checksum != nil && output.Checksum.ChecksumSHA256 != nil && !multipart {
+		checksum, err := base64.StdEncoding.DecodeString(*output.Checksum.ChecksumSHA256)
+		if err != nil {
+			return model.MediaAttributes{}, errortypes.NewInputOutputErrorf(
+				"invalid checksum of %v in bucket: %v",
+				key,
+				err,
+			)
+		}
+
+		attributes.Checksum = hex.EncodeToString(checksum)
+	}
+
+	return attributes, nil
+}
+
 func (r *mediaRepository) DeleteAll(ctx context.Context, keys []string) error {
+	// S3 rejects a delete request without any objects
+	if len(keys) < 1 {
+		return nil
+	}
+
 	err := ensureBucketExists(ctx, r.client, r.bucket)
 	if err != nil {
 		return err
@@ -91,3 +343,38 @@ func (r *mediaRepository) DeleteAll(ctx context.Context, keys []string) error {
 
 	return nil
 }
+
+func (r *mediaRepository) ListMediaPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.StoredMedia, bool, error) {
+	err := ensureBucketExists(ctx, r.client, r.bucket)
+	if err != nil {
+		return nil, false, err
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(r.bucket),
+		MaxKeys: aws.Int32(int32(page.Limit)),
+	}
+
+	if page.After != "" {
+		input.StartAfter = aws.String(page.After)
+	}
+
+	output, err := r.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, false, errortypes.NewInputOutputErrorf("failed to list objects in bucket: %v", err)
+	}
+
+	media := make([]model.StoredMedia, 0, len(output.Contents))
+	for _, object := range output.Contents {
+		media = append(media, model.StoredMedia{
+			Key:          aws.ToString(object.Key),
+			Size:         aws.ToInt64(object.Size),
+			LastModified: aws.ToTime(object.LastModified),
+		})
+	}
+
+	return media, aws.ToBool(output.IsTruncated), nil
+}
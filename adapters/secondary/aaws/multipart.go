@@ -0,0 +1,235 @@
+package aaws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-nexus/errortypes"
+	"media-nexus/util"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// objects larger than this can't be copied with a single CopyObject
+	maxCopyObjectSize int64 = 5 * 1024 * 1024 * 1024
+
+	abortTimeout = 30 * time.Second
+)
+
+// MultipartOptions configures how media is uploaded in parts.
+type MultipartOptions struct {
+	PartSize    int64
+	Concurrency int
+	// multipart uploads started longer ago are aborted, because whoever started them must have crashed
+	StaleUploadLifetime      time.Duration
+	StaleUploadCheckInterval time.Duration
+}
+
+// abortFailedUpload aborts the upload behind err, if there is one. The context of the upload might be canceled
+// already, e.g. because the client went away, so the abort gets a context of its own.
+func (r *mediaRepository) abortFailedUpload(ctx context.Context, key string, err error) {
+	var uploadFailure manager.MultiUploadFailure
+	if !errors.As(err, &uploadFailure) {
+		return
+	}
+
+	r.abortUpload(ctx, key, uploadFailure.UploadID())
+}
+
+func (r *mediaRepository) abortUpload(ctx context.Context, key string, uploadID string) {
+	abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+	defer cancel()
+
+	_, err := r.client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		util.Logger(ctx).Errorf("failed to abort multipart upload %v of %v: %v", uploadID, key, err)
+	}
+}
+
+// copyObjectMultipart copies objects too large for CopyObject part by part. Unlike CopyObject it doesn't take over the
+// content type, so it has to be passed.
+func (r *mediaRepository) copyObjectMultipart(
+	ctx context.Context,
+	fromKey string,
+	toKey string,
+	size int64,
+	contentType *string,
+) error {
+	created, err := r.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(r.bucket),
+		Key:         aws.String(toKey),
+		ContentType: contentType,
+	})
+	if err != nil {
+		return errortypes.NewInputOutputErrorf("failed to start multipart copy of %v: %v", fromKey, err)
+	}
+
+	parts, err := r.copyParts(ctx, fromKey, toKey, aws.ToString(created.UploadId), size)
+	if err != nil {
+		r.abortUpload(ctx, toKey, aws.ToString(created.UploadId))
+		return err
+	}
+
+	_, err = r.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(r.bucket),
+		Key:             aws.String(toKey),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		r.abortUpload(ctx, toKey, aws.ToString(created.UploadId))
+		return errortypes.NewInputOutputErrorf("failed to complete multipart copy of %v: %v", fromKey, err)
+	}
+
+	return nil
+}
+
+func (r *mediaRepository) copyParts(
+	ctx context.Context,
+	fromKey string,
+	toKey string,
+	uploadID string,
+	size int64,
+) ([]types.CompletedPart, error) {
+	// S3 allows at most 10000 parts, so large objects need larger parts
+	partSize := max(r.multipart.PartSize, (size+int64(manager.MaxUploadParts)-1)/int64(manager.MaxUploadParts))
+	partCount := (size + partSize - 1) / partSize
+
+	parts := make([]types.CompletedPart, partCount)
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(r.multipart.Concurrency)
+
+	for i := int64(0); i < partCount; i++ {
+		partNumber := int32(i + 1)
+		start := i * partSize
+		end := min(start+partSize, size) - 1
+
+		group.Go(func() error {
+			output, err := r.client.UploadPartCopy(groupCtx, &s3.UploadPartCopyInput{
+				Bucket:          aws.String(r.bucket),
+				Key:             aws.String(toKey),
+				UploadId:        aws.String(uploadID),
+				PartNumber:      aws.Int32(partNumber),
+				CopySource:      aws.String(r.copySource(fromKey)),
+				CopySourceRange: aws.String(fmt.Sprintf("bytes=%v-%v", start, end)),
+			})
+			if err != nil {
+				return errortypes.NewInputOutputErrorf("failed to copy part %v of %v: %v", partNumber, fromKey, err)
+			}
+
+			parts[partNumber-1] = types.CompletedPart{
+				ETag:       output.CopyPartResult.ETag,
+				PartNumber: aws.Int32(partNumber),
+			}
+
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	return parts, nil
+}
+
+// abortStaleUploads aborts multipart uploads, which were started before the stale upload lifetime. They only cost
+// money, because nobody is going to complete them anymore.
+func (r *mediaRepository) abortStaleUploads(ctx context.Context) error {
+	log := util.Logger(ctx)
+
+	staleBefore := time.Now().Add(-r.multipart.StaleUploadLifetime)
+
+	paginator := newMultipartUploadsPaginator(r.client, r.bucket)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return errortypes.NewUpstreamCommunicationErrorf(
+				r.bucket,
+				"failed to list multipart uploads: %v",
+				err,
+			)
+		}
+
+		for _, upload := range page.Uploads {
+			if upload.Initiated == nil || !upload.Initiated.Before(staleBefore) {
+				continue
+			}
+
+			log.Infof("aborting stale multipart upload %v of %v", aws.ToString(upload.UploadId), aws.ToString(upload.Key))
+			r.abortUpload(ctx, aws.ToString(upload.Key), aws.ToString(upload.UploadId))
+		}
+	}
+
+	return nil
+}
+
+// runAbortStaleUploads checks for stale uploads every check interval until ctx is done.
+func (r *mediaRepository) runAbortStaleUploads(ctx context.Context) {
+	log := util.Logger(ctx)
+
+	ticker := time.NewTicker(r.multipart.StaleUploadCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		err := ensureBucketExists(ctx, r.client, r.bucket)
+		if err != nil {
+			log.Errorf("failed to ensure bucket %v exists: %v", r.bucket, err)
+		} else if err = r.abortStaleUploads(ctx); err != nil {
+			log.Errorf("failed to abort stale multipart uploads: %v", err)
+		}
+
+		r.metrics.CountRun("abort_stale_uploads", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// multipartUploadsPaginator pages through ListMultipartUploads, for which the SDK has no paginator.
+type multipartUploadsPaginator struct {
+	client         *s3.Client
+	bucket         string
+	keyMarker      *string
+	uploadIDMarker *string
+	done           bool
+}
+
+func newMultipartUploadsPaginator(client *s3.Client, bucket string) *multipartUploadsPaginator {
+	return &multipartUploadsPaginator{client: client, bucket: bucket}
+}
+
+func (p *multipartUploadsPaginator) HasMorePages() bool {
+	return !p.done
+}
+
+func (p *multipartUploadsPaginator) NextPage(ctx context.Context) (*s3.ListMultipartUploadsOutput, error) {
+	output, err := p.client.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+		Bucket:         aws.String(p.bucket),
+		KeyMarker:      p.keyMarker,
+		UploadIdMarker: p.uploadIDMarker,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.keyMarker = output.NextKeyMarker
+	p.uploadIDMarker = output.NextUploadIdMarker
+	p.done = !aws.ToBool(output.IsTruncated)
+
+	return output, nil
+}
@@ -0,0 +1,20 @@
+package afs
+
+import (
+	"media-nexus/ports"
+	"media-nexus/ports/portstest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMediaRepositoryContract(t *testing.T) {
+	rootDir := t.TempDir()
+
+	suite.Run(t, &portstest.MediaRepositoryContract{
+		Repository: func() ports.MediaRepository {
+			repo, _, _ := NewMediaRepository(rootDir, "http://localhost/api/v1/blobs", []byte("key"))
+			return repo
+		},
+	})
+}
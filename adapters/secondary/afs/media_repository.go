@@ -0,0 +1,344 @@
+package afs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	shardPrefixLength = 2
+	shardLevels       = 2
+)
+
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type mediaRepository struct {
+	rootDir     string
+	downloadURL string
+	signer      *util.URLSigner
+}
+
+// NewMediaRepository stores media below rootDir. Media and upload URLs point to downloadURL/<key> and are signed with
+// signingKey, so they have to be served by the returned downloader and uploader.
+func NewMediaRepository(
+	rootDir string,
+	downloadURL string,
+	signingKey []byte,
+) (ports.MediaRepository, ports.SignedMediaDownloader, ports.SignedMediaUploader) {
+	repo := &mediaRepository{rootDir, downloadURL, util.NewURLSigner(signingKey)}
+	return repo, repo, repo
+}
+
+func (r *mediaRepository) CreateMedia(ctx context.Context, key string, file io.Reader, mimeType string) error {
+	return r.createMedia(key, file, true)
+}
+
+// createMedia returns Conflict if replace is false and there is media at key already.
+func (r *mediaRepository) createMedia(key string, file io.Reader, replace bool) error {
+	path, err := r.pathForKey(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return errortypes.NewInputOutputErrorf("failed to create directory %v: %v", dir, err)
+	}
+
+	// write to a temp file in the same directory first and rename it afterwards. This way readers either see
+	// the complete file or none at all.
+	tmpFile, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return errortypes.NewInputOutputErrorf("failed to create temp file in %v: %v", dir, err)
+	}
+
+	tmpPath := tmpFile.Name()
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := io.Copy(tmpFile, file); err != nil {
+		_ = tmpFile.Close()
+		return errortypes.NewInputOutputErrorf("failed to write media %v: %v", key, err)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return errortypes.NewInputOutputErrorf("failed to sync media %v: %v", key, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return errortypes.NewInputOutputErrorf("failed to close media %v: %v", key, err)
+	}
+
+	if !replace {
+		// unlike rename, link fails if there is a file at path already. The temp file is removed afterwards.
+		if err := os.Link(tmpPath, path); os.IsExist(err) {
+			return errortypes.NewConflictf("media %v exists already", key)
+		} else if err != nil {
+			return errortypes.NewInputOutputErrorf("failed to move media %v into place: %v", key, err)
+		}
+
+		return nil
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return errortypes.NewInputOutputErrorf("failed to move media %v into place: %v", key, err)
+	}
+
+	renamed = true
+
+	return nil
+}
+
+func (r *mediaRepository) MoveMedia(ctx context.Context, fromKey string, toKey string) error {
+	fromPath, err := r.pathForKey(fromKey)
+	if err != nil {
+		return err
+	}
+
+	toPath, err := r.pathForKey(toKey)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(fromPath); os.IsNotExist(err) {
+		return errortypes.NewResourceNotFound(fromKey)
+	}
+
+	dir := filepath.Dir(toPath)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return errortypes.NewInputOutputErrorf("failed to create directory %v: %v", dir, err)
+	}
+
+	// rename is atomic within the same file system, so readers see either the old or the new media
+	if err := os.Rename(fromPath, toPath); err != nil {
+		if os.IsNotExist(err) {
+			return errortypes.NewResourceNotFound(fromKey)
+		}
+
+		return errortypes.NewInputOutputErrorf("failed to move media %v to %v: %v", fromKey, toKey, err)
+	}
+
+	return nil
+}
+
+func (r *mediaRepository) GetMediaURL(ctx context.Context, key string, lifetime time.Duration) (string, error) {
+	if _, err := r.pathForKey(key); err != nil {
+		return "", err
+	}
+
+	return r.signer.SignedURL(r.downloadURL, key, lifetime), nil
+}
+
+// GetUploadURL can't enforce size, checksum and content type. They have to be verified after the upload.
+func (r *mediaRepository) GetUploadURL(
+	ctx context.Context,
+	key string,
+	size int64,
+	checksum string,
+	mimeType string,
+	lifetime time.Duration,
+) (model.UploadURL, error) {
+	if _, err := r.pathForKey(key); err != nil {
+		return model.UploadURL{}, err
+	}
+
+	return model.UploadURL{URL: r.signer.SignedUploadURL(r.downloadURL, key, lifetime)}, nil
+}
+
+// GetMediaAttributes doesn't know the checksum, it would have to read the whole file.
+func (r *mediaRepository) GetMediaAttributes(ctx context.Context, key string) (model.MediaAttributes, error) {
+	path, err := r.pathForKey(key)
+	if err != nil {
+		return model.MediaAttributes{}, err
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return model.MediaAttributes{}, errortypes.NewResourceNotFound(key)
+	}
+
+	if err != nil {
+		return model.MediaAttributes{}, errortypes.NewInputOutputErrorf("failed to stat media %v: %v", key, err)
+	}
+
+	return model.MediaAttributes{Size: info.Size()}, nil
+}
+
+func (r *mediaRepository) DeleteAll(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		path, err := r.pathForKey(key)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errortypes.NewInputOutputErrorf("failed to delete media %v: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *mediaRepository) OpenSignedMedia(
+	ctx context.Context,
+	key string,
+	expires time.Time,
+	signature string,
+) (io.ReadCloser, error) {
+	if !r.signer.Verify(key, expires, signature) {
+		return nil, errortypes.NewBadUserInput("invalid signature")
+	}
+
+	if time.Now().After(expires) {
+		return nil, errortypes.NewBadUserInput("url expired")
+	}
+
+	return r.OpenMedia(ctx, key, nil)
+}
+
+func (r *mediaRepository) CreateSignedMedia(
+	ctx context.Context,
+	key string,
+	expires time.Time,
+	signature string,
+	file io.Reader,
+) error {
+	if !r.signer.VerifyUpload(key, expires, signature) {
+		return errortypes.NewBadUserInput("invalid signature")
+	}
+
+	if time.Now().After(expires) {
+		return errortypes.NewBadUserInput("url expired")
+	}
+
+	// upload URLs stay valid until they expire. Not replacing media keeps them from being used once more.
+	return r.createMedia(key, file, false)
+}
+
+func (r *mediaRepository) OpenMedia(
+	ctx context.Context,
+	key string,
+	byteRange *model.ByteRange,
+) (io.ReadCloser, error) {
+	path, err := r.pathForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errortypes.NewResourceNotFound(key)
+	}
+
+	if err != nil {
+		return nil, errortypes.NewInputOutputErrorf("failed to open media %v: %v", key, err)
+	}
+
+	if byteRange == nil {
+		return file, nil
+	}
+
+	if _, err := file.Seek(byteRange.Offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, errortypes.NewInputOutputErrorf("failed to seek in media %v: %v", key, err)
+	}
+
+	return &rangeReader{io.LimitReader(file, byteRange.Length), file}, nil
+}
+
+// rangeReader reads a range of a file and closes the file
+type rangeReader struct {
+	io.Reader
+	io.Closer
+}
+
+// pathForKey shards the media into subdirectories by the prefix of its key, so we don't end up with
+// one huge directory.
+func (r *mediaRepository) pathForKey(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", errortypes.NewInvalidArgumentf("invalid media key '%v'", key)
+	}
+
+	parts := make([]string, 0, shardLevels+2)
+	parts = append(parts, r.rootDir)
+
+	for i := 0; i < shardLevels; i++ {
+		start := i * shardPrefixLength
+		end := start + shardPrefixLength
+		if end > len(key) {
+			break
+		}
+
+		parts = append(parts, key[start:end])
+	}
+
+	parts = append(parts, key)
+
+	return filepath.Join(parts...), nil
+}
+
+// ListMediaPage walks the whole directory tree for every page, as the sharding doesn't keep the keys in order.
+// Temp files of uploads in progress are left out.
+func (r *mediaRepository) ListMediaPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.StoredMedia, bool, error) {
+	var media []model.StoredMedia
+
+	err := filepath.WalkDir(r.rootDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == r.rootDir {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+
+		key := entry.Name()
+		if entry.IsDir() || !validKey.MatchString(key) || key <= page.After {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			// deleted while walking
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		media = append(media, model.StoredMedia{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, errortypes.NewInputOutputErrorf("failed to list media in %v: %v", r.rootDir, err)
+	}
+
+	slices.SortFunc(media, func(a, b model.StoredMedia) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	hasMore := len(media) > page.Limit
+	if hasMore {
+		media = media[:page.Limit]
+	}
+
+	return media, hasMore, nil
+}
@@ -0,0 +1,34 @@
+package afs
+
+import (
+	"context"
+	"io"
+	"media-nexus/errortypes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSignedMediaDoesNotReplaceMedia(t *testing.T) {
+	ctx := context.Background()
+	_, _, uploader := NewMediaRepository(t.TempDir(), "http://localhost/api/v1/blobs", []byte("key"))
+	repo := uploader.(*mediaRepository)
+
+	expires := time.Now().Add(time.Minute).Truncate(time.Second)
+	signature := repo.signer.SignUpload("media", expires)
+
+	require.NoError(t, uploader.CreateSignedMedia(ctx, "media", expires, signature, strings.NewReader("content")))
+
+	err := uploader.CreateSignedMedia(ctx, "media", expires, signature, strings.NewReader("other content"))
+	require.True(t, errortypes.IsConflict(err), "expected conflict, got %v", err)
+
+	media, err := repo.OpenMedia(ctx, "media", nil)
+	require.NoError(t, err)
+	defer media.Close()
+
+	content, err := io.ReadAll(media)
+	require.NoError(t, err)
+	require.Equal(t, "content", string(content))
+}
@@ -0,0 +1,45 @@
+package amemory
+
+import (
+	"media-nexus/ports"
+	"media-nexus/ports/portstest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTagRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.TagRepositoryContract{
+		Repository: NewTagRepository,
+	})
+}
+
+func TestMediaMetadataRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.MediaMetadataRepositoryContract{
+		Repository: func() ports.MediaMetadataRepository {
+			return NewMediaMetadataRepository(time.Minute)
+		},
+	})
+}
+
+func TestMediaRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.MediaRepositoryContract{
+		Repository: func() ports.MediaRepository {
+			repo, _, _ := NewMediaRepository("http://localhost/api/v1/blobs", []byte("key"))
+			return repo
+		},
+	})
+}
+
+func TestUploadRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.UploadRepositoryContract{
+		Repository: NewUploadRepository,
+	})
+}
+
+func TestJobQueueContract(t *testing.T) {
+	suite.Run(t, &portstest.JobQueueContract{
+		Queue: NewJobQueue,
+	})
+}
@@ -0,0 +1,196 @@
+package amemory
+
+import (
+	"cmp"
+	"context"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type jobQueue struct {
+	mutex     sync.Mutex
+	jobs      map[model.JobID]*model.Job
+	lastLease int64
+}
+
+// NewJobQueue keeps the jobs in memory. They are lost on restart and not shared with other instances.
+func NewJobQueue() ports.JobQueue {
+	return &jobQueue{jobs: map[model.JobID]*model.Job{}}
+}
+
+func (q *jobQueue) Enqueue(ctx context.Context, job *model.Job) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, exists := q.jobs[job.ID]; exists {
+		return errortypes.NewResourceAlreadyExistsf("job %v", job.ID)
+	}
+
+	c := *job
+	q.jobs[job.ID] = &c
+
+	return nil
+}
+
+func (q *jobQueue) Lease(
+	ctx context.Context,
+	jobTypes []string,
+	visibilityTimeout time.Duration,
+) (*model.Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+
+	var visible []*model.Job
+	for _, job := range q.jobs {
+		if !job.DeadLetter && !job.VisibleAt.After(now) && slices.Contains(jobTypes, job.Type) {
+			visible = append(visible, job)
+		}
+	}
+
+	if len(visible) < 1 {
+		return nil, errortypes.NewResourceNotFoundf("no visible jobs of types %v", jobTypes)
+	}
+
+	job := slices.MinFunc(visible, func(lhs, rhs *model.Job) int {
+		return cmp.Or(lhs.VisibleAt.Compare(rhs.VisibleAt), cmp.Compare(lhs.ID, rhs.ID))
+	})
+
+	q.lastLease++
+	job.LeaseID = strconv.FormatInt(q.lastLease, 10)
+	job.Attempts++
+	job.VisibleAt = now.Add(visibilityTimeout)
+
+	c := *job
+	return &c, nil
+}
+
+func (q *jobQueue) ExtendLease(
+	ctx context.Context,
+	id model.JobID,
+	leaseID string,
+	visibilityTimeout time.Duration,
+) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, err := q.leasedJob(id, leaseID)
+	if err != nil {
+		return err
+	}
+
+	job.VisibleAt = time.Now().Add(visibilityTimeout)
+
+	return nil
+}
+
+func (q *jobQueue) Complete(ctx context.Context, id model.JobID, leaseID string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, err := q.leasedJob(id, leaseID); err != nil {
+		return err
+	}
+
+	delete(q.jobs, id)
+
+	return nil
+}
+
+func (q *jobQueue) Fail(
+	ctx context.Context,
+	id model.JobID,
+	leaseID string,
+	reason string,
+	retryAt time.Time,
+) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, err := q.leasedJob(id, leaseID)
+	if err != nil {
+		return err
+	}
+
+	job.LeaseID = ""
+	job.LastError = reason
+	job.VisibleAt = retryAt
+
+	return nil
+}
+
+func (q *jobQueue) DeadLetter(ctx context.Context, id model.JobID, leaseID string, reason string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, err := q.leasedJob(id, leaseID)
+	if err != nil {
+		return err
+	}
+
+	job.LeaseID = ""
+	job.LastError = reason
+	job.DeadLetter = true
+
+	return nil
+}
+
+// leasedJob returns the job, if it is still leased by leaseID. Must be called with the mutex held.
+func (q *jobQueue) leasedJob(id model.JobID, leaseID string) (*model.Job, error) {
+	job, ok := q.jobs[id]
+	if !ok || job.LeaseID != leaseID || job.DeadLetter {
+		return nil, errortypes.NewConflictf("job %v is not leased by %v anymore", id, leaseID)
+	}
+
+	return job, nil
+}
+
+func (q *jobQueue) FindDeadLetters(ctx context.Context, limit int) ([]*model.Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var deadLetters []*model.Job
+	for _, job := range q.jobs {
+		if job.DeadLetter {
+			c := *job
+			deadLetters = append(deadLetters, &c)
+		}
+	}
+
+	slices.SortFunc(deadLetters, func(lhs, rhs *model.Job) int {
+		return cmp.Or(lhs.CreatedAt.Compare(rhs.CreatedAt), cmp.Compare(lhs.ID, rhs.ID))
+	})
+
+	return deadLetters[:min(limit, len(deadLetters))], nil
+}
+
+func (q *jobQueue) Requeue(ctx context.Context, id model.JobID) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok || !job.DeadLetter {
+		return errortypes.NewResourceNotFoundf("dead letter %v", id)
+	}
+
+	job.DeadLetter = false
+	job.Attempts = 0
+	job.VisibleAt = time.Now()
+
+	return nil
+}
+
+func (q *jobQueue) Delete(ctx context.Context, id model.JobID) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.jobs, id)
+
+	return nil
+}
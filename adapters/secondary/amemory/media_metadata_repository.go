@@ -0,0 +1,395 @@
+package amemory
+
+import (
+	"context"
+	"maps"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/services/query"
+	"media-nexus/util"
+	"slices"
+	"sync"
+	"time"
+)
+
+type mediaMetadataEntry struct {
+	id                model.MediaID
+	name              string
+	tagIDs            []model.TagID
+	checksum          string
+	storedChecksum    string
+	size              int64
+	mimeType          string
+	imageInfo         *model.ImageInfo
+	variants          []int
+	uploadComplete    bool
+	uploadCompletedAt time.Time
+	deleting          bool
+	lastUpdate        time.Time
+}
+
+func (e *mediaMetadataEntry) toModel() model.MediaMetadata {
+	return model.NewMediaMetadata(
+		e.id,
+		e.name,
+		append([]model.TagID(nil), e.tagIDs...),
+		e.checksum,
+		e.storedChecksum,
+		e.size,
+		e.mimeType,
+		e.imageInfo,
+		append([]int(nil), e.variants...),
+		e.uploadComplete,
+		e.uploadCompletedAt,
+		e.deleting,
+		e.lastUpdate,
+	)
+}
+
+type mediaMetadataRepository struct {
+	mutex sync.Mutex
+	// ids in insertion order to mimic mongodb's natural order
+	ids                        []model.MediaID
+	entries                    map[model.MediaID]*mediaMetadataEntry
+	incompleteMetadataLifetime time.Duration
+}
+
+// NewMediaMetadataRepository keeps media metadata in memory. Incomplete metadata expires after
+// incompleteMetadataLifetime, like the TTL index does in mongodb.
+func NewMediaMetadataRepository(incompleteMetadataLifetime time.Duration) ports.MediaMetadataRepository {
+	return &mediaMetadataRepository{
+		entries:                    map[model.MediaID]*mediaMetadataEntry{},
+		incompleteMetadataLifetime: incompleteMetadataLifetime,
+	}
+}
+
+func (r *mediaMetadataRepository) Upsert(ctx context.Context, metadata model.MediaMetadata) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	entry, exists := r.entries[metadata.ID()]
+	if !exists {
+		entry = &mediaMetadataEntry{id: metadata.ID()}
+		r.entries[entry.id] = entry
+		r.ids = append(r.ids, entry.id)
+	}
+
+	// mongodb's $set skips empty fields of the document, because they are tagged with omitempty
+	if metadata.Name() != "" {
+		entry.name = metadata.Name()
+	}
+
+	if len(metadata.TagIDs()) > 0 {
+		entry.tagIDs = append([]model.TagID(nil), metadata.TagIDs()...)
+	}
+
+	if metadata.Checksum() != "" {
+		entry.checksum = metadata.Checksum()
+	}
+
+	if metadata.StoredChecksum() != "" {
+		entry.storedChecksum = metadata.StoredChecksum()
+	}
+
+	if metadata.Size() != 0 {
+		entry.size = metadata.Size()
+	}
+
+	if metadata.MimeType() != "" {
+		entry.mimeType = metadata.MimeType()
+	}
+
+	if metadata.ImageInfo() != nil {
+		entry.imageInfo = metadata.ImageInfo()
+	}
+
+	if len(metadata.Variants()) > 0 {
+		entry.variants = append([]int(nil), metadata.Variants()...)
+	}
+
+	entry.uploadComplete = metadata.UploadComplete()
+	entry.uploadCompletedAt = metadata.UploadCompletedAt()
+	entry.deleting = metadata.Deleting()
+	entry.lastUpdate = metadata.LastUpdate()
+
+	return nil
+}
+
+func (r *mediaMetadataRepository) Get(ctx context.Context, id model.MediaID) (model.MediaMetadata, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return nil, errortypes.NewResourceNotFound("media metadata not found")
+	}
+
+	return entry.toModel(), nil
+}
+
+func (r *mediaMetadataRepository) SetUploadComplete(ctx context.Context, id model.MediaID, complete bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	entry.uploadComplete = complete
+	entry.lastUpdate = time.Now()
+	entry.uploadCompletedAt = time.Time{}
+	if complete {
+		entry.uploadCompletedAt = entry.lastUpdate
+	}
+
+	return nil
+}
+
+func (r *mediaMetadataRepository) SetDeleting(ctx context.Context, id model.MediaID, deleting bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	entry.deleting = deleting
+	entry.lastUpdate = time.Now()
+
+	return nil
+}
+
+// AddVariant leaves the last update as it is, because generating variants doesn't lock the metadata
+func (r *mediaMetadataRepository) AddVariant(ctx context.Context, id model.MediaID, size int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	if !slices.Contains(entry.variants, size) {
+		entry.variants = append(entry.variants, size)
+	}
+
+	return nil
+}
+
+func (r *mediaMetadataRepository) Update(
+	ctx context.Context,
+	id model.MediaID,
+	update model.MediaMetadataUpdate,
+) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	entry, ok := r.entries[id]
+	if !ok || entry.deleting {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	if !entry.uploadComplete {
+		return errortypes.NewConflictf("media with id '%v' is currently being uploaded", id)
+	}
+
+	if update.Name != nil {
+		entry.name = *update.Name
+	}
+
+	if update.TagIDs != nil {
+		entry.tagIDs = append([]model.TagID{}, update.TagIDs...)
+	} else {
+		tagIDs := make([]model.TagID, 0, len(entry.tagIDs)+len(update.AddTagIDs))
+		for _, tagID := range entry.tagIDs {
+			if !contains(update.RemoveTagIDs, tagID) && !contains(tagIDs, tagID) {
+				tagIDs = append(tagIDs, tagID)
+			}
+		}
+
+		for _, tagID := range update.AddTagIDs {
+			if !contains(tagIDs, tagID) {
+				tagIDs = append(tagIDs, tagID)
+			}
+		}
+
+		entry.tagIDs = tagIDs
+	}
+
+	entry.lastUpdate = time.Now()
+
+	return nil
+}
+
+func (r *mediaMetadataRepository) FindByTagID(ctx context.Context, id model.TagID) ([]model.MediaMetadata, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	result := make([]model.MediaMetadata, 0)
+	for _, entryID := range r.ids {
+		entry := r.entries[entryID]
+		if contains(entry.tagIDs, id) {
+			result = append(result, entry.toModel())
+		}
+	}
+
+	return result, nil
+}
+
+func (r *mediaMetadataRepository) FindByQuery(
+	ctx context.Context,
+	expression query.Expression,
+) ([]model.MediaMetadata, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	result := make([]model.MediaMetadata, 0)
+	for _, entryID := range r.ids {
+		entry := r.entries[entryID]
+		if expression.Matches(entry.tagIDs) {
+			result = append(result, entry.toModel())
+		}
+	}
+
+	return result, nil
+}
+
+func (r *mediaMetadataRepository) FindByTagIDPage(
+	ctx context.Context,
+	id model.TagID,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	return r.findPage(page, func(entry *mediaMetadataEntry) bool {
+		return contains(entry.tagIDs, id) && filter.Matches(entry.imageInfo)
+	})
+}
+
+func (r *mediaMetadataRepository) FindByQueryPage(
+	ctx context.Context,
+	expression query.Expression,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	return r.findPage(page, func(entry *mediaMetadataEntry) bool {
+		return expression.Matches(entry.tagIDs) && filter.Matches(entry.imageInfo)
+	})
+}
+
+func (r *mediaMetadataRepository) FindAllPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	return r.findPage(page, func(entry *mediaMetadataEntry) bool {
+		return true
+	})
+}
+
+func (r *mediaMetadataRepository) findPage(
+	page model.PageRequest,
+	matches func(entry *mediaMetadataEntry) bool,
+) ([]model.MediaMetadata, bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	ids := slices.Sorted(maps.Keys(r.entries))
+
+	result := make([]model.MediaMetadata, 0)
+	for _, entryID := range ids {
+		entry := r.entries[entryID]
+		if entryID <= page.After || !matches(entry) {
+			continue
+		}
+
+		if len(result) == page.Limit {
+			return result, true, nil
+		}
+
+		result = append(result, entry.toModel())
+	}
+
+	return result, false, nil
+}
+
+func (r *mediaMetadataRepository) FindByChecksum(ctx context.Context, checksum string) (model.MediaMetadata, error) {
+	log := util.Logger(ctx)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.expireIncompleteEntries()
+
+	var found []*mediaMetadataEntry
+	for _, entryID := range r.ids {
+		entry := r.entries[entryID]
+		if entry.checksum == checksum {
+			found = append(found, entry)
+		}
+	}
+
+	if len(found) < 1 {
+		return nil, errortypes.NewResourceNotFoundf("media by checksum %v", checksum)
+	}
+
+	if len(found) > 1 {
+		log.Errorf("found multiple documents with same checksum: %v. Will proceed with first one only.", checksum)
+	}
+
+	return found[0].toModel(), nil
+}
+
+func (r *mediaMetadataRepository) DeleteAll(ctx context.Context, ids []model.MediaID) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.deleteEntries(func(entry *mediaMetadataEntry) bool {
+		return contains(ids, entry.id)
+	})
+
+	return nil
+}
+
+// expireIncompleteEntries mimics the TTL index of mongodb. Must be called with the mutex locked.
+func (r *mediaMetadataRepository) expireIncompleteEntries() {
+	expiredBefore := time.Now().Add(-r.incompleteMetadataLifetime)
+
+	r.deleteEntries(func(entry *mediaMetadataEntry) bool {
+		return !entry.uploadComplete && entry.lastUpdate.Before(expiredBefore)
+	})
+}
+
+// deleteEntries must be called with the mutex locked.
+func (r *mediaMetadataRepository) deleteEntries(shouldDelete func(entry *mediaMetadataEntry) bool) {
+	remaining := make([]model.MediaID, 0, len(r.ids))
+	for _, id := range r.ids {
+		if shouldDelete(r.entries[id]) {
+			delete(r.entries, id)
+			continue
+		}
+
+		remaining = append(remaining, id)
+	}
+
+	r.ids = remaining
+}
@@ -0,0 +1,211 @@
+package amemory
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"slices"
+	"sync"
+	"time"
+)
+
+type mediaRepository struct {
+	mutex       sync.RWMutex
+	blobs       map[string][]byte
+	modified    map[string]time.Time
+	downloadURL string
+	signer      *util.URLSigner
+}
+
+// NewMediaRepository keeps media in memory. Media and upload URLs point to downloadURL/<key> and are signed with
+// signingKey, so they have to be served by the returned downloader and uploader.
+func NewMediaRepository(
+	downloadURL string,
+	signingKey []byte,
+) (ports.MediaRepository, ports.SignedMediaDownloader, ports.SignedMediaUploader) {
+	repo := &mediaRepository{
+		blobs:       map[string][]byte{},
+		modified:    map[string]time.Time{},
+		downloadURL: downloadURL,
+		signer:      util.NewURLSigner(signingKey),
+	}
+
+	return repo, repo, repo
+}
+
+func (r *mediaRepository) CreateMedia(ctx context.Context, key string, file io.Reader, mimeType string) error {
+	return r.createMedia(key, file, true)
+}
+
+// createMedia returns Conflict if replace is false and there is media at key already.
+func (r *mediaRepository) createMedia(key string, file io.Reader, replace bool) error {
+	blob, err := io.ReadAll(file)
+	if err != nil {
+		return errortypes.NewInputOutputErrorf("failed to read media %v: %v", key, err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.blobs[key]; ok && !replace {
+		return errortypes.NewConflictf("media %v exists already", key)
+	}
+
+	r.blobs[key] = blob
+	r.modified[key] = time.Now()
+
+	return nil
+}
+
+func (r *mediaRepository) MoveMedia(ctx context.Context, fromKey string, toKey string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	blob, ok := r.blobs[fromKey]
+	if !ok {
+		return errortypes.NewResourceNotFound(fromKey)
+	}
+
+	r.blobs[toKey] = blob
+	r.modified[toKey] = time.Now()
+	delete(r.blobs, fromKey)
+	delete(r.modified, fromKey)
+
+	return nil
+}
+
+func (r *mediaRepository) OpenMedia(
+	ctx context.Context,
+	key string,
+	byteRange *model.ByteRange,
+) (io.ReadCloser, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	blob, ok := r.blobs[key]
+	if !ok {
+		return nil, errortypes.NewResourceNotFound(key)
+	}
+
+	if byteRange != nil {
+		start := min(byteRange.Offset, int64(len(blob)))
+		end := min(start+byteRange.Length, int64(len(blob)))
+		blob = blob[start:end]
+	}
+
+	// blobs are never modified in place, only replaced. So it's safe to hand out a reader on it.
+	return io.NopCloser(bytes.NewReader(blob)), nil
+}
+
+func (r *mediaRepository) GetMediaURL(ctx context.Context, key string, lifetime time.Duration) (string, error) {
+	return r.signer.SignedURL(r.downloadURL, key, lifetime), nil
+}
+
+// GetUploadURL can't enforce size, checksum and content type. They have to be verified after the upload.
+func (r *mediaRepository) GetUploadURL(
+	ctx context.Context,
+	key string,
+	size int64,
+	checksum string,
+	mimeType string,
+	lifetime time.Duration,
+) (model.UploadURL, error) {
+	return model.UploadURL{URL: r.signer.SignedUploadURL(r.downloadURL, key, lifetime)}, nil
+}
+
+// GetMediaAttributes doesn't know the checksum, like the file system repository.
+func (r *mediaRepository) GetMediaAttributes(ctx context.Context, key string) (model.MediaAttributes, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	blob, ok := r.blobs[key]
+	if !ok {
+		return model.MediaAttributes{}, errortypes.NewResourceNotFound(key)
+	}
+
+	return model.MediaAttributes{Size: int64(len(blob))}, nil
+}
+
+func (r *mediaRepository) DeleteAll(ctx context.Context, keys []string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(r.blobs, key)
+		delete(r.modified, key)
+	}
+
+	return nil
+}
+
+func (r *mediaRepository) OpenSignedMedia(
+	ctx context.Context,
+	key string,
+	expires time.Time,
+	signature string,
+) (io.ReadCloser, error) {
+	if !r.signer.Verify(key, expires, signature) {
+		return nil, errortypes.NewBadUserInput("invalid signature")
+	}
+
+	if time.Now().After(expires) {
+		return nil, errortypes.NewBadUserInput("url expired")
+	}
+
+	return r.OpenMedia(ctx, key, nil)
+}
+
+func (r *mediaRepository) CreateSignedMedia(
+	ctx context.Context,
+	key string,
+	expires time.Time,
+	signature string,
+	file io.Reader,
+) error {
+	if !r.signer.VerifyUpload(key, expires, signature) {
+		return errortypes.NewBadUserInput("invalid signature")
+	}
+
+	if time.Now().After(expires) {
+		return errortypes.NewBadUserInput("url expired")
+	}
+
+	// upload URLs stay valid until they expire. Not replacing media keeps them from being used once more.
+	return r.createMedia(key, file, false)
+}
+
+func (r *mediaRepository) ListMediaPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.StoredMedia, bool, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	keys := slices.Sorted(maps.Keys(r.blobs))
+	start, _ := slices.BinarySearch(keys, page.After)
+	if start < len(keys) && keys[start] == page.After {
+		start++
+	}
+
+	keys = keys[start:]
+	hasMore := len(keys) > page.Limit
+	if hasMore {
+		keys = keys[:page.Limit]
+	}
+
+	media := make([]model.StoredMedia, 0, len(keys))
+	for _, key := range keys {
+		media = append(media, model.StoredMedia{
+			Key:          key,
+			Size:         int64(len(r.blobs[key])),
+			LastModified: r.modified[key],
+		})
+	}
+
+	return media, hasMore, nil
+}
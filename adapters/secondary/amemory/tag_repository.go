@@ -0,0 +1,139 @@
+package amemory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"slices"
+	"strings"
+	"sync"
+)
+
+func NewTagRepository() ports.TagRepository {
+	return &tagRepository{}
+}
+
+type tagRepository struct {
+	mutex sync.RWMutex
+	// kept as slice to list tags in insertion order, like mongodb's natural order does
+	tags []*model.Tag
+}
+
+func createIDForName(name string) (string, error) {
+	hasher := sha256.New()
+	if _, err := hasher.Write([]byte(name)); err != nil {
+		return "", errortypes.NewInputOutputErrorf("failed to hash %v", name)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func (r *tagRepository) CreateTag(ctx context.Context, name string) (model.TagID, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, tag := range r.tags {
+		if tag.Name == name {
+			return tag.ID, nil
+		}
+	}
+
+	id, err := createIDForName(name)
+	if err != nil {
+		return "", err
+	}
+
+	r.tags = append(r.tags, &model.Tag{ID: id, Name: name})
+
+	return id, nil
+}
+
+func (r *tagRepository) ListTags(ctx context.Context) ([]*model.Tag, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var tags []*model.Tag
+
+	for _, tag := range r.tags {
+		tags = append(tags, &model.Tag{ID: tag.ID, Name: tag.Name})
+	}
+
+	return tags, nil
+}
+
+func (r *tagRepository) ListTagsPage(ctx context.Context, page model.PageRequest) ([]*model.Tag, bool, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var tags []*model.Tag
+
+	for _, tag := range r.tags {
+		if tag.ID > page.After {
+			tags = append(tags, &model.Tag{ID: tag.ID, Name: tag.Name})
+		}
+	}
+
+	slices.SortFunc(tags, func(a, b *model.Tag) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	hasMore := len(tags) > page.Limit
+	if hasMore {
+		tags = tags[:page.Limit]
+	}
+
+	return tags, hasMore, nil
+}
+
+func (r *tagRepository) DeleteTags(ctx context.Context, ids []model.TagID) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	remaining := make([]*model.Tag, 0, len(r.tags))
+	for _, tag := range r.tags {
+		if !contains(ids, tag.ID) {
+			remaining = append(remaining, tag)
+		}
+	}
+
+	r.tags = remaining
+
+	return nil
+}
+
+func (r *tagRepository) AllExist(ctx context.Context, ids []model.TagID) (bool, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, id := range ids {
+		if !r.exists(id) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// exists must be called with the mutex locked.
+func (r *tagRepository) exists(id model.TagID) bool {
+	for _, tag := range r.tags {
+		if tag.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package amemory
+
+import (
+	"context"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"sync"
+	"time"
+)
+
+type uploadRepository struct {
+	mutex   sync.Mutex
+	uploads map[model.UploadID]*model.Upload
+}
+
+// NewUploadRepository keeps the state of resumable uploads in memory.
+func NewUploadRepository() ports.UploadRepository {
+	return &uploadRepository{uploads: map[model.UploadID]*model.Upload{}}
+}
+
+func (r *uploadRepository) Create(ctx context.Context, upload *model.Upload) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.uploads[upload.ID]; exists {
+		return errortypes.NewResourceAlreadyExistsf("upload %v", upload.ID)
+	}
+
+	r.uploads[upload.ID] = copyUpload(upload)
+
+	return nil
+}
+
+func (r *uploadRepository) Get(ctx context.Context, id model.UploadID) (*model.Upload, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	upload, ok := r.uploads[id]
+	if !ok {
+		return nil, errortypes.NewResourceNotFound(id)
+	}
+
+	return copyUpload(upload), nil
+}
+
+func (r *uploadRepository) AppendChunk(
+	ctx context.Context,
+	id model.UploadID,
+	offset int64,
+	chunk model.UploadChunk,
+	expiresAt time.Time,
+) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	upload, ok := r.uploads[id]
+	if !ok {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	if upload.Offset != offset {
+		return errortypes.NewConflictf("upload %v is at offset %v, not %v", id, upload.Offset, offset)
+	}
+
+	upload.Chunks = append(upload.Chunks, chunk)
+	upload.Offset += chunk.Size
+	upload.ExpiresAt = expiresAt
+
+	return nil
+}
+
+func (r *uploadRepository) Complete(ctx context.Context, id model.UploadID, mediaID model.MediaID) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	upload, ok := r.uploads[id]
+	if !ok {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	upload.MediaID = mediaID
+	upload.Chunks = nil
+
+	return nil
+}
+
+func (r *uploadRepository) FindExpired(ctx context.Context, before time.Time) ([]*model.Upload, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var expired []*model.Upload
+	for _, upload := range r.uploads {
+		if upload.ExpiresAt.Before(before) {
+			expired = append(expired, copyUpload(upload))
+		}
+	}
+
+	return expired, nil
+}
+
+func (r *uploadRepository) FindAll(ctx context.Context) ([]*model.Upload, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	uploads := make([]*model.Upload, 0, len(r.uploads))
+	for _, upload := range r.uploads {
+		uploads = append(uploads, copyUpload(upload))
+	}
+
+	return uploads, nil
+}
+
+func (r *uploadRepository) Delete(ctx context.Context, id model.UploadID) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.uploads, id)
+
+	return nil
+}
+
+func copyUpload(upload *model.Upload) *model.Upload {
+	c := *upload
+	c.TagIDs = append([]model.TagID(nil), upload.TagIDs...)
+	c.Chunks = append([]model.UploadChunk(nil), upload.Chunks...)
+
+	return &c
+}
@@ -0,0 +1,59 @@
+package ametrics
+
+import (
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/metrics"
+	"media-nexus/ports"
+	"media-nexus/ports/portstest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// the decorators must not change what the repositories do, so they fulfill the contracts of the ones they decorate
+
+func TestTagRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.TagRepositoryContract{
+		Repository: func() ports.TagRepository {
+			return NewTagRepository(amemory.NewTagRepository(), "memory", metrics.NewPrometheusMetrics())
+		},
+	})
+}
+
+func TestMediaMetadataRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.MediaMetadataRepositoryContract{
+		Repository: func() ports.MediaMetadataRepository {
+			return NewMediaMetadataRepository(
+				amemory.NewMediaMetadataRepository(time.Minute),
+				"memory",
+				metrics.NewPrometheusMetrics(),
+			)
+		},
+	})
+}
+
+func TestMediaRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.MediaRepositoryContract{
+		Repository: func() ports.MediaRepository {
+			repo, _, _ := amemory.NewMediaRepository("http://localhost/api/v1/blobs", []byte("key"))
+			return NewMediaRepository(repo, "memory", metrics.NewPrometheusMetrics())
+		},
+	})
+}
+
+func TestUploadRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.UploadRepositoryContract{
+		Repository: func() ports.UploadRepository {
+			return NewUploadRepository(amemory.NewUploadRepository(), "memory", metrics.NewPrometheusMetrics())
+		},
+	})
+}
+
+func TestJobQueueContract(t *testing.T) {
+	suite.Run(t, &portstest.JobQueueContract{
+		Queue: func() ports.JobQueue {
+			return NewJobQueue(amemory.NewJobQueue(), "memory", metrics.NewPrometheusMetrics())
+		},
+	})
+}
@@ -0,0 +1,96 @@
+package ametrics
+
+import (
+	"context"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"time"
+)
+
+type jobQueue struct {
+	next     ports.JobQueue
+	observer observer
+}
+
+// NewJobQueue records the latency of every call to next
+func NewJobQueue(next ports.JobQueue, backend string, m metrics.Metrics) ports.JobQueue {
+	return &jobQueue{next, observer{m, backend, repositoryJobs}}
+}
+
+func (q *jobQueue) Enqueue(ctx context.Context, job *model.Job) error {
+	started := time.Now()
+	err := q.next.Enqueue(ctx, job)
+	q.observer.observe("Enqueue", started, err)
+
+	return err
+}
+
+func (q *jobQueue) Lease(ctx context.Context, jobTypes []string, visibilityTimeout time.Duration) (*model.Job, error) {
+	started := time.Now()
+	job, err := q.next.Lease(ctx, jobTypes, visibilityTimeout)
+	q.observer.observe("Lease", started, err)
+
+	return job, err
+}
+
+func (q *jobQueue) ExtendLease(
+	ctx context.Context,
+	id model.JobID,
+	leaseID string,
+	visibilityTimeout time.Duration,
+) error {
+	started := time.Now()
+	err := q.next.ExtendLease(ctx, id, leaseID, visibilityTimeout)
+	q.observer.observe("ExtendLease", started, err)
+
+	return err
+}
+
+func (q *jobQueue) Complete(ctx context.Context, id model.JobID, leaseID string) error {
+	started := time.Now()
+	err := q.next.Complete(ctx, id, leaseID)
+	q.observer.observe("Complete", started, err)
+
+	return err
+}
+
+func (q *jobQueue) Fail(ctx context.Context, id model.JobID, leaseID string, reason string, retryAt time.Time) error {
+	started := time.Now()
+	err := q.next.Fail(ctx, id, leaseID, reason, retryAt)
+	q.observer.observe("Fail", started, err)
+
+	return err
+}
+
+func (q *jobQueue) DeadLetter(ctx context.Context, id model.JobID, leaseID string, reason string) error {
+	started := time.Now()
+	err := q.next.DeadLetter(ctx, id, leaseID, reason)
+	q.observer.observe("DeadLetter", started, err)
+
+	return err
+}
+
+func (q *jobQueue) FindDeadLetters(ctx context.Context, limit int) ([]*model.Job, error) {
+	started := time.Now()
+	jobs, err := q.next.FindDeadLetters(ctx, limit)
+	q.observer.observe("FindDeadLetters", started, err)
+
+	return jobs, err
+}
+
+func (q *jobQueue) Requeue(ctx context.Context, id model.JobID) error {
+	started := time.Now()
+	err := q.next.Requeue(ctx, id)
+	q.observer.observe("Requeue", started, err)
+
+	return err
+}
+
+func (q *jobQueue) Delete(ctx context.Context, id model.JobID) error {
+	started := time.Now()
+	err := q.next.Delete(ctx, id)
+	q.observer.observe("Delete", started, err)
+
+	return err
+}
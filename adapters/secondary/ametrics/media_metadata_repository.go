@@ -0,0 +1,148 @@
+package ametrics
+
+import (
+	"context"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/services/query"
+	"time"
+)
+
+type mediaMetadataRepository struct {
+	next     ports.MediaMetadataRepository
+	observer observer
+}
+
+// NewMediaMetadataRepository records the latency of every call to next
+func NewMediaMetadataRepository(
+	next ports.MediaMetadataRepository,
+	backend string,
+	m metrics.Metrics,
+) ports.MediaMetadataRepository {
+	return &mediaMetadataRepository{next, observer{m, backend, repositoryMediaMetadata}}
+}
+
+func (r *mediaMetadataRepository) Upsert(ctx context.Context, metadata model.MediaMetadata) error {
+	started := time.Now()
+	err := r.next.Upsert(ctx, metadata)
+	r.observer.observe("Upsert", started, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) Get(ctx context.Context, id model.MediaID) (model.MediaMetadata, error) {
+	started := time.Now()
+	metadata, err := r.next.Get(ctx, id)
+	r.observer.observe("Get", started, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) SetUploadComplete(ctx context.Context, id model.MediaID, complete bool) error {
+	started := time.Now()
+	err := r.next.SetUploadComplete(ctx, id, complete)
+	r.observer.observe("SetUploadComplete", started, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) SetDeleting(ctx context.Context, id model.MediaID, deleting bool) error {
+	started := time.Now()
+	err := r.next.SetDeleting(ctx, id, deleting)
+	r.observer.observe("SetDeleting", started, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) AddVariant(ctx context.Context, id model.MediaID, size int) error {
+	started := time.Now()
+	err := r.next.AddVariant(ctx, id, size)
+	r.observer.observe("AddVariant", started, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) Update(
+	ctx context.Context,
+	id model.MediaID,
+	update model.MediaMetadataUpdate,
+) error {
+	started := time.Now()
+	err := r.next.Update(ctx, id, update)
+	r.observer.observe("Update", started, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) FindByTagID(ctx context.Context, id model.TagID) ([]model.MediaMetadata, error) {
+	started := time.Now()
+	metadata, err := r.next.FindByTagID(ctx, id)
+	r.observer.observe("FindByTagID", started, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) FindByQuery(
+	ctx context.Context,
+	expression query.Expression,
+) ([]model.MediaMetadata, error) {
+	started := time.Now()
+	metadata, err := r.next.FindByQuery(ctx, expression)
+	r.observer.observe("FindByQuery", started, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) FindByTagIDPage(
+	ctx context.Context,
+	id model.TagID,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	started := time.Now()
+	metadata, more, err := r.next.FindByTagIDPage(ctx, id, filter, page)
+	r.observer.observe("FindByTagIDPage", started, err)
+
+	return metadata, more, err
+}
+
+func (r *mediaMetadataRepository) FindByQueryPage(
+	ctx context.Context,
+	expression query.Expression,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	started := time.Now()
+	metadata, more, err := r.next.FindByQueryPage(ctx, expression, filter, page)
+	r.observer.observe("FindByQueryPage", started, err)
+
+	return metadata, more, err
+}
+
+func (r *mediaMetadataRepository) FindAllPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	started := time.Now()
+	metadata, more, err := r.next.FindAllPage(ctx, page)
+	r.observer.observe("FindAllPage", started, err)
+
+	return metadata, more, err
+}
+
+func (r *mediaMetadataRepository) FindByChecksum(ctx context.Context, checksum string) (model.MediaMetadata, error) {
+	started := time.Now()
+	metadata, err := r.next.FindByChecksum(ctx, checksum)
+	r.observer.observe("FindByChecksum", started, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) DeleteAll(ctx context.Context, ids []model.MediaID) error {
+	started := time.Now()
+	err := r.next.DeleteAll(ctx, ids)
+	r.observer.observe("DeleteAll", started, err)
+
+	return err
+}
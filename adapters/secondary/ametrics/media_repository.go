@@ -0,0 +1,99 @@
+package ametrics
+
+import (
+	"context"
+	"io"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"time"
+)
+
+type mediaRepository struct {
+	next     ports.MediaRepository
+	observer observer
+}
+
+// NewMediaRepository records the latency of every call to next. Opening media is recorded until it's opened, not
+// until it's read.
+func NewMediaRepository(next ports.MediaRepository, backend string, m metrics.Metrics) ports.MediaRepository {
+	return &mediaRepository{next, observer{m, backend, repositoryMedia}}
+}
+
+func (r *mediaRepository) CreateMedia(ctx context.Context, key string, file io.Reader, mimeType string) error {
+	started := time.Now()
+	err := r.next.CreateMedia(ctx, key, file, mimeType)
+	r.observer.observe("CreateMedia", started, err)
+
+	return err
+}
+
+func (r *mediaRepository) MoveMedia(ctx context.Context, fromKey string, toKey string) error {
+	started := time.Now()
+	err := r.next.MoveMedia(ctx, fromKey, toKey)
+	r.observer.observe("MoveMedia", started, err)
+
+	return err
+}
+
+func (r *mediaRepository) OpenMedia(
+	ctx context.Context,
+	key string,
+	byteRange *model.ByteRange,
+) (io.ReadCloser, error) {
+	started := time.Now()
+	media, err := r.next.OpenMedia(ctx, key, byteRange)
+	r.observer.observe("OpenMedia", started, err)
+
+	return media, err
+}
+
+func (r *mediaRepository) GetMediaAttributes(ctx context.Context, key string) (model.MediaAttributes, error) {
+	started := time.Now()
+	attributes, err := r.next.GetMediaAttributes(ctx, key)
+	r.observer.observe("GetMediaAttributes", started, err)
+
+	return attributes, err
+}
+
+func (r *mediaRepository) GetMediaURL(ctx context.Context, key string, lifetime time.Duration) (string, error) {
+	started := time.Now()
+	url, err := r.next.GetMediaURL(ctx, key, lifetime)
+	r.observer.observe("GetMediaURL", started, err)
+
+	return url, err
+}
+
+func (r *mediaRepository) GetUploadURL(
+	ctx context.Context,
+	key string,
+	size int64,
+	checksum string,
+	mimeType string,
+	lifetime time.Duration,
+) (model.UploadURL, error) {
+	started := time.Now()
+	url, err := r.next.GetUploadURL(ctx, key, size, checksum, mimeType, lifetime)
+	r.observer.observe("GetUploadURL", started, err)
+
+	return url, err
+}
+
+func (r *mediaRepository) DeleteAll(ctx context.Context, keys []string) error {
+	started := time.Now()
+	err := r.next.DeleteAll(ctx, keys)
+	r.observer.observe("DeleteAll", started, err)
+
+	return err
+}
+
+func (r *mediaRepository) ListMediaPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.StoredMedia, bool, error) {
+	started := time.Now()
+	media, more, err := r.next.ListMediaPage(ctx, page)
+	r.observer.observe("ListMediaPage", started, err)
+
+	return media, more, err
+}
@@ -0,0 +1,26 @@
+package ametrics
+
+import (
+	"media-nexus/metrics"
+	"time"
+)
+
+// repository names used as label
+const (
+	repositoryMedia         = "media"
+	repositoryMediaMetadata = "media_metadata"
+	repositoryTags          = "tags"
+	repositoryUploads       = "uploads"
+	repositoryJobs          = "jobs"
+)
+
+// observer records the calls of one repository of a backend, e.g. the media metadata in mongodb
+type observer struct {
+	metrics    metrics.Metrics
+	backend    string
+	repository string
+}
+
+func (o observer) observe(operation string, started time.Time, err error) {
+	o.metrics.ObserveStorageCall(o.backend, o.repository, operation, time.Since(started), err)
+}
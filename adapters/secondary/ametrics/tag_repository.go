@@ -0,0 +1,59 @@
+package ametrics
+
+import (
+	"context"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"time"
+)
+
+type tagRepository struct {
+	next     ports.TagRepository
+	observer observer
+}
+
+// NewTagRepository records the latency of every call to next
+func NewTagRepository(next ports.TagRepository, backend string, m metrics.Metrics) ports.TagRepository {
+	return &tagRepository{next, observer{m, backend, repositoryTags}}
+}
+
+func (r *tagRepository) CreateTag(ctx context.Context, name string) (model.TagID, error) {
+	started := time.Now()
+	id, err := r.next.CreateTag(ctx, name)
+	r.observer.observe("CreateTag", started, err)
+
+	return id, err
+}
+
+func (r *tagRepository) ListTags(ctx context.Context) ([]*model.Tag, error) {
+	started := time.Now()
+	tags, err := r.next.ListTags(ctx)
+	r.observer.observe("ListTags", started, err)
+
+	return tags, err
+}
+
+func (r *tagRepository) ListTagsPage(ctx context.Context, page model.PageRequest) ([]*model.Tag, bool, error) {
+	started := time.Now()
+	tags, more, err := r.next.ListTagsPage(ctx, page)
+	r.observer.observe("ListTagsPage", started, err)
+
+	return tags, more, err
+}
+
+func (r *tagRepository) DeleteTags(ctx context.Context, ids []model.TagID) error {
+	started := time.Now()
+	err := r.next.DeleteTags(ctx, ids)
+	r.observer.observe("DeleteTags", started, err)
+
+	return err
+}
+
+func (r *tagRepository) AllExist(ctx context.Context, ids []model.TagID) (bool, error) {
+	started := time.Now()
+	allExist, err := r.next.AllExist(ctx, ids)
+	r.observer.observe("AllExist", started, err)
+
+	return allExist, err
+}
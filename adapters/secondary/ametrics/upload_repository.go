@@ -0,0 +1,81 @@
+package ametrics
+
+import (
+	"context"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"time"
+)
+
+type uploadRepository struct {
+	next     ports.UploadRepository
+	observer observer
+}
+
+// NewUploadRepository records the latency of every call to next
+func NewUploadRepository(next ports.UploadRepository, backend string, m metrics.Metrics) ports.UploadRepository {
+	return &uploadRepository{next, observer{m, backend, repositoryUploads}}
+}
+
+func (r *uploadRepository) Create(ctx context.Context, upload *model.Upload) error {
+	started := time.Now()
+	err := r.next.Create(ctx, upload)
+	r.observer.observe("Create", started, err)
+
+	return err
+}
+
+func (r *uploadRepository) Get(ctx context.Context, id model.UploadID) (*model.Upload, error) {
+	started := time.Now()
+	upload, err := r.next.Get(ctx, id)
+	r.observer.observe("Get", started, err)
+
+	return upload, err
+}
+
+func (r *uploadRepository) AppendChunk(
+	ctx context.Context,
+	id model.UploadID,
+	offset int64,
+	chunk model.UploadChunk,
+	expiresAt time.Time,
+) error {
+	started := time.Now()
+	err := r.next.AppendChunk(ctx, id, offset, chunk, expiresAt)
+	r.observer.observe("AppendChunk", started, err)
+
+	return err
+}
+
+func (r *uploadRepository) Complete(ctx context.Context, id model.UploadID, mediaID model.MediaID) error {
+	started := time.Now()
+	err := r.next.Complete(ctx, id, mediaID)
+	r.observer.observe("Complete", started, err)
+
+	return err
+}
+
+func (r *uploadRepository) FindExpired(ctx context.Context, before time.Time) ([]*model.Upload, error) {
+	started := time.Now()
+	uploads, err := r.next.FindExpired(ctx, before)
+	r.observer.observe("FindExpired", started, err)
+
+	return uploads, err
+}
+
+func (r *uploadRepository) FindAll(ctx context.Context) ([]*model.Upload, error) {
+	started := time.Now()
+	uploads, err := r.next.FindAll(ctx)
+	r.observer.observe("FindAll", started, err)
+
+	return uploads, err
+}
+
+func (r *uploadRepository) Delete(ctx context.Context, id model.UploadID) error {
+	started := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observer.observe("Delete", started, err)
+
+	return err
+}
@@ -0,0 +1,65 @@
+package ammodel
+
+import (
+	"media-nexus/model"
+	"time"
+)
+
+// ImageInfoDocument stores the capture time as date, so it can be compared in filters.
+type ImageInfoDocument struct {
+	Width       int             `bson:"width"`
+	Height      int             `bson:"height"`
+	Orientation int             `bson:"orientation,omitempty"`
+	CaptureTime *time.Time      `bson:"capture_time,omitempty"`
+	CameraModel string          `bson:"camera_model,omitempty"`
+	GPS         *GPSCoordinates `bson:"gps,omitempty"`
+}
+
+type GPSCoordinates struct {
+	Latitude  float64 `bson:"latitude"`
+	Longitude float64 `bson:"longitude"`
+}
+
+func NewImageInfoDocument(info *model.ImageInfo) *ImageInfoDocument {
+	if info == nil {
+		return nil
+	}
+
+	doc := &ImageInfoDocument{
+		Width:       info.Width,
+		Height:      info.Height,
+		Orientation: info.Orientation,
+		CaptureTime: info.CaptureTime,
+		CameraModel: info.CameraModel,
+	}
+
+	if info.GPS != nil {
+		doc.GPS = &GPSCoordinates{Latitude: info.GPS.Latitude, Longitude: info.GPS.Longitude}
+	}
+
+	return doc
+}
+
+func (d *ImageInfoDocument) ToModel() *model.ImageInfo {
+	if d == nil {
+		return nil
+	}
+
+	info := &model.ImageInfo{
+		Width:       d.Width,
+		Height:      d.Height,
+		Orientation: d.Orientation,
+		CameraModel: d.CameraModel,
+	}
+
+	if d.CaptureTime != nil {
+		captureTime := d.CaptureTime.UTC()
+		info.CaptureTime = &captureTime
+	}
+
+	if d.GPS != nil {
+		info.GPS = &model.GPSCoordinates{Latitude: d.GPS.Latitude, Longitude: d.GPS.Longitude}
+	}
+
+	return info
+}
@@ -0,0 +1,46 @@
+package ammodel
+
+import (
+	"media-nexus/model"
+	"time"
+)
+
+type JobDocument struct {
+	ID         string    `bson:"_id"`
+	Type       string    `bson:"type"`
+	Payload    string    `bson:"payload"`
+	Attempts   int       `bson:"attempts"`
+	LeaseID    string    `bson:"lease_id,omitempty"`
+	VisibleAt  time.Time `bson:"visible_at"`
+	LastError  string    `bson:"last_error,omitempty"`
+	DeadLetter bool      `bson:"dead_letter"`
+	CreatedAt  time.Time `bson:"created_at"`
+}
+
+func NewJobDocument(job *model.Job) *JobDocument {
+	return &JobDocument{
+		ID:         job.ID,
+		Type:       job.Type,
+		Payload:    job.Payload,
+		Attempts:   job.Attempts,
+		LeaseID:    job.LeaseID,
+		VisibleAt:  job.VisibleAt,
+		LastError:  job.LastError,
+		DeadLetter: job.DeadLetter,
+		CreatedAt:  job.CreatedAt,
+	}
+}
+
+func (d *JobDocument) ToModel() *model.Job {
+	return &model.Job{
+		ID:         d.ID,
+		Type:       d.Type,
+		Payload:    d.Payload,
+		Attempts:   d.Attempts,
+		LeaseID:    d.LeaseID,
+		VisibleAt:  d.VisibleAt,
+		LastError:  d.LastError,
+		DeadLetter: d.DeadLetter,
+		CreatedAt:  d.CreatedAt,
+	}
+}
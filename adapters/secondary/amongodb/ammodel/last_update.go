@@ -0,0 +1,46 @@
+package ammodel
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// LastUpdate is stored as BSON date, so the TTL index and date range queries work on it. Documents written before
+// migration 1 store it as RFC3339 string, which is still read until they are migrated.
+type LastUpdate time.Time
+
+func (t LastUpdate) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(time.Time(t))
+}
+
+func (t *LastUpdate) UnmarshalBSONValue(valueType bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: valueType, Value: data}
+
+	switch valueType {
+	case bson.TypeDateTime:
+		*t = LastUpdate(value.Time())
+	case bson.TypeString:
+		parsed, err := time.Parse(time.RFC3339Nano, value.StringValue())
+		if err != nil {
+			return fmt.Errorf("failed to parse last update: %w", err)
+		}
+
+		*t = LastUpdate(parsed)
+	default:
+		return fmt.Errorf("last update of unexpected type %v", valueType)
+	}
+
+	return nil
+}
+
+// IsZero lets omitempty leave out unset last updates
+func (t LastUpdate) IsZero() bool {
+	return time.Time(t).IsZero()
+}
+
+func (t LastUpdate) Time() time.Time {
+	return time.Time(t)
+}
@@ -1,45 +1,61 @@
 package ammodel
 
 import (
-	"media-nexus/errortypes"
 	"media-nexus/logger"
 	"media-nexus/model"
 	"time"
 )
 
 type MediaMetadataDocument struct {
-	ID             string   `bson:"_id,omitempty"`
-	Name           string   `bson:"name,omitempty"`
-	TagIDs         []string `bson:"tag_ids,omitempty"`
-	Checksum       string   `bson:"checksum,omitempty"`
-	UploadComplete bool     `bson:"upload_complete"`
-	LastUpdate     string   `bson:"last_update,omitempty"`
+	ID             string             `bson:"_id,omitempty"`
+	Name           string             `bson:"name,omitempty"`
+	TagIDs         []string           `bson:"tag_ids,omitempty"`
+	Checksum       string             `bson:"checksum,omitempty"`
+	StoredChecksum string             `bson:"stored_checksum,omitempty"`
+	Size           int64              `bson:"size,omitempty"`
+	MimeType       string             `bson:"mime_type,omitempty"`
+	ImageInfo      *ImageInfoDocument `bson:"image_info,omitempty"`
+	Variants       []int              `bson:"variants,omitempty"`
+	UploadComplete bool               `bson:"upload_complete"`
+	// UploadCompletedAt is missing for media completed before migration 2
+	UploadCompletedAt time.Time  `bson:"upload_completed_at,omitempty"`
+	Deleting          bool       `bson:"deleting,omitempty"`
+	LastUpdate        LastUpdate `bson:"last_update,omitempty"`
 }
 
 func NewMediaMetadataDocument(metadata model.MediaMetadata) *MediaMetadataDocument {
 	return &MediaMetadataDocument{
-		ID:             metadata.ID(),
-		Name:           metadata.Name(),
-		TagIDs:         metadata.TagIDs(),
-		Checksum:       metadata.Checksum(),
-		UploadComplete: metadata.UploadComplete(),
-		LastUpdate:     LastUpdateToString(metadata.LastUpdate()),
+		ID:                metadata.ID(),
+		Name:              metadata.Name(),
+		TagIDs:            metadata.TagIDs(),
+		Checksum:          metadata.Checksum(),
+		StoredChecksum:    metadata.StoredChecksum(),
+		Size:              metadata.Size(),
+		MimeType:          metadata.MimeType(),
+		ImageInfo:         NewImageInfoDocument(metadata.ImageInfo()),
+		Variants:          metadata.Variants(),
+		UploadComplete:    metadata.UploadComplete(),
+		UploadCompletedAt: metadata.UploadCompletedAt(),
+		Deleting:          metadata.Deleting(),
+		LastUpdate:        LastUpdate(metadata.LastUpdate()),
 	}
 }
 
 func (d *MediaMetadataDocument) ToModel() (model.MediaMetadata, error) {
-	t, err := time.Parse(time.RFC3339Nano, d.LastUpdate)
-	if err != nil {
-		return nil, errortypes.NewInputOutputErrorf("failed to parse last update of media metadata document: %v", err)
-	}
-
 	return model.NewMediaMetadata(
 		model.MediaID(d.ID),
 		d.Name,
 		d.TagIDs,
 		d.Checksum,
+		d.StoredChecksum,
+		d.Size,
+		d.MimeType,
+		d.ImageInfo.ToModel(),
+		d.Variants,
 		d.UploadComplete,
-		t,
+		d.UploadCompletedAt,
+		d.Deleting,
+		d.LastUpdate.Time(),
 	), nil
 }
 
@@ -57,7 +73,3 @@ func MediaMetadataDocumentsToModel(docs []*MediaMetadataDocument, log logger.Log
 
 	return result, nil
 }
-
-func LastUpdateToString(t time.Time) string {
-	return t.Format(time.RFC3339Nano)
-}
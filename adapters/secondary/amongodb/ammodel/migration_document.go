@@ -0,0 +1,12 @@
+package ammodel
+
+import (
+	"time"
+)
+
+// MigrationDocument records an applied migration. The version is the ID, so a migration is recorded only once.
+type MigrationDocument struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
@@ -0,0 +1,62 @@
+package ammodel
+
+import (
+	"media-nexus/model"
+	"time"
+)
+
+type UploadDocument struct {
+	ID        string                 `bson:"_id"`
+	Name      string                 `bson:"name"`
+	TagIDs    []string               `bson:"tag_ids"`
+	Length    int64                  `bson:"length"`
+	Offset    int64                  `bson:"offset"`
+	Chunks    []*UploadChunkDocument `bson:"chunks"`
+	MediaID   string                 `bson:"media_id,omitempty"`
+	ExpiresAt time.Time              `bson:"expires_at"`
+}
+
+type UploadChunkDocument struct {
+	Key  string `bson:"key"`
+	Size int64  `bson:"size"`
+}
+
+func NewUploadDocument(upload *model.Upload) *UploadDocument {
+	chunks := make([]*UploadChunkDocument, 0, len(upload.Chunks))
+	for _, chunk := range upload.Chunks {
+		chunks = append(chunks, NewUploadChunkDocument(chunk))
+	}
+
+	return &UploadDocument{
+		ID:        upload.ID,
+		Name:      upload.Name,
+		TagIDs:    upload.TagIDs,
+		Length:    upload.Length,
+		Offset:    upload.Offset,
+		Chunks:    chunks,
+		MediaID:   upload.MediaID,
+		ExpiresAt: upload.ExpiresAt,
+	}
+}
+
+func NewUploadChunkDocument(chunk model.UploadChunk) *UploadChunkDocument {
+	return &UploadChunkDocument{Key: chunk.Key, Size: chunk.Size}
+}
+
+func (d *UploadDocument) ToModel() *model.Upload {
+	chunks := make([]model.UploadChunk, 0, len(d.Chunks))
+	for _, chunk := range d.Chunks {
+		chunks = append(chunks, model.UploadChunk{Key: chunk.Key, Size: chunk.Size})
+	}
+
+	return &model.Upload{
+		ID:        d.ID,
+		Name:      d.Name,
+		TagIDs:    d.TagIDs,
+		Length:    d.Length,
+		Offset:    d.Offset,
+		Chunks:    chunks,
+		MediaID:   d.MediaID,
+		ExpiresAt: d.ExpiresAt,
+	}
+}
@@ -6,10 +6,21 @@ import (
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func NewMongoDBClient(ctx context.Context, uri string) (*mongo.Client, error) {
-	mongodbClient, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+// NewMongoDBClient traces the commands sent to MongoDB with the tracer provider
+func NewMongoDBClient(
+	ctx context.Context,
+	uri string,
+	tracerProvider trace.TracerProvider,
+) (*mongo.Client, error) {
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetMonitor(otelmongo.NewMonitor(otelmongo.WithTracerProvider(tracerProvider)))
+
+	mongodbClient, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, errortypes.NewUpstreamUnavailablef("mongodb unavailable: %v", err)
 	}
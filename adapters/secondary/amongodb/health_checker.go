@@ -0,0 +1,26 @@
+package amongodb
+
+import (
+	"context"
+	"media-nexus/ports"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+type healthChecker struct {
+	client *mongo.Client
+}
+
+// NewHealthChecker pings the primary, which all writes go to
+func NewHealthChecker(client *mongo.Client) ports.HealthChecker {
+	return &healthChecker{client}
+}
+
+func (c *healthChecker) Name() string {
+	return "mongodb"
+}
+
+func (c *healthChecker) Check(ctx context.Context) error {
+	return handleError(c.client.Ping(ctx, readpref.Primary()))
+}
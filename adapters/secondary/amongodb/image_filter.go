@@ -0,0 +1,63 @@
+package amongodb
+
+import (
+	"media-nexus/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// withImageFilter narrows down filter by the image info. An empty image filter leaves it as it is, so media without
+// image info is found as well.
+func withImageFilter(filter bson.M, imageFilter model.ImageFilter) bson.M {
+	if imageFilter.IsEmpty() {
+		return filter
+	}
+
+	return bson.M{"$and": bson.A{filter, imageFilterToBSON(imageFilter)}}
+}
+
+func imageFilterToBSON(filter model.ImageFilter) bson.M {
+	result := bson.M{"image_info": bson.M{"$exists": true}}
+
+	addRange(result, "image_info.width", filter.MinWidth, filter.MaxWidth)
+	addRange(result, "image_info.height", filter.MinHeight, filter.MaxHeight)
+
+	if filter.CameraModel != "" {
+		result["image_info.camera_model"] = filter.CameraModel
+	}
+
+	captureTime := bson.M{}
+	if filter.CapturedAfter != nil {
+		captureTime["$gt"] = *filter.CapturedAfter
+	}
+
+	if filter.CapturedBefore != nil {
+		captureTime["$lt"] = *filter.CapturedBefore
+	}
+
+	if len(captureTime) > 0 {
+		result["image_info.capture_time"] = captureTime
+	}
+
+	if filter.HasGPS != nil {
+		result["image_info.gps"] = bson.M{"$exists": *filter.HasGPS}
+	}
+
+	return result
+}
+
+// addRange adds min and max, if they are set
+func addRange(filter bson.M, field string, minValue int, maxValue int) {
+	valueRange := bson.M{}
+	if minValue > 0 {
+		valueRange["$gte"] = minValue
+	}
+
+	if maxValue > 0 {
+		valueRange["$lte"] = maxValue
+	}
+
+	if len(valueRange) > 0 {
+		filter[field] = valueRange
+	}
+}
@@ -0,0 +1,48 @@
+package amongodb
+
+import (
+	"media-nexus/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type imageFilterTestSuite struct {
+	suite.Suite
+}
+
+func TestImageFilter(t *testing.T) {
+	suite.Run(t, &imageFilterTestSuite{})
+}
+
+func (s *imageFilterTestSuite) TestEmptyFilter() {
+	filter := bson.M{"tag_ids": "a"}
+	s.Equal(filter, withImageFilter(filter, model.ImageFilter{}))
+}
+
+func (s *imageFilterTestSuite) TestFilter() {
+	capturedAfter := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	hasGPS := false
+
+	imageFilter := model.ImageFilter{
+		MinWidth:      100,
+		MaxHeight:     200,
+		CameraModel:   "Pixel 7",
+		CapturedAfter: &capturedAfter,
+		HasGPS:        &hasGPS,
+	}
+
+	s.Equal(bson.M{"$and": bson.A{
+		bson.M{"tag_ids": "a"},
+		bson.M{
+			"image_info":              bson.M{"$exists": true},
+			"image_info.width":        bson.M{"$gte": 100},
+			"image_info.height":       bson.M{"$lte": 200},
+			"image_info.camera_model": "Pixel 7",
+			"image_info.capture_time": bson.M{"$gt": capturedAfter},
+			"image_info.gps":          bson.M{"$exists": false},
+		},
+	}}, withImageFilter(bson.M{"tag_ids": "a"}, imageFilter))
+}
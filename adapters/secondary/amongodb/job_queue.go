@@ -0,0 +1,219 @@
+package amongodb
+
+import (
+	"context"
+	"media-nexus/adapters/secondary/amongodb/ammodel"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+type jobQueue struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewJobQueue shares the jobs between all instances. Leases are taken with a single findOneAndUpdate, so two workers
+// never lease the same job at once.
+func NewJobQueue(client *mongo.Client, database string, collection string) (ports.JobQueue, util.Runner) {
+	queue := &jobQueue{client, database, collection}
+
+	runner := func(ctx context.Context) {
+		log := util.Logger(ctx)
+
+		if err := queue.ensureLeaseIndex(ctx); err != nil {
+			log.Errorf("failed to ensure indices for jobs %v:%v: %v", database, collection, err)
+		}
+	}
+
+	return queue, runner
+}
+
+// jobs uses the majority write concern, because leases act as locks
+func (q *jobQueue) jobs() *mongo.Collection {
+	return q.client.Database(q.database).
+		Collection(q.collection, options.Collection().SetWriteConcern(writeconcern.Majority()))
+}
+
+// ensureLeaseIndex covers the query of Lease. FindDeadLetters is rare enough to scan the dead letters.
+func (q *jobQueue) ensureLeaseIndex(ctx context.Context) error {
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "dead_letter", Value: 1},
+			{Key: "type", Value: 1},
+			{Key: "visible_at", Value: 1},
+		},
+		Options: options.Index().SetName("lease_index"),
+	}
+
+	_, err := q.jobs().Indexes().CreateOne(ctx, index)
+	return handleError(err)
+}
+
+func (q *jobQueue) Enqueue(ctx context.Context, job *model.Job) error {
+	_, err := q.jobs().InsertOne(ctx, ammodel.NewJobDocument(job))
+	return handleError(err)
+}
+
+func (q *jobQueue) Lease(
+	ctx context.Context,
+	jobTypes []string,
+	visibilityTimeout time.Duration,
+) (*model.Job, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"dead_letter": false,
+		"type":        bson.M{"$in": jobTypes},
+		"visible_at":  bson.M{"$lte": now},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"lease_id":   primitive.NewObjectID().Hex(),
+			"visible_at": now.Add(visibilityTimeout),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "visible_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var doc ammodel.JobDocument
+
+	err := q.jobs().FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, errortypes.NewResourceNotFoundf("no visible jobs of types %v", jobTypes)
+	} else if err := handleError(err); err != nil {
+		return nil, err
+	}
+
+	return doc.ToModel(), nil
+}
+
+func (q *jobQueue) ExtendLease(
+	ctx context.Context,
+	id model.JobID,
+	leaseID string,
+	visibilityTimeout time.Duration,
+) error {
+	update := bson.M{"$set": bson.M{"visible_at": time.Now().Add(visibilityTimeout)}}
+
+	return q.updateLeasedJob(ctx, id, leaseID, update)
+}
+
+func (q *jobQueue) Complete(ctx context.Context, id model.JobID, leaseID string) error {
+	result, err := q.jobs().DeleteOne(ctx, leasedJobFilter(id, leaseID))
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.DeletedCount < 1 {
+		return errortypes.NewConflictf("job %v is not leased by %v anymore", id, leaseID)
+	}
+
+	return nil
+}
+
+func (q *jobQueue) Fail(
+	ctx context.Context,
+	id model.JobID,
+	leaseID string,
+	reason string,
+	retryAt time.Time,
+) error {
+	update := bson.M{
+		"$set":   bson.M{"last_error": reason, "visible_at": retryAt},
+		"$unset": bson.M{"lease_id": ""},
+	}
+
+	return q.updateLeasedJob(ctx, id, leaseID, update)
+}
+
+func (q *jobQueue) DeadLetter(ctx context.Context, id model.JobID, leaseID string, reason string) error {
+	update := bson.M{
+		"$set":   bson.M{"last_error": reason, "dead_letter": true},
+		"$unset": bson.M{"lease_id": ""},
+	}
+
+	return q.updateLeasedJob(ctx, id, leaseID, update)
+}
+
+func (q *jobQueue) updateLeasedJob(ctx context.Context, id model.JobID, leaseID string, update bson.M) error {
+	result, err := q.jobs().UpdateOne(ctx, leasedJobFilter(id, leaseID), update)
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		return errortypes.NewConflictf("job %v is not leased by %v anymore", id, leaseID)
+	}
+
+	return nil
+}
+
+func leasedJobFilter(id model.JobID, leaseID string) bson.M {
+	return bson.M{"_id": id, "lease_id": leaseID, "dead_letter": false}
+}
+
+func (q *jobQueue) FindDeadLetters(ctx context.Context, limit int) ([]*model.Job, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := q.jobs().Find(ctx, bson.M{"dead_letter": true}, opts)
+	if err := handleError(err); err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	var deadLetters []*model.Job
+
+	for cursor.Next(ctx) {
+		var doc ammodel.JobDocument
+		if err := handleError(cursor.Decode(&doc)); err != nil {
+			return nil, err
+		}
+
+		deadLetters = append(deadLetters, doc.ToModel())
+	}
+
+	if err := handleError(cursor.Err()); err != nil {
+		return nil, err
+	}
+
+	return deadLetters, nil
+}
+
+func (q *jobQueue) Requeue(ctx context.Context, id model.JobID) error {
+	update := bson.M{
+		"$set": bson.M{"dead_letter": false, "attempts": 0, "visible_at": time.Now()},
+	}
+
+	result, err := q.jobs().UpdateOne(ctx, bson.M{"_id": id, "dead_letter": true}, update)
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		return errortypes.NewResourceNotFoundf("dead letter %v", id)
+	}
+
+	return nil
+}
+
+func (q *jobQueue) Delete(ctx context.Context, id model.JobID) error {
+	_, err := q.jobs().DeleteOne(ctx, bson.M{"_id": id})
+	return handleError(err)
+}
@@ -6,6 +6,7 @@ import (
 	"media-nexus/errortypes"
 	"media-nexus/model"
 	"media-nexus/ports"
+	"media-nexus/services/query"
 	"media-nexus/util"
 	"time"
 
@@ -109,6 +110,20 @@ func (r *mediaMetadataRepository) Upsert(ctx context.Context, metadata model.Med
 
 	filter := bson.M{"_id": doc.ID}
 	update := bson.M{"$set": doc}
+
+	// omitted fields are cleared explicitly
+	unset := bson.M{}
+	if !doc.Deleting {
+		unset["deleting"] = ""
+	}
+
+	if doc.UploadCompletedAt.IsZero() {
+		unset["upload_completed_at"] = ""
+	}
+
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
 	opts := options.Update().SetUpsert(true)
 
 	_, err := collection.UpdateOne(ctx, filter, update, opts)
@@ -120,9 +135,10 @@ func (r *mediaMetadataRepository) Upsert(ctx context.Context, metadata model.Med
 }
 
 func (r *mediaMetadataRepository) SetUploadComplete(ctx context.Context, id model.MediaID, complete bool) error {
+	now := time.Now()
 	doc := &ammodel.MediaMetadataDocument{
 		UploadComplete: complete,
-		LastUpdate:     ammodel.LastUpdateToString(time.Now()),
+		LastUpdate:     ammodel.LastUpdate(now),
 	}
 
 	collection := r.client.Database(r.database).Collection(r.collection)
@@ -132,6 +148,43 @@ func (r *mediaMetadataRepository) SetUploadComplete(ctx context.Context, id mode
 		"$set": doc,
 	}
 
+	if complete {
+		doc.UploadCompletedAt = now
+	} else {
+		update["$unset"] = bson.M{"upload_completed_at": ""}
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	return nil
+}
+
+func (r *mediaMetadataRepository) SetDeleting(ctx context.Context, id model.MediaID, deleting bool) error {
+	// majority writeconcern: marking the metadata as deleting acts as a lock, like the upsert does
+	collection := r.client.Database(r.database).
+		Collection(r.collection, options.Collection().SetWriteConcern(writeconcern.Majority()))
+
+	filter := bson.M{"_id": id}
+
+	var update bson.M
+	if deleting {
+		update = bson.M{
+			"$set": bson.M{"deleting": true, "last_update": time.Now()},
+		}
+	} else {
+		update = bson.M{
+			"$set":   bson.M{"last_update": time.Now()},
+			"$unset": bson.M{"deleting": ""},
+		}
+	}
+
 	result, err := collection.UpdateOne(ctx, filter, update)
 	if err := handleError(err); err != nil {
 		return err
@@ -144,6 +197,79 @@ func (r *mediaMetadataRepository) SetUploadComplete(ctx context.Context, id mode
 	return nil
 }
 
+// AddVariant leaves the last update as it is, because generating variants doesn't lock the metadata
+func (r *mediaMetadataRepository) AddVariant(ctx context.Context, id model.MediaID, size int) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"variants": size}})
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	return nil
+}
+
+func (r *mediaMetadataRepository) Update(
+	ctx context.Context,
+	id model.MediaID,
+	update model.MediaMetadataUpdate,
+) error {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	// the upload must be complete, so metadata of media being uploaded is never changed, even if the upload starts
+	// after the service checked it
+	filter := bson.M{"_id": id, "upload_complete": true, "deleting": bson.M{"$ne": true}}
+
+	// an update pipeline, so adding and removing tags happens atomically. Values are wrapped into $literal, else
+	// strings starting with $ would be taken as field paths.
+	set := bson.M{"last_update": bson.M{"$literal": time.Now()}}
+
+	if update.Name != nil {
+		set["name"] = bson.M{"$literal": *update.Name}
+	}
+
+	if update.TagIDs != nil {
+		set["tag_ids"] = bson.M{"$literal": update.TagIDs}
+	} else if len(update.AddTagIDs) > 0 || len(update.RemoveTagIDs) > 0 {
+		set["tag_ids"] = bson.M{
+			"$setUnion": bson.A{
+				bson.M{"$setDifference": bson.A{
+					bson.M{"$ifNull": bson.A{"$tag_ids", bson.A{}}},
+					bson.M{"$literal": append([]model.TagID{}, update.RemoveTagIDs...)},
+				}},
+				bson.M{"$literal": append([]model.TagID{}, update.AddTagIDs...)},
+			},
+		}
+	}
+
+	pipeline := mongo.Pipeline{bson.D{{Key: "$set", Value: set}}}
+
+	result, err := collection.UpdateOne(ctx, filter, pipeline)
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		// tell missing media apart from media being uploaded
+		metadata, err := r.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if metadata.Deleting() {
+			return errortypes.NewResourceNotFound(id)
+		}
+
+		return errortypes.NewConflictf("media with id '%v' is currently being uploaded", id)
+	}
+
+	return nil
+}
+
 func (r *mediaMetadataRepository) FindByChecksum(ctx context.Context, checksum string) (model.MediaMetadata, error) {
 	log := util.Logger(ctx)
 
@@ -185,52 +311,111 @@ func (r *mediaMetadataRepository) FindByTagID(ctx context.Context, id model.TagI
 	return result, nil
 }
 
-func (r *mediaMetadataRepository) findDocumentsByChecksum(
+func (r *mediaMetadataRepository) FindByQuery(
 	ctx context.Context,
-	checksum string,
-) ([]*ammodel.MediaMetadataDocument, error) {
-	collection := r.client.Database(r.database).Collection(r.collection)
-
-	filter := bson.M{"checksum": checksum}
+	expression query.Expression,
+) ([]model.MediaMetadata, error) {
+	filter, err := queryToFilter(expression)
+	if err != nil {
+		return nil, err
+	}
 
-	cursor, err := collection.Find(ctx, filter)
-	if err := handleError(err); err != nil {
+	docs, err := r.findDocuments(ctx, filter)
+	if err != nil {
 		return nil, err
 	}
 
-	defer cursor.Close(ctx)
+	return ammodel.MediaMetadataDocumentsToModel(docs, util.Logger(ctx))
+}
 
-	var docs []*ammodel.MediaMetadataDocument
+func (r *mediaMetadataRepository) FindByTagIDPage(
+	ctx context.Context,
+	id model.TagID,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	return r.findPage(ctx, withImageFilter(bson.M{"tag_ids": bson.M{"$in": []string{id}}}, filter), page)
+}
 
-	for cursor.Next(ctx) {
-		var doc ammodel.MediaMetadataDocument
-		if err := handleError(cursor.Decode(&doc)); err != nil {
-			return nil, err
-		}
+func (r *mediaMetadataRepository) FindByQueryPage(
+	ctx context.Context,
+	expression query.Expression,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	queryFilter, err := queryToFilter(expression)
+	if err != nil {
+		return nil, false, err
+	}
 
-		docs = append(docs, &doc)
+	return r.findPage(ctx, withImageFilter(queryFilter, filter), page)
+}
+
+func (r *mediaMetadataRepository) FindAllPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	return r.findPage(ctx, bson.M{}, page)
+}
+
+// findPage fetches one document more than requested to find out whether another page follows.
+func (r *mediaMetadataRepository) findPage(
+	ctx context.Context,
+	filter bson.M,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	if page.After != "" {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": page.After}}}}
 	}
 
-	if err := handleError(cursor.Err()); err != nil {
-		return nil, err
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(page.Limit) + 1)
+
+	docs, err := r.findDocuments(ctx, filter, opts)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return docs, nil
+	hasMore := len(docs) > page.Limit
+	if hasMore {
+		docs = docs[:page.Limit]
+	}
+
+	result, err := ammodel.MediaMetadataDocumentsToModel(docs, util.Logger(ctx))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, hasMore, nil
+}
+
+func (r *mediaMetadataRepository) findDocumentsByChecksum(
+	ctx context.Context,
+	checksum string,
+) ([]*ammodel.MediaMetadataDocument, error) {
+	return r.findDocuments(ctx, bson.M{"checksum": checksum})
 }
 
 func (r *mediaMetadataRepository) findDocumentsByTagID(
 	ctx context.Context,
 	id model.TagID,
 ) ([]*ammodel.MediaMetadataDocument, error) {
-	collection := r.client.Database(r.database).Collection(r.collection)
-
 	filter := bson.M{
 		"tag_ids": bson.M{
 			"$in": []string{id},
 		},
 	}
 
-	cursor, err := collection.Find(ctx, filter)
+	return r.findDocuments(ctx, filter)
+}
+
+func (r *mediaMetadataRepository) findDocuments(
+	ctx context.Context,
+	filter bson.M,
+	opts ...*options.FindOptions,
+) ([]*ammodel.MediaMetadataDocument, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	cursor, err := collection.Find(ctx, filter, opts...)
 	if err := handleError(err); err != nil {
 		return nil, err
 	}
@@ -271,6 +456,10 @@ func (r *mediaMetadataRepository) Get(ctx context.Context, id model.MediaID) (mo
 }
 
 func (r *mediaMetadataRepository) DeleteAll(ctx context.Context, ids []model.MediaID) error {
+	if len(ids) < 1 {
+		return nil
+	}
+
 	collection := r.client.Database(r.database).Collection(r.collection)
 
 	filter := bson.M{"_id": bson.M{"$in": ids}}
@@ -0,0 +1,78 @@
+package amongodb
+
+import (
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/services/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// queryToFilter translates a tag query into a filter on the tag_ids of media metadata. Operators on plain tags
+// become $all, $in and $nin, everything else $and, $or and $nor.
+func queryToFilter(expression query.Expression) (bson.M, error) {
+	switch e := expression.(type) {
+	case *query.Tag:
+		return bson.M{"tag_ids": bson.M{"$in": []model.TagID{e.ID}}}, nil
+	case *query.And:
+		if tagIDs, ok := onlyTagIDs(e.Operands); ok {
+			return bson.M{"tag_ids": bson.M{"$all": tagIDs}}, nil
+		}
+
+		return operandsToFilter("$and", e.Operands)
+	case *query.Or:
+		if tagIDs, ok := onlyTagIDs(e.Operands); ok {
+			return bson.M{"tag_ids": bson.M{"$in": tagIDs}}, nil
+		}
+
+		return operandsToFilter("$or", e.Operands)
+	case *query.Not:
+		if tagIDs, ok := negatedTagIDs(e.Operand); ok {
+			return bson.M{"tag_ids": bson.M{"$nin": tagIDs}}, nil
+		}
+
+		return operandsToFilter("$nor", []query.Expression{e.Operand})
+	}
+
+	return nil, errortypes.NewIllegalStatef("unknown query expression %T", expression)
+}
+
+func operandsToFilter(operator string, operands []query.Expression) (bson.M, error) {
+	filters := make(bson.A, 0, len(operands))
+	for _, operand := range operands {
+		filter, err := queryToFilter(operand)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return bson.M{operator: filters}, nil
+}
+
+// negatedTagIDs returns the tags of which none must be present, if the operand of a NOT is that simple.
+func negatedTagIDs(operand query.Expression) ([]model.TagID, bool) {
+	switch e := operand.(type) {
+	case *query.Tag:
+		return []model.TagID{e.ID}, true
+	case *query.Or:
+		return onlyTagIDs(e.Operands)
+	}
+
+	return nil, false
+}
+
+func onlyTagIDs(operands []query.Expression) ([]model.TagID, bool) {
+	tagIDs := make([]model.TagID, 0, len(operands))
+	for _, operand := range operands {
+		tag, ok := operand.(*query.Tag)
+		if !ok {
+			return nil, false
+		}
+
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	return tagIDs, true
+}
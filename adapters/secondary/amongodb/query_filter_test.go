@@ -0,0 +1,47 @@
+package amongodb
+
+import (
+	"media-nexus/model"
+	"media-nexus/services/query"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type queryFilterTestSuite struct {
+	suite.Suite
+}
+
+func TestQueryFilter(t *testing.T) {
+	suite.Run(t, &queryFilterTestSuite{})
+}
+
+func (s *queryFilterTestSuite) filter(q string) bson.M {
+	expression, err := query.Parse(q)
+	s.Require().NoError(err)
+
+	filter, err := queryToFilter(expression)
+	s.Require().NoError(err)
+
+	return filter
+}
+
+func (s *queryFilterTestSuite) TestTagOperators() {
+	s.Equal(bson.M{"tag_ids": bson.M{"$in": []model.TagID{"a"}}}, s.filter("a"))
+	s.Equal(bson.M{"tag_ids": bson.M{"$all": []model.TagID{"a", "b"}}}, s.filter("a AND b"))
+	s.Equal(bson.M{"tag_ids": bson.M{"$in": []model.TagID{"a", "b"}}}, s.filter("a OR b"))
+	s.Equal(bson.M{"tag_ids": bson.M{"$nin": []model.TagID{"a"}}}, s.filter("NOT a"))
+	s.Equal(bson.M{"tag_ids": bson.M{"$nin": []model.TagID{"a", "b"}}}, s.filter("NOT (a OR b)"))
+}
+
+func (s *queryFilterTestSuite) TestNestedOperators() {
+	s.Equal(bson.M{"$or": bson.A{
+		bson.M{"tag_ids": bson.M{"$all": []model.TagID{"a", "b"}}},
+		bson.M{"tag_ids": bson.M{"$nin": []model.TagID{"c"}}},
+	}}, s.filter("(a AND b) OR NOT c"))
+
+	s.Equal(bson.M{"$nor": bson.A{
+		bson.M{"tag_ids": bson.M{"$all": []model.TagID{"a", "b"}}},
+	}}, s.filter("NOT (a AND b)"))
+}
@@ -0,0 +1,108 @@
+package amongodb
+
+import (
+	"context"
+	"media-nexus/util"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationBatchSize is the number of documents updated by one bulk write
+const migrationBatchSize = 1000
+
+// SchemaMigrations lists the migrations of all collections. New ones are appended with the next version. Applied ones
+// must never be changed or removed, they are recorded by version only.
+func SchemaMigrations(mediaMetadataCollection string) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "store last_update of media metadata as date instead of RFC3339 string",
+			Up:          lastUpdateToDate(mediaMetadataCollection),
+		},
+		{
+			Version:     2,
+			Description: "set upload_completed_at of complete media metadata to its last_update",
+			Up:          setUploadCompletedAt(mediaMetadataCollection),
+		},
+	}
+}
+
+// lastUpdateToDate parses the strings here instead of using $dateFromString, so they are read exactly like
+// ammodel.LastUpdate reads them
+func lastUpdateToDate(collection string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		log := util.Logger(ctx)
+		metadata := db.Collection(collection)
+
+		filter := bson.M{"last_update": bson.M{"$type": "string"}}
+		opts := options.Find().SetProjection(bson.M{"last_update": 1})
+
+		cursor, err := metadata.Find(ctx, filter, opts)
+		if err := handleError(err); err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		var writes []mongo.WriteModel
+		write := func() error {
+			if len(writes) < 1 {
+				return nil
+			}
+
+			_, err := metadata.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+			writes = nil
+
+			return handleError(err)
+		}
+
+		for cursor.Next(ctx) {
+			var doc struct {
+				ID         string `bson:"_id"`
+				LastUpdate string `bson:"last_update"`
+			}
+
+			if err := cursor.Decode(&doc); err != nil {
+				return handleError(err)
+			}
+
+			lastUpdate, err := time.Parse(time.RFC3339Nano, doc.LastUpdate)
+			if err != nil {
+				// it couldn't be read before either
+				log.Errorf("failed to parse last update '%v' of media metadata %v: %v", doc.LastUpdate, doc.ID, err)
+				continue
+			}
+
+			// the filter includes the string, so a last update written meanwhile isn't overwritten
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": doc.ID, "last_update": doc.LastUpdate}).
+				SetUpdate(bson.M{"$set": bson.M{"last_update": lastUpdate}}))
+
+			if len(writes) >= migrationBatchSize {
+				if err := write(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := cursor.Err(); err != nil {
+			return handleError(err)
+		}
+
+		return write()
+	}
+}
+
+// setUploadCompletedAt takes the last update as the best guess for media completed before the time was stored. It
+// relies on migration 1, so last_update is a date.
+func setUploadCompletedAt(collection string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		filter := bson.M{"upload_complete": true, "upload_completed_at": bson.M{"$exists": false}}
+		update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{"upload_completed_at": "$last_update"}}}}
+
+		_, err := db.Collection(collection).UpdateMany(ctx, filter, update)
+		return handleError(err)
+	}
+}
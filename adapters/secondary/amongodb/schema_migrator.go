@@ -0,0 +1,237 @@
+package amongodb
+
+import (
+	"context"
+	"media-nexus/adapters/secondary/amongodb/ammodel"
+	"media-nexus/errortypes"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// migrationLockID is the ID of the lock document. Applied migrations have their version as numeric ID.
+const migrationLockID = "lock"
+
+// Migration changes the documents from the previous version of the schema to this one. It must be idempotent, because
+// an instance crashing after migrating, but before recording the migration, runs it again.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+type schemaMigrator struct {
+	client       *mongo.Client
+	database     string
+	collection   string
+	migrations   []Migration
+	lockLifetime time.Duration
+	pollInterval time.Duration
+}
+
+// NewSchemaMigrator records the applied migrations in the collection, next to the lock. The lock expires unless it's
+// renewed, so an instance crashing while migrating doesn't block the others forever.
+func NewSchemaMigrator(
+	client *mongo.Client,
+	database string,
+	collection string,
+	migrations []Migration,
+) ports.SchemaMigrator {
+	return &schemaMigrator{
+		client:       client,
+		database:     database,
+		collection:   collection,
+		migrations:   migrations,
+		lockLifetime: time.Minute,
+		pollInterval: time.Second,
+	}
+}
+
+// migrationsCollection uses the majority write concern, because the lock document acts as lock
+func (m *schemaMigrator) migrationsCollection() *mongo.Collection {
+	return m.client.Database(m.database).
+		Collection(m.collection, options.Collection().SetWriteConcern(writeconcern.Majority()))
+}
+
+func (m *schemaMigrator) Migrate(ctx context.Context) error {
+	log := util.Logger(ctx)
+
+	migrations, err := sortMigrations(m.migrations)
+	if err != nil {
+		return err
+	}
+
+	owner := primitive.NewObjectID().Hex()
+	if err := m.lock(ctx, owner); err != nil {
+		return err
+	}
+
+	defer func() {
+		// the lock is released even if ctx is done, else the others wait for it to expire
+		if err := m.unlock(context.WithoutCancel(ctx), owner); err != nil {
+			log.Errorf("failed to release the schema migration lock: %v", err)
+		}
+	}()
+
+	// losing the lock stops the migration, another instance might be running it by now
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go m.keepLock(ctx, owner, cancel)
+
+	// read after locking, the previous holder might just have applied some
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	db := m.client.Database(m.database)
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		log.Infof("applying schema migration %v: %v ...", migration.Version, migration.Description)
+		started := time.Now()
+
+		if err := migration.Up(ctx, db); err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				err = cause
+			}
+
+			log.Errorf("failed to apply schema migration %v: %v", migration.Version, err)
+			return err
+		}
+
+		doc := &ammodel.MigrationDocument{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+		}
+
+		if _, err := m.migrationsCollection().InsertOne(ctx, doc); err != nil {
+			return handleError(err)
+		}
+
+		log.Infof("applied schema migration %v in %v", migration.Version, time.Since(started))
+	}
+
+	return nil
+}
+
+// sortMigrations returns the migrations in order of their versions, which must be positive and unique
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	for i, migration := range sorted {
+		if migration.Version < 1 {
+			return nil, errortypes.NewIllegalStatef("schema migration version %v is not positive", migration.Version)
+		}
+
+		if i > 0 && sorted[i-1].Version == migration.Version {
+			return nil, errortypes.NewIllegalStatef("schema migration version %v is not unique", migration.Version)
+		}
+	}
+
+	return sorted, nil
+}
+
+func (m *schemaMigrator) appliedVersions(ctx context.Context) (map[int]bool, error) {
+	cursor, err := m.migrationsCollection().Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err := handleError(err); err != nil {
+		return nil, err
+	}
+
+	var docs []*ammodel.MigrationDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, handleError(err)
+	}
+
+	applied := map[int]bool{}
+	for _, doc := range docs {
+		applied[doc.Version] = true
+	}
+
+	return applied, nil
+}
+
+// lock waits until the lock is free or expired and takes it
+func (m *schemaMigrator) lock(ctx context.Context, owner string) error {
+	for logged := false; ; logged = true {
+		err := m.tryLock(ctx, owner)
+		if !errortypes.IsResourceAlreadyExists(err) {
+			return err
+		}
+
+		if !logged {
+			util.Logger(ctx).Info("waiting for another instance to finish the schema migrations ...")
+		}
+
+		select {
+		case <-ctx.Done():
+			return errortypes.NewTimeoutf("gave up waiting for the schema migration lock: %v", ctx.Err())
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
+
+// tryLock returns ResourceAlreadyExists if another instance holds the lock. The upsert then fails to insert a second
+// lock document with the same ID.
+func (m *schemaMigrator) tryLock(ctx context.Context, owner string) error {
+	now := time.Now()
+
+	filter := bson.M{"_id": migrationLockID, "expires_at": bson.M{"$lt": now}}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(m.lockLifetime)}}
+
+	_, err := m.migrationsCollection().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return handleError(err)
+}
+
+// keepLock renews the lock until ctx is done. If that fails, ctx is canceled with the error as cause.
+func (m *schemaMigrator) keepLock(ctx context.Context, owner string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(m.lockLifetime / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.renewLock(ctx, owner); err != nil {
+			cancel(err)
+			return
+		}
+	}
+}
+
+func (m *schemaMigrator) renewLock(ctx context.Context, owner string) error {
+	filter := bson.M{"_id": migrationLockID, "owner": owner}
+	update := bson.M{"$set": bson.M{"expires_at": time.Now().Add(m.lockLifetime)}}
+
+	result, err := m.migrationsCollection().UpdateOne(ctx, filter, update)
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		return errortypes.NewConflict("lost the schema migration lock")
+	}
+
+	return nil
+}
+
+func (m *schemaMigrator) unlock(ctx context.Context, owner string) error {
+	_, err := m.migrationsCollection().DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": owner})
+	return handleError(err)
+}
@@ -0,0 +1,71 @@
+package amongodb
+
+import (
+	"media-nexus/adapters/secondary/amongodb/ammodel"
+	"media-nexus/errortypes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type schemaMigratorTestSuite struct {
+	suite.Suite
+}
+
+func TestSchemaMigrator(t *testing.T) {
+	suite.Run(t, &schemaMigratorTestSuite{})
+}
+
+func (s *schemaMigratorTestSuite) TestSortMigrations() {
+	sorted, err := sortMigrations([]Migration{{Version: 3}, {Version: 1}, {Version: 2}})
+	s.Require().NoError(err)
+	s.Equal([]Migration{{Version: 1}, {Version: 2}, {Version: 3}}, sorted)
+}
+
+func (s *schemaMigratorTestSuite) TestSortInvalidMigrations() {
+	_, err := sortMigrations([]Migration{{Version: 1}, {Version: 0}})
+	s.True(errortypes.IsIllegalState(err), "expected illegal state, got %v", err)
+
+	_, err = sortMigrations([]Migration{{Version: 2}, {Version: 1}, {Version: 2}})
+	s.True(errortypes.IsIllegalState(err), "expected illegal state, got %v", err)
+}
+
+func (s *schemaMigratorTestSuite) TestSchemaMigrationsAreValid() {
+	_, err := sortMigrations(SchemaMigrations("media_metadata"))
+	s.NoError(err)
+}
+
+func (s *schemaMigratorTestSuite) TestLastUpdateIsStoredAsDate() {
+	lastUpdate := time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC)
+
+	data, err := bson.Marshal(&ammodel.MediaMetadataDocument{ID: "a", LastUpdate: ammodel.LastUpdate(lastUpdate)})
+	s.Require().NoError(err)
+	s.Equal(bson.TypeDateTime, bson.Raw(data).Lookup("last_update").Type)
+
+	var doc ammodel.MediaMetadataDocument
+	s.Require().NoError(bson.Unmarshal(data, &doc))
+	s.True(lastUpdate.Equal(doc.LastUpdate.Time()))
+}
+
+func (s *schemaMigratorTestSuite) TestLastUpdateIsReadFromString() {
+	data, err := bson.Marshal(bson.M{"_id": "a", "last_update": "2024-05-01T12:00:00.123456789+02:00"})
+	s.Require().NoError(err)
+
+	var doc ammodel.MediaMetadataDocument
+	s.Require().NoError(bson.Unmarshal(data, &doc))
+	s.True(time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC).Equal(doc.LastUpdate.Time()))
+
+	data, err = bson.Marshal(bson.M{"_id": "a", "last_update": "yesterday"})
+	s.Require().NoError(err)
+	s.Error(bson.Unmarshal(data, &doc))
+}
+
+func (s *schemaMigratorTestSuite) TestUnsetLastUpdateIsOmitted() {
+	data, err := bson.Marshal(&ammodel.MediaMetadataDocument{ID: "a"})
+	s.Require().NoError(err)
+
+	_, err = bson.Raw(data).LookupErr("last_update")
+	s.Error(err)
+}
@@ -7,6 +7,7 @@ import (
 	"media-nexus/errortypes"
 	"media-nexus/model"
 	"media-nexus/ports"
+	"slices"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -99,11 +100,35 @@ func (r *tagRepository) insertTagIfNotExists(ctx context.Context, name string) (
 }
 
 func (r *tagRepository) ListTags(ctx context.Context) ([]*model.Tag, error) {
-	collection := r.client.Database(r.database).Collection(r.collection)
+	return r.findTags(ctx, bson.M{})
+}
 
+func (r *tagRepository) ListTagsPage(ctx context.Context, page model.PageRequest) ([]*model.Tag, bool, error) {
 	filter := bson.M{}
+	if page.After != "" {
+		filter["_id"] = bson.M{"$gt": page.After}
+	}
 
-	cursor, err := collection.Find(ctx, filter)
+	// fetch one more tag than requested to find out whether another page follows
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(page.Limit) + 1)
+
+	tags, err := r.findTags(ctx, filter, opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(tags) > page.Limit
+	if hasMore {
+		tags = tags[:page.Limit]
+	}
+
+	return tags, hasMore, nil
+}
+
+func (r *tagRepository) findTags(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*model.Tag, error) {
+	collection := r.client.Database(r.database).Collection(r.collection)
+
+	cursor, err := collection.Find(ctx, filter, opts...)
 	if err != nil {
 		return nil, errortypes.NewUpstreamCommunicationErrorf("mongodb find", "failed to find tags: %v", err)
 	}
@@ -135,6 +160,10 @@ func (r *tagRepository) ListTags(ctx context.Context) ([]*model.Tag, error) {
 }
 
 func (r *tagRepository) DeleteTags(ctx context.Context, tagIds []model.TagID) error {
+	if len(tagIds) < 1 {
+		return nil
+	}
+
 	collection := r.client.Database(r.database).Collection(r.collection)
 
 	filter := bson.M{"_id": bson.M{"$in": tagIds}}
@@ -148,14 +177,20 @@ func (r *tagRepository) DeleteTags(ctx context.Context, tagIds []model.TagID) er
 }
 
 func (r *tagRepository) AllExist(ctx context.Context, ids []model.TagID) (bool, error) {
+	// the same id might be given multiple times, but is counted only once by mongodb
+	uniqueIDs := slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(uniqueIDs) < 1 {
+		return true, nil
+	}
+
 	collection := r.client.Database(r.database).Collection(r.collection)
 
-	filter := bson.M{"_id": bson.M{"$in": ids}}
+	filter := bson.M{"_id": bson.M{"$in": uniqueIDs}}
 
 	count, err := collection.CountDocuments(ctx, filter)
 	if err := handleError(err); err != nil {
 		return false, err
 	}
 
-	return count == int64(len(ids)), nil
+	return count == int64(len(uniqueIDs)), nil
 }
@@ -0,0 +1,153 @@
+package amongodb
+
+import (
+	"context"
+	"media-nexus/adapters/secondary/amongodb/ammodel"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type uploadRepository struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// NewUploadRepository keeps the state of resumable uploads. Expired uploads are not removed by a TTL index, because
+// whoever removes them has to delete their chunks as well.
+func NewUploadRepository(
+	client *mongo.Client,
+	database string,
+	collection string,
+) (ports.UploadRepository, util.Runner) {
+	repo := &uploadRepository{client, database, collection}
+
+	runner := func(ctx context.Context) {
+		log := util.Logger(ctx)
+
+		err := ensureFieldIndex(ctx, repo.uploads(), "expires_at_index", "expires_at")
+		if err != nil {
+			log.Errorf("failed to ensure indices for uploads %v:%v: %v", database, collection, err)
+		}
+	}
+
+	return repo, runner
+}
+
+func (r *uploadRepository) uploads() *mongo.Collection {
+	return r.client.Database(r.database).Collection(r.collection)
+}
+
+func (r *uploadRepository) Create(ctx context.Context, upload *model.Upload) error {
+	_, err := r.uploads().InsertOne(ctx, ammodel.NewUploadDocument(upload))
+	return handleError(err)
+}
+
+func (r *uploadRepository) Get(ctx context.Context, id model.UploadID) (*model.Upload, error) {
+	var doc ammodel.UploadDocument
+
+	err := r.uploads().FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if err := handleError(err); err != nil {
+		return nil, err
+	}
+
+	return doc.ToModel(), nil
+}
+
+func (r *uploadRepository) AppendChunk(
+	ctx context.Context,
+	id model.UploadID,
+	offset int64,
+	chunk model.UploadChunk,
+	expiresAt time.Time,
+) error {
+	filter := bson.M{"_id": id, "offset": offset}
+
+	update := bson.M{
+		"$push": bson.M{"chunks": ammodel.NewUploadChunkDocument(chunk)},
+		"$inc":  bson.M{"offset": chunk.Size},
+		"$set":  bson.M{"expires_at": expiresAt},
+	}
+
+	result, err := r.uploads().UpdateOne(ctx, filter, update)
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// either the upload doesn't exist or it moved on
+	upload, err := r.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return errortypes.NewConflictf("upload %v is at offset %v, not %v", id, upload.Offset, offset)
+}
+
+func (r *uploadRepository) Complete(ctx context.Context, id model.UploadID, mediaID model.MediaID) error {
+	update := bson.M{
+		"$set": bson.M{
+			"media_id": mediaID,
+			"chunks":   bson.A{},
+		},
+	}
+
+	result, err := r.uploads().UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err := handleError(err); err != nil {
+		return err
+	}
+
+	if result.MatchedCount < 1 {
+		return errortypes.NewResourceNotFound(id)
+	}
+
+	return nil
+}
+
+func (r *uploadRepository) FindExpired(ctx context.Context, before time.Time) ([]*model.Upload, error) {
+	return r.findUploads(ctx, bson.M{"expires_at": bson.M{"$lt": before}})
+}
+
+func (r *uploadRepository) FindAll(ctx context.Context) ([]*model.Upload, error) {
+	return r.findUploads(ctx, bson.M{})
+}
+
+func (r *uploadRepository) findUploads(ctx context.Context, filter bson.M) ([]*model.Upload, error) {
+	cursor, err := r.uploads().Find(ctx, filter)
+	if err := handleError(err); err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	var uploads []*model.Upload
+
+	for cursor.Next(ctx) {
+		var doc ammodel.UploadDocument
+		if err := handleError(cursor.Decode(&doc)); err != nil {
+			return nil, err
+		}
+
+		uploads = append(uploads, doc.ToModel())
+	}
+
+	if err := handleError(cursor.Err()); err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
+
+func (r *uploadRepository) Delete(ctx context.Context, id model.UploadID) error {
+	_, err := r.uploads().DeleteOne(ctx, bson.M{"_id": id})
+	return handleError(err)
+}
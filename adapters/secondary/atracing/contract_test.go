@@ -0,0 +1,42 @@
+package atracing
+
+import (
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/ports"
+	"media-nexus/ports/portstest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// the decorators must not change what the repositories do, so they fulfill the contracts of the ones they decorate
+
+func TestTagRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.TagRepositoryContract{
+		Repository: func() ports.TagRepository {
+			return NewTagRepository(amemory.NewTagRepository(), sdktrace.NewTracerProvider())
+		},
+	})
+}
+
+func TestMediaMetadataRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.MediaMetadataRepositoryContract{
+		Repository: func() ports.MediaMetadataRepository {
+			return NewMediaMetadataRepository(
+				amemory.NewMediaMetadataRepository(time.Minute),
+				sdktrace.NewTracerProvider(),
+			)
+		},
+	})
+}
+
+func TestMediaRepositoryContract(t *testing.T) {
+	suite.Run(t, &portstest.MediaRepositoryContract{
+		Repository: func() ports.MediaRepository {
+			repo, _, _ := amemory.NewMediaRepository("http://localhost/api/v1/blobs", []byte("key"))
+			return NewMediaRepository(repo, sdktrace.NewTracerProvider())
+		},
+	})
+}
@@ -0,0 +1,165 @@
+package atracing
+
+import (
+	"context"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/services/query"
+	"media-nexus/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type mediaMetadataRepository struct {
+	next   ports.MediaMetadataRepository
+	tracer trace.Tracer
+}
+
+// NewMediaMetadataRepository wraps every call to next in a span
+func NewMediaMetadataRepository(
+	next ports.MediaMetadataRepository,
+	tracerProvider trace.TracerProvider,
+) ports.MediaMetadataRepository {
+	return &mediaMetadataRepository{next, tracerProvider.Tracer(tracing.TracerName)}
+}
+
+func (r *mediaMetadataRepository) start(
+	ctx context.Context,
+	operation string,
+	attributes ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "MediaMetadataRepository."+operation, trace.WithAttributes(attributes...))
+}
+
+func mediaID(id model.MediaID) attribute.KeyValue {
+	return attribute.String(tracing.AttributeMediaID, string(id))
+}
+
+func tagID(id model.TagID) attribute.KeyValue {
+	return attribute.String(tracing.AttributeTagID, string(id))
+}
+
+func (r *mediaMetadataRepository) Upsert(ctx context.Context, metadata model.MediaMetadata) error {
+	ctx, span := r.start(ctx, "Upsert", mediaID(metadata.ID()))
+	err := r.next.Upsert(ctx, metadata)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) Get(ctx context.Context, id model.MediaID) (model.MediaMetadata, error) {
+	ctx, span := r.start(ctx, "Get", mediaID(id))
+	metadata, err := r.next.Get(ctx, id)
+	tracing.End(span, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) SetUploadComplete(ctx context.Context, id model.MediaID, complete bool) error {
+	ctx, span := r.start(ctx, "SetUploadComplete", mediaID(id))
+	err := r.next.SetUploadComplete(ctx, id, complete)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) SetDeleting(ctx context.Context, id model.MediaID, deleting bool) error {
+	ctx, span := r.start(ctx, "SetDeleting", mediaID(id))
+	err := r.next.SetDeleting(ctx, id, deleting)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) AddVariant(ctx context.Context, id model.MediaID, size int) error {
+	ctx, span := r.start(ctx, "AddVariant", mediaID(id))
+	err := r.next.AddVariant(ctx, id, size)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) Update(
+	ctx context.Context,
+	id model.MediaID,
+	update model.MediaMetadataUpdate,
+) error {
+	ctx, span := r.start(ctx, "Update", mediaID(id))
+	err := r.next.Update(ctx, id, update)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaMetadataRepository) FindByTagID(ctx context.Context, id model.TagID) ([]model.MediaMetadata, error) {
+	ctx, span := r.start(ctx, "FindByTagID", tagID(id))
+	metadata, err := r.next.FindByTagID(ctx, id)
+	tracing.End(span, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) FindByQuery(
+	ctx context.Context,
+	expression query.Expression,
+) ([]model.MediaMetadata, error) {
+	ctx, span := r.start(ctx, "FindByQuery")
+	metadata, err := r.next.FindByQuery(ctx, expression)
+	tracing.End(span, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) FindByTagIDPage(
+	ctx context.Context,
+	id model.TagID,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	ctx, span := r.start(ctx, "FindByTagIDPage", tagID(id))
+	metadata, more, err := r.next.FindByTagIDPage(ctx, id, filter, page)
+	tracing.End(span, err)
+
+	return metadata, more, err
+}
+
+func (r *mediaMetadataRepository) FindByQueryPage(
+	ctx context.Context,
+	expression query.Expression,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	ctx, span := r.start(ctx, "FindByQueryPage")
+	metadata, more, err := r.next.FindByQueryPage(ctx, expression, filter, page)
+	tracing.End(span, err)
+
+	return metadata, more, err
+}
+
+func (r *mediaMetadataRepository) FindAllPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.MediaMetadata, bool, error) {
+	ctx, span := r.start(ctx, "FindAllPage")
+	metadata, more, err := r.next.FindAllPage(ctx, page)
+	tracing.End(span, err)
+
+	return metadata, more, err
+}
+
+func (r *mediaMetadataRepository) FindByChecksum(ctx context.Context, checksum string) (model.MediaMetadata, error) {
+	ctx, span := r.start(ctx, "FindByChecksum")
+	metadata, err := r.next.FindByChecksum(ctx, checksum)
+	tracing.End(span, err)
+
+	return metadata, err
+}
+
+func (r *mediaMetadataRepository) DeleteAll(ctx context.Context, ids []model.MediaID) error {
+	ctx, span := r.start(ctx, "DeleteAll", attribute.Int(tracing.AttributeCount, len(ids)))
+	err := r.next.DeleteAll(ctx, ids)
+	tracing.End(span, err)
+
+	return err
+}
@@ -0,0 +1,115 @@
+package atracing
+
+import (
+	"context"
+	"io"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type mediaRepository struct {
+	next   ports.MediaRepository
+	tracer trace.Tracer
+}
+
+// NewMediaRepository wraps every call to next in a span. The span of opening media ends once it's opened, not once
+// it's read.
+func NewMediaRepository(next ports.MediaRepository, tracerProvider trace.TracerProvider) ports.MediaRepository {
+	return &mediaRepository{next, tracerProvider.Tracer(tracing.TracerName)}
+}
+
+func (r *mediaRepository) start(ctx context.Context, operation string, key string) (context.Context, trace.Span) {
+	return r.tracer.Start(
+		ctx,
+		"MediaRepository."+operation,
+		trace.WithAttributes(attribute.String(tracing.AttributeMediaKey, key)),
+	)
+}
+
+func (r *mediaRepository) CreateMedia(ctx context.Context, key string, file io.Reader, mimeType string) error {
+	ctx, span := r.start(ctx, "CreateMedia", key)
+	err := r.next.CreateMedia(ctx, key, file, mimeType)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaRepository) MoveMedia(ctx context.Context, fromKey string, toKey string) error {
+	ctx, span := r.start(ctx, "MoveMedia", fromKey)
+	span.SetAttributes(attribute.String(tracing.AttributeMediaToKey, toKey))
+	err := r.next.MoveMedia(ctx, fromKey, toKey)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaRepository) OpenMedia(
+	ctx context.Context,
+	key string,
+	byteRange *model.ByteRange,
+) (io.ReadCloser, error) {
+	ctx, span := r.start(ctx, "OpenMedia", key)
+	media, err := r.next.OpenMedia(ctx, key, byteRange)
+	tracing.End(span, err)
+
+	return media, err
+}
+
+func (r *mediaRepository) GetMediaAttributes(ctx context.Context, key string) (model.MediaAttributes, error) {
+	ctx, span := r.start(ctx, "GetMediaAttributes", key)
+	attributes, err := r.next.GetMediaAttributes(ctx, key)
+	tracing.End(span, err)
+
+	return attributes, err
+}
+
+func (r *mediaRepository) GetMediaURL(ctx context.Context, key string, lifetime time.Duration) (string, error) {
+	ctx, span := r.start(ctx, "GetMediaURL", key)
+	url, err := r.next.GetMediaURL(ctx, key, lifetime)
+	tracing.End(span, err)
+
+	return url, err
+}
+
+func (r *mediaRepository) GetUploadURL(
+	ctx context.Context,
+	key string,
+	size int64,
+	checksum string,
+	mimeType string,
+	lifetime time.Duration,
+) (model.UploadURL, error) {
+	ctx, span := r.start(ctx, "GetUploadURL", key)
+	url, err := r.next.GetUploadURL(ctx, key, size, checksum, mimeType, lifetime)
+	tracing.End(span, err)
+
+	return url, err
+}
+
+func (r *mediaRepository) DeleteAll(ctx context.Context, keys []string) error {
+	ctx, span := r.tracer.Start(
+		ctx,
+		"MediaRepository.DeleteAll",
+		trace.WithAttributes(attribute.Int(tracing.AttributeCount, len(keys))),
+	)
+	err := r.next.DeleteAll(ctx, keys)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *mediaRepository) ListMediaPage(
+	ctx context.Context,
+	page model.PageRequest,
+) ([]model.StoredMedia, bool, error) {
+	ctx, span := r.tracer.Start(ctx, "MediaRepository.ListMediaPage")
+	media, more, err := r.next.ListMediaPage(ctx, page)
+	tracing.End(span, err)
+
+	return media, more, err
+}
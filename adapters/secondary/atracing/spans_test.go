@@ -0,0 +1,85 @@
+package atracing
+
+import (
+	"context"
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/errortypes"
+	"media-nexus/logger"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/tracing"
+	"media-nexus/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type spansTestSuite struct {
+	suite.Suite
+	ctx            context.Context
+	recorder       *tracetest.SpanRecorder
+	tracerProvider *sdktrace.TracerProvider
+}
+
+func TestSpans(t *testing.T) {
+	suite.Run(t, &spansTestSuite{})
+}
+
+func (s *spansTestSuite) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("test"))
+	s.recorder = tracetest.NewSpanRecorder()
+	s.tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder))
+}
+
+func (s *spansTestSuite) TestSpanOfCall() {
+	repo := NewTagRepository(amemory.NewTagRepository(), s.tracerProvider)
+
+	_, err := repo.CreateTag(s.ctx, "tag")
+	s.Require().NoError(err)
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("TagRepository.CreateTag", spans[0].Name())
+	s.Equal(codes.Unset, spans[0].Status().Code)
+}
+
+func (s *spansTestSuite) TestSpanOfMissingResource() {
+	repo := NewMediaMetadataRepository(amemory.NewMediaMetadataRepository(time.Minute), s.tracerProvider)
+
+	_, err := repo.Get(s.ctx, "unknown")
+	s.Require().Error(err)
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("MediaMetadataRepository.Get", spans[0].Name())
+	s.Contains(spans[0].Attributes(), attribute.String(tracing.AttributeMediaID, "unknown"))
+	// looking up what might not exist is no failure, but still recorded
+	s.Equal(codes.Unset, spans[0].Status().Code)
+	s.Len(spans[0].Events(), 1)
+}
+
+// failingTagRepository fails creating tags
+type failingTagRepository struct {
+	ports.TagRepository
+}
+
+func (r failingTagRepository) CreateTag(ctx context.Context, name string) (model.TagID, error) {
+	return "", errortypes.NewUpstreamUnavailablef("mongodb is down")
+}
+
+func (s *spansTestSuite) TestSpanOfFailedCall() {
+	repo := NewTagRepository(failingTagRepository{amemory.NewTagRepository()}, s.tracerProvider)
+
+	_, err := repo.CreateTag(s.ctx, "tag")
+	s.Require().Error(err)
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal(codes.Error, spans[0].Status().Code)
+	s.Equal("mongodb is down", spans[0].Status().Description)
+}
@@ -0,0 +1,60 @@
+package atracing
+
+import (
+	"context"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tagRepository struct {
+	next   ports.TagRepository
+	tracer trace.Tracer
+}
+
+// NewTagRepository wraps every call to next in a span
+func NewTagRepository(next ports.TagRepository, tracerProvider trace.TracerProvider) ports.TagRepository {
+	return &tagRepository{next, tracerProvider.Tracer(tracing.TracerName)}
+}
+
+func (r *tagRepository) CreateTag(ctx context.Context, name string) (model.TagID, error) {
+	ctx, span := r.tracer.Start(ctx, "TagRepository.CreateTag")
+	id, err := r.next.CreateTag(ctx, name)
+	tracing.End(span, err)
+
+	return id, err
+}
+
+func (r *tagRepository) ListTags(ctx context.Context) ([]*model.Tag, error) {
+	ctx, span := r.tracer.Start(ctx, "TagRepository.ListTags")
+	tags, err := r.next.ListTags(ctx)
+	tracing.End(span, err)
+
+	return tags, err
+}
+
+func (r *tagRepository) ListTagsPage(ctx context.Context, page model.PageRequest) ([]*model.Tag, bool, error) {
+	ctx, span := r.tracer.Start(ctx, "TagRepository.ListTagsPage")
+	tags, more, err := r.next.ListTagsPage(ctx, page)
+	tracing.End(span, err)
+
+	return tags, more, err
+}
+
+func (r *tagRepository) DeleteTags(ctx context.Context, ids []model.TagID) error {
+	ctx, span := r.tracer.Start(ctx, "TagRepository.DeleteTags")
+	err := r.next.DeleteTags(ctx, ids)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (r *tagRepository) AllExist(ctx context.Context, ids []model.TagID) (bool, error) {
+	ctx, span := r.tracer.Start(ctx, "TagRepository.AllExist")
+	allExist, err := r.next.AllExist(ctx, ids)
+	tracing.End(span, err)
+
+	return allExist, err
+}
@@ -2,28 +2,48 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
 
 	"media-nexus/adapters/primary/ahttp"
 	"media-nexus/adapters/secondary/aaws"
+	"media-nexus/adapters/secondary/afs"
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/adapters/secondary/ametrics"
 	"media-nexus/adapters/secondary/amongodb"
+	"media-nexus/adapters/secondary/atracing"
 	"media-nexus/config"
 	"media-nexus/errortypes"
 	"media-nexus/logger"
+	"media-nexus/metrics"
 	"media-nexus/ports"
 	"media-nexus/services"
+	"media-nexus/services/imagestrip"
 	"media-nexus/util"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type App interface {
 	Setup() error
 	Run() error
+	// Migrate prepares the storage and applies the schema migrations, like Run does on startup, but waits for it to
+	// finish. Setup must be called first.
+	Migrate(ctx context.Context) error
+	// Shutdown flushes the spans not exported yet
+	Shutdown(ctx context.Context) error
 
 	TagRepo() ports.TagRepository
 	MediaRepo() ports.MediaRepository
 	MediaMetadataRepo() ports.MediaMetadataRepository
+	UploadRepo() ports.UploadRepository
+	JobQueue() ports.JobQueue
+	MediaService() services.MediaService
+	Reconciler() services.Reconciler
 }
 
 func NewApp(log logger.Logger, config *config.Configuration) App {
@@ -34,14 +54,30 @@ func NewApp(log logger.Logger, config *config.Configuration) App {
 }
 
 type app struct {
-	log    logger.Logger
-	config *config.Configuration
+	log            logger.Logger
+	config         *config.Configuration
+	metrics        metrics.Metrics
+	tracerProvider trace.TracerProvider
+	// shutdownTracing is nil, if spans aren't exported
+	shutdownTracing func(ctx context.Context) error
 
+	// setupRunners prepare the storage once, e.g. create indices, and return
+	setupRunners      []util.Runner
 	runners           []util.Runner
 	mediaService      services.MediaService
+	uploadService     services.UploadService
+	reconciler        services.Reconciler
+	healthService     services.HealthService
+	healthChecks      []services.HealthCheck
 	tagRepo           ports.TagRepository
 	mediaRepo         ports.MediaRepository
 	mediaMetadataRepo ports.MediaMetadataRepository
+	uploadRepo        ports.UploadRepository
+	jobQueue          ports.JobQueue
+	schemaMigrator    ports.SchemaMigrator
+	mediaDownloader   ports.SignedMediaDownloader
+	mediaUploader     ports.SignedMediaUploader
+	cursorSigningKey  []byte
 }
 
 func (a *app) Setup() error {
@@ -49,51 +85,326 @@ func (a *app) Setup() error {
 
 	a.log.Info("setting up services ...")
 
-	awsConfig, err := awsconfig.LoadDefaultConfig(ctx)
-	if err != nil {
-		return errortypes.NewIllegalStatef("failed to load aws config: %v", err)
+	a.metrics = metrics.NewPrometheusMetrics()
+
+	if err := a.setupTracing(ctx); err != nil {
+		return err
+	}
+
+	if err := a.setupMetadataRepositories(ctx); err != nil {
+		return err
+	}
+
+	if err := a.setupMediaRepository(ctx); err != nil {
+		return err
 	}
 
-	s3Client := s3.NewFromConfig(awsConfig, aaws.WithRegion(a.config.MediaBucketRegion))
-	presignClient := s3.NewPresignClient(s3Client)
+	a.instrumentRepositories()
+	a.traceRepositories()
 
-	mongodbClient, err := amongodb.NewMongoDBClient(ctx, a.config.MongoDBURI)
+	cursorSigningKey, err := a.signingKey(a.config.CursorSigningKey, "cursor", "paging cursors")
 	if err != nil {
-		return errortypes.NewIllegalStatef("failed to create mongodb client: %v", err)
+		return err
 	}
 
-	a.tagRepo = amongodb.NewTagRepository(mongodbClient, a.config.MediaDatabase, a.config.MediaTagCollection)
-	var mediaMetadataRunner util.Runner
-	a.mediaMetadataRepo, mediaMetadataRunner = amongodb.NewMediaMetadataRepository(
-		mongodbClient,
-		a.config.MediaDatabase,
-		a.config.MediaMetadataCollection,
-		a.config.IncompleteMediaMetadataLifetime,
-	)
-	a.runners = append(a.runners, mediaMetadataRunner)
+	a.cursorSigningKey = cursorSigningKey
+
+	// without metadata stripping, media is stored as uploaded
+	var transformation services.MediaTransformation
+	if a.config.StripImageMetadata {
+		a.log.Infof("stripping metadata of uploaded images, keeping orientation: %v", a.config.KeepImageOrientation)
+		transformation = imagestrip.NewStripper(a.config.KeepImageOrientation)
+	}
+
+	variantURL := fmt.Sprintf("%v:%v/api/v1/media", a.config.BaseURL, a.config.HTTPPort)
 
-	a.mediaRepo = aaws.NewMediaRepository(s3Client, presignClient, a.config.MediaBucket)
-	a.mediaService = services.NewMediaService(
+	mediaService := services.NewMediaService(
 		a.tagRepo,
 		a.mediaMetadataRepo,
 		a.mediaRepo,
 		a.config.GetMediaURLLifetime,
 		a.config.IncompleteMediaMetadataLifetime,
+		a.config.DirectUploadLifetime,
+		a.config.AllowedMediaTypes,
+		transformation,
+		a.jobQueue,
+		a.config.MediaVariantSizes,
+		variantURL,
+		a.metrics,
 	)
+	a.mediaService = services.NewTracingMediaService(mediaService, a.tracerProvider)
+
+	var deleteExpiredUploadsRunner util.Runner
+	a.uploadService, deleteExpiredUploadsRunner = services.NewUploadService(
+		a.tagRepo,
+		a.uploadRepo,
+		a.mediaRepo,
+		a.mediaService,
+		a.config.ResumableUploadLifetime,
+		a.config.ExpiredUploadCheckInterval,
+		a.metrics,
+	)
+	a.runners = append(a.runners, deleteExpiredUploadsRunner)
+
+	var scheduleReconcileRunner util.Runner
+	a.reconciler, scheduleReconcileRunner = services.NewReconciler(
+		a.mediaMetadataRepo,
+		a.mediaRepo,
+		a.uploadRepo,
+		a.jobQueue,
+		a.config.ReconcileInterval,
+		a.config.ReconcileGracePeriod,
+		a.metrics,
+	)
+	a.runners = append(a.runners, scheduleReconcileRunner)
+
+	jobHandlers := map[string]services.JobHandler{
+		services.JobTypeGenerateVariants: services.GenerateVariantsJobHandler(a.mediaService),
+		services.JobTypeReconcile:        services.ReconcileJobHandler(a.reconciler),
+	}
+
+	a.runners = append(a.runners, services.NewJobWorker(a.jobQueue, jobHandlers, services.JobOptions{
+		VisibilityTimeout: a.config.JobVisibilityTimeout,
+		PollInterval:      a.config.JobPollInterval,
+		MaxAttempts:       a.config.JobMaxAttempts,
+		InitialBackoff:    a.config.JobInitialBackoff,
+		MaxBackoff:        a.config.JobMaxBackoff,
+	}, a.metrics))
+
+	a.healthService = services.NewHealthService(
+		a.healthChecks,
+		a.config.HealthCheckTimeout,
+		a.config.HealthCheckCacheLifetime,
+	)
+
+	return nil
+}
+
+func (a *app) setupMetadataRepositories(ctx context.Context) error {
+	switch a.config.MetadataStorageBackend {
+	case config.MetadataStorageBackendMemory:
+		a.tagRepo = amemory.NewTagRepository()
+		a.mediaMetadataRepo = amemory.NewMediaMetadataRepository(a.config.IncompleteMediaMetadataLifetime)
+		a.uploadRepo = amemory.NewUploadRepository()
+		a.jobQueue = amemory.NewJobQueue()
+	case config.MetadataStorageBackendMongoDB:
+		mongodbClient, err := amongodb.NewMongoDBClient(ctx, a.config.MongoDBURI, a.tracerProvider)
+		if err != nil {
+			return errortypes.NewIllegalStatef("failed to create mongodb client: %v", err)
+		}
+
+		a.healthChecks = append(a.healthChecks, services.HealthCheck{
+			Checker:  amongodb.NewHealthChecker(mongodbClient),
+			Critical: true,
+		})
+
+		a.tagRepo = amongodb.NewTagRepository(mongodbClient, a.config.MediaDatabase, a.config.MediaTagCollection)
+		var mediaMetadataRunner util.Runner
+		a.mediaMetadataRepo, mediaMetadataRunner = amongodb.NewMediaMetadataRepository(
+			mongodbClient,
+			a.config.MediaDatabase,
+			a.config.MediaMetadataCollection,
+			a.config.IncompleteMediaMetadataLifetime,
+		)
+		a.setupRunners = append(a.setupRunners, mediaMetadataRunner)
+
+		var uploadRunner util.Runner
+		a.uploadRepo, uploadRunner = amongodb.NewUploadRepository(
+			mongodbClient,
+			a.config.MediaDatabase,
+			a.config.MediaUploadCollection,
+		)
+		a.setupRunners = append(a.setupRunners, uploadRunner)
+
+		var jobQueueRunner util.Runner
+		a.jobQueue, jobQueueRunner = amongodb.NewJobQueue(mongodbClient, a.config.MediaDatabase, a.config.MediaJobCollection)
+		a.setupRunners = append(a.setupRunners, jobQueueRunner)
+
+		a.schemaMigrator = amongodb.NewSchemaMigrator(
+			mongodbClient,
+			a.config.MediaDatabase,
+			a.config.MediaMigrationCollection,
+			amongodb.SchemaMigrations(a.config.MediaMetadataCollection),
+		)
+		a.runners = append(a.runners, a.migrateSchema)
+	default:
+		return errortypes.NewIllegalStatef("unknown metadata storage backend '%v'", a.config.MetadataStorageBackend)
+	}
+
+	return nil
+}
+
+func (a *app) setupMediaRepository(ctx context.Context) error {
+	downloadURL := fmt.Sprintf("%v:%v/api/v1/blobs", a.config.BaseURL, a.config.HTTPPort)
+
+	switch a.config.MediaStorageBackend {
+	case config.MediaStorageBackendMemory:
+		signingKey, err := a.signingKey(a.config.MediaURLSigningKey, "media url", "media URLs")
+		if err != nil {
+			return err
+		}
+
+		a.mediaRepo, a.mediaDownloader, a.mediaUploader = amemory.NewMediaRepository(downloadURL, signingKey)
+	case config.MediaStorageBackendFileSystem:
+		signingKey, err := a.signingKey(a.config.MediaURLSigningKey, "media url", "media URLs")
+		if err != nil {
+			return err
+		}
+
+		a.mediaRepo, a.mediaDownloader, a.mediaUploader = afs.NewMediaRepository(
+			a.config.MediaRootDir,
+			downloadURL,
+			signingKey,
+		)
+	case config.MediaStorageBackendS3:
+		awsConfig, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return errortypes.NewIllegalStatef("failed to load aws config: %v", err)
+		}
+
+		otelaws.AppendMiddlewares(&awsConfig.APIOptions, otelaws.WithTracerProvider(a.tracerProvider))
+
+		s3Client := s3.NewFromConfig(awsConfig, aaws.WithRegion(a.config.MediaBucketRegion))
+		presignClient := s3.NewPresignClient(s3Client)
+
+		var abortStaleUploadsRunner util.Runner
+		a.mediaRepo, abortStaleUploadsRunner = aaws.NewMediaRepository(
+			s3Client,
+			presignClient,
+			a.config.MediaBucket,
+			aaws.MultipartOptions{
+				PartSize:                 int64(a.config.MediaUploadPartSizeMB) << 20,
+				Concurrency:              a.config.MediaUploadConcurrency,
+				StaleUploadLifetime:      a.config.StaleMediaUploadLifetime,
+				StaleUploadCheckInterval: a.config.StaleMediaUploadCheckInterval,
+			},
+			a.metrics,
+		)
+		a.runners = append(a.runners, abortStaleUploadsRunner)
+
+		a.healthChecks = append(a.healthChecks, services.HealthCheck{
+			Checker:  aaws.NewHealthChecker(s3Client, a.config.MediaBucket),
+			Critical: true,
+		})
+	default:
+		return errortypes.NewIllegalStatef("unknown media storage backend '%v'", a.config.MediaStorageBackend)
+	}
 
 	return nil
 }
 
+// instrumentRepositories records the latency of all calls to the repositories by their backend
+func (a *app) instrumentRepositories() {
+	metadataBackend := a.config.MetadataStorageBackend
+	a.tagRepo = ametrics.NewTagRepository(a.tagRepo, metadataBackend, a.metrics)
+	a.mediaMetadataRepo = ametrics.NewMediaMetadataRepository(a.mediaMetadataRepo, metadataBackend, a.metrics)
+	a.uploadRepo = ametrics.NewUploadRepository(a.uploadRepo, metadataBackend, a.metrics)
+	a.jobQueue = ametrics.NewJobQueue(a.jobQueue, metadataBackend, a.metrics)
+
+	a.mediaRepo = ametrics.NewMediaRepository(a.mediaRepo, a.config.MediaStorageBackend, a.metrics)
+}
+
+// traceRepositories wraps the calls to the repositories in spans. The calls to MongoDB and S3 they make are traced by
+// the instrumentations of their clients.
+func (a *app) traceRepositories() {
+	a.tagRepo = atracing.NewTagRepository(a.tagRepo, a.tracerProvider)
+	a.mediaMetadataRepo = atracing.NewMediaMetadataRepository(a.mediaMetadataRepo, a.tracerProvider)
+	a.mediaRepo = atracing.NewMediaRepository(a.mediaRepo, a.tracerProvider)
+}
+
+// signingKey returns the configured key or generates a random one, which doesn't survive a restart.
+func (a *app) signingKey(configured string, name string, usage string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+
+	a.log.Warnf("no %v signing key configured. Generating a random one, %v won't survive a restart.", name, usage)
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errortypes.NewIllegalStatef("failed to generate %v signing key: %v", name, err)
+	}
+
+	return key, nil
+}
+
 func (a *app) Run() error {
 	ctx := util.WithLogger(context.Background(), a.log)
 
 	a.log.Info("starting async tasks ...")
+	go a.runSetupRunners(ctx)
 	for _, runner := range a.runners {
 		go runner(ctx)
 	}
 
 	a.log.Info("starting API ...")
-	return ahttp.StartAPI(a.log, a.config.BaseURL, a.config.HTTPPort, a.mediaService, a.tagRepo)
+	return ahttp.StartAPI(
+		a.log,
+		a.metrics,
+		a.tracerProvider,
+		a.config.BaseURL,
+		a.config.HTTPPort,
+		a.mediaService,
+		a.uploadService,
+		a.reconciler,
+		a.healthService,
+		a.tagRepo,
+		a.mediaDownloader,
+		a.mediaUploader,
+		a.cursorSigningKey,
+	)
+}
+
+// runSetupRunners runs the setup runners at once and reports to the startup probe, when all of them are done
+func (a *app) runSetupRunners(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, runner := range a.setupRunners {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			runner(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	a.log.Info("storage is set up")
+	a.healthService.SetStarted()
+}
+
+func (a *app) Migrate(ctx context.Context) error {
+	ctx = util.WithLogger(ctx, a.log)
+
+	a.log.Info("preparing storage ...")
+	for _, runner := range a.setupRunners {
+		runner(ctx)
+	}
+
+	if a.schemaMigrator == nil {
+		return nil
+	}
+
+	a.log.Info("migrating schema ...")
+	return a.schemaMigrator.Migrate(ctx)
+}
+
+// migrateSchema migrates on startup. Until it's done, documents of the previous schema are read as well.
+func (a *app) migrateSchema(ctx context.Context) {
+	err := a.schemaMigrator.Migrate(ctx)
+	if err != nil {
+		a.log.Errorf("failed to migrate schema: %v", err)
+	}
+
+	a.metrics.CountRun("migrate_schema", err)
+}
+
+func (a *app) Shutdown(ctx context.Context) error {
+	if a.shutdownTracing == nil {
+		return nil
+	}
+
+	return a.shutdownTracing(ctx)
 }
 
 func (a *app) TagRepo() ports.TagRepository {
@@ -107,3 +418,19 @@ func (a *app) MediaRepo() ports.MediaRepository {
 func (a *app) MediaMetadataRepo() ports.MediaMetadataRepository {
 	return a.mediaMetadataRepo
 }
+
+func (a *app) UploadRepo() ports.UploadRepository {
+	return a.uploadRepo
+}
+
+func (a *app) JobQueue() ports.JobQueue {
+	return a.jobQueue
+}
+
+func (a *app) MediaService() services.MediaService {
+	return a.mediaService
+}
+
+func (a *app) Reconciler() services.Reconciler {
+	return a.reconciler
+}
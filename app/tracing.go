@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"os"
+
+	"media-nexus/config"
+	"media-nexus/errortypes"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// setupTracing creates the tracer provider of the configured exporter. Without exporter, spans aren't even recorded.
+func (a *app) setupTracing(ctx context.Context) error {
+	var tracerProviderOption sdktrace.TracerProviderOption
+
+	switch a.config.TracingExporter {
+	case config.TracingExporterNone:
+		a.tracerProvider = noop.NewTracerProvider()
+		return nil
+	case config.TracingExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return errortypes.NewIllegalStatef("failed to create stdout trace exporter: %v", err)
+		}
+
+		// spans are printed right away, when they end
+		tracerProviderOption = sdktrace.WithSyncer(exporter)
+	case config.TracingExporterOTLP:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(a.config.TracingOTLPEndpoint)}
+		if a.config.TracingOTLPInsecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		// doesn't connect yet, so a collector not up yet doesn't keep the service from starting
+		exporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return errortypes.NewIllegalStatef("failed to create otlp trace exporter: %v", err)
+		}
+
+		tracerProviderOption = sdktrace.WithBatcher(exporter)
+	default:
+		return errortypes.NewIllegalStatef("unknown tracing exporter '%v'", a.config.TracingExporter)
+	}
+
+	serviceResource, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(a.config.TracingServiceName)),
+	)
+	if err != nil {
+		return errortypes.NewIllegalStatef("failed to create tracing resource: %v", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		tracerProviderOption,
+		sdktrace.WithResource(serviceResource),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(a.config.TracingSampleRatio))),
+	)
+
+	a.tracerProvider = tracerProvider
+	a.shutdownTracing = tracerProvider.Shutdown
+
+	return nil
+}
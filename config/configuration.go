@@ -1,38 +1,145 @@
 package config
 
 import (
+	"net/url"
 	"time"
 
+	"media-nexus/errortypes"
 	"media-nexus/validation"
 )
 
+const (
+	MediaStorageBackendS3         = "s3"
+	MediaStorageBackendFileSystem = "fs"
+	MediaStorageBackendMemory     = "memory"
+)
+
+const (
+	MetadataStorageBackendMongoDB = "mongodb"
+	MetadataStorageBackendMemory  = "memory"
+)
+
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
 // Configuration defines options for the application.
 type Configuration struct {
 	BaseURL  string
 	HTTPPort int
 
+	MetadataStorageBackend          string
 	MongoDBURI                      string
 	MediaDatabase                   string
 	MediaTagCollection              string
 	MediaMetadataCollection         string
+	MediaUploadCollection           string
+	MediaJobCollection              string
+	MediaMigrationCollection        string
+	MediaStorageBackend             string
 	MediaBucket                     string
 	MediaBucketRegion               string
+	MediaUploadPartSizeMB           int
+	MediaUploadConcurrency          int
+	StaleMediaUploadLifetime        time.Duration
+	StaleMediaUploadCheckInterval   time.Duration
+	MediaRootDir                    string
+	MediaURLSigningKey              string
+	CursorSigningKey                string
 	GetMediaURLLifetime             time.Duration
+	DirectUploadLifetime            time.Duration
 	IncompleteMediaMetadataLifetime time.Duration
+	ResumableUploadLifetime         time.Duration
+	ExpiredUploadCheckInterval      time.Duration
+	// AllowedMediaTypes are the MIME types media may have, sniffed from its content. Entries like `image/*` allow all
+	// subtypes.
+	AllowedMediaTypes []string
+	// StripImageMetadata removes EXIF, XMP and other metadata like GPS coordinates from uploaded JPEG and PNG images
+	// before they are stored.
+	StripImageMetadata bool
+	// KeepImageOrientation keeps the EXIF orientation when stripping the metadata, so images are still displayed
+	// upright.
+	KeepImageOrientation bool
+	// MediaVariantSizes are the sizes in pixels of the scaled down variants generated for images. An empty list
+	// disables the variants.
+	MediaVariantSizes []int
+	// JobVisibilityTimeout is the time a job stays leased without its worker extending the lease, e.g. after a crash
+	JobVisibilityTimeout time.Duration
+	JobPollInterval      time.Duration
+	// failed jobs are retried with exponential backoff and dead lettered after JobMaxAttempts
+	JobMaxAttempts    int
+	JobInitialBackoff time.Duration
+	JobMaxBackoff     time.Duration
+	// ReconcileInterval is the time between two reconciliations of the media storage with the metadata
+	ReconcileInterval time.Duration
+	// ReconcileGracePeriod is the age media without metadata must have, before the reconciliation deletes it. It must
+	// exceed the time uploads take.
+	ReconcileGracePeriod time.Duration
+	// HealthCheckTimeout is the time each dependency has to answer a health check
+	HealthCheckTimeout time.Duration
+	// HealthCheckCacheLifetime is the time a health report is reused, so frequent probes don't put load on the
+	// dependencies
+	HealthCheckCacheLifetime time.Duration
+	// TracingExporter is none by default, so spans are dropped. stdout prints them for local use, otlp sends them to
+	// TracingOTLPEndpoint.
+	TracingExporter string
+	// TracingOTLPEndpoint is the host and port of a collector receiving OTLP over HTTP
+	TracingOTLPEndpoint string
+	// TracingOTLPInsecure sends the spans without TLS, e.g. to a collector running next to the service
+	TracingOTLPInsecure bool
+	// TracingSampleRatio is the share of traces started by this service, that is recorded. Traces continued from a
+	// caller are recorded, if the caller recorded them.
+	TracingSampleRatio float64
+	TracingServiceName string
 }
 
 func NewConfiguration() Configuration {
 	return Configuration{
 		BaseURL:                         "http://localhost",
 		HTTPPort:                        8081,
+		MetadataStorageBackend:          MetadataStorageBackendMongoDB,
 		MongoDBURI:                      "http://localhost:27017",
 		MediaDatabase:                   "media",
 		MediaTagCollection:              "tags",
 		MediaMetadataCollection:         "media_metadata",
+		MediaUploadCollection:           "media_uploads",
+		MediaJobCollection:              "media_jobs",
+		MediaMigrationCollection:        "schema_migrations",
+		MediaStorageBackend:             MediaStorageBackendS3,
 		MediaBucket:                     "hintergarten.de-media-nexus-media",
 		MediaBucketRegion:               "eu-central-1",
+		MediaUploadPartSizeMB:           16,
+		MediaUploadConcurrency:          4,
+		StaleMediaUploadLifetime:        24 * time.Hour,
+		StaleMediaUploadCheckInterval:   time.Hour,
+		MediaRootDir:                    "./media",
+		MediaURLSigningKey:              "",
+		CursorSigningKey:                "",
 		GetMediaURLLifetime:             15 * 60 * time.Second,
+		DirectUploadLifetime:            time.Hour,
 		IncompleteMediaMetadataLifetime: 60 * time.Second,
+		ResumableUploadLifetime:         24 * time.Hour,
+		ExpiredUploadCheckInterval:      time.Hour,
+		AllowedMediaTypes:               []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp"},
+		StripImageMetadata:              false,
+		KeepImageOrientation:            true,
+		MediaVariantSizes:               []int{128, 512, 1024},
+		JobVisibilityTimeout:            5 * time.Minute,
+		JobPollInterval:                 5 * time.Second,
+		JobMaxAttempts:                  5,
+		JobInitialBackoff:               10 * time.Second,
+		JobMaxBackoff:                   time.Hour,
+		ReconcileInterval:               24 * time.Hour,
+		ReconcileGracePeriod:            24 * time.Hour,
+		HealthCheckTimeout:              2 * time.Second,
+		HealthCheckCacheLifetime:        5 * time.Second,
+		TracingExporter:                 TracingExporterNone,
+		TracingOTLPEndpoint:             "localhost:4318",
+		TracingOTLPInsecure:             false,
+		TracingSampleRatio:              1,
+		TracingServiceName:              "media-nexus",
 	}
 }
 
@@ -45,6 +152,166 @@ func (c *Configuration) Validate() error {
 		return err
 	}
 
+	if err := validation.IsValidEnumProperty(
+		"<root>",
+		"metadataStorageBackend",
+		c.MetadataStorageBackend,
+		[]string{MetadataStorageBackendMongoDB, MetadataStorageBackendMemory},
+	); err != nil {
+		return err
+	}
+
+	if c.MetadataStorageBackend == MetadataStorageBackendMongoDB {
+		if err := c.validateMongoDB(); err != nil {
+			return err
+		}
+	}
+
+	if err := validation.IsValidEnumProperty(
+		"<root>",
+		"mediaStorageBackend",
+		c.MediaStorageBackend,
+		[]string{MediaStorageBackendS3, MediaStorageBackendFileSystem, MediaStorageBackendMemory},
+	); err != nil {
+		return err
+	}
+
+	if c.DirectUploadLifetime <= 0 {
+		return errortypes.NewBadUserInput("directUploadLifetime in <root> must be positive")
+	}
+
+	if c.ResumableUploadLifetime <= 0 {
+		return errortypes.NewBadUserInput("resumableUploadLifetime in <root> must be positive")
+	}
+
+	if c.ExpiredUploadCheckInterval <= 0 {
+		return errortypes.NewBadUserInput("expiredUploadCheckInterval in <root> must be positive")
+	}
+
+	if len(c.AllowedMediaTypes) < 1 {
+		return errortypes.NewBadUserInput("allowedMediaTypes in <root> must not be empty")
+	}
+
+	for _, size := range c.MediaVariantSizes {
+		if err := validation.IsValidIntProperty("<root>", "mediaVariantSizes", size, 1, 8192); err != nil {
+			return err
+		}
+	}
+
+	if err := c.validateJobs(); err != nil {
+		return err
+	}
+
+	if c.ReconcileInterval <= 0 {
+		return errortypes.NewBadUserInput("reconcileInterval in <root> must be positive")
+	}
+
+	// media uploaded directly has no metadata anymore, if the upload took longer than the metadata lives
+	if c.ReconcileGracePeriod < c.DirectUploadLifetime {
+		return errortypes.NewBadUserInput("reconcileGracePeriod in <root> must be at least directUploadLifetime")
+	}
+
+	if c.HealthCheckTimeout <= 0 {
+		return errortypes.NewBadUserInput("healthCheckTimeout in <root> must be positive")
+	}
+
+	if c.HealthCheckCacheLifetime < 0 {
+		return errortypes.NewBadUserInput("healthCheckCacheLifetime in <root> must not be negative")
+	}
+
+	if err := c.validateTracing(); err != nil {
+		return err
+	}
+
+	switch c.MediaStorageBackend {
+	case MediaStorageBackendS3:
+		if err := validation.IsValidStringProperty("<root>", "mediaBucket", c.MediaBucket); err != nil {
+			return err
+		}
+
+		if err := validation.IsValidStringProperty("<root>", "mediaBucketRegion", c.MediaBucketRegion); err != nil {
+			return err
+		}
+
+		if err := c.validateMediaUpload(); err != nil {
+			return err
+		}
+	case MediaStorageBackendFileSystem:
+		if err := validation.IsValidStringProperty("<root>", "mediaRootDir", c.MediaRootDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Configuration) validateMediaUpload() error {
+	// S3 requires parts of at least 5 MB and at most 5 GB
+	partSizeMB := c.MediaUploadPartSizeMB
+	if err := validation.IsValidIntProperty("<root>", "mediaUploadPartSizeMB", partSizeMB, 5, 5*1024); err != nil {
+		return err
+	}
+
+	concurrency := c.MediaUploadConcurrency
+	if err := validation.IsValidIntProperty("<root>", "mediaUploadConcurrency", concurrency, 1, 64); err != nil {
+		return err
+	}
+
+	if c.StaleMediaUploadLifetime <= 0 {
+		return errortypes.NewBadUserInput("staleMediaUploadLifetime in <root> must be positive")
+	}
+
+	if c.StaleMediaUploadCheckInterval <= 0 {
+		return errortypes.NewBadUserInput("staleMediaUploadCheckInterval in <root> must be positive")
+	}
+
+	return nil
+}
+
+func (c *Configuration) validateJobs() error {
+	if c.JobVisibilityTimeout <= 0 {
+		return errortypes.NewBadUserInput("jobVisibilityTimeout in <root> must be positive")
+	}
+
+	if c.JobPollInterval <= 0 {
+		return errortypes.NewBadUserInput("jobPollInterval in <root> must be positive")
+	}
+
+	if err := validation.IsValidIntProperty("<root>", "jobMaxAttempts", c.JobMaxAttempts, 1, 100); err != nil {
+		return err
+	}
+
+	if c.JobInitialBackoff <= 0 || c.JobMaxBackoff < c.JobInitialBackoff {
+		return errortypes.NewBadUserInput("jobInitialBackoff in <root> must be positive and at most jobMaxBackoff")
+	}
+
+	return nil
+}
+
+func (c *Configuration) validateTracing() error {
+	if err := validation.IsValidEnumProperty(
+		"<root>",
+		"tracingExporter",
+		c.TracingExporter,
+		[]string{TracingExporterNone, TracingExporterStdout, TracingExporterOTLP},
+	); err != nil {
+		return err
+	}
+
+	if c.TracingExporter == TracingExporterOTLP {
+		if err := validation.IsValidStringProperty("<root>", "tracingOtlpEndpoint", c.TracingOTLPEndpoint); err != nil {
+			return err
+		}
+	}
+
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		return errortypes.NewBadUserInput("tracingSampleRatio in <root> must be between 0 and 1")
+	}
+
+	return validation.IsValidStringProperty("<root>", "tracingServiceName", c.TracingServiceName)
+}
+
+func (c *Configuration) validateMongoDB() error {
 	if err := validation.IsValidStringProperty("<root>", "mongDbUri", c.MongoDBURI); err != nil {
 		return err
 	}
@@ -61,13 +328,39 @@ func (c *Configuration) Validate() error {
 		return err
 	}
 
-	if err := validation.IsValidStringProperty("<root>", "mediaBucket", c.MediaBucket); err != nil {
+	if err := validation.IsValidStringProperty("<root>", "mediaUploadCollection", c.MediaUploadCollection); err != nil {
+		return err
+	}
+
+	if err := validation.IsValidStringProperty("<root>", "mediaJobCollection", c.MediaJobCollection); err != nil {
 		return err
 	}
 
-	if err := validation.IsValidStringProperty("<root>", "mediaBucketRegion", c.MediaBucketRegion); err != nil {
+	if err := validation.IsValidStringProperty("<root>", "mediaMigrationCollection", c.MediaMigrationCollection); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Redacted returns a copy without the signing keys and the password in the MongoDB URI, e.g. for printing it.
+func (c Configuration) Redacted() Configuration {
+	const redacted = "REDACTED"
+
+	if c.MediaURLSigningKey != "" {
+		c.MediaURLSigningKey = redacted
+	}
+
+	if c.CursorSigningKey != "" {
+		c.CursorSigningKey = redacted
+	}
+
+	if uri, err := url.Parse(c.MongoDBURI); err == nil && uri.User != nil {
+		if _, ok := uri.User.Password(); ok {
+			uri.User = url.UserPassword(uri.User.Username(), redacted)
+			c.MongoDBURI = uri.String()
+		}
+	}
+
+	return c
+}
@@ -17,9 +17,9 @@ func viperDecoderConfigOptions(config *mapstructure.DecoderConfig) {
 	config.ZeroFields = true
 }
 
-func LoadConfiguration() (*Configuration, error) {
-	envPrefix := "MEDIANEXUS"
+const envPrefix = "MEDIANEXUS"
 
+func LoadConfiguration() (*Configuration, error) {
 	configFile := os.Getenv(envPrefix + "_CONFIG")
 	if len(configFile) < 1 {
 		flag.Parse()
@@ -31,6 +31,16 @@ func LoadConfiguration() (*Configuration, error) {
 	return parseConfiguration(configFile, envPrefix)
 }
 
+// LoadConfigurationFile reads configFile, or the file given by MEDIANEXUS_CONFIG if empty. Unlike LoadConfiguration
+// it leaves the command line alone.
+func LoadConfigurationFile(configFile string) (*Configuration, error) {
+	if len(configFile) < 1 {
+		configFile = os.Getenv(envPrefix + "_CONFIG")
+	}
+
+	return parseConfiguration(configFile, envPrefix)
+}
+
 func parseConfiguration(configFilePath string, envPrefix string) (*Configuration, error) {
 	if err := initializeViper(envPrefix); err != nil {
 		return nil, err
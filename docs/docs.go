@@ -22,13 +22,192 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/reconcile": {
+            "get": {
+                "description": "retrieve the report of the last reconciliation of the media storage with the metadata run by the\ninstance answering the request. Reconciliations run periodically on one of the instances.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get reconciliation report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.ReconcileReport"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "delete stored media without metadata, that is older than the grace period, and report media with\ncomplete metadata, that isn't stored. Waits for the reconciliation to finish.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reconcile media storage with metadata",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.ReconcileReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/blobs/{key}": {
+            "get": {
+                "description": "download the blob of a media item through a signed URL as returned in file_url.\nOnly available when media is stored on the local file system.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Download media blob",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "key of the blob",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "expiry of the URL as unix timestamp",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature of the URL",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "upload the blob of a media item through a signed URL as returned in upload_url.\nOnly available when media is stored on the local file system. Fails with 409, if the blob was\nuploaded already.",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Upload media blob",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "key of the blob",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "expiry of the URL as unix timestamp",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature of the URL",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            }
+        },
         "/health/live": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tags"
+                    "health"
                 ],
                 "summary": "Retrieve live health status of server",
                 "responses": {
@@ -40,88 +219,934 @@ const docTemplate = `{
         },
         "/health/ready": {
             "get": {
+                "description": "check MongoDB and S3, depending on the configured backends. Fails with 503, if a critical one\nfails. Reports are cached for a few seconds.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tags"
+                    "health"
                 ],
                 "summary": "Retrieve ready health status of server",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.HealthReport"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.HealthReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/startup": {
+            "get": {
+                "description": "succeeds once the storage is set up, e.g. the indices are created",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Retrieve startup status of server",
                 "responses": {
                     "204": {
                         "description": "No Content"
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/media": {
+            "get": {
+                "description": "query media items either by a single tag ID or by a query combining tag IDs with AND, OR, NOT\nand parentheses, e.g. ` + "`" + `(tagA AND tagB) OR NOT tagC` + "`" + `. Items are returned in pages ordered by\nID. Pass next_cursor as cursor to get the next page. The image filters only match images. Tag\nIDs with other characters than letters, digits, _ and -, or named like an operator, are put in\ndouble quotes, escaping \" and \\ with a backslash.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Query media items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "tag ID to search for",
+                        "name": "tag_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "tag query to search for",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "minimum image width in pixels",
+                        "name": "min_width",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum image width in pixels",
+                        "name": "max_width",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "minimum image height in pixels",
+                        "name": "min_height",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum image height in pixels",
+                        "name": "max_height",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "camera model the image was taken with",
+                        "name": "camera_model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time the image was captured after",
+                        "name": "captured_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time the image was captured before",
+                        "name": "captured_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "whether the image has GPS coordinates",
+                        "name": "has_gps",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum number of media items per page, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.GetMediaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "create a new media with a list of tags and a name. The file is streamed, so it has to be the last\npart of the form, after name and tag_ids[]. The type of the file is sniffed from its content\nand must be one of the types allowed by the deployment.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Create media",
+                "parameters": [
+                    {
+                        "description": "media to be created",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/ahttp.postMediaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.PostMediaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/media/tus": {
+            "post": {
+                "description": "create a resumable upload for a media with the tus protocol. Upload-Metadata must contain a\nname and may contain tag_ids, a comma separated list of tag IDs. Both base64 encoded, as\ndefined by tus. Once all bytes are sent with PATCH, the media is created.",
+                "tags": [
+                    "media"
+                ],
+                "summary": "Create resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "tus version, must be 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "size of the media in bytes",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "name and tag_ids of the media",
+                        "name": "Upload-Metadata",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the upload"
+                            },
+                            "Upload-Expires": {
+                                "type": "string",
+                                "description": "time the upload expires, if not continued"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            },
+            "options": {
+                "description": "get the tus version, extensions and maximum upload size supported for resumable uploads",
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get tus capabilities",
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "headers": {
+                            "Tus-Extension": {
+                                "type": "string",
+                                "description": "supported tus extensions"
+                            },
+                            "Tus-Max-Size": {
+                                "type": "integer",
+                                "description": "maximum upload size in bytes"
+                            },
+                            "Tus-Version": {
+                                "type": "string",
+                                "description": "supported tus versions"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/media/tus/{id}": {
+            "delete": {
+                "description": "terminate a resumable upload and delete the bytes received so far",
+                "tags": [
+                    "media"
+                ],
+                "summary": "Terminate resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tus version, must be 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "get the offset of a resumable upload, so the client knows where to continue",
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get resumable upload offset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tus version, must be 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "headers": {
+                            "Media-Id": {
+                                "type": "string",
+                                "description": "ID of the media, once the upload is complete"
+                            },
+                            "Upload-Expires": {
+                                "type": "string",
+                                "description": "time the upload expires, if not continued"
+                            },
+                            "Upload-Length": {
+                                "type": "integer",
+                                "description": "size of the media in bytes"
+                            },
+                            "Upload-Offset": {
+                                "type": "integer",
+                                "description": "number of bytes received"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    }
+                }
+            },
+            "patch": {
+                "description": "append bytes to a resumable upload at the given offset. The media is created with the last\nbytes. If that failed, send an empty PATCH at the full length to retry. Media of a type not\nallowed is rejected with 415 and the upload is deleted.",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Continue resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tus version, must be 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "offset of the bytes sent",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "headers": {
+                            "Media-Id": {
+                                "type": "string",
+                                "description": "ID of the media, once the upload is complete"
+                            },
+                            "Upload-Expires": {
+                                "type": "string",
+                                "description": "time the upload expires, if not continued"
+                            },
+                            "Upload-Offset": {
+                                "type": "integer",
+                                "description": "number of bytes received"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/media/uploads": {
+            "post": {
+                "description": "create a media, that the client uploads on its own with a PUT request to upload_url, sending\nupload_headers along. Then complete the upload. If the media exists already, there is no\nupload_url and nothing left to do.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Create direct media upload",
+                "parameters": [
+                    {
+                        "description": "media to be uploaded",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.PostMediaUploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.PostMediaUploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/media/uploads/{id}/complete": {
+            "post": {
+                "description": "complete a media uploaded with the upload_url. Fails if the file doesn't match size, checksum\nand MIME type announced. Safe to retry.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Complete direct media upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.MediaItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/media/{id}": {
+            "get": {
+                "description": "get a single media item by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get media item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.MediaItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "delete a media item with its file. Safe to retry if it failed.",
+                "tags": [
+                    "media"
+                ],
+                "summary": "Delete media item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "change the name or the tags of a media item. The media ID stays the same.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Update media item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "fields to be changed",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.PatchMediaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ahmodel.MediaItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
                     }
                 }
             }
         },
-        "/media": {
+        "/media/{id}/content": {
             "get": {
-                "description": "query media items based on some parameters",
+                "description": "download the file of a media item through this service. Supports Range requests as well as\nIf-None-Match and If-Range with the stored checksum as ETag and If-Modified-Since with the time\nthe upload completed. The content type is the MIME type sniffed on upload.",
                 "produces": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "tags": [
                     "media"
                 ],
-                "summary": "Query media items",
+                "summary": "Download media content",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "tag ID to search for",
-                        "name": "tag_id",
-                        "in": "query",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "byte ranges to download, e.g. bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached file",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "time a cached file was last modified",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the Range must match, else the whole file is sent",
+                        "name": "If-Range",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/ahmodel.GetMediaResponse"
+                            "type": "file"
                         }
                     },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "416": {
+                        "description": "Requested Range Not Satisfiable",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "create a new media with a list of tags and a name",
-                "consumes": [
-                    "multipart/form-data"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+            }
+        },
+        "/media/{id}/variants/{size}": {
+            "get": {
+                "description": "redirects to the variant of an image scaled down to fit into size x size pixels. Variants are\ngenerated in the background after upload. Missing ones are generated before redirecting. Images\nnot larger than the size redirect to the original file.",
                 "tags": [
                     "media"
                 ],
-                "summary": "Create media",
+                "summary": "Get a scaled down variant of an image",
                 "parameters": [
                     {
-                        "description": "media to be created",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/ahttp.postMediaRequest"
-                        }
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "one of the configured variant sizes in pixels",
+                        "name": "size",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/ahmodel.PostMediaResponse"
-                        }
+                    "302": {
+                        "description": "Found"
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
                         }
                     }
                 }
@@ -129,7 +1154,7 @@ const docTemplate = `{
         },
         "/tags": {
             "get": {
-                "description": "retrieve all tags",
+                "description": "retrieve tags in pages ordered by ID. Pass next_cursor as cursor to get the next page.",
                 "produces": [
                     "application/json"
                 ],
@@ -137,14 +1162,37 @@ const docTemplate = `{
                     "tags"
                 ],
                 "summary": "List tags",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "maximum number of tags per page, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/ahmodel.Tag"
-                            }
+                            "$ref": "#/definitions/ahmodel.GetTagsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
                         }
                     }
                 }
@@ -182,7 +1230,13 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httputils.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.Problem"
                         }
                     }
                 }
@@ -190,6 +1244,17 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "ahmodel.GPSCoordinates": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
+        },
         "ahmodel.GetMediaResponse": {
             "type": "object",
             "properties": {
@@ -198,18 +1263,153 @@ const docTemplate = `{
                     "items": {
                         "$ref": "#/definitions/ahmodel.MediaItem"
                     }
+                },
+                "next_cursor": {
+                    "description": "NextCursor is empty on the last page",
+                    "type": "string"
+                }
+            }
+        },
+        "ahmodel.GetTagsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ahmodel.Tag"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor is empty on the last page",
+                    "type": "string"
+                }
+            }
+        },
+        "ahmodel.HealthCheckResult": {
+            "type": "object",
+            "properties": {
+                "critical": {
+                    "type": "boolean"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "healthy": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "mongodb"
+                }
+            }
+        },
+        "ahmodel.HealthReport": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ahmodel.HealthCheckResult"
+                    }
+                },
+                "healthy": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "ahmodel.ImageInfo": {
+            "type": "object",
+            "properties": {
+                "camera_model": {
+                    "type": "string"
+                },
+                "capture_time": {
+                    "type": "string"
+                },
+                "gps": {
+                    "$ref": "#/definitions/ahmodel.GPSCoordinates"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "orientation": {
+                    "description": "Orientation is the EXIF orientation from 1 to 8",
+                    "type": "integer"
+                },
+                "width": {
+                    "type": "integer"
                 }
             }
         },
         "ahmodel.MediaItem": {
             "type": "object",
             "properties": {
+                "checksum": {
+                    "type": "string"
+                },
                 "file_url": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "string"
+                },
+                "image_info": {
+                    "$ref": "#/definitions/ahmodel.ImageInfo"
+                },
+                "last_update": {
+                    "type": "string"
+                },
+                "mime_type": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "stored_checksum": {
+                    "type": "string"
+                },
+                "tag_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "upload_complete": {
+                    "type": "boolean"
+                },
+                "variants": {
+                    "description": "Variants maps the sizes of the scaled down variants of images to their URLs",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "ahmodel.PatchMediaRequest": {
+            "type": "object",
+            "properties": {
+                "add_tag_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "name": {
                     "type": "string"
                 },
+                "remove_tag_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "tag_ids": {
                     "type": "array",
                     "items": {
@@ -226,6 +1426,51 @@ const docTemplate = `{
                 }
             }
         },
+        "ahmodel.PostMediaUploadRequest": {
+            "type": "object",
+            "properties": {
+                "checksum": {
+                    "description": "Checksum is the hex encoded SHA-256 of the file",
+                    "type": "string"
+                },
+                "mime_type": {
+                    "description": "MimeType of the file like ` + "`" + `image/png` + "`" + `. It is verified against the content on completion.",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "description": "Size of the file in bytes",
+                    "type": "integer"
+                },
+                "tag_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "ahmodel.PostMediaUploadResponse": {
+            "type": "object",
+            "properties": {
+                "media_id": {
+                    "type": "string"
+                },
+                "upload_headers": {
+                    "description": "UploadHeaders must be sent along with the PUT request",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "upload_url": {
+                    "description": "UploadURL takes the file with a PUT request",
+                    "type": "string"
+                }
+            }
+        },
         "ahmodel.PostTagsRequest": {
             "type": "object",
             "properties": {
@@ -242,6 +1487,38 @@ const docTemplate = `{
                 }
             }
         },
+        "ahmodel.ReconcileReport": {
+            "type": "object",
+            "properties": {
+                "deleted_orphans": {
+                    "description": "DeletedOrphans are the keys of stored media without metadata, that were older than the grace period",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "missing_media": {
+                    "description": "MissingMedia are the IDs of media, that has complete metadata, but isn't stored",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scanned_media": {
+                    "description": "ScannedMedia counts the stored keys, including variants and media still being uploaded",
+                    "type": "integer"
+                },
+                "scanned_metadata": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
         "ahmodel.Tag": {
             "type": "object",
             "properties": {
@@ -273,6 +1550,54 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "httputils.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "Detail explains this occurrence. It's left out for server errors, whose details are only logged.",
+                    "type": "string",
+                    "example": "resource 'abc' not found"
+                },
+                "errors": {
+                    "description": "Errors lists the invalid fields of the request, e.g. query parameters, if the problem is caused by them",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/httputils.ProblemFieldError"
+                    }
+                },
+                "request_id": {
+                    "description": "RequestID is the X-Request-ID header of the response. Logs of the error include it as well.",
+                    "type": "string",
+                    "example": "8f14e45fceea167a5a36dedd4bea2543"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "description": "Type identifies the kind of problem. Clients should switch on it rather than on the title.",
+                    "type": "string",
+                    "example": "urn:media-nexus:problem:not-found"
+                }
+            }
+        },
+        "httputils.ProblemFieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "limit"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "limit has to be between 1 and 1000"
+                }
+            }
         }
     }
 }`
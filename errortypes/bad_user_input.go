@@ -30,3 +30,9 @@ func (b BadUserInput) Is(err error) bool {
 	_, ok := err.(BadUserInput)
 	return ok
 }
+
+// NewBadUserInputFieldf is BadUserInput caused by a single field, e.g. a query parameter, that is returned along
+func NewBadUserInputFieldf(field string, format string, a ...interface{}) error {
+	reason := fmt.Sprintf(format, a...)
+	return WithFieldErrors(NewBadUserInput(reason), FieldError{Field: field, Reason: reason})
+}
@@ -0,0 +1,33 @@
+package errortypes
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Conflict is an error for requests that clash with an operation currently running on the same resource.
+type Conflict struct {
+	what string
+}
+
+func NewConflict(what string) error {
+	return errors.WithStack(Conflict{what: what})
+}
+
+func NewConflictf(format string, a ...interface{}) error {
+	return errors.WithStack(Conflict{what: fmt.Sprintf(format, a...)})
+}
+
+func (b Conflict) Error() string {
+	return b.what
+}
+
+func IsConflict(err error) bool {
+	return errors.Is(err, Conflict{})
+}
+
+func (b Conflict) Is(err error) bool {
+	_, ok := err.(Conflict)
+	return ok
+}
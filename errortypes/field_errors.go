@@ -0,0 +1,39 @@
+package errortypes
+
+import (
+	"github.com/pkg/errors"
+)
+
+// FieldError tells which field of the user input is invalid and why
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+type withFieldErrors struct {
+	error
+	fields []FieldError
+}
+
+// WithFieldErrors attaches the invalid fields to err. Its type stays the same.
+func WithFieldErrors(err error, fields ...FieldError) error {
+	return withFieldErrors{err, fields}
+}
+
+func (w withFieldErrors) Cause() error {
+	return w.error
+}
+
+func (w withFieldErrors) Unwrap() error {
+	return w.error
+}
+
+// FieldErrors returns the invalid fields attached to err, if any
+func FieldErrors(err error) []FieldError {
+	var withFields withFieldErrors
+	if errors.As(err, &withFields) {
+		return withFields.fields
+	}
+
+	return nil
+}
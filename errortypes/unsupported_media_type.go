@@ -0,0 +1,34 @@
+package errortypes
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// UnsupportedMediaType is an error for media of a type the deployment doesn't accept.
+type UnsupportedMediaType struct {
+	what string
+}
+
+func NewUnsupportedMediaType(mimeType string) error {
+	what := fmt.Sprintf("media type '%s' is not supported", mimeType)
+	return errors.WithStack(UnsupportedMediaType{what: what})
+}
+
+func NewUnsupportedMediaTypef(format string, a ...interface{}) error {
+	return errors.WithStack(UnsupportedMediaType{what: fmt.Sprintf(format, a...)})
+}
+
+func (b UnsupportedMediaType) Error() string {
+	return b.what
+}
+
+func IsUnsupportedMediaType(err error) bool {
+	return errors.Is(err, UnsupportedMediaType{})
+}
+
+func (b UnsupportedMediaType) Is(err error) bool {
+	_, ok := err.(UnsupportedMediaType)
+	return ok
+}
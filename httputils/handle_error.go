@@ -5,8 +5,6 @@ import (
 
 	"media-nexus/errortypes"
 	"media-nexus/logger"
-
-	"github.com/pkg/errors"
 )
 
 type LogSomething int
@@ -17,39 +15,28 @@ const (
 	LogError
 )
 
+// HandleError responds with the problem matching the type of err. It returns false if there is no error.
 func HandleError(err error, response http.ResponseWriter, logger logger.Logger) bool {
 	if err == nil {
 		return false
 	}
 
-	var code int
-	log := LogError
-	respondWithError := true
-	responseMessage := err.Error()
-
-	switch errors.Cause(err).(type) {
-	case errortypes.BadUserInput:
-		code = http.StatusBadRequest
-		log = LogInfo
-	case errortypes.InputOutputError:
-		code = http.StatusInternalServerError
-	case errortypes.ResourceAlreadyExists:
-		code = http.StatusConflict
-	default:
-		code = RespondWithInternalError(response)
-		respondWithError = false
+	problemType := problemTypeOfError(err)
+	problem := newProblem(problemType, err.Error(), errortypes.FieldErrors(err))
+
+	requestID := response.Header().Get(HeaderRequestID)
+	if requestID != "" {
+		logger = logger.WithFields(map[string]interface{}{"requestId": requestID})
 	}
 
-	switch log {
+	switch problemType.log() {
 	case LogInfo:
 		logger.Infof("%v\n", err)
 	case LogError:
 		logger.Errorf("%v\n", err)
 	}
 
-	if respondWithError {
-		RespondWithError(response, code, "%v", responseMessage)
-	}
+	RespondWithProblem(response, problem)
 
 	return true
 }
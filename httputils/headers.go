@@ -1,14 +1,19 @@
 package httputils
 
 const (
-	HeaderAccept         string = "Accept"
-	HeaderAcceptEncoding string = "Accept-Encoding"
-	HeaderCacheControl   string = "Cache-Control"
-	HeaderContentLength  string = "Content-Length"
-	HeaderContentType    string = "Content-Type"
+	HeaderAccept             string = "Accept"
+	HeaderAcceptEncoding     string = "Accept-Encoding"
+	HeaderCacheControl       string = "Cache-Control"
+	HeaderContentLength      string = "Content-Length"
+	HeaderContentType        string = "Content-Type"
+	HeaderContentTypeOptions string = "X-Content-Type-Options"
+	HeaderETag               string = "ETag"
+	HeaderRequestID          string = "X-Request-ID"
 )
 
 const (
-	ContentTypeJSON string = "application/json; charset=UTF-8"
-	ContentTypeForm string = "application/x-www-form-urlencoded"
+	ContentTypeJSON        string = "application/json; charset=UTF-8"
+	ContentTypeProblemJSON string = "application/problem+json; charset=UTF-8"
+	ContentTypeForm        string = "application/x-www-form-urlencoded"
+	ContentTypeOctetStream string = "application/octet-stream"
 )
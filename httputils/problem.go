@@ -0,0 +1,156 @@
+package httputils
+
+import (
+	"net/http"
+
+	"media-nexus/errortypes"
+
+	"github.com/pkg/errors"
+)
+
+// problemTypePrefix makes the problem types URIs. They are listed in the README.
+const problemTypePrefix = "urn:media-nexus:problem:"
+
+// Problem is the body of all error responses, as defined by RFC 7807
+type Problem struct {
+	// Type identifies the kind of problem. Clients should switch on it rather than on the title.
+	Type   string `json:"type"   example:"urn:media-nexus:problem:not-found"`
+	Title  string `json:"title"  example:"Not Found"`
+	Status int    `json:"status" example:"404"`
+	// Detail explains this occurrence. It's left out for server errors, whose details are only logged.
+	Detail string `json:"detail,omitempty" example:"resource 'abc' not found"`
+	// RequestID is the X-Request-ID header of the response. Logs of the error include it as well.
+	RequestID string `json:"request_id,omitempty" example:"8f14e45fceea167a5a36dedd4bea2543"`
+	// Errors lists the invalid fields of the request, e.g. query parameters, if the problem is caused by them
+	Errors []ProblemFieldError `json:"errors,omitempty"`
+}
+
+type ProblemFieldError struct {
+	Field  string `json:"field"  example:"limit"`
+	Reason string `json:"reason" example:"limit has to be between 1 and 1000"`
+}
+
+type problemType struct {
+	name   string
+	title  string
+	status int
+}
+
+var (
+	problemBadRequest         = problemType{"bad-request", "Bad Request", http.StatusBadRequest}
+	problemNotFound           = problemType{"not-found", "Not Found", http.StatusNotFound}
+	problemAlreadyExists      = problemType{"already-exists", "Already Exists", http.StatusConflict}
+	problemConflict           = problemType{"conflict", "Conflict", http.StatusConflict}
+	problemInvalidArgument    = problemType{"invalid-argument", "Invalid Argument", http.StatusUnprocessableEntity}
+	problemInternal           = problemType{"internal", "Internal Server Error", http.StatusInternalServerError}
+	problemInputOutput        = problemType{"input-output", "Input/Output Error", http.StatusInternalServerError}
+	problemIllegalState       = problemType{"illegal-state", "Illegal State", http.StatusInternalServerError}
+	problemUpstreamError      = problemType{"upstream-error", "Bad Gateway", http.StatusBadGateway}
+	problemServiceUnavailable = problemType{"service-unavailable", "Service Unavailable", http.StatusServiceUnavailable}
+	problemTimeout            = problemType{"timeout", "Gateway Timeout", http.StatusGatewayTimeout}
+)
+
+var (
+	problemUnsupportedMediaType = problemType{
+		"unsupported-media-type",
+		"Unsupported Media Type",
+		http.StatusUnsupportedMediaType,
+	}
+	problemUpstreamUnavailable = problemType{
+		"upstream-unavailable",
+		"Upstream Unavailable",
+		http.StatusBadGateway,
+	}
+)
+
+// problemTypes are used for responses not caused by an error type, by their status. Each status maps to the most
+// general type with that status.
+var problemTypes = []problemType{
+	problemBadRequest,
+	problemNotFound,
+	problemConflict,
+	problemUnsupportedMediaType,
+	problemInvalidArgument,
+	problemInternal,
+	problemUpstreamError,
+	problemServiceUnavailable,
+	problemTimeout,
+}
+
+func problemTypeOfError(err error) problemType {
+	switch errors.Cause(err).(type) {
+	case errortypes.BadUserInput:
+		return problemBadRequest
+	case errortypes.ResourceNotFound:
+		return problemNotFound
+	case errortypes.ResourceAlreadyExists:
+		return problemAlreadyExists
+	case errortypes.Conflict:
+		return problemConflict
+	case errortypes.UnsupportedMediaType:
+		return problemUnsupportedMediaType
+	case errortypes.InvalidArgument:
+		return problemInvalidArgument
+	case errortypes.InputOutputError:
+		return problemInputOutput
+	case errortypes.IllegalState:
+		return problemIllegalState
+	case errortypes.UpstreamCommunicationError:
+		return problemUpstreamError
+	case errortypes.ServiceUnavailable:
+		return problemServiceUnavailable
+	case errortypes.UpstreamUnavailable:
+		return problemUpstreamUnavailable
+	case errortypes.Timeout:
+		return problemTimeout
+	default:
+		return problemInternal
+	}
+}
+
+// problemTypeOfStatus falls back to about:blank, which RFC 7807 defines as the plain meaning of the status
+func problemTypeOfStatus(status int) problemType {
+	for _, problemType := range problemTypes {
+		if problemType.status == status {
+			return problemType
+		}
+	}
+
+	return problemType{"", http.StatusText(status), status}
+}
+
+// log tells how errors of the type are logged. Client errors aren't ours to fix.
+func (t problemType) log() LogSomething {
+	if t.status < http.StatusInternalServerError {
+		return LogInfo
+	}
+
+	return LogError
+}
+
+func (t problemType) uri() string {
+	if t.name == "" {
+		return "about:blank"
+	}
+
+	return problemTypePrefix + t.name
+}
+
+// newProblem leaves out the detail of server errors, they might tell internals like hosts of upstreams
+func newProblem(problemType problemType, detail string, fields []errortypes.FieldError) *Problem {
+	problem := &Problem{
+		Type:   problemType.uri(),
+		Title:  problemType.title,
+		Status: problemType.status,
+	}
+
+	if problemType.status < http.StatusInternalServerError {
+		problem.Detail = detail
+	}
+
+	for _, field := range fields {
+		problem.Errors = append(problem.Errors, ProblemFieldError{Field: field.Field, Reason: field.Reason})
+	}
+
+	return problem
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/url"
 	"strconv"
+	"time"
 
 	"media-nexus/errortypes"
 )
@@ -13,17 +14,45 @@ func ParseInt32QueryParameter(queryValues url.Values, paramName string) (int32,
 	param := queryValues.Get(paramName)
 
 	if len(param) < 1 {
-		return 0, errortypes.NewBadUserInputf("param %v empty", paramName)
+		return 0, errortypes.NewBadUserInputFieldf(paramName, "param %v empty", paramName)
 	}
 
 	i, err := strconv.ParseInt(param, 10, 32)
 	if err != nil {
-		return 0, errortypes.NewBadUserInputf("failed to parse %v as integer: %v", paramName, err)
+		return 0, errortypes.NewBadUserInputFieldf(paramName, "failed to parse %v as integer: %v", paramName, err)
 	}
 
 	return int32(i), nil
 }
 
+func ParseBoolQueryParameter(queryValues url.Values, paramName string) (bool, error) {
+	param := queryValues.Get(paramName)
+
+	b, err := strconv.ParseBool(param)
+	if err != nil {
+		return false, errortypes.NewBadUserInputFieldf(paramName, "failed to parse %v as boolean: %v", paramName, err)
+	}
+
+	return b, nil
+}
+
+// ParseTimeQueryParameter expects an RFC 3339 timestamp like 2006-01-02T15:04:05Z
+func ParseTimeQueryParameter(queryValues url.Values, paramName string) (time.Time, error) {
+	param := queryValues.Get(paramName)
+
+	t, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return time.Time{}, errortypes.NewBadUserInputFieldf(
+			paramName,
+			"failed to parse %v as RFC 3339 time: %v",
+			paramName,
+			err,
+		)
+	}
+
+	return t, nil
+}
+
 func ParseJSONRequestBody(body io.Reader, output interface{}) error {
 	if err := json.NewDecoder(body).Decode(output); err != nil {
 		return errortypes.NewBadUserInputf("failed to parse request body as JSON: %v", err)
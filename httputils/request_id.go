@@ -0,0 +1,33 @@
+package httputils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+// requestIDPattern limits request IDs taken from clients, since they end up in logs
+var requestIDPattern = regexp.MustCompile(`^[0-9A-Za-z._-]{1,128}$`)
+
+// RequestID sets the X-Request-ID header of the response to the one of the request, e.g. set by a proxy, or to a new
+// one. Problems and the logs of their errors include it, so they can be matched.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderRequestID)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(HeaderRequestID, requestID)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	// crypto/rand doesn't fail
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
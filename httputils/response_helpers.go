@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"media-nexus/errortypes"
 	"media-nexus/logger"
 
 	"github.com/pkg/errors"
@@ -48,27 +49,46 @@ func EncodeJSON(i interface{}, escapeHTML bool) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// RespondWithError responds with the problem type matching the status and the message as detail
 func RespondWithError(
 	response http.ResponseWriter,
 	httpStatusCode int,
 	format string,
 	a ...interface{},
 ) {
-	errorMessage := fmt.Sprintf(format, a...)
-	http.Error(response, errorMessage, httpStatusCode)
+	RespondWithProblem(response, newProblem(problemTypeOfStatus(httpStatusCode), fmt.Sprintf(format, a...), nil))
+}
+
+// RespondWithFieldError responds with BadRequest, telling which field of the request is invalid
+func RespondWithFieldError(response http.ResponseWriter, field string, format string, a ...interface{}) {
+	reason := fmt.Sprintf(format, a...)
+	fields := []errortypes.FieldError{{Field: field, Reason: reason}}
+
+	RespondWithProblem(response, newProblem(problemBadRequest, reason, fields))
 }
 
 func RespondWithInternalError(response http.ResponseWriter) int {
-	RespondWithError(response, http.StatusInternalServerError, "internal server error")
+	RespondWithProblem(response, newProblem(problemInternal, "", nil))
 	return http.StatusInternalServerError
 }
 
 func RespondWithBadParameter(response http.ResponseWriter, parameterName string, reason error) {
-	RespondWithError(
-		response,
-		http.StatusBadRequest,
-		"failed to parse '%v': %v",
-		parameterName,
-		reason,
-	)
+	RespondWithFieldError(response, parameterName, "failed to parse '%v': %v", parameterName, reason)
+}
+
+// RespondWithProblem writes the problem as application/problem+json. The request ID is taken from the response
+// header, where RequestID put it.
+func RespondWithProblem(response http.ResponseWriter, problem *Problem) {
+	problem.RequestID = response.Header().Get(HeaderRequestID)
+
+	// encoding a problem doesn't fail
+	body, _ := EncodeJSON(problem, true)
+
+	response.Header().Set(HeaderContentType, ContentTypeProblemJSON)
+	// like http.Error, a problem replaces whatever the handler meant to send, e.g. a file
+	response.Header().Del(HeaderContentLength)
+	response.Header().Set(HeaderContentTypeOptions, "nosniff")
+	response.WriteHeader(problem.Status)
+
+	_, _ = response.Write(body)
 }
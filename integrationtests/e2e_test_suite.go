@@ -82,7 +82,7 @@ func (s *E2ETestSuite) isServerReady() bool {
 
 	defer response.Body.Close()
 
-	return response.StatusCode == http.StatusNoContent
+	return response.StatusCode == http.StatusOK
 }
 
 func (s *E2ETestSuite) TearDownSuite() {
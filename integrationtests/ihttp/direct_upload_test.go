@@ -0,0 +1,171 @@
+package ihttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"media-nexus/model"
+	"net/http"
+	"os"
+)
+
+func (s *mediaE2ETestSuite) TestDirectUpload() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 1)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	content, err := os.ReadFile("./../assets/test2.png")
+	s.Require().NoError(err)
+
+	checksum := sha256.Sum256(content)
+	name := s.GenerateAlphanumeric(10)
+
+	upload := s.createDirectUpload(&ahmodel.PostMediaUploadRequest{
+		Name:     name,
+		TagIDs:   tagIDs,
+		Size:     int64(len(content)),
+		Checksum: hex.EncodeToString(checksum[:]),
+		MimeType: "image/png",
+	}, http.StatusOK)
+	s.Require().NotEmpty(upload.UploadURL)
+
+	defer func() {
+		s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, []model.MediaID{upload.MediaID}), "delete media metadata")
+	}()
+	defer func() {
+		s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{upload.MediaID, upload.MediaID + "_direct"}), "delete media")
+	}()
+
+	s.completeDirectUpload(upload.MediaID, http.StatusConflict)
+
+	s.putUpload(upload, content, http.StatusOK)
+
+	mediaItem := s.completeDirectUpload(upload.MediaID, http.StatusOK)
+	s.Equal(name, mediaItem.Name)
+	s.True(mediaItem.UploadComplete)
+	s.Equal(int64(len(content)), mediaItem.Size)
+	s.Equal("image/png", mediaItem.MimeType)
+
+	// the upload URL is still valid, but can't replace the verified media
+	otherContent, err := os.ReadFile("./../assets/test.png")
+	s.Require().NoError(err)
+
+	s.putUpload(upload, otherContent, http.StatusConflict)
+	_, served := s.getMediaContent(upload.MediaID, nil, http.StatusOK)
+	s.Equal(content, served)
+
+	// now that it exists, there is nothing to upload
+	upload = s.createDirectUpload(&ahmodel.PostMediaUploadRequest{
+		Name:     name,
+		TagIDs:   tagIDs,
+		Size:     int64(len(content)),
+		Checksum: hex.EncodeToString(checksum[:]),
+		MimeType: "image/png",
+	}, http.StatusOK)
+	s.Equal(mediaItem.ID, upload.MediaID)
+	s.Empty(upload.UploadURL)
+}
+
+func (s *mediaE2ETestSuite) TestDirectUploadWithOtherContent() {
+	ctx := s.Context()
+
+	content, err := os.ReadFile("./../assets/test.png")
+	s.Require().NoError(err)
+
+	otherContent, err := os.ReadFile("./../assets/test2.png")
+	s.Require().NoError(err)
+
+	checksum := sha256.Sum256(content)
+
+	upload := s.createDirectUpload(&ahmodel.PostMediaUploadRequest{
+		Name:     s.GenerateAlphanumeric(10),
+		Size:     int64(len(content)),
+		Checksum: hex.EncodeToString(checksum[:]),
+		MimeType: "image/png",
+	}, http.StatusOK)
+
+	defer func() {
+		s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, []model.MediaID{upload.MediaID}), "delete media metadata")
+	}()
+	defer func() {
+		s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{upload.MediaID, upload.MediaID + "_direct"}), "delete media")
+	}()
+
+	s.putUpload(upload, otherContent, http.StatusOK)
+	s.completeDirectUpload(upload.MediaID, http.StatusBadRequest)
+}
+
+func (s *mediaE2ETestSuite) TestDirectUploadOfUnsupportedType() {
+	checksum := sha256.Sum256([]byte("content"))
+
+	s.createDirectUpload(&ahmodel.PostMediaUploadRequest{
+		Name:     s.GenerateAlphanumeric(10),
+		Size:     int64(len("content")),
+		Checksum: hex.EncodeToString(checksum[:]),
+		MimeType: "text/plain",
+	}, http.StatusUnsupportedMediaType)
+}
+
+func (s *mediaE2ETestSuite) createDirectUpload(
+	request *ahmodel.PostMediaUploadRequest,
+	expectedStatusCode int,
+) *ahmodel.PostMediaUploadResponse {
+	body, err := json.Marshal(request)
+	s.Require().NoError(err)
+
+	response, err := s.Client().Post(s.CreateServerURL("/media/uploads"), "application/json", bytes.NewReader(body))
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	if !s.Equal(expectedStatusCode, response.StatusCode) {
+		message, _ := io.ReadAll(response.Body)
+		s.T().Logf("unexpected response: %s", message)
+	}
+
+	if expectedStatusCode != http.StatusOK {
+		return nil
+	}
+
+	var upload ahmodel.PostMediaUploadResponse
+	s.Require().NoError(json.NewDecoder(response.Body).Decode(&upload))
+
+	return &upload
+}
+
+func (s *mediaE2ETestSuite) putUpload(
+	upload *ahmodel.PostMediaUploadResponse,
+	content []byte,
+	expectedStatusCode int,
+) {
+	req, err := http.NewRequest(http.MethodPut, upload.UploadURL, bytes.NewReader(content))
+	s.Require().NoError(err)
+
+	for name, value := range upload.UploadHeaders {
+		req.Header.Set(name, value)
+	}
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
+}
+
+func (s *mediaE2ETestSuite) completeDirectUpload(mediaID model.MediaID, expectedStatusCode int) *ahmodel.MediaItem {
+	response, err := s.Client().Post(s.CreateServerURL("/media/uploads/%v/complete", mediaID), "", nil)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	if !s.Equal(expectedStatusCode, response.StatusCode) || expectedStatusCode != http.StatusOK {
+		return nil
+	}
+
+	var mediaItem ahmodel.MediaItem
+	s.Require().NoError(json.NewDecoder(response.Body).Decode(&mediaItem))
+
+	return &mediaItem
+}
@@ -0,0 +1,36 @@
+package ihttp
+
+import (
+	"encoding/json"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"net/http"
+	"time"
+)
+
+func (s *mediaE2ETestSuite) TestHealthReady() {
+	response, err := s.Client().Get(s.CreateServerURL("/health/ready"))
+	s.Require().NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(http.StatusOK, response.StatusCode)
+
+	var report ahmodel.HealthReport
+	s.Require().NoError(json.NewDecoder(response.Body).Decode(&report))
+	s.True(report.Healthy)
+	s.NotNil(report.Checks)
+
+	for _, check := range report.Checks {
+		s.True(check.Healthy, "%v failed", check.Name)
+	}
+}
+
+func (s *mediaE2ETestSuite) TestHealthStartup() {
+	s.Eventually(func() bool {
+		response, err := s.Client().Get(s.CreateServerURL("/health/startup"))
+		s.Require().NoError(err)
+		s.NoError(response.Body.Close())
+
+		return response.StatusCode == http.StatusNoContent
+	}, 10*time.Second, 10*time.Millisecond)
+}
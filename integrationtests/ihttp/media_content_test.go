@@ -0,0 +1,111 @@
+package ihttp
+
+import (
+	"fmt"
+	"io"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"media-nexus/model"
+	"net/http"
+	"os"
+	"time"
+)
+
+func (s *mediaE2ETestSuite) TestGetMediaContent() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 1)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test.png")
+
+	defer func() {
+		s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, []model.MediaID{mediaID}), "delete media metadata")
+	}()
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{mediaID}), "delete media") }()
+
+	content, err := os.ReadFile("./../assets/test.png")
+	s.Require().NoError(err)
+
+	response, body := s.getMediaContent(mediaID, nil, http.StatusOK)
+	s.Equal(content, body)
+	s.Equal(fmt.Sprint(len(content)), response.Header.Get("Content-Length"))
+	s.Equal("image/png", response.Header.Get("Content-Type"))
+	s.NotEmpty(response.Header.Get("Cache-Control"))
+
+	etag := response.Header.Get("ETag")
+	s.Equal(fmt.Sprintf("%q", s.getMediaByID(mediaID, http.StatusOK).StoredChecksum), etag)
+
+	response, body = s.getMediaContent(mediaID, map[string]string{"Range": "bytes=10-19"}, http.StatusPartialContent)
+	s.Equal(content[10:20], body)
+	s.Equal(fmt.Sprintf("bytes 10-19/%v", len(content)), response.Header.Get("Content-Range"))
+
+	_, body = s.getMediaContent(mediaID, map[string]string{"Range": "bytes=-5"}, http.StatusPartialContent)
+	s.Equal(content[len(content)-5:], body)
+
+	s.getMediaContent(mediaID, map[string]string{"If-None-Match": etag}, http.StatusNotModified)
+	s.getMediaContent(mediaID, map[string]string{"If-None-Match": `"other"`}, http.StatusOK)
+
+	lastModified := response.Header.Get("Last-Modified")
+	s.NotEmpty(lastModified)
+	s.getMediaContent(mediaID, map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified)
+
+	later := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	s.getMediaContent(mediaID, map[string]string{"If-Modified-Since": later}, http.StatusNotModified)
+
+	// updating the metadata leaves the content and so the validators alone
+	time.Sleep(time.Second)
+	name := s.GenerateAlphanumeric(10)
+	s.patchMedia(mediaID, &ahmodel.PatchMediaRequest{Name: &name}, http.StatusOK)
+
+	response, _ = s.getMediaContent(mediaID, nil, http.StatusOK)
+	s.Equal(lastModified, response.Header.Get("Last-Modified"))
+	s.Equal(etag, response.Header.Get("ETag"))
+	s.getMediaContent(mediaID, map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified)
+
+	_, body = s.getMediaContent(
+		mediaID,
+		map[string]string{"Range": "bytes=10-19", "If-Range": etag},
+		http.StatusPartialContent,
+	)
+	s.Equal(content[10:20], body)
+
+	_, body = s.getMediaContent(mediaID, map[string]string{"Range": "bytes=10-19", "If-Range": `"other"`}, http.StatusOK)
+	s.Equal(content, body)
+
+	longAgo := time.Now().Add(-24 * time.Hour).UTC().Format(http.TimeFormat)
+	s.getMediaContent(mediaID, map[string]string{"If-Modified-Since": longAgo}, http.StatusOK)
+
+	s.getMediaContent(
+		mediaID,
+		map[string]string{"Range": fmt.Sprintf("bytes=%v-", len(content))},
+		http.StatusRequestedRangeNotSatisfiable,
+	)
+}
+
+func (s *mediaE2ETestSuite) TestGetUnknownMediaContent() {
+	s.getMediaContent(s.GenerateAlphanumeric(64), nil, http.StatusNotFound)
+}
+
+func (s *mediaE2ETestSuite) getMediaContent(
+	mediaID model.MediaID,
+	headers map[string]string,
+	expectedStatusCode int,
+) (*http.Response, []byte) {
+	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("/media/%v/content", mediaID), nil)
+	s.Require().NoError(err)
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	s.Require().NoError(err)
+
+	s.Equal(expectedStatusCode, response.StatusCode, "unexpected response: %s", body)
+
+	return response, body
+}
@@ -0,0 +1,53 @@
+package ihttp
+
+import (
+	"encoding/json"
+	"media-nexus/adapters/primary/ahttp/ahmodel"
+	"net/http"
+	"strings"
+)
+
+func (s *mediaE2ETestSuite) TestReconcile() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 1)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test.png")
+	defer s.deleteMedia(mediaID, http.StatusNoContent)
+
+	// media without metadata, that is still within the grace period
+	orphanKey := strings.Repeat("0", 64)
+	s.Require().NoError(s.App().MediaRepo().CreateMedia(ctx, orphanKey, strings.NewReader("orphan"), ""))
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{orphanKey}), "delete orphan") }()
+
+	report := s.reconcile(http.MethodPost, http.StatusOK)
+	s.Positive(report.ScannedMedia)
+	s.Positive(report.ScannedMetadata)
+	s.NotContains(report.DeletedOrphans, orphanKey)
+	s.NotContains(report.MissingMedia, mediaID)
+	s.False(report.FinishedAt.Before(report.StartedAt))
+
+	_, err := s.App().MediaRepo().GetMediaAttributes(ctx, orphanKey)
+	s.NoError(err)
+
+	lastReport := s.reconcile(http.MethodGet, http.StatusOK)
+	s.Equal(report.StartedAt.UnixNano(), lastReport.StartedAt.UnixNano())
+}
+
+func (s *mediaE2ETestSuite) reconcile(method string, expectedStatusCode int) *ahmodel.ReconcileReport {
+	req, err := http.NewRequest(method, s.CreateServerURL("/admin/reconcile"), nil)
+	s.Require().NoError(err)
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
+
+	var report ahmodel.ReconcileReport
+	s.Require().NoError(json.NewDecoder(response.Body).Decode(&report))
+
+	return &report
+}
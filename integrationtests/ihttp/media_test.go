@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"media-nexus/adapters/primary/ahttp/ahmodel"
 	"media-nexus/integrationtests"
 	"media-nexus/model"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"testing"
 
@@ -61,6 +63,50 @@ func (s *mediaE2ETestSuite) TestCreateMediaIdempotency() {
 	}
 }
 
+func (s *mediaE2ETestSuite) TestCreateMediaWithFileBeforeName() {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	fileWriter, err := writer.CreateFormFile("file", "test.txt")
+	s.Require().NoError(err)
+
+	_, err = fileWriter.Write([]byte(s.GenerateAlphanumeric(100)))
+	s.Require().NoError(err)
+
+	s.Require().NoError(writer.WriteField("name", s.GenerateAlphanumeric(10)))
+	s.Require().NoError(writer.Close())
+
+	s.NoError(s.postMedia(&body, writer.FormDataContentType(), http.StatusBadRequest).Body.Close())
+}
+
+func (s *mediaE2ETestSuite) TestCreateMediaWithoutFile() {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	s.Require().NoError(writer.WriteField("name", s.GenerateAlphanumeric(10)))
+	s.Require().NoError(writer.Close())
+
+	s.NoError(s.postMedia(&body, writer.FormDataContentType(), http.StatusBadRequest).Body.Close())
+}
+
+func (s *mediaE2ETestSuite) TestCreateMediaOfUnsupportedType() {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	s.Require().NoError(writer.WriteField("name", s.GenerateAlphanumeric(10)))
+
+	// the content decides, not the file name or the content type of the part
+	fileWriter, err := writer.CreateFormFile("file", "test.png")
+	s.Require().NoError(err)
+
+	_, err = fileWriter.Write([]byte(s.GenerateAlphanumeric(100)))
+	s.Require().NoError(err)
+
+	s.Require().NoError(writer.Close())
+
+	s.NoError(s.postMedia(&body, writer.FormDataContentType(), http.StatusUnsupportedMediaType).Body.Close())
+}
+
 func (s *mediaE2ETestSuite) TestGetMediaByTagIds() {
 	ctx := s.Context()
 
@@ -82,6 +128,158 @@ func (s *mediaE2ETestSuite) TestGetMediaByTagIds() {
 	s.Equal(1, len(mediaItems))
 }
 
+func (s *mediaE2ETestSuite) TestGetMediaPaged() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 2)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test.png")
+	mediaID2 := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test2.png")
+
+	mediaIDs := []model.MediaID{mediaID, mediaID2}
+
+	defer func() { s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, mediaIDs), "delete media metadatas") }()
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, mediaIDs), "delete medias") }()
+
+	page := s.getMediaPage(tagIDs[0], 1, "", http.StatusOK)
+	s.Require().Len(page.Items, 1)
+	s.Require().NotEmpty(page.NextCursor)
+
+	page2 := s.getMediaPage(tagIDs[0], 1, page.NextCursor, http.StatusOK)
+	s.Require().Len(page2.Items, 1)
+	s.Empty(page2.NextCursor)
+
+	s.ElementsMatch(mediaIDs, []model.MediaID{page.Items[0].ID, page2.Items[0].ID})
+
+	// cursors only work for the search they were returned for
+	s.getMediaPage(tagIDs[1], 1, page.NextCursor, http.StatusBadRequest)
+	s.getMediaPage(tagIDs[0], 1, "forged", http.StatusBadRequest)
+	s.getMediaPage(tagIDs[0], 0, "", http.StatusBadRequest)
+}
+
+func (s *mediaE2ETestSuite) TestGetMediaByID() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 2)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	name := s.GenerateAlphanumeric(10)
+	mediaID := s.createMedia(name, tagIDs, "./../assets/test.png")
+
+	defer func() {
+		s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, []model.MediaID{mediaID}), "delete media metadata")
+	}()
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{mediaID}), "delete media") }()
+
+	mediaItem := s.getMediaByID(mediaID, http.StatusOK)
+	s.Equal(mediaID, mediaItem.ID)
+	s.Equal(name, mediaItem.Name)
+	s.ElementsMatch(tagIDs, mediaItem.TagIds)
+	s.NotEmpty(mediaItem.Checksum)
+	s.NotZero(mediaItem.Size)
+	s.Equal("image/png", mediaItem.MimeType)
+	s.Require().NotNil(mediaItem.ImageInfo)
+	s.Equal(10, mediaItem.ImageInfo.Width)
+	s.Equal(10, mediaItem.ImageInfo.Height)
+	s.True(mediaItem.UploadComplete)
+	s.NotEmpty(mediaItem.FileURL)
+}
+
+func (s *mediaE2ETestSuite) TestGetMediaWithImageFilter() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 1)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test.png")
+
+	defer func() {
+		s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, []model.MediaID{mediaID}), "delete media metadata")
+	}()
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{mediaID}), "delete media") }()
+
+	found := s.filterMedia(tagIDs[0], url.Values{"min_width": {"10"}, "max_height": {"10"}}, http.StatusOK)
+	s.Require().Len(found, 1)
+	s.Equal(mediaID, found[0].ID)
+
+	s.Empty(s.filterMedia(tagIDs[0], url.Values{"min_width": {"11"}}, http.StatusOK))
+	s.Empty(s.filterMedia(tagIDs[0], url.Values{"has_gps": {"true"}}, http.StatusOK))
+
+	s.filterMedia(tagIDs[0], url.Values{"min_width": {"0"}}, http.StatusBadRequest)
+	s.filterMedia(tagIDs[0], url.Values{"has_gps": {"maybe"}}, http.StatusBadRequest)
+	s.filterMedia(tagIDs[0], url.Values{"captured_after": {"yesterday"}}, http.StatusBadRequest)
+}
+
+func (s *mediaE2ETestSuite) TestGetMediaByUnknownID() {
+	s.getMediaByID(s.GenerateAlphanumeric(64), http.StatusNotFound)
+}
+
+func (s *mediaE2ETestSuite) TestDeleteMedia() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 1)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test.png")
+
+	s.deleteMedia(mediaID, http.StatusNoContent)
+	s.getMediaByID(mediaID, http.StatusNotFound)
+	s.deleteMedia(mediaID, http.StatusNotFound)
+}
+
+func (s *mediaE2ETestSuite) TestPatchMedia() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 2)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), []model.TagID{tagIDs[0]}, "./../assets/test.png")
+
+	defer func() {
+		s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, []model.MediaID{mediaID}), "delete media metadata")
+	}()
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{mediaID}), "delete media") }()
+
+	name := s.GenerateAlphanumeric(10)
+	mediaItem := s.patchMedia(mediaID, &ahmodel.PatchMediaRequest{
+		Name:         &name,
+		AddTagIDs:    []string{tagIDs[1]},
+		RemoveTagIDs: []string{tagIDs[0]},
+	}, http.StatusOK)
+
+	s.Equal(mediaID, mediaItem.ID)
+	s.Equal(name, mediaItem.Name)
+	s.Equal([]string{tagIDs[1]}, mediaItem.TagIds)
+
+	s.Empty(s.getMedia(tagIDs[0]))
+	s.Len(s.getMedia(tagIDs[1]), 1)
+}
+
+func (s *mediaE2ETestSuite) TestGetMediaByQuery() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 2)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test.png")
+	mediaID2 := s.createMedia(s.GenerateAlphanumeric(10), []model.TagID{tagIDs[0]}, "./../assets/test2.png")
+
+	mediaIDs := []model.MediaID{mediaID, mediaID2}
+
+	defer func() { s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, mediaIDs), "delete media metadatas") }()
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, mediaIDs), "delete medias") }()
+
+	mediaItems := s.searchMedia(fmt.Sprintf("%v AND NOT %v", tagIDs[0], tagIDs[1]), http.StatusOK)
+	s.Require().Equal(1, len(mediaItems))
+	s.Equal(mediaID2, mediaItems[0].ID)
+
+	mediaItems = s.searchMedia(fmt.Sprintf("%v OR %v", tagIDs[0], tagIDs[1]), http.StatusOK)
+	s.Equal(2, len(mediaItems))
+
+	s.searchMedia(fmt.Sprintf("(%v OR", tagIDs[0]), http.StatusBadRequest)
+}
+
 func (s *mediaE2ETestSuite) createTags(ctx context.Context, count int) []model.TagID {
 	var tagIDs []model.TagID
 
@@ -119,17 +317,9 @@ func (s *mediaE2ETestSuite) createMedia(name string, tagIds []model.TagID, fileP
 	err = writer.Close()
 	s.Require().NoError(err)
 
-	req, err := http.NewRequest("POST", s.CreateServerURL("/media"), &body)
-	s.Require().NoError(err)
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	resp, err := s.Client().Do(req)
-	s.Require().NoError(err)
+	resp := s.postMedia(&body, writer.FormDataContentType(), http.StatusOK)
 	defer resp.Body.Close()
 
-	s.Equal(http.StatusOK, resp.StatusCode)
-
 	var postMediaResponse ahmodel.PostMediaResponse
 	decoder := json.NewDecoder(resp.Body)
 	s.NoError(decoder.Decode(&postMediaResponse))
@@ -139,6 +329,23 @@ func (s *mediaE2ETestSuite) createMedia(name string, tagIds []model.TagID, fileP
 	return postMediaResponse.MediaID
 }
 
+func (s *mediaE2ETestSuite) postMedia(body io.Reader, contentType string, expectedStatusCode int) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, s.CreateServerURL("/media"), body)
+	s.Require().NoError(err)
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.Client().Do(req)
+	s.Require().NoError(err)
+
+	if !s.Equal(expectedStatusCode, resp.StatusCode) {
+		message, _ := io.ReadAll(resp.Body)
+		s.T().Logf("unexpected response: %s", message)
+	}
+
+	return resp
+}
+
 func (s *mediaE2ETestSuite) getMedia(tagID model.TagID) []*ahmodel.MediaItem {
 	url := s.CreateServerURL("/media?tag_id=%v", tagID)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -159,3 +366,143 @@ func (s *mediaE2ETestSuite) getMedia(tagID model.TagID) []*ahmodel.MediaItem {
 
 	return getMediaResponse.Items
 }
+
+func (s *mediaE2ETestSuite) getMediaPage(
+	tagID model.TagID,
+	limit int,
+	cursor string,
+	expectedStatusCode int,
+) *ahmodel.GetMediaResponse {
+	query := url.Values{}
+	query.Set("tag_id", tagID)
+	query.Set("limit", fmt.Sprintf("%v", limit))
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	return s.getMediaPageWithQuery(query, expectedStatusCode)
+}
+
+func (s *mediaE2ETestSuite) getMediaPageWithQuery(query url.Values, expectedStatusCode int) *ahmodel.GetMediaResponse {
+	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("/media?%v", query.Encode()), nil)
+	s.NoError(err)
+
+	response, err := s.Client().Do(req)
+	s.NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
+
+	if expectedStatusCode != http.StatusOK {
+		return nil
+	}
+
+	var getMediaResponse ahmodel.GetMediaResponse
+	decoder := json.NewDecoder(response.Body)
+	s.NoError(decoder.Decode(&getMediaResponse))
+
+	return &getMediaResponse
+}
+
+func (s *mediaE2ETestSuite) filterMedia(
+	tagID model.TagID,
+	filter url.Values,
+	expectedStatusCode int,
+) []*ahmodel.MediaItem {
+	filter.Set("tag_id", tagID)
+
+	page := s.getMediaPageWithQuery(filter, expectedStatusCode)
+	if page == nil {
+		return nil
+	}
+
+	return page.Items
+}
+
+func (s *mediaE2ETestSuite) getMediaByID(mediaID model.MediaID, expectedStatusCode int) *ahmodel.MediaItem {
+	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("/media/%v", mediaID), nil)
+	s.NoError(err)
+
+	response, err := s.Client().Do(req)
+	s.NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
+
+	if expectedStatusCode != http.StatusOK {
+		return nil
+	}
+
+	var mediaItem ahmodel.MediaItem
+	decoder := json.NewDecoder(response.Body)
+	s.NoError(decoder.Decode(&mediaItem))
+
+	return &mediaItem
+}
+
+func (s *mediaE2ETestSuite) deleteMedia(mediaID model.MediaID, expectedStatusCode int) {
+	req, err := http.NewRequest(http.MethodDelete, s.CreateServerURL("/media/%v", mediaID), nil)
+	s.NoError(err)
+
+	response, err := s.Client().Do(req)
+	s.NoError(err)
+
+	defer response.Body.Close()
+
+	s.Equal(expectedStatusCode, response.StatusCode)
+}
+
+func (s *mediaE2ETestSuite) patchMedia(
+	mediaID model.MediaID,
+	request *ahmodel.PatchMediaRequest,
+	expectedStatusCode int,
+) *ahmodel.MediaItem {
+	body, err := json.Marshal(request)
+	s.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodPatch, s.CreateServerURL("/media/%v", mediaID), bytes.NewReader(body))
+	s.NoError(err)
+
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := s.Client().Do(req)
+	s.NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
+
+	if expectedStatusCode != http.StatusOK {
+		return nil
+	}
+
+	var mediaItem ahmodel.MediaItem
+	decoder := json.NewDecoder(response.Body)
+	s.NoError(decoder.Decode(&mediaItem))
+
+	return &mediaItem
+}
+
+func (s *mediaE2ETestSuite) searchMedia(q string, expectedStatusCode int) []*ahmodel.MediaItem {
+	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("/media?q=%v", url.QueryEscape(q)), nil)
+	s.NoError(err)
+
+	response, err := s.Client().Do(req)
+	s.NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
+
+	if expectedStatusCode != http.StatusOK {
+		return nil
+	}
+
+	var getMediaResponse ahmodel.GetMediaResponse
+	decoder := json.NewDecoder(response.Body)
+	s.NoError(decoder.Decode(&getMediaResponse))
+
+	return getMediaResponse.Items
+}
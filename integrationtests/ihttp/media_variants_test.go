@@ -0,0 +1,78 @@
+package ihttp
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"media-nexus/model"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+func (s *mediaE2ETestSuite) TestGetMediaVariants() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 1)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	filePath := filepath.Join(s.T().TempDir(), "large.png")
+	var buf bytes.Buffer
+	s.Require().NoError(png.Encode(&buf, image.NewGray(image.Rect(0, 0, 300, 200))))
+	s.Require().NoError(os.WriteFile(filePath, buf.Bytes(), 0o600))
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, filePath)
+	defer s.deleteMedia(mediaID, http.StatusNoContent)
+
+	mediaItem := s.getMediaByID(mediaID, http.StatusOK)
+	s.Len(mediaItem.Variants, 3)
+	s.Equal(mediaItem.FileURL, mediaItem.Variants["512"])
+	s.NotEqual(mediaItem.FileURL, mediaItem.Variants["128"])
+
+	variant := s.getMediaVariant(mediaID, "128", http.StatusOK)
+	config, err := png.DecodeConfig(bytes.NewReader(variant))
+	s.Require().NoError(err)
+	s.Equal(128, config.Width)
+	s.Equal(85, config.Height)
+
+	s.Equal(buf.Bytes(), s.getMediaVariant(mediaID, "1024", http.StatusOK))
+
+	s.getMediaVariant(mediaID, "100", http.StatusBadRequest)
+	s.getMediaVariant(mediaID, "large", http.StatusBadRequest)
+}
+
+func (s *mediaE2ETestSuite) TestGetMediaVariantOfSmallImage() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 1)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	mediaID := s.createMedia(s.GenerateAlphanumeric(10), tagIDs, "./../assets/test.png")
+	defer s.deleteMedia(mediaID, http.StatusNoContent)
+
+	content, err := os.ReadFile("./../assets/test.png")
+	s.Require().NoError(err)
+
+	s.Equal(content, s.getMediaVariant(mediaID, "128", http.StatusOK))
+}
+
+func (s *mediaE2ETestSuite) TestGetVariantOfUnknownMedia() {
+	s.getMediaVariant(s.GenerateAlphanumeric(64), "128", http.StatusNotFound)
+}
+
+// getMediaVariant follows the redirect and returns the variant
+func (s *mediaE2ETestSuite) getMediaVariant(mediaID model.MediaID, size string, expectedStatusCode int) []byte {
+	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("/media/%v/variants/%v", mediaID, size), nil)
+	s.Require().NoError(err)
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	s.Require().NoError(err)
+	s.Require().Equal(expectedStatusCode, response.StatusCode, string(body))
+
+	return body
+}
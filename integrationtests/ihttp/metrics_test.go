@@ -0,0 +1,47 @@
+package ihttp
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+func (s *mediaE2ETestSuite) TestMetrics() {
+	mediaResponse, err := s.Client().Get(s.CreateServerURL("/media/%v", s.GenerateAlphanumeric(64)))
+	s.Require().NoError(err)
+	s.NoError(mediaResponse.Body.Close())
+
+	body := s.getMetrics()
+
+	// requests are recorded by route, not by path
+	s.Contains(body, `medianexus_http_requests_total{method="GET",route="/api/v1/media/{id}",status="404"}`)
+	s.Contains(body, `medianexus_storage_call_duration_seconds_count{backend="memory",operation="Get",outcome="not_found"`)
+	s.Contains(body, "go_goroutines")
+}
+
+func (s *mediaE2ETestSuite) TestMetricsOfUnmatchedRoute() {
+	response, err := s.Client().Get(s.CreateServerURL("/unknown/%v", s.GenerateAlphanumeric(16)))
+	s.Require().NoError(err)
+	s.NoError(response.Body.Close())
+
+	s.Contains(s.getMetrics(), `medianexus_http_requests_total{method="GET",route="unmatched",status="404"}`)
+}
+
+func (s *mediaE2ETestSuite) getMetrics() string {
+	response, err := s.Client().Get(s.metricsURL())
+	s.Require().NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(http.StatusOK, response.StatusCode)
+
+	body, err := io.ReadAll(response.Body)
+	s.Require().NoError(err)
+
+	return string(body)
+}
+
+// metricsURL is at the root of the server, not below /api/v1
+func (s *mediaE2ETestSuite) metricsURL() string {
+	return strings.TrimSuffix(s.CreateServerURL(""), "/api/v1") + "/metrics"
+}
@@ -0,0 +1,71 @@
+package ihttp
+
+import (
+	"encoding/json"
+	"media-nexus/httputils"
+	"net/http"
+)
+
+func (s *mediaE2ETestSuite) TestProblemOfUnknownMedia() {
+	problem, response := s.getProblem("/media/"+s.GenerateAlphanumeric(64), "", http.StatusNotFound)
+	s.Equal("urn:media-nexus:problem:not-found", problem.Type)
+	s.Equal("Not Found", problem.Title)
+	s.Equal(http.StatusNotFound, problem.Status)
+	s.NotEmpty(problem.Detail)
+	s.NotEmpty(problem.RequestID)
+	s.Equal(response.Header.Get(httputils.HeaderRequestID), problem.RequestID)
+	s.Empty(problem.Errors)
+}
+
+func (s *mediaE2ETestSuite) TestProblemOfInvalidParameter() {
+	problem, _ := s.getProblem("/media?tag_id=a&min_width=0", "", http.StatusBadRequest)
+	s.Equal("urn:media-nexus:problem:bad-request", problem.Type)
+	s.Equal([]httputils.ProblemFieldError{{Field: "min_width", Reason: "min_width has to be positive"}}, problem.Errors)
+
+	problem, _ = s.getProblem("/media?tag_id=a&limit=many", "", http.StatusBadRequest)
+	s.Require().Len(problem.Errors, 1)
+	s.Equal("limit", problem.Errors[0].Field)
+}
+
+func (s *mediaE2ETestSuite) TestProblemKeepsRequestID() {
+	problem, response := s.getProblem("/media/"+s.GenerateAlphanumeric(64), "request-1", http.StatusNotFound)
+	s.Equal("request-1", problem.RequestID)
+	s.Equal("request-1", response.Header.Get(httputils.HeaderRequestID))
+
+	// IDs, that don't look like one, are replaced
+	problem, _ = s.getProblem("/media/"+s.GenerateAlphanumeric(64), "request #1", http.StatusNotFound)
+	s.NotEmpty(problem.RequestID)
+	s.NotEqual("request #1", problem.RequestID)
+}
+
+func (s *mediaE2ETestSuite) TestProblemOfUnknownRoute() {
+	problem, _ := s.getProblem("/unknown", "", http.StatusNotFound)
+	s.Equal("urn:media-nexus:problem:not-found", problem.Type)
+	s.NotEmpty(problem.RequestID)
+}
+
+func (s *mediaE2ETestSuite) getProblem(
+	path string,
+	requestID string,
+	expectedStatusCode int,
+) (*httputils.Problem, *http.Response) {
+	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("%v", path), nil)
+	s.Require().NoError(err)
+
+	if requestID != "" {
+		req.Header.Set(httputils.HeaderRequestID, requestID)
+	}
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+
+	defer response.Body.Close()
+
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
+	s.Equal(httputils.ContentTypeProblemJSON, response.Header.Get(httputils.HeaderContentType))
+
+	var problem httputils.Problem
+	s.Require().NoError(json.NewDecoder(response.Body).Decode(&problem))
+
+	return &problem, response
+}
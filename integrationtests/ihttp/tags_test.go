@@ -7,6 +7,7 @@ import (
 	"media-nexus/integrationtests"
 	"media-nexus/model"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -60,6 +61,27 @@ func (s *tagsE2ETestSuite) TestListTags() {
 	}
 }
 
+func (s *tagsE2ETestSuite) TestListTagsPaged() {
+	ctx := s.Context()
+
+	var tagIds []model.TagID
+	tagIds = append(tagIds, s.createTag(s.GenerateAlphanumeric(10)))
+	tagIds = append(tagIds, s.createTag(s.GenerateAlphanumeric(10)))
+
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIds), "delete tags") }()
+
+	page := s.listTagsPage(1, "", http.StatusOK)
+	s.Require().Len(page.Items, 1)
+	s.Require().NotEmpty(page.NextCursor)
+
+	page2 := s.listTagsPage(1, page.NextCursor, http.StatusOK)
+	s.Require().Len(page2.Items, 1)
+	s.Less(page.Items[0].ID, page2.Items[0].ID)
+
+	s.listTagsPage(1, "forged", http.StatusBadRequest)
+	s.listTagsPage(1001, "", http.StatusBadRequest)
+}
+
 func (s *tagsE2ETestSuite) createTag(tagName string) model.TagID {
 	reqStr := fmt.Sprintf(`{"name":"%v"}`, tagName)
 
@@ -84,27 +106,49 @@ func (s *tagsE2ETestSuite) createTag(tagName string) model.TagID {
 	return model.TagID(postTagsResponse.TagID)
 }
 
+// listTags pages through all tags
 func (s *tagsE2ETestSuite) listTags() []model.TagID {
-	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("/tags"), nil)
-	s.NoError(err)
+	var result []model.TagID
 
-	req.Header.Set("Content-Type", "application/json")
+	cursor := ""
+	for {
+		page := s.listTagsPage(1000, cursor, http.StatusOK)
+		for _, t := range page.Items {
+			result = append(result, model.TagID(t.ID))
+		}
+
+		if page.NextCursor == "" {
+			return result
+		}
+
+		cursor = page.NextCursor
+	}
+}
+
+func (s *tagsE2ETestSuite) listTagsPage(limit int, cursor string, expectedStatusCode int) *ahmodel.GetTagsResponse {
+	query := url.Values{}
+	query.Set("limit", fmt.Sprintf("%v", limit))
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.CreateServerURL("/tags?%v", query.Encode()), nil)
+	s.NoError(err)
 
 	response, err := s.Client().Do(req)
 	s.NoError(err)
 
 	defer response.Body.Close()
 
-	s.Equal(http.StatusOK, response.StatusCode)
+	s.Require().Equal(expectedStatusCode, response.StatusCode)
 
-	var postTagsResponse []*ahmodel.Tag
-	decoder := json.NewDecoder(response.Body)
-	s.NoError(decoder.Decode(&postTagsResponse))
-
-	result := make([]model.TagID, 0, len(postTagsResponse))
-	for _, t := range postTagsResponse {
-		result = append(result, model.TagID(t.ID))
+	if expectedStatusCode != http.StatusOK {
+		return nil
 	}
 
-	return result
+	var getTagsResponse ahmodel.GetTagsResponse
+	decoder := json.NewDecoder(response.Body)
+	s.NoError(decoder.Decode(&getTagsResponse))
+
+	return &getTagsResponse
 }
@@ -0,0 +1,163 @@
+package ihttp
+
+import (
+	"encoding/base64"
+	"io"
+	"media-nexus/model"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func (s *mediaE2ETestSuite) TestTusUpload() {
+	ctx := s.Context()
+
+	tagIDs := s.createTags(ctx, 2)
+	defer func() { s.LogIfError(s.App().TagRepo().DeleteTags(ctx, tagIDs), "delete tags") }()
+
+	content, err := os.ReadFile("./../assets/test.png")
+	s.Require().NoError(err)
+
+	name := s.GenerateAlphanumeric(10)
+	location := s.createTusUpload(name, tagIDs, len(content), http.StatusCreated)
+	s.Require().NotEmpty(location)
+
+	half := len(content) / 2
+
+	response := s.tusRequest(http.MethodPatch, location, 0, content[:half])
+	s.Require().Equal(http.StatusNoContent, response.StatusCode)
+	s.Equal(strconv.Itoa(half), response.Header.Get("Upload-Offset"))
+	s.Empty(response.Header.Get("Media-Id"))
+
+	response = s.tusRequest(http.MethodHead, location, -1, nil)
+	s.Require().Equal(http.StatusOK, response.StatusCode)
+	s.Equal(strconv.Itoa(half), response.Header.Get("Upload-Offset"))
+	s.Equal(strconv.Itoa(len(content)), response.Header.Get("Upload-Length"))
+	s.Equal("no-store", response.Header.Get("Cache-Control"))
+
+	response = s.tusRequest(http.MethodPatch, location, half, content[half:])
+	s.Require().Equal(http.StatusNoContent, response.StatusCode)
+	s.Equal(strconv.Itoa(len(content)), response.Header.Get("Upload-Offset"))
+
+	mediaID := response.Header.Get("Media-Id")
+	s.Require().NotEmpty(mediaID)
+
+	defer func() {
+		s.LogIfError(s.App().MediaMetadataRepo().DeleteAll(ctx, []model.MediaID{mediaID}), "delete media metadata")
+	}()
+	defer func() { s.LogIfError(s.App().MediaRepo().DeleteAll(ctx, []string{mediaID}), "delete media") }()
+
+	mediaItem := s.getMediaByID(mediaID, http.StatusOK)
+	s.Equal(name, mediaItem.Name)
+	s.ElementsMatch(tagIDs, mediaItem.TagIds)
+	s.True(mediaItem.UploadComplete)
+
+	// the same media uploaded in one go gets the same ID
+	s.Equal(mediaID, s.createMedia(name, tagIDs, "./../assets/test.png"))
+}
+
+func (s *mediaE2ETestSuite) TestTusUploadAtWrongOffset() {
+	location := s.createTusUpload(s.GenerateAlphanumeric(10), nil, 10, http.StatusCreated)
+	defer func() { s.tusRequest(http.MethodDelete, location, -1, nil) }()
+
+	response := s.tusRequest(http.MethodPatch, location, 5, []byte("12345"))
+	s.Equal(http.StatusConflict, response.StatusCode)
+}
+
+func (s *mediaE2ETestSuite) TestTusTermination() {
+	location := s.createTusUpload(s.GenerateAlphanumeric(10), nil, 10, http.StatusCreated)
+
+	response := s.tusRequest(http.MethodPatch, location, 0, []byte("12345"))
+	s.Require().Equal(http.StatusNoContent, response.StatusCode)
+
+	response = s.tusRequest(http.MethodDelete, location, -1, nil)
+	s.Equal(http.StatusNoContent, response.StatusCode)
+
+	response = s.tusRequest(http.MethodHead, location, -1, nil)
+	s.Equal(http.StatusNotFound, response.StatusCode)
+}
+
+func (s *mediaE2ETestSuite) TestTusUnsupportedVersion() {
+	req, err := http.NewRequest(http.MethodPost, s.CreateServerURL("/media/tus"), nil)
+	s.Require().NoError(err)
+
+	req.Header.Set("Tus-Resumable", "0.2.2")
+	req.Header.Set("Upload-Length", "10")
+	req.Header.Set("Upload-Metadata", "name "+base64.StdEncoding.EncodeToString([]byte("name")))
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	s.Equal(http.StatusPreconditionFailed, response.StatusCode)
+	s.Equal("1.0.0", response.Header.Get("Tus-Version"))
+}
+
+func (s *mediaE2ETestSuite) TestTusOptions() {
+	req, err := http.NewRequest(http.MethodOptions, s.CreateServerURL("/media/tus"), nil)
+	s.Require().NoError(err)
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	s.Equal(http.StatusNoContent, response.StatusCode)
+	s.Equal("1.0.0", response.Header.Get("Tus-Version"))
+	s.Contains(response.Header.Get("Tus-Extension"), "creation")
+	s.NotEmpty(response.Header.Get("Tus-Max-Size"))
+}
+
+// createTusUpload returns the location of the created upload.
+func (s *mediaE2ETestSuite) createTusUpload(
+	name string,
+	tagIDs []model.TagID,
+	length int,
+	expectedStatusCode int,
+) string {
+	metadata := "name " + base64.StdEncoding.EncodeToString([]byte(name))
+	if len(tagIDs) > 0 {
+		metadata += ",tag_ids " + base64.StdEncoding.EncodeToString([]byte(strings.Join(tagIDs, ",")))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.CreateServerURL("/media/tus"), nil)
+	s.Require().NoError(err)
+
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", strconv.Itoa(length))
+	req.Header.Set("Upload-Metadata", metadata)
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+	defer response.Body.Close()
+
+	if !s.Equal(expectedStatusCode, response.StatusCode) {
+		message, _ := io.ReadAll(response.Body)
+		s.T().Logf("unexpected response: %s", message)
+	}
+
+	s.Equal("1.0.0", response.Header.Get("Tus-Resumable"))
+	s.NotEmpty(response.Header.Get("Upload-Expires"))
+
+	return response.Header.Get("Location")
+}
+
+// tusRequest sends a tus request to the upload at location. Upload-Offset is only set if offset isn't negative.
+func (s *mediaE2ETestSuite) tusRequest(method string, location string, offset int, body []byte) *http.Response {
+	url := strings.TrimSuffix(s.CreateServerURL(""), "/api/v1") + location
+
+	req, err := http.NewRequest(method, url, strings.NewReader(string(body)))
+	s.Require().NoError(err)
+
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	if offset >= 0 {
+		req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+	}
+
+	response, err := s.Client().Do(req)
+	s.Require().NoError(err)
+	s.Require().NoError(response.Body.Close())
+
+	return response
+}
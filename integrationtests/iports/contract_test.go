@@ -0,0 +1,64 @@
+package iports
+
+import (
+	"media-nexus/app"
+	"media-nexus/config"
+	"media-nexus/logger"
+	"media-nexus/ports"
+	"media-nexus/ports/portstest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// setupApp creates the repositories as configured for the integration tests, without starting the API.
+func setupApp(t *testing.T) app.App {
+	configuration, err := config.LoadConfiguration()
+	require.NoError(t, err)
+
+	appl := app.NewApp(logger.NewLogger("test"), configuration)
+	require.NoError(t, appl.Setup())
+
+	return appl
+}
+
+func TestTagRepositoryContract(t *testing.T) {
+	appl := setupApp(t)
+
+	suite.Run(t, &portstest.TagRepositoryContract{
+		Repository: func() ports.TagRepository { return appl.TagRepo() },
+	})
+}
+
+func TestMediaMetadataRepositoryContract(t *testing.T) {
+	appl := setupApp(t)
+
+	suite.Run(t, &portstest.MediaMetadataRepositoryContract{
+		Repository: func() ports.MediaMetadataRepository { return appl.MediaMetadataRepo() },
+	})
+}
+
+func TestMediaRepositoryContract(t *testing.T) {
+	appl := setupApp(t)
+
+	suite.Run(t, &portstest.MediaRepositoryContract{
+		Repository: func() ports.MediaRepository { return appl.MediaRepo() },
+	})
+}
+
+func TestUploadRepositoryContract(t *testing.T) {
+	appl := setupApp(t)
+
+	suite.Run(t, &portstest.UploadRepositoryContract{
+		Repository: func() ports.UploadRepository { return appl.UploadRepo() },
+	})
+}
+
+func TestJobQueueContract(t *testing.T) {
+	appl := setupApp(t)
+
+	suite.Run(t, &portstest.JobQueueContract{
+		Queue: func() ports.JobQueue { return appl.JobQueue() },
+	})
+}
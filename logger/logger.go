@@ -16,4 +16,7 @@ type Logger interface {
 	Error(arg interface{})
 	Fatal(arg interface{})
 	Panic(arg interface{})
+
+	// WithFields returns a logger adding the fields to every entry, so they can be searched for
+	WithFields(fields map[string]interface{}) Logger
 }
@@ -90,3 +90,7 @@ func (l *logger) Fatal(arg interface{}) {
 func (l *logger) Panic(arg interface{}) {
 	l.log(logrus.PanicLevel, arg)
 }
+
+func (l *logger) WithFields(fields map[string]interface{}) Logger {
+	return &logger{l.Entry.WithFields(fields)}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"os"
 
+	"media-nexus/adapters/primary/acli"
 	"media-nexus/app"
 	"media-nexus/config"
 	"media-nexus/logger"
@@ -18,18 +20,23 @@ func main() {
 }
 
 func run(log logger.Logger) error {
+	configFile := flag.String("config", "", "config file. MEDIANEXUS_CONFIG is read, if not given.")
+	output := flag.String("output", acli.OutputTable, "output of the commands, table or json")
+	flag.Parse()
+
+	file, args := acli.ConfigFile(*configFile, flag.Args())
+
 	log.Infof("Reading config ...")
 
-	config, err := config.LoadConfiguration()
+	config, err := config.LoadConfigurationFile(file)
 	if err != nil {
 		return err
 	}
 
-	application := app.NewApp(log, config)
-
-	if err := application.Setup(); err != nil {
+	cli, err := acli.NewCLI(log, app.NewApp(log, config), config, *output, os.Stdout, os.Stderr)
+	if err != nil {
 		return err
 	}
 
-	return application.Run()
+	return cli.Run(args)
 }
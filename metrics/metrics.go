@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// upload kinds, by how the media reaches the service
+const (
+	UploadForm      = "form"
+	UploadResumable = "resumable"
+	UploadDirect    = "direct"
+)
+
+// Metrics records what the service does, so it can be scraped at /metrics. The names and labels are listed in the
+// README.
+type Metrics interface {
+	// ObserveHTTPRequest records a request by the template of the route it matched, e.g. /api/v1/media/{id}, so IDs
+	// don't make up new series
+	ObserveHTTPRequest(route string, method string, status int, duration time.Duration)
+	// ObserveUpload records the bytes received by an upload and how long receiving them took
+	ObserveUpload(kind string, size int64, duration time.Duration)
+	// CountDedupHit counts uploads of media that exists already
+	CountDedupHit()
+	// ObserveStorageCall records a call of a repository method, e.g. repository media_metadata and operation Get
+	ObserveStorageCall(backend string, repository string, operation string, duration time.Duration, err error)
+	// CountRun counts a run of a background task, like one pass of a periodic cleanup or one job
+	CountRun(runner string, err error)
+	// Handler serves the metrics in the Prometheus exposition format
+	Handler() http.Handler
+}
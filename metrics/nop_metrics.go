@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+type nopMetrics struct{}
+
+// NewNopMetrics records nothing, e.g. for tests and the CLI
+func NewNopMetrics() Metrics {
+	return nopMetrics{}
+}
+
+func (nopMetrics) ObserveHTTPRequest(string, string, int, time.Duration) {}
+
+func (nopMetrics) ObserveUpload(string, int64, time.Duration) {}
+
+func (nopMetrics) CountDedupHit() {}
+
+func (nopMetrics) ObserveStorageCall(string, string, string, time.Duration, error) {}
+
+func (nopMetrics) CountRun(string, error) {}
+
+func (nopMetrics) Handler() http.Handler {
+	return http.NotFoundHandler()
+}
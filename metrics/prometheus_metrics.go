@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"media-nexus/errortypes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "medianexus"
+
+// outcomes of storage calls and runs. Missing resources are told apart from errors, since looking up what might not
+// exist is common, e.g. leasing jobs from an empty queue.
+const (
+	outcomeSuccess  = "success"
+	outcomeNotFound = "not_found"
+	outcomeFailure  = "failure"
+)
+
+type prometheusMetrics struct {
+	registry            *prometheus.Registry
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	uploadBytes         *prometheus.CounterVec
+	uploadDuration      *prometheus.HistogramVec
+	dedupHits           prometheus.Counter
+	storageCallDuration *prometheus.HistogramVec
+	runs                *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics registers the metrics in a registry of their own, together with the metrics of the Go runtime
+// and the process.
+func NewPrometheusMetrics() Metrics {
+	m := &prometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		uploadBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upload_bytes_total",
+			Help:      "Bytes received by uploads by kind of upload.",
+		}, []string{"kind"}),
+		uploadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upload_duration_seconds",
+			Help:      "Time taken to receive and store uploads by kind of upload.",
+			// uploads take way longer than other requests
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+		}, []string{"kind"}),
+		dedupHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dedup_hits_total",
+			Help:      "Uploads of media, that existed already.",
+		}),
+		storageCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_call_duration_seconds",
+			Help:      "Latency of calls to the storage backends by backend, repository, operation and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"backend", "repository", "operation", "outcome"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "background_runs_total",
+			Help:      "Runs of background tasks and jobs by runner and outcome.",
+		}, []string{"runner", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.uploadBytes,
+		m.uploadDuration,
+		m.dedupHits,
+		m.storageCallDuration,
+		m.runs,
+	)
+
+	return m
+}
+
+func (m *prometheusMetrics) ObserveHTTPRequest(route string, method string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, statusLabel).Inc()
+	m.httpRequestDuration.WithLabelValues(route, method, statusLabel).Observe(duration.Seconds())
+}
+
+func (m *prometheusMetrics) ObserveUpload(kind string, size int64, duration time.Duration) {
+	m.uploadBytes.WithLabelValues(kind).Add(float64(size))
+	m.uploadDuration.WithLabelValues(kind).Observe(duration.Seconds())
+}
+
+func (m *prometheusMetrics) CountDedupHit() {
+	m.dedupHits.Inc()
+}
+
+func (m *prometheusMetrics) ObserveStorageCall(
+	backend string,
+	repository string,
+	operation string,
+	duration time.Duration,
+	err error,
+) {
+	m.storageCallDuration.WithLabelValues(backend, repository, operation, outcome(err)).Observe(duration.Seconds())
+}
+
+func (m *prometheusMetrics) CountRun(runner string, err error) {
+	if err != nil {
+		m.runs.WithLabelValues(runner, outcomeFailure).Inc()
+		return
+	}
+
+	m.runs.WithLabelValues(runner, outcomeSuccess).Inc()
+}
+
+func (m *prometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errortypes.IsResourceNotFound(err):
+		return outcomeNotFound
+	default:
+		return outcomeFailure
+	}
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"media-nexus/errortypes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type prometheusMetricsTestSuite struct {
+	suite.Suite
+	metrics Metrics
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	suite.Run(t, &prometheusMetricsTestSuite{})
+}
+
+func (s *prometheusMetricsTestSuite) SetupTest() {
+	s.metrics = NewPrometheusMetrics()
+}
+
+func (s *prometheusMetricsTestSuite) scrape() string {
+	response := httptest.NewRecorder()
+	s.metrics.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	s.Require().Equal(http.StatusOK, response.Code)
+
+	body, err := io.ReadAll(response.Body)
+	s.Require().NoError(err)
+
+	return string(body)
+}
+
+func (s *prometheusMetricsTestSuite) TestHTTPRequests() {
+	s.metrics.ObserveHTTPRequest("/api/v1/media/{id}", http.MethodGet, http.StatusNotFound, time.Millisecond)
+
+	labels := `{method="GET",route="/api/v1/media/{id}",status="404"}`
+	body := s.scrape()
+	s.Contains(body, `medianexus_http_requests_total`+labels+` 1`)
+	s.Contains(body, `medianexus_http_request_duration_seconds_count`+labels+` 1`)
+}
+
+func (s *prometheusMetricsTestSuite) TestUploads() {
+	s.metrics.ObserveUpload(UploadResumable, 100, time.Second)
+	s.metrics.ObserveUpload(UploadResumable, 50, time.Second)
+	s.metrics.CountDedupHit()
+
+	body := s.scrape()
+	s.Contains(body, `medianexus_upload_bytes_total{kind="resumable"} 150`)
+	s.Contains(body, `medianexus_upload_duration_seconds_count{kind="resumable"} 2`)
+	s.Contains(body, `medianexus_dedup_hits_total 1`)
+}
+
+func (s *prometheusMetricsTestSuite) TestStorageCallOutcomes() {
+	s.metrics.ObserveStorageCall("mongodb", "media_metadata", "Get", time.Millisecond, nil)
+	s.metrics.ObserveStorageCall("mongodb", "media_metadata", "Get", time.Millisecond, errortypes.NewResourceNotFound("a"))
+	s.metrics.ObserveStorageCall("mongodb", "media_metadata", "Get", time.Millisecond, errors.New("failed"))
+
+	body := s.scrape()
+	for _, outcome := range []string{"success", "not_found", "failure"} {
+		s.Contains(
+			body,
+			`medianexus_storage_call_duration_seconds_count{backend="mongodb",operation="Get",outcome="`+outcome+
+				`",repository="media_metadata"} 1`,
+		)
+	}
+}
+
+func (s *prometheusMetricsTestSuite) TestRuns() {
+	s.metrics.CountRun("delete_expired_uploads", nil)
+	s.metrics.CountRun("delete_expired_uploads", errors.New("failed"))
+	s.metrics.CountRun("delete_expired_uploads", nil)
+
+	body := s.scrape()
+	s.Contains(body, `medianexus_background_runs_total{outcome="success",runner="delete_expired_uploads"} 2`)
+	s.Contains(body, `medianexus_background_runs_total{outcome="failure",runner="delete_expired_uploads"} 1`)
+}
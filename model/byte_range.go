@@ -0,0 +1,7 @@
+package model
+
+// ByteRange selects Length bytes starting at Offset.
+type ByteRange struct {
+	Offset int64
+	Length int64
+}
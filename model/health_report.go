@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// HealthReport tells whether the dependencies can be used. It's healthy if all critical ones are.
+type HealthReport struct {
+	Healthy   bool
+	CheckedAt time.Time
+	Checks    []HealthCheckResult
+}
+
+type HealthCheckResult struct {
+	Name string
+	// Critical dependencies are needed to serve requests. Failures of others are only reported.
+	Critical bool
+	// Healthy is false if the check failed. The error is only logged, it might tell hosts and credentials.
+	Healthy  bool
+	Duration time.Duration
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// ImageFilter narrows down a search by the image info of the media. Zero values don't filter. Media without image
+// info only matches the empty filter.
+type ImageFilter struct {
+	MinWidth       int
+	MaxWidth       int
+	MinHeight      int
+	MaxHeight      int
+	CameraModel    string
+	CapturedAfter  *time.Time
+	CapturedBefore *time.Time
+	// HasGPS filters for media with or without GPS coordinates
+	HasGPS *bool
+}
+
+func (f ImageFilter) IsEmpty() bool {
+	return f == ImageFilter{}
+}
+
+func (f ImageFilter) Matches(info *ImageInfo) bool {
+	if f.IsEmpty() {
+		return true
+	}
+
+	if info == nil {
+		return false
+	}
+
+	if (f.MinWidth > 0 && info.Width < f.MinWidth) || (f.MaxWidth > 0 && info.Width > f.MaxWidth) {
+		return false
+	}
+
+	if (f.MinHeight > 0 && info.Height < f.MinHeight) || (f.MaxHeight > 0 && info.Height > f.MaxHeight) {
+		return false
+	}
+
+	if f.CameraModel != "" && info.CameraModel != f.CameraModel {
+		return false
+	}
+
+	if f.CapturedAfter != nil && (info.CaptureTime == nil || !info.CaptureTime.After(*f.CapturedAfter)) {
+		return false
+	}
+
+	if f.CapturedBefore != nil && (info.CaptureTime == nil || !info.CaptureTime.Before(*f.CapturedBefore)) {
+		return false
+	}
+
+	if f.HasGPS != nil && *f.HasGPS != (info.GPS != nil) {
+		return false
+	}
+
+	return true
+}
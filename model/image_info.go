@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// ImageInfo is what the headers of an image tell about it. Everything but the dimensions is optional.
+type ImageInfo struct {
+	// Width and Height in pixels as stored. Orientations 5 to 8 rotate the image, so it's displayed the other way
+	// round.
+	Width  int
+	Height int
+	// Orientation is the EXIF orientation from 1 to 8. 0 if unknown.
+	Orientation int
+	// CaptureTime is when the picture was taken. The EXIF date has no time zone, so it's taken as UTC.
+	CaptureTime *time.Time
+	CameraModel string
+	GPS         *GPSCoordinates
+}
+
+// GPSCoordinates in decimal degrees. South and west are negative.
+type GPSCoordinates struct {
+	Latitude  float64
+	Longitude float64
+}
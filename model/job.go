@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Job is work, that must be done eventually, even if the instance enqueuing it goes down. It is run by the handler
+// registered for its Type. What Payload holds is up to the type, e.g. the ID of a media.
+type Job struct {
+	ID      JobID
+	Type    string
+	Payload string
+	// Attempts counts the leases so far, including the current one
+	Attempts int
+	// LeaseID identifies the current lease. Only its holder may complete or fail the job.
+	LeaseID string
+	// VisibleAt is the time the job may be leased (again). While leased, it is the end of the visibility timeout.
+	VisibleAt time.Time
+	LastError string
+	// DeadLetter is set for jobs, that failed too often. They are kept for inspection, but not leased anymore.
+	DeadLetter bool
+	CreatedAt  time.Time
+}
@@ -0,0 +1,9 @@
+package model
+
+// MediaAttributes are what the media repository knows about stored media.
+type MediaAttributes struct {
+	// Size in bytes
+	Size int64
+	// Checksum is the hex encoded SHA-256 of the media. Empty if the repository doesn't know it.
+	Checksum string
+}
@@ -4,17 +4,24 @@ type MediaItem interface {
 	MediaMetadata
 
 	FileURL() string
+	// VariantURLs holds the URLs of the scaled down variants by size. Nil for media without variants.
+	VariantURLs() map[int]string
 }
 
-func NewMediaItem(metadata MediaMetadata, fileURL string) MediaItem {
-	return &mediaItem{metadata, fileURL}
+func NewMediaItem(metadata MediaMetadata, fileURL string, variantURLs map[int]string) MediaItem {
+	return &mediaItem{metadata, fileURL, variantURLs}
 }
 
 type mediaItem struct {
 	MediaMetadata
-	fileURL string
+	fileURL     string
+	variantURLs map[int]string
 }
 
 func (m *mediaItem) FileURL() string {
 	return m.fileURL
 }
+
+func (m *mediaItem) VariantURLs() map[int]string {
+	return m.variantURLs
+}
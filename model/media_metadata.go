@@ -6,8 +6,25 @@ type MediaMetadata interface {
 	ID() MediaID
 	Name() string
 	TagIDs() []TagID
+	// Checksum is the SHA-256 of the uploaded file. The media ID is derived from it.
 	Checksum() string
+	// StoredChecksum is the SHA-256 of the stored file. It differs from Checksum, if the file was transformed on
+	// upload, e.g. to strip the image metadata.
+	StoredChecksum() string
+	// Size of the stored media in bytes, after any transformation
+	Size() int64
+	// MimeType is sniffed from the content, like `image/png`. Empty for media created before it was stored.
+	MimeType() string
+	// ImageInfo is nil for media, that isn't an image or whose headers couldn't be read
+	ImageInfo() *ImageInfo
+	// Variants are the sizes of the scaled down variants generated so far
+	Variants() []int
 	UploadComplete() bool
+	// UploadCompletedAt is when the content was stored for good, so it changes with the content only, unlike
+	// LastUpdate. Zero while the upload isn't complete.
+	UploadCompletedAt() time.Time
+	// Deleting is set while the media is being deleted
+	Deleting() bool
 	LastUpdate() time.Time
 }
 
@@ -16,26 +33,52 @@ func NewMediaMetadata(
 	name string,
 	tagIds []TagID,
 	checksum string,
+	storedChecksum string,
+	size int64,
+	mimeType string,
+	imageInfo *ImageInfo,
+	variants []int,
 	uploadComplete bool,
+	uploadCompletedAt time.Time,
+	deleting bool,
 	lastUpdate time.Time,
 ) MediaMetadata {
+	// media stored before transformations existed is stored as uploaded
+	if storedChecksum == "" {
+		storedChecksum = checksum
+	}
+
 	return &mediaMetadata{
-		id:             id,
-		name:           name,
-		tagIds:         tagIds,
-		checksum:       checksum,
-		uploadComplete: uploadComplete,
-		lastUpdate:     lastUpdate,
+		id:                id,
+		name:              name,
+		tagIds:            tagIds,
+		checksum:          checksum,
+		storedChecksum:    storedChecksum,
+		size:              size,
+		mimeType:          mimeType,
+		imageInfo:         imageInfo,
+		variants:          variants,
+		uploadComplete:    uploadComplete,
+		uploadCompletedAt: uploadCompletedAt,
+		deleting:          deleting,
+		lastUpdate:        lastUpdate,
 	}
 }
 
 type mediaMetadata struct {
-	id             MediaID
-	name           string
-	tagIds         []TagID
-	checksum       string
-	uploadComplete bool
-	lastUpdate     time.Time
+	id                MediaID
+	name              string
+	tagIds            []TagID
+	checksum          string
+	storedChecksum    string
+	size              int64
+	mimeType          string
+	imageInfo         *ImageInfo
+	variants          []int
+	uploadComplete    bool
+	uploadCompletedAt time.Time
+	deleting          bool
+	lastUpdate        time.Time
 }
 
 func (m *mediaMetadata) ID() MediaID {
@@ -54,10 +97,38 @@ func (m *mediaMetadata) Checksum() string {
 	return m.checksum
 }
 
+func (m *mediaMetadata) StoredChecksum() string {
+	return m.storedChecksum
+}
+
+func (m *mediaMetadata) Size() int64 {
+	return m.size
+}
+
+func (m *mediaMetadata) MimeType() string {
+	return m.mimeType
+}
+
+func (m *mediaMetadata) ImageInfo() *ImageInfo {
+	return m.imageInfo
+}
+
+func (m *mediaMetadata) Variants() []int {
+	return m.variants
+}
+
 func (m *mediaMetadata) UploadComplete() bool {
 	return m.uploadComplete
 }
 
+func (m *mediaMetadata) UploadCompletedAt() time.Time {
+	return m.uploadCompletedAt
+}
+
+func (m *mediaMetadata) Deleting() bool {
+	return m.deleting
+}
+
 func (m *mediaMetadata) LastUpdate() time.Time {
 	return m.lastUpdate
 }
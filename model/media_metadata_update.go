@@ -0,0 +1,10 @@
+package model
+
+// MediaMetadataUpdate describes changes to the mutable fields of media metadata. Unset fields stay unchanged.
+type MediaMetadataUpdate struct {
+	Name *string
+	// TagIDs replaces all tags if not nil. Can't be combined with AddTagIDs and RemoveTagIDs.
+	TagIDs       []TagID
+	AddTagIDs    []TagID
+	RemoveTagIDs []TagID
+}
@@ -0,0 +1,8 @@
+package model
+
+// PageRequest requests up to Limit items ordered by ID, starting after the ID After. An empty After starts with
+// the first item.
+type PageRequest struct {
+	After string
+	Limit int
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// ReconcileReport is what a reconciliation of the media repository with the metadata found.
+type ReconcileReport struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// ScannedMedia counts the keys listed in the media repository, including variants, staged media and chunks
+	ScannedMedia    int
+	ScannedMetadata int
+	// DeletedOrphans are the keys of media without metadata, that were older than the grace period
+	DeletedOrphans []string
+	// MissingMedia are the IDs of complete metadata without media
+	MissingMedia []MediaID
+}
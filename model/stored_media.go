@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// StoredMedia is what the media repository lists about the media it stores.
+type StoredMedia struct {
+	Key string
+	// Size in bytes
+	Size         int64
+	LastModified time.Time
+}
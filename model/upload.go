@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Upload is a resumable upload, that receives the media in chunks. Once all Length bytes are there, it becomes
+// the media with MediaID.
+type Upload struct {
+	ID     UploadID
+	Name   string
+	TagIDs []TagID
+	Length int64
+	// Offset is the number of bytes received so far, i.e. the sum of the chunk sizes
+	Offset    int64
+	Chunks    []UploadChunk
+	MediaID   MediaID
+	ExpiresAt time.Time
+}
+
+// UploadChunk is stored in the media repository at Key until the upload completes.
+type UploadChunk struct {
+	Key  string
+	Size int64
+}
+
+func (u *Upload) Complete() bool {
+	return u.MediaID != ""
+}
+
+func (u *Upload) Expired() bool {
+	return u.ExpiresAt.Before(time.Now())
+}
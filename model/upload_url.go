@@ -0,0 +1,8 @@
+package model
+
+// UploadURL lets clients upload media straight to the media repository with a PUT request.
+type UploadURL struct {
+	URL string
+	// Headers must be sent along with the PUT request, they are part of the signature
+	Headers map[string]string
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+)
+
+// HealthChecker checks whether a dependency, like the database or the bucket, can be used
+type HealthChecker interface {
+	// Name identifies the dependency in health reports, e.g. mongodb
+	Name() string
+	// Check returns an error if the dependency can't be used. It returns once ctx is done at the latest.
+	Check(ctx context.Context) error
+}
@@ -0,0 +1,33 @@
+package ports
+
+import (
+	"context"
+	"media-nexus/model"
+	"time"
+)
+
+// JobQueue persists jobs, so they survive restarts and are shared by all instances. A leased job is hidden from
+// other workers for the visibility timeout. If its worker neither completes nor fails it in time, e.g. because it
+// crashed, the job becomes visible again.
+type JobQueue interface {
+	Enqueue(ctx context.Context, job *model.Job) error
+	// Lease atomically hands out the visible job of one of the types, that is due the longest, and hides it for the
+	// visibility timeout. Returns ResourceNotFound if no job is visible.
+	Lease(ctx context.Context, jobTypes []string, visibilityTimeout time.Duration) (*model.Job, error)
+	// ExtendLease hides the job for the visibility timeout from now on, so jobs running longer keep their lease. It
+	// and Complete, Fail and DeadLetter return Conflict if the lease is lost, i.e. the job was leased again after the
+	// visibility timeout.
+	ExtendLease(ctx context.Context, id model.JobID, leaseID string, visibilityTimeout time.Duration) error
+	// Complete removes the job.
+	Complete(ctx context.Context, id model.JobID, leaseID string) error
+	// Fail records the error and releases the job, so it is retried at retryAt.
+	Fail(ctx context.Context, id model.JobID, leaseID string, reason string, retryAt time.Time) error
+	// DeadLetter records the error and stops retrying the job.
+	DeadLetter(ctx context.Context, id model.JobID, leaseID string, reason string) error
+	// FindDeadLetters returns the oldest dead letters first.
+	FindDeadLetters(ctx context.Context, limit int) ([]*model.Job, error)
+	// Requeue makes a dead letter visible again with its attempts reset. Returns ResourceNotFound for jobs, that are
+	// no dead letters.
+	Requeue(ctx context.Context, id model.JobID) error
+	Delete(ctx context.Context, id model.JobID) error
+}
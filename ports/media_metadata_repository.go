@@ -3,13 +3,36 @@ package ports
 import (
 	"context"
 	"media-nexus/model"
+	"media-nexus/services/query"
 )
 
 type MediaMetadataRepository interface {
 	Upsert(ctx context.Context, metadata model.MediaMetadata) error
 	Get(ctx context.Context, id model.MediaID) (model.MediaMetadata, error)
 	SetUploadComplete(ctx context.Context, metadata model.MediaID, complete bool) error
+	SetDeleting(ctx context.Context, metadata model.MediaID, deleting bool) error
+	// AddVariant records that the variant of the size has been generated
+	AddVariant(ctx context.Context, id model.MediaID, size int) error
+	// Update changes the metadata unless it's being deleted. Returns Conflict while the upload isn't complete.
+	Update(ctx context.Context, id model.MediaID, update model.MediaMetadataUpdate) error
 	FindByTagID(ctx context.Context, id model.TagID) ([]model.MediaMetadata, error)
+	FindByQuery(ctx context.Context, expression query.Expression) ([]model.MediaMetadata, error)
+	// FindByTagIDPage and FindByQueryPage find metadata matching the filter ordered by ID. The bool tells whether more
+	// metadata follows the page.
+	FindByTagIDPage(
+		ctx context.Context,
+		id model.TagID,
+		filter model.ImageFilter,
+		page model.PageRequest,
+	) ([]model.MediaMetadata, bool, error)
+	FindByQueryPage(
+		ctx context.Context,
+		expression query.Expression,
+		filter model.ImageFilter,
+		page model.PageRequest,
+	) ([]model.MediaMetadata, bool, error)
+	// FindAllPage pages through all metadata, including incomplete and deleting metadata.
+	FindAllPage(ctx context.Context, page model.PageRequest) ([]model.MediaMetadata, bool, error)
 	FindByChecksum(ctx context.Context, checksum string) (model.MediaMetadata, error)
 	DeleteAll(ctx context.Context, ids []model.MediaID) error
 }
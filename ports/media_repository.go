@@ -3,11 +3,35 @@ package ports
 import (
 	"context"
 	"io"
+	"media-nexus/model"
 	"time"
 )
 
 type MediaRepository interface {
-	CreateMedia(ctx context.Context, key string, file io.Reader) error
+	// CreateMedia stores the file at key. mimeType is served with the media by repositories able to store it. Empty if
+	// unknown.
+	CreateMedia(ctx context.Context, key string, file io.Reader, mimeType string) error
+	// MoveMedia moves the media at fromKey to toKey, replacing media that is already stored at toKey. Returns
+	// ResourceNotFound if there is no media at fromKey.
+	MoveMedia(ctx context.Context, fromKey string, toKey string) error
+	// OpenMedia reads the whole media or only byteRange, if given. A byte range exceeding the media is cut at its end.
+	// Returns ResourceNotFound if there is no media at key.
+	OpenMedia(ctx context.Context, key string, byteRange *model.ByteRange) (io.ReadCloser, error)
+	// GetMediaAttributes returns ResourceNotFound if there is no media at key.
+	GetMediaAttributes(ctx context.Context, key string) (model.MediaAttributes, error)
 	GetMediaURL(ctx context.Context, key string, lifetime time.Duration) (string, error)
+	// GetUploadURL returns a URL to PUT the media at key without passing through this service. size, checksum, the
+	// hex encoded SHA-256, and mimeType are what the client announced. Repositories able to enforce them reject other
+	// uploads.
+	GetUploadURL(
+		ctx context.Context,
+		key string,
+		size int64,
+		checksum string,
+		mimeType string,
+		lifetime time.Duration,
+	) (model.UploadURL, error)
 	DeleteAll(ctx context.Context, keys []string) error
+	// ListMediaPage lists the stored media ordered by key. The bool tells whether more media follows the page.
+	ListMediaPage(ctx context.Context, page model.PageRequest) ([]model.StoredMedia, bool, error)
 }
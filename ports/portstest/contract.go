@@ -0,0 +1,39 @@
+// Package portstest contains contract test suites for the ports. Every adapter implementing a port should run
+// the respective suite against itself to prove it behaves like the other adapters.
+package portstest
+
+import (
+	"context"
+	"media-nexus/logger"
+	"media-nexus/util"
+
+	"github.com/stretchr/testify/suite"
+	"go.step.sm/crypto/randutil"
+)
+
+type contract struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (s *contract) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("contract"))
+}
+
+func (s *contract) generateAlphanumeric(length int) string {
+	str, err := randutil.Alphanumeric(length)
+	s.Require().NoError(err)
+	return str
+}
+
+func (s *contract) generateHex(length int) string {
+	str, err := randutil.Hex(length)
+	s.Require().NoError(err)
+	return str
+}
+
+func (s *contract) logIfError(err error, msg string) {
+	if err != nil {
+		util.Logger(s.ctx).Errorf("failed: %v. Details: %v", msg, err)
+	}
+}
@@ -0,0 +1,228 @@
+package portstest
+
+import (
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"time"
+)
+
+// JobQueueContract runs against the queue returned by Queue. Every test uses its own job type, so jobs left over
+// by other tests are never leased. Jobs enqueued by the suite are deleted after each test.
+type JobQueueContract struct {
+	contract
+	Queue func() ports.JobQueue
+
+	queue      ports.JobQueue
+	jobType    string
+	createdIDs []model.JobID
+}
+
+func (s *JobQueueContract) SetupTest() {
+	s.contract.SetupTest()
+	s.queue = s.Queue()
+	s.jobType = s.generateAlphanumeric(16)
+	s.createdIDs = nil
+}
+
+func (s *JobQueueContract) TearDownTest() {
+	for _, id := range s.createdIDs {
+		s.logIfError(s.queue.Delete(s.ctx, id), "delete job")
+	}
+}
+
+func (s *JobQueueContract) enqueue(visibleAt time.Time) *model.Job {
+	job := &model.Job{
+		ID:        s.generateHex(32),
+		Type:      s.jobType,
+		Payload:   s.generateAlphanumeric(10),
+		VisibleAt: visibleAt,
+		CreatedAt: time.Now(),
+	}
+
+	s.Require().NoError(s.queue.Enqueue(s.ctx, job))
+	s.createdIDs = append(s.createdIDs, job.ID)
+
+	return job
+}
+
+func (s *JobQueueContract) lease(visibilityTimeout time.Duration) *model.Job {
+	job, err := s.queue.Lease(s.ctx, []string{s.jobType}, visibilityTimeout)
+	s.Require().NoError(err)
+
+	return job
+}
+
+func (s *JobQueueContract) requireNoVisibleJob() {
+	_, err := s.queue.Lease(s.ctx, []string{s.jobType}, time.Minute)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *JobQueueContract) TestEnqueueAndLease() {
+	job := s.enqueue(time.Now())
+
+	leased := s.lease(time.Minute)
+	s.Equal(job.ID, leased.ID)
+	s.Equal(job.Type, leased.Type)
+	s.Equal(job.Payload, leased.Payload)
+	s.Equal(1, leased.Attempts)
+	s.NotEmpty(leased.LeaseID)
+	s.WithinDuration(time.Now().Add(time.Minute), leased.VisibleAt, time.Second)
+	s.WithinDuration(job.CreatedAt, leased.CreatedAt, time.Millisecond)
+
+	s.requireNoVisibleJob()
+}
+
+func (s *JobQueueContract) TestEnqueueExisting() {
+	job := s.enqueue(time.Now())
+
+	err := s.queue.Enqueue(s.ctx, job)
+	s.True(errortypes.IsResourceAlreadyExists(err), "expected resource already exists, got %v", err)
+}
+
+func (s *JobQueueContract) TestLeaseOldestFirst() {
+	now := time.Now()
+	newer := s.enqueue(now.Add(-time.Second))
+	older := s.enqueue(now.Add(-time.Minute))
+
+	s.Equal(older.ID, s.lease(time.Minute).ID)
+	s.Equal(newer.ID, s.lease(time.Minute).ID)
+}
+
+func (s *JobQueueContract) TestLeaseSkipsFutureJobs() {
+	s.enqueue(time.Now().Add(time.Hour))
+
+	s.requireNoVisibleJob()
+}
+
+func (s *JobQueueContract) TestLeaseSkipsOtherTypes() {
+	s.enqueue(time.Now())
+
+	_, err := s.queue.Lease(s.ctx, []string{s.generateAlphanumeric(16)}, time.Minute)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *JobQueueContract) TestLeaseAfterVisibilityTimeout() {
+	s.enqueue(time.Now())
+
+	first := s.lease(50 * time.Millisecond)
+	s.requireNoVisibleJob()
+
+	time.Sleep(100 * time.Millisecond)
+
+	second := s.lease(time.Minute)
+	s.Equal(first.ID, second.ID)
+	s.Equal(2, second.Attempts)
+	s.NotEqual(first.LeaseID, second.LeaseID)
+
+	err := s.queue.Complete(s.ctx, first.ID, first.LeaseID)
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+
+	s.Require().NoError(s.queue.Complete(s.ctx, second.ID, second.LeaseID))
+}
+
+func (s *JobQueueContract) TestExtendLease() {
+	s.enqueue(time.Now())
+	job := s.lease(50 * time.Millisecond)
+
+	s.Require().NoError(s.queue.ExtendLease(s.ctx, job.ID, job.LeaseID, time.Minute))
+
+	time.Sleep(100 * time.Millisecond)
+	s.requireNoVisibleJob()
+
+	err := s.queue.ExtendLease(s.ctx, job.ID, s.generateHex(24), time.Minute)
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+
+	s.Require().NoError(s.queue.Complete(s.ctx, job.ID, job.LeaseID))
+
+	err = s.queue.ExtendLease(s.ctx, job.ID, job.LeaseID, time.Minute)
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+}
+
+func (s *JobQueueContract) TestExtendLostLease() {
+	s.enqueue(time.Now())
+	first := s.lease(50 * time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+	second := s.lease(time.Minute)
+
+	err := s.queue.ExtendLease(s.ctx, first.ID, first.LeaseID, time.Minute)
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+
+	s.Require().NoError(s.queue.Complete(s.ctx, second.ID, second.LeaseID))
+}
+
+func (s *JobQueueContract) TestComplete() {
+	s.enqueue(time.Now())
+	job := s.lease(time.Minute)
+
+	s.Require().NoError(s.queue.Complete(s.ctx, job.ID, job.LeaseID))
+
+	err := s.queue.Complete(s.ctx, job.ID, job.LeaseID)
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+}
+
+func (s *JobQueueContract) TestFail() {
+	s.enqueue(time.Now())
+	job := s.lease(time.Minute)
+
+	s.Require().NoError(s.queue.Fail(s.ctx, job.ID, job.LeaseID, "failed", time.Now().Add(-time.Second)))
+
+	retried := s.lease(time.Minute)
+	s.Equal(job.ID, retried.ID)
+	s.Equal(2, retried.Attempts)
+	s.Equal("failed", retried.LastError)
+
+	err := s.queue.Fail(s.ctx, job.ID, job.LeaseID, "failed", time.Now())
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+}
+
+func (s *JobQueueContract) TestFailRetriesLater() {
+	s.enqueue(time.Now())
+	job := s.lease(time.Minute)
+
+	s.Require().NoError(s.queue.Fail(s.ctx, job.ID, job.LeaseID, "failed", time.Now().Add(time.Hour)))
+
+	s.requireNoVisibleJob()
+}
+
+func (s *JobQueueContract) TestDeadLetterAndRequeue() {
+	s.enqueue(time.Now())
+	job := s.lease(time.Minute)
+
+	s.Require().NoError(s.queue.DeadLetter(s.ctx, job.ID, job.LeaseID, "failed for good"))
+	s.requireNoVisibleJob()
+
+	err := s.queue.Complete(s.ctx, job.ID, job.LeaseID)
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+
+	deadLetters, err := s.queue.FindDeadLetters(s.ctx, 1000)
+	s.Require().NoError(err)
+
+	var deadLetter *model.Job
+	for _, letter := range deadLetters {
+		if letter.ID == job.ID {
+			deadLetter = letter
+		}
+	}
+
+	s.Require().NotNil(deadLetter)
+	s.True(deadLetter.DeadLetter)
+	s.Equal("failed for good", deadLetter.LastError)
+
+	s.Require().NoError(s.queue.Requeue(s.ctx, job.ID))
+
+	requeued := s.lease(time.Minute)
+	s.Equal(job.ID, requeued.ID)
+	s.Equal(1, requeued.Attempts)
+}
+
+func (s *JobQueueContract) TestRequeueWithoutDeadLetter() {
+	job := s.enqueue(time.Now())
+
+	err := s.queue.Requeue(s.ctx, job.ID)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+
+	err = s.queue.Requeue(s.ctx, s.generateHex(32))
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
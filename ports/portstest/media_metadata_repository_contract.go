@@ -0,0 +1,637 @@
+package portstest
+
+import (
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/services/query"
+	"slices"
+	"time"
+)
+
+// MediaMetadataRepositoryContract runs against the repository returned by Repository. Metadata created by the suite
+// is deleted after each test.
+type MediaMetadataRepositoryContract struct {
+	contract
+	Repository func() ports.MediaMetadataRepository
+
+	repo       ports.MediaMetadataRepository
+	createdIDs []model.MediaID
+}
+
+func (s *MediaMetadataRepositoryContract) SetupTest() {
+	s.contract.SetupTest()
+	s.repo = s.Repository()
+	s.createdIDs = nil
+}
+
+func (s *MediaMetadataRepositoryContract) TearDownTest() {
+	if len(s.createdIDs) > 0 {
+		s.logIfError(s.repo.DeleteAll(s.ctx, s.createdIDs), "delete media metadata")
+	}
+}
+
+func (s *MediaMetadataRepositoryContract) newMetadata(
+	tagIDs []model.TagID,
+	checksum string,
+	uploadComplete bool,
+) model.MediaMetadata {
+	return s.newImageMetadata(tagIDs, checksum, uploadComplete, nil)
+}
+
+func (s *MediaMetadataRepositoryContract) newImageMetadata(
+	tagIDs []model.TagID,
+	checksum string,
+	uploadComplete bool,
+	imageInfo *model.ImageInfo,
+) model.MediaMetadata {
+	var completedAt time.Time
+	if uploadComplete {
+		// times are stored with millisecond precision
+		completedAt = time.Now().UTC().Truncate(time.Millisecond)
+	}
+
+	return model.NewMediaMetadata(
+		s.generateHex(64),
+		s.generateAlphanumeric(10),
+		tagIDs,
+		checksum,
+		checksum,
+		1024,
+		"image/png",
+		imageInfo,
+		nil,
+		uploadComplete,
+		completedAt,
+		false,
+		time.Now().UTC(),
+	)
+}
+
+// newImageInfo returns image info of a 640x480 picture. Capture times are stored with millisecond precision.
+func (s *MediaMetadataRepositoryContract) newImageInfo(captureTime time.Time, gps bool) *model.ImageInfo {
+	captureTime = captureTime.UTC().Truncate(time.Millisecond)
+	info := &model.ImageInfo{
+		Width:       640,
+		Height:      480,
+		Orientation: 6,
+		CaptureTime: &captureTime,
+		CameraModel: "Pixel 7",
+	}
+
+	if gps {
+		info.GPS = &model.GPSCoordinates{Latitude: -33.5, Longitude: -70.76}
+	}
+
+	return info
+}
+
+func (s *MediaMetadataRepositoryContract) upsert(metadata model.MediaMetadata) {
+	s.Require().NoError(s.repo.Upsert(s.ctx, metadata))
+	s.createdIDs = append(s.createdIDs, metadata.ID())
+}
+
+func (s *MediaMetadataRepositoryContract) requireEqualMetadata(expected, actual model.MediaMetadata) {
+	s.Require().NotNil(actual)
+	s.Equal(expected.ID(), actual.ID())
+	s.Equal(expected.Name(), actual.Name())
+	s.ElementsMatch(expected.TagIDs(), actual.TagIDs())
+	s.Equal(expected.Checksum(), actual.Checksum())
+	s.Equal(expected.StoredChecksum(), actual.StoredChecksum())
+	s.Equal(expected.Size(), actual.Size())
+	s.Equal(expected.MimeType(), actual.MimeType())
+	s.Equal(expected.ImageInfo(), actual.ImageInfo())
+	s.Equal(expected.UploadComplete(), actual.UploadComplete())
+	s.WithinDuration(expected.UploadCompletedAt(), actual.UploadCompletedAt(), time.Millisecond)
+	s.Equal(expected.Deleting(), actual.Deleting())
+	s.WithinDuration(expected.LastUpdate(), actual.LastUpdate(), time.Millisecond)
+}
+
+func (s *MediaMetadataRepositoryContract) ids(metadatas []model.MediaMetadata) []model.MediaID {
+	ids := make([]model.MediaID, 0, len(metadatas))
+	for _, metadata := range metadatas {
+		ids = append(ids, metadata.ID())
+	}
+
+	return ids
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpsertAndGet() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), false)
+	s.upsert(metadata)
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.requireEqualMetadata(metadata, stored)
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpsertAndGetImageInfo() {
+	imageInfo := s.newImageInfo(time.Now(), true)
+	metadata := s.newImageMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), false, imageInfo)
+	s.upsert(metadata)
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.requireEqualMetadata(metadata, stored)
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpsertUpdatesByID() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), false)
+	s.upsert(metadata)
+
+	updated := model.NewMediaMetadata(
+		metadata.ID(),
+		s.generateAlphanumeric(10),
+		[]model.TagID{s.generateHex(64)},
+		metadata.Checksum(),
+		s.generateHex(64),
+		metadata.Size(),
+		metadata.MimeType(),
+		nil,
+		nil,
+		true,
+		time.Now().UTC(),
+		false,
+		time.Now().UTC(),
+	)
+	s.upsert(updated)
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.requireEqualMetadata(updated, stored)
+}
+
+func (s *MediaMetadataRepositoryContract) TestGetMissing() {
+	_, err := s.repo.Get(s.ctx, s.generateHex(64))
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestSetUploadComplete() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), false)
+	s.upsert(metadata)
+
+	s.Require().NoError(s.repo.SetUploadComplete(s.ctx, metadata.ID(), true))
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.True(stored.UploadComplete())
+	s.False(stored.LastUpdate().Before(metadata.LastUpdate()))
+	s.WithinDuration(time.Now(), stored.UploadCompletedAt(), time.Minute)
+
+	s.Require().NoError(s.repo.SetUploadComplete(s.ctx, metadata.ID(), false))
+
+	stored, err = s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.False(stored.UploadComplete())
+	s.True(stored.UploadCompletedAt().IsZero())
+}
+
+func (s *MediaMetadataRepositoryContract) TestSetUploadCompleteOnMissingID() {
+	err := s.repo.SetUploadComplete(s.ctx, s.generateHex(64), true)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestSetDeleting() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+
+	s.Require().NoError(s.repo.SetDeleting(s.ctx, metadata.ID(), true))
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.True(stored.Deleting())
+	s.True(stored.UploadComplete())
+
+	s.Require().NoError(s.repo.SetDeleting(s.ctx, metadata.ID(), false))
+
+	stored, err = s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.False(stored.Deleting())
+}
+
+func (s *MediaMetadataRepositoryContract) TestSetDeletingOnMissingID() {
+	err := s.repo.SetDeleting(s.ctx, s.generateHex(64), true)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestAddVariant() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+
+	s.Require().NoError(s.repo.AddVariant(s.ctx, metadata.ID(), 512))
+	s.Require().NoError(s.repo.AddVariant(s.ctx, metadata.ID(), 128))
+	s.Require().NoError(s.repo.AddVariant(s.ctx, metadata.ID(), 512))
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.ElementsMatch([]int{128, 512}, stored.Variants())
+	s.WithinDuration(metadata.LastUpdate(), stored.LastUpdate(), time.Millisecond)
+}
+
+func (s *MediaMetadataRepositoryContract) TestAddVariantOnMissingID() {
+	err := s.repo.AddVariant(s.ctx, s.generateHex(64), 128)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpsertKeepsVariants() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+	s.Require().NoError(s.repo.AddVariant(s.ctx, metadata.ID(), 128))
+
+	s.upsert(metadata)
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.Equal([]int{128}, stored.Variants())
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpsertClearsDeleting() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+	s.Require().NoError(s.repo.SetDeleting(s.ctx, metadata.ID(), true))
+
+	s.upsert(metadata)
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.False(stored.Deleting())
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpdateName() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+
+	name := "$" + s.generateAlphanumeric(10)
+	s.Require().NoError(s.repo.Update(s.ctx, metadata.ID(), model.MediaMetadataUpdate{Name: &name}))
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.Equal(name, stored.Name())
+	s.ElementsMatch(metadata.TagIDs(), stored.TagIDs())
+	s.Equal(metadata.Checksum(), stored.Checksum())
+	s.True(stored.UploadComplete())
+
+	// the content didn't change
+	s.WithinDuration(metadata.UploadCompletedAt(), stored.UploadCompletedAt(), time.Millisecond)
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpdateReplacesTags() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64), s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+
+	tagIDs := []model.TagID{s.generateHex(64)}
+	s.Require().NoError(s.repo.Update(s.ctx, metadata.ID(), model.MediaMetadataUpdate{TagIDs: tagIDs}))
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.Equal(metadata.Name(), stored.Name())
+	s.ElementsMatch(tagIDs, stored.TagIDs())
+
+	s.Require().NoError(s.repo.Update(s.ctx, metadata.ID(), model.MediaMetadataUpdate{TagIDs: []model.TagID{}}))
+
+	stored, err = s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.Empty(stored.TagIDs())
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpdateAddsAndRemovesTags() {
+	keptTagID := s.generateHex(64)
+	removedTagID := s.generateHex(64)
+	addedTagID := s.generateHex(64)
+
+	metadata := s.newMetadata([]model.TagID{keptTagID, removedTagID}, s.generateHex(64), true)
+	s.upsert(metadata)
+
+	s.Require().NoError(s.repo.Update(s.ctx, metadata.ID(), model.MediaMetadataUpdate{
+		AddTagIDs:    []model.TagID{addedTagID, keptTagID},
+		RemoveTagIDs: []model.TagID{removedTagID, s.generateHex(64)},
+	}))
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.ElementsMatch([]model.TagID{keptTagID, addedTagID}, stored.TagIDs())
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpdateOnMissingID() {
+	name := s.generateAlphanumeric(10)
+	err := s.repo.Update(s.ctx, s.generateHex(64), model.MediaMetadataUpdate{Name: &name})
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpdateWhileDeleting() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+	s.Require().NoError(s.repo.SetDeleting(s.ctx, metadata.ID(), true))
+
+	name := s.generateAlphanumeric(10)
+	err := s.repo.Update(s.ctx, metadata.ID(), model.MediaMetadataUpdate{Name: &name})
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestUpdateWhileUploading() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), false)
+	s.upsert(metadata)
+
+	name := s.generateAlphanumeric(10)
+	err := s.repo.Update(s.ctx, metadata.ID(), model.MediaMetadataUpdate{Name: &name})
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+
+	stored, err := s.repo.Get(s.ctx, metadata.ID())
+	s.Require().NoError(err)
+	s.Equal(metadata.Name(), stored.Name())
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByTagID() {
+	tagID := s.generateHex(64)
+	otherTagID := s.generateHex(64)
+
+	metadata := s.newMetadata([]model.TagID{tagID, otherTagID}, s.generateHex(64), true)
+	metadata2 := s.newMetadata([]model.TagID{tagID}, s.generateHex(64), true)
+	s.upsert(metadata)
+	s.upsert(metadata2)
+
+	found, err := s.repo.FindByTagID(s.ctx, tagID)
+	s.Require().NoError(err)
+	s.ElementsMatch([]model.MediaID{metadata.ID(), metadata2.ID()}, s.ids(found))
+
+	found, err = s.repo.FindByTagID(s.ctx, otherTagID)
+	s.Require().NoError(err)
+	s.Equal([]model.MediaID{metadata.ID()}, s.ids(found))
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByUnknownTagID() {
+	found, err := s.repo.FindByTagID(s.ctx, s.generateHex(64))
+	s.Require().NoError(err)
+	s.Empty(found)
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByTagIDPage() {
+	tagID := s.generateHex(64)
+
+	var expected []model.MediaID
+	for i := 0; i < 5; i++ {
+		metadata := s.newMetadata([]model.TagID{tagID}, s.generateHex(64), true)
+		s.upsert(metadata)
+		expected = append(expected, metadata.ID())
+	}
+
+	slices.Sort(expected)
+
+	found, hasMore, err := s.repo.FindByTagIDPage(s.ctx, tagID, model.ImageFilter{}, model.PageRequest{Limit: 2})
+	s.Require().NoError(err)
+	s.True(hasMore)
+	s.Equal(expected[:2], s.ids(found))
+
+	page := model.PageRequest{After: expected[1], Limit: 2}
+	found, hasMore, err = s.repo.FindByTagIDPage(s.ctx, tagID, model.ImageFilter{}, page)
+	s.Require().NoError(err)
+	s.True(hasMore)
+	s.Equal(expected[2:4], s.ids(found))
+
+	page = model.PageRequest{After: expected[3], Limit: 2}
+	found, hasMore, err = s.repo.FindByTagIDPage(s.ctx, tagID, model.ImageFilter{}, page)
+	s.Require().NoError(err)
+	s.False(hasMore)
+	s.Equal(expected[4:], s.ids(found))
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByTagIDPageWithExactLimit() {
+	tagID := s.generateHex(64)
+
+	metadata := s.newMetadata([]model.TagID{tagID}, s.generateHex(64), true)
+	s.upsert(metadata)
+
+	found, hasMore, err := s.repo.FindByTagIDPage(s.ctx, tagID, model.ImageFilter{}, model.PageRequest{Limit: 1})
+	s.Require().NoError(err)
+	s.False(hasMore)
+	s.Equal([]model.MediaID{metadata.ID()}, s.ids(found))
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindAllPage() {
+	var expected []model.MediaID
+	for i := 0; i < 3; i++ {
+		// incomplete metadata is listed as well
+		metadata := s.newMetadata(nil, s.generateHex(64), i > 0)
+		s.upsert(metadata)
+		expected = append(expected, metadata.ID())
+	}
+
+	s.Require().NoError(s.repo.SetDeleting(s.ctx, expected[1], true))
+
+	// other tests might have left metadata behind
+	var found []model.MediaID
+	page := model.PageRequest{Limit: 2}
+	for {
+		metadatas, hasMore, err := s.repo.FindAllPage(s.ctx, page)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(metadatas), 2)
+
+		found = append(found, s.ids(metadatas)...)
+		if !hasMore {
+			break
+		}
+
+		page.After = found[len(found)-1]
+	}
+
+	s.True(slices.IsSorted(found), "expected ids in order, got %v", found)
+	s.Subset(found, expected)
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByQueryPage() {
+	tagA := s.generateHex(64)
+	tagB := s.generateHex(64)
+
+	var expected []model.MediaID
+	for i := 0; i < 3; i++ {
+		metadata := s.newMetadata([]model.TagID{tagA, tagB}, s.generateHex(64), true)
+		s.upsert(metadata)
+		expected = append(expected, metadata.ID())
+	}
+
+	s.upsert(s.newMetadata([]model.TagID{tagA}, s.generateHex(64), true))
+
+	slices.Sort(expected)
+
+	expression, err := query.Parse(tagA + " AND " + tagB)
+	s.Require().NoError(err)
+
+	found, hasMore, err := s.repo.FindByQueryPage(s.ctx, expression, model.ImageFilter{}, model.PageRequest{Limit: 2})
+	s.Require().NoError(err)
+	s.True(hasMore)
+	s.Equal(expected[:2], s.ids(found))
+
+	page := model.PageRequest{After: expected[1], Limit: 2}
+	found, hasMore, err = s.repo.FindByQueryPage(s.ctx, expression, model.ImageFilter{}, page)
+	s.Require().NoError(err)
+	s.False(hasMore)
+	s.Equal(expected[2:], s.ids(found))
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByTagIDPageWithImageFilter() {
+	tagID := s.generateHex(64)
+	captureTime := time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC)
+
+	withGPS := s.newImageMetadata([]model.TagID{tagID}, s.generateHex(64), true, s.newImageInfo(captureTime, true))
+	withoutGPS := s.newImageMetadata([]model.TagID{tagID}, s.generateHex(64), true, s.newImageInfo(captureTime, false))
+	noImage := s.newMetadata([]model.TagID{tagID}, s.generateHex(64), true)
+
+	for _, metadata := range []model.MediaMetadata{withGPS, withoutGPS, noImage} {
+		s.upsert(metadata)
+	}
+
+	hasGPS := true
+	before := captureTime.Add(-time.Hour)
+	after := captureTime.Add(time.Hour)
+	all := []model.MediaID{withGPS.ID(), withoutGPS.ID(), noImage.ID()}
+	images := []model.MediaID{withGPS.ID(), withoutGPS.ID()}
+
+	tests := map[string]struct {
+		filter   model.ImageFilter
+		expected []model.MediaID
+	}{
+		"empty":          {model.ImageFilter{}, all},
+		"min width":      {model.ImageFilter{MinWidth: 640}, images},
+		"max width":      {model.ImageFilter{MaxWidth: 639}, nil},
+		"height":         {model.ImageFilter{MinHeight: 400, MaxHeight: 500}, images},
+		"camera model":   {model.ImageFilter{CameraModel: "Pixel 7"}, images},
+		"other camera":   {model.ImageFilter{CameraModel: "Pixel 8"}, nil},
+		"captured":       {model.ImageFilter{CapturedAfter: &before, CapturedBefore: &after}, images},
+		"captured after": {model.ImageFilter{CapturedAfter: &after}, nil},
+		"has gps":        {model.ImageFilter{HasGPS: &hasGPS}, []model.MediaID{withGPS.ID()}},
+	}
+
+	for name, test := range tests {
+		found, _, err := s.repo.FindByTagIDPage(s.ctx, tagID, test.filter, model.PageRequest{Limit: 10})
+		s.Require().NoError(err)
+		s.ElementsMatch(test.expected, s.ids(found), name)
+	}
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByQueryPageWithImageFilter() {
+	tagA := s.generateHex(64)
+	tagB := s.generateHex(64)
+
+	withoutGPS := s.newImageInfo(time.Now(), false)
+	large := s.newImageMetadata([]model.TagID{tagA, tagB}, s.generateHex(64), true, withoutGPS)
+	s.upsert(large)
+	s.upsert(s.newMetadata([]model.TagID{tagA, tagB}, s.generateHex(64), true))
+	s.upsert(s.newImageMetadata([]model.TagID{tagA}, s.generateHex(64), true, withoutGPS))
+
+	expression, err := query.Parse(tagA + " AND " + tagB)
+	s.Require().NoError(err)
+
+	hasGPS := false
+	filter := model.ImageFilter{MinWidth: 100, HasGPS: &hasGPS}
+	found, hasMore, err := s.repo.FindByQueryPage(s.ctx, expression, filter, model.PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.False(hasMore)
+	s.Equal([]model.MediaID{large.ID()}, s.ids(found))
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByQuery() {
+	tagA := s.generateHex(64)
+	tagB := s.generateHex(64)
+	tagC := s.generateHex(64)
+
+	withAB := s.newMetadata([]model.TagID{tagA, tagB}, s.generateHex(64), true)
+	withABC := s.newMetadata([]model.TagID{tagA, tagB, tagC}, s.generateHex(64), true)
+	withAC := s.newMetadata([]model.TagID{tagA, tagC}, s.generateHex(64), true)
+	withC := s.newMetadata([]model.TagID{tagC}, s.generateHex(64), true)
+
+	for _, metadata := range []model.MediaMetadata{withAB, withABC, withAC, withC} {
+		s.upsert(metadata)
+	}
+
+	tests := map[string][]model.MediaID{
+		tagA:                      {withAB.ID(), withABC.ID(), withAC.ID()},
+		tagA + " AND " + tagB:     {withAB.ID(), withABC.ID()},
+		tagB + " OR " + tagC:      {withAB.ID(), withABC.ID(), withAC.ID(), withC.ID()},
+		tagA + " AND NOT " + tagB: {withAC.ID()},
+		tagC + " AND NOT (" + tagA + " OR " + tagB + ")": {withC.ID()},
+		"(" + tagA + " AND " + tagB + ") OR (" + tagC + " AND NOT " + tagA + ")": {
+			withAB.ID(), withABC.ID(), withC.ID(),
+		},
+	}
+
+	for q, expectedIDs := range tests {
+		expression, err := query.Parse(q)
+		s.Require().NoError(err)
+
+		found, err := s.repo.FindByQuery(s.ctx, expression)
+		s.Require().NoError(err)
+		s.ElementsMatch(expectedIDs, s.ids(found), "query %v", q)
+	}
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByNegatedQuery() {
+	tagA := s.generateHex(64)
+	tagB := s.generateHex(64)
+
+	withA := s.newMetadata([]model.TagID{tagA}, s.generateHex(64), true)
+	withB := s.newMetadata([]model.TagID{tagB}, s.generateHex(64), true)
+	s.upsert(withA)
+	s.upsert(withB)
+
+	expression, err := query.Parse("NOT " + tagA)
+	s.Require().NoError(err)
+
+	found, err := s.repo.FindByQuery(s.ctx, expression)
+	s.Require().NoError(err)
+
+	ids := s.ids(found)
+	s.Contains(ids, withB.ID())
+	s.NotContains(ids, withA.ID())
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByChecksum() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+
+	found, err := s.repo.FindByChecksum(s.ctx, metadata.Checksum())
+	s.Require().NoError(err)
+	s.requireEqualMetadata(metadata, found)
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByChecksumWithMultipleHits() {
+	checksum := s.generateHex(64)
+
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, checksum, true)
+	metadata2 := s.newMetadata([]model.TagID{s.generateHex(64)}, checksum, true)
+	s.upsert(metadata)
+	s.upsert(metadata2)
+
+	found, err := s.repo.FindByChecksum(s.ctx, checksum)
+	s.Require().NoError(err)
+	s.Equal(checksum, found.Checksum())
+	s.Contains([]model.MediaID{metadata.ID(), metadata2.ID()}, found.ID())
+}
+
+func (s *MediaMetadataRepositoryContract) TestFindByUnknownChecksum() {
+	_, err := s.repo.FindByChecksum(s.ctx, s.generateHex(64))
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestDeleteAll() {
+	metadata := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	metadata2 := s.newMetadata([]model.TagID{s.generateHex(64)}, s.generateHex(64), true)
+	s.upsert(metadata)
+	s.upsert(metadata2)
+
+	s.Require().NoError(s.repo.DeleteAll(s.ctx, []model.MediaID{metadata.ID()}))
+
+	_, err := s.repo.Get(s.ctx, metadata.ID())
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+
+	_, err = s.repo.Get(s.ctx, metadata2.ID())
+	s.NoError(err)
+}
+
+func (s *MediaMetadataRepositoryContract) TestDeleteAllWithUnknownIDs() {
+	s.NoError(s.repo.DeleteAll(s.ctx, []model.MediaID{s.generateHex(64)}))
+}
+
+func (s *MediaMetadataRepositoryContract) TestDeleteAllWithEmptyInput() {
+	s.NoError(s.repo.DeleteAll(s.ctx, []model.MediaID{}))
+	s.NoError(s.repo.DeleteAll(s.ctx, nil))
+}
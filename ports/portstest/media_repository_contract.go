@@ -0,0 +1,238 @@
+package portstest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MediaRepositoryContract runs against the repository returned by Repository. Media created by the suite is deleted
+// after each test.
+type MediaRepositoryContract struct {
+	contract
+	Repository func() ports.MediaRepository
+
+	repo        ports.MediaRepository
+	createdKeys []string
+}
+
+func (s *MediaRepositoryContract) SetupTest() {
+	s.contract.SetupTest()
+	s.repo = s.Repository()
+	s.createdKeys = nil
+}
+
+func (s *MediaRepositoryContract) TearDownTest() {
+	if len(s.createdKeys) > 0 {
+		s.logIfError(s.repo.DeleteAll(s.ctx, s.createdKeys), "delete media")
+	}
+}
+
+func (s *MediaRepositoryContract) createMedia(content string) string {
+	key := s.generateHex(64)
+
+	s.Require().NoError(s.repo.CreateMedia(s.ctx, key, strings.NewReader(content), "text/plain"))
+	s.createdKeys = append(s.createdKeys, key)
+
+	return key
+}
+
+func (s *MediaRepositoryContract) TestCreateMediaAndGetURL() {
+	key := s.createMedia(s.generateAlphanumeric(100))
+
+	url, err := s.repo.GetMediaURL(s.ctx, key, time.Minute)
+	s.Require().NoError(err)
+	s.NotEmpty(url)
+}
+
+func (s *MediaRepositoryContract) TestCreateMediaOverwrites() {
+	key := s.createMedia(s.generateAlphanumeric(100))
+
+	content := s.generateAlphanumeric(100)
+	s.Require().NoError(s.repo.CreateMedia(s.ctx, key, strings.NewReader(content), "text/plain"))
+	s.Equal(content, s.readMedia(key))
+}
+
+func (s *MediaRepositoryContract) readMedia(key string) string {
+	return s.readMediaRange(key, nil)
+}
+
+func (s *MediaRepositoryContract) readMediaRange(key string, byteRange *model.ByteRange) string {
+	media, err := s.repo.OpenMedia(s.ctx, key, byteRange)
+	s.Require().NoError(err)
+	defer media.Close()
+
+	content, err := io.ReadAll(media)
+	s.Require().NoError(err)
+
+	return string(content)
+}
+
+func (s *MediaRepositoryContract) TestOpenMedia() {
+	content := s.generateAlphanumeric(100)
+	key := s.createMedia(content)
+
+	s.Equal(content, s.readMedia(key))
+}
+
+func (s *MediaRepositoryContract) TestOpenMissingMedia() {
+	_, err := s.repo.OpenMedia(s.ctx, s.generateHex(64), nil)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaRepositoryContract) TestOpenMediaRange() {
+	content := s.generateAlphanumeric(100)
+	key := s.createMedia(content)
+
+	s.Equal(content[10:30], s.readMediaRange(key, &model.ByteRange{Offset: 10, Length: 20}))
+	s.Equal(content[:1], s.readMediaRange(key, &model.ByteRange{Offset: 0, Length: 1}))
+}
+
+func (s *MediaRepositoryContract) TestOpenMediaRangeExceedingMedia() {
+	content := s.generateAlphanumeric(100)
+	key := s.createMedia(content)
+
+	s.Equal(content[90:], s.readMediaRange(key, &model.ByteRange{Offset: 90, Length: 20}))
+	s.Empty(s.readMediaRange(key, &model.ByteRange{Offset: 100, Length: 20}))
+	s.Empty(s.readMediaRange(key, &model.ByteRange{Offset: 10, Length: 0}))
+}
+
+func (s *MediaRepositoryContract) TestOpenMissingMediaRange() {
+	_, err := s.repo.OpenMedia(s.ctx, s.generateHex(64), &model.ByteRange{Offset: 0, Length: 0})
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaRepositoryContract) TestGetMediaAttributes() {
+	content := s.generateAlphanumeric(100)
+	key := s.createMedia(content)
+
+	attributes, err := s.repo.GetMediaAttributes(s.ctx, key)
+	s.Require().NoError(err)
+	s.Equal(int64(len(content)), attributes.Size)
+
+	// repositories may not know the checksum, but if they do, it must be right
+	if attributes.Checksum != "" {
+		checksum := sha256.Sum256([]byte(content))
+		s.Equal(hex.EncodeToString(checksum[:]), attributes.Checksum)
+	}
+}
+
+func (s *MediaRepositoryContract) TestGetMissingMediaAttributes() {
+	_, err := s.repo.GetMediaAttributes(s.ctx, s.generateHex(64))
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *MediaRepositoryContract) TestGetUploadURL() {
+	checksum := sha256.Sum256([]byte("content"))
+
+	uploadURL, err := s.repo.GetUploadURL(
+		s.ctx,
+		s.generateHex(64),
+		7,
+		hex.EncodeToString(checksum[:]),
+		"text/plain",
+		time.Minute,
+	)
+	s.Require().NoError(err)
+	s.NotEmpty(uploadURL.URL)
+}
+
+func (s *MediaRepositoryContract) TestMoveMedia() {
+	content := s.generateAlphanumeric(100)
+	key := s.createMedia(content)
+	toKey := s.generateHex(64)
+	s.createdKeys = append(s.createdKeys, toKey)
+
+	s.Require().NoError(s.repo.MoveMedia(s.ctx, key, toKey))
+	s.Equal(content, s.readMedia(toKey))
+
+	err := s.repo.MoveMedia(s.ctx, key, toKey)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *MediaRepositoryContract) TestMoveMediaReplaces() {
+	content := s.generateAlphanumeric(100)
+	key := s.createMedia(content)
+	toKey := s.createMedia(s.generateAlphanumeric(100))
+
+	s.Require().NoError(s.repo.MoveMedia(s.ctx, key, toKey))
+	s.Equal(content, s.readMedia(toKey))
+}
+
+func (s *MediaRepositoryContract) TestMoveMissingMedia() {
+	err := s.repo.MoveMedia(s.ctx, s.generateHex(64), s.generateHex(64))
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+// listAllMedia pages through all media, which includes media stored by others
+func (s *MediaRepositoryContract) listAllMedia(limit int) []model.StoredMedia {
+	var all []model.StoredMedia
+
+	page := model.PageRequest{Limit: limit}
+	for {
+		media, hasMore, err := s.repo.ListMediaPage(s.ctx, page)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(media), limit)
+
+		all = append(all, media...)
+		if !hasMore {
+			return all
+		}
+
+		page.After = media[len(media)-1].Key
+	}
+}
+
+func (s *MediaRepositoryContract) TestListMediaPage() {
+	created := map[string]string{}
+	for i := 0; i < 3; i++ {
+		content := s.generateAlphanumeric(10 + i)
+		created[s.createMedia(content)] = content
+	}
+
+	listed := map[string]model.StoredMedia{}
+	var keys []string
+	for _, media := range s.listAllMedia(2) {
+		listed[media.Key] = media
+		keys = append(keys, media.Key)
+	}
+
+	s.True(slices.IsSorted(keys), "expected keys in order, got %v", keys)
+
+	for key, content := range created {
+		s.Require().Contains(listed, key)
+		s.Equal(int64(len(content)), listed[key].Size)
+		s.WithinDuration(time.Now(), listed[key].LastModified, time.Minute)
+	}
+}
+
+func (s *MediaRepositoryContract) TestListMediaPageLeavesOutDeletedMedia() {
+	key := s.createMedia(s.generateAlphanumeric(100))
+	s.Require().NoError(s.repo.DeleteAll(s.ctx, []string{key}))
+
+	for _, media := range s.listAllMedia(100) {
+		s.NotEqual(key, media.Key)
+	}
+}
+
+func (s *MediaRepositoryContract) TestDeleteAll() {
+	key := s.createMedia(s.generateAlphanumeric(100))
+	key2 := s.createMedia(s.generateAlphanumeric(100))
+
+	s.NoError(s.repo.DeleteAll(s.ctx, []string{key, key2}))
+}
+
+func (s *MediaRepositoryContract) TestDeleteAllWithUnknownKeys() {
+	s.NoError(s.repo.DeleteAll(s.ctx, []string{s.generateHex(64)}))
+}
+
+func (s *MediaRepositoryContract) TestDeleteAllWithEmptyInput() {
+	s.NoError(s.repo.DeleteAll(s.ctx, []string{}))
+	s.NoError(s.repo.DeleteAll(s.ctx, nil))
+}
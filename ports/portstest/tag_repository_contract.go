@@ -0,0 +1,176 @@
+package portstest
+
+import (
+	"media-nexus/model"
+	"media-nexus/ports"
+	"slices"
+)
+
+// TagRepositoryContract runs against the repository returned by Repository. Tags created by the suite are deleted
+// after each test.
+type TagRepositoryContract struct {
+	contract
+	Repository func() ports.TagRepository
+
+	repo          ports.TagRepository
+	createdTagIDs []model.TagID
+}
+
+func (s *TagRepositoryContract) SetupTest() {
+	s.contract.SetupTest()
+	s.repo = s.Repository()
+	s.createdTagIDs = nil
+}
+
+func (s *TagRepositoryContract) TearDownTest() {
+	if len(s.createdTagIDs) > 0 {
+		s.logIfError(s.repo.DeleteTags(s.ctx, s.createdTagIDs), "delete tags")
+	}
+}
+
+func (s *TagRepositoryContract) createTag(name string) model.TagID {
+	tagID, err := s.repo.CreateTag(s.ctx, name)
+	s.Require().NoError(err)
+	s.Require().NotEmpty(tagID)
+
+	s.createdTagIDs = append(s.createdTagIDs, tagID)
+
+	return tagID
+}
+
+func (s *TagRepositoryContract) listTagIDs() []model.TagID {
+	tags, err := s.repo.ListTags(s.ctx)
+	s.Require().NoError(err)
+
+	ids := make([]model.TagID, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+
+	return ids
+}
+
+func (s *TagRepositoryContract) TestCreateTagIsIdempotent() {
+	name := s.generateAlphanumeric(10)
+
+	tagID := s.createTag(name)
+	tagID2 := s.createTag(name)
+
+	s.Equal(tagID, tagID2)
+}
+
+func (s *TagRepositoryContract) TestCreateTagWithDifferentNames() {
+	tagID := s.createTag(s.generateAlphanumeric(10))
+	tagID2 := s.createTag(s.generateAlphanumeric(10))
+
+	s.NotEqual(tagID, tagID2)
+}
+
+func (s *TagRepositoryContract) TestListTags() {
+	name := s.generateAlphanumeric(10)
+	tagID := s.createTag(name)
+
+	tags, err := s.repo.ListTags(s.ctx)
+	s.Require().NoError(err)
+	s.Contains(tags, &model.Tag{ID: tagID, Name: name})
+}
+
+func (s *TagRepositoryContract) TestListTagsPage() {
+	tagIDs := []model.TagID{
+		s.createTag(s.generateAlphanumeric(10)),
+		s.createTag(s.generateAlphanumeric(10)),
+		s.createTag(s.generateAlphanumeric(10)),
+	}
+
+	// other tests might have left tags behind, so page through all of them
+	var listed []model.TagID
+	page := model.PageRequest{Limit: 2}
+	for {
+		tags, hasMore, err := s.repo.ListTagsPage(s.ctx, page)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(tags), page.Limit)
+
+		for _, tag := range tags {
+			listed = append(listed, tag.ID)
+		}
+
+		if !hasMore {
+			break
+		}
+
+		s.Require().Len(tags, page.Limit)
+		page.After = tags[len(tags)-1].ID
+	}
+
+	s.True(slices.IsSorted(listed), "expected tags ordered by id")
+	s.Equal(len(listed), len(slices.Compact(slices.Clone(listed))), "expected no tag listed twice")
+
+	for _, tagID := range tagIDs {
+		s.Contains(listed, tagID)
+	}
+}
+
+func (s *TagRepositoryContract) TestListTagsPageAfterLastTag() {
+	tags, hasMore, err := s.repo.ListTagsPage(s.ctx, model.PageRequest{After: "~", Limit: 10})
+	s.Require().NoError(err)
+	s.Empty(tags)
+	s.False(hasMore)
+}
+
+func (s *TagRepositoryContract) TestDeleteTags() {
+	tagID := s.createTag(s.generateAlphanumeric(10))
+	tagID2 := s.createTag(s.generateAlphanumeric(10))
+
+	s.Require().NoError(s.repo.DeleteTags(s.ctx, []model.TagID{tagID}))
+
+	tagIDs := s.listTagIDs()
+	s.NotContains(tagIDs, tagID)
+	s.Contains(tagIDs, tagID2)
+}
+
+func (s *TagRepositoryContract) TestDeleteTagsWithUnknownIDs() {
+	s.NoError(s.repo.DeleteTags(s.ctx, []model.TagID{s.generateHex(64)}))
+}
+
+func (s *TagRepositoryContract) TestDeleteTagsWithEmptyInput() {
+	s.NoError(s.repo.DeleteTags(s.ctx, []model.TagID{}))
+	s.NoError(s.repo.DeleteTags(s.ctx, nil))
+}
+
+func (s *TagRepositoryContract) TestAllExist() {
+	tagIDs := []model.TagID{s.createTag(s.generateAlphanumeric(10)), s.createTag(s.generateAlphanumeric(10))}
+
+	allExist, err := s.repo.AllExist(s.ctx, tagIDs)
+	s.Require().NoError(err)
+	s.True(allExist)
+}
+
+func (s *TagRepositoryContract) TestAllExistWithUnknownID() {
+	tagIDs := []model.TagID{s.createTag(s.generateAlphanumeric(10)), s.generateHex(64)}
+
+	allExist, err := s.repo.AllExist(s.ctx, tagIDs)
+	s.Require().NoError(err)
+	s.False(allExist)
+}
+
+func (s *TagRepositoryContract) TestAllExistWithDuplicates() {
+	tagID := s.createTag(s.generateAlphanumeric(10))
+
+	allExist, err := s.repo.AllExist(s.ctx, []model.TagID{tagID, tagID})
+	s.Require().NoError(err)
+	s.True(allExist)
+
+	allExist, err = s.repo.AllExist(s.ctx, []model.TagID{s.generateHex(64), tagID, tagID})
+	s.Require().NoError(err)
+	s.False(allExist)
+}
+
+func (s *TagRepositoryContract) TestAllExistWithEmptyInput() {
+	allExist, err := s.repo.AllExist(s.ctx, []model.TagID{})
+	s.Require().NoError(err)
+	s.True(allExist)
+
+	allExist, err = s.repo.AllExist(s.ctx, nil)
+	s.Require().NoError(err)
+	s.True(allExist)
+}
@@ -0,0 +1,178 @@
+package portstest
+
+import (
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"time"
+)
+
+// UploadRepositoryContract runs against the repository returned by Repository. Uploads created by the suite are
+// deleted after each test.
+type UploadRepositoryContract struct {
+	contract
+	Repository func() ports.UploadRepository
+
+	repo       ports.UploadRepository
+	createdIDs []model.UploadID
+}
+
+func (s *UploadRepositoryContract) SetupTest() {
+	s.contract.SetupTest()
+	s.repo = s.Repository()
+	s.createdIDs = nil
+}
+
+func (s *UploadRepositoryContract) TearDownTest() {
+	for _, id := range s.createdIDs {
+		s.logIfError(s.repo.Delete(s.ctx, id), "delete upload")
+	}
+}
+
+func (s *UploadRepositoryContract) createUpload(expiresAt time.Time) *model.Upload {
+	upload := &model.Upload{
+		ID:        s.generateHex(32),
+		Name:      s.generateAlphanumeric(10),
+		TagIDs:    []model.TagID{s.generateHex(64)},
+		Length:    100,
+		ExpiresAt: expiresAt,
+	}
+
+	s.Require().NoError(s.repo.Create(s.ctx, upload))
+	s.createdIDs = append(s.createdIDs, upload.ID)
+
+	return upload
+}
+
+func (s *UploadRepositoryContract) get(id model.UploadID) *model.Upload {
+	upload, err := s.repo.Get(s.ctx, id)
+	s.Require().NoError(err)
+
+	return upload
+}
+
+func (s *UploadRepositoryContract) TestCreateAndGet() {
+	upload := s.createUpload(time.Now().Add(time.Hour))
+
+	stored := s.get(upload.ID)
+	s.Equal(upload.ID, stored.ID)
+	s.Equal(upload.Name, stored.Name)
+	s.Equal(upload.TagIDs, stored.TagIDs)
+	s.Equal(upload.Length, stored.Length)
+	s.Zero(stored.Offset)
+	s.Empty(stored.Chunks)
+	s.False(stored.Complete())
+	s.WithinDuration(upload.ExpiresAt, stored.ExpiresAt, time.Millisecond)
+}
+
+func (s *UploadRepositoryContract) TestCreateExisting() {
+	upload := s.createUpload(time.Now().Add(time.Hour))
+
+	err := s.repo.Create(s.ctx, upload)
+	s.True(errortypes.IsResourceAlreadyExists(err), "expected resource already exists, got %v", err)
+}
+
+func (s *UploadRepositoryContract) TestGetMissing() {
+	_, err := s.repo.Get(s.ctx, s.generateHex(32))
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *UploadRepositoryContract) TestAppendChunk() {
+	upload := s.createUpload(time.Now().Add(time.Hour))
+	expiresAt := time.Now().Add(2 * time.Hour)
+
+	chunk := model.UploadChunk{Key: s.generateHex(32), Size: 40}
+	chunk2 := model.UploadChunk{Key: s.generateHex(32), Size: 60}
+
+	s.Require().NoError(s.repo.AppendChunk(s.ctx, upload.ID, 0, chunk, expiresAt))
+	s.Require().NoError(s.repo.AppendChunk(s.ctx, upload.ID, 40, chunk2, expiresAt))
+
+	stored := s.get(upload.ID)
+	s.Equal(int64(100), stored.Offset)
+	s.Equal([]model.UploadChunk{chunk, chunk2}, stored.Chunks)
+	s.WithinDuration(expiresAt, stored.ExpiresAt, time.Millisecond)
+}
+
+func (s *UploadRepositoryContract) TestAppendChunkAtWrongOffset() {
+	upload := s.createUpload(time.Now().Add(time.Hour))
+	chunk := model.UploadChunk{Key: s.generateHex(32), Size: 40}
+
+	s.Require().NoError(s.repo.AppendChunk(s.ctx, upload.ID, 0, chunk, upload.ExpiresAt))
+
+	// e.g. a concurrent request appended at the same offset already
+	err := s.repo.AppendChunk(s.ctx, upload.ID, 0, chunk, upload.ExpiresAt)
+	s.True(errortypes.IsConflict(err), "expected conflict, got %v", err)
+
+	s.Equal(int64(40), s.get(upload.ID).Offset)
+}
+
+func (s *UploadRepositoryContract) TestAppendChunkToMissingUpload() {
+	chunk := model.UploadChunk{Key: s.generateHex(32), Size: 40}
+
+	err := s.repo.AppendChunk(s.ctx, s.generateHex(32), 0, chunk, time.Now())
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *UploadRepositoryContract) TestComplete() {
+	upload := s.createUpload(time.Now().Add(time.Hour))
+	chunk := model.UploadChunk{Key: s.generateHex(32), Size: 100}
+	s.Require().NoError(s.repo.AppendChunk(s.ctx, upload.ID, 0, chunk, upload.ExpiresAt))
+
+	mediaID := s.generateHex(64)
+	s.Require().NoError(s.repo.Complete(s.ctx, upload.ID, mediaID))
+
+	stored := s.get(upload.ID)
+	s.True(stored.Complete())
+	s.Equal(mediaID, stored.MediaID)
+	s.Equal(int64(100), stored.Offset)
+	s.Empty(stored.Chunks)
+}
+
+func (s *UploadRepositoryContract) TestCompleteMissingUpload() {
+	err := s.repo.Complete(s.ctx, s.generateHex(32), s.generateHex(64))
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *UploadRepositoryContract) TestFindExpired() {
+	expired := s.createUpload(time.Now().Add(-time.Minute))
+	active := s.createUpload(time.Now().Add(time.Hour))
+
+	found, err := s.repo.FindExpired(s.ctx, time.Now())
+	s.Require().NoError(err)
+
+	// other tests might have left expired uploads behind
+	var foundIDs []model.UploadID
+	for _, upload := range found {
+		foundIDs = append(foundIDs, upload.ID)
+	}
+
+	s.Contains(foundIDs, expired.ID)
+	s.NotContains(foundIDs, active.ID)
+}
+
+func (s *UploadRepositoryContract) TestFindAll() {
+	expired := s.createUpload(time.Now().Add(-time.Minute))
+	active := s.createUpload(time.Now().Add(time.Hour))
+
+	found, err := s.repo.FindAll(s.ctx)
+	s.Require().NoError(err)
+
+	var foundIDs []model.UploadID
+	for _, upload := range found {
+		foundIDs = append(foundIDs, upload.ID)
+	}
+
+	s.Contains(foundIDs, expired.ID)
+	s.Contains(foundIDs, active.ID)
+}
+
+func (s *UploadRepositoryContract) TestDelete() {
+	upload := s.createUpload(time.Now().Add(time.Hour))
+
+	s.Require().NoError(s.repo.Delete(s.ctx, upload.ID))
+
+	_, err := s.repo.Get(s.ctx, upload.ID)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+
+	s.NoError(s.repo.Delete(s.ctx, upload.ID))
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+)
+
+// SchemaMigrator evolves the stored documents to the schema the code expects. Repositories read documents of the
+// previous schema as well, until all of them are migrated.
+type SchemaMigrator interface {
+	// Migrate applies the migrations, that were not applied yet, in order. Only one instance migrates at a time, the
+	// others wait for it to finish.
+	Migrate(ctx context.Context) error
+}
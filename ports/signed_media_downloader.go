@@ -0,0 +1,12 @@
+package ports
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// SignedMediaDownloader serves media for URLs handed out by a MediaRepository that cannot presign URLs on its own.
+type SignedMediaDownloader interface {
+	OpenSignedMedia(ctx context.Context, key string, expires time.Time, signature string) (io.ReadCloser, error)
+}
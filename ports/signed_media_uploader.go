@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// SignedMediaUploader stores media for upload URLs handed out by a MediaRepository that cannot presign URLs on its
+// own.
+type SignedMediaUploader interface {
+	// CreateSignedMedia returns Conflict if there is media at key already, so an upload URL can't replace media.
+	CreateSignedMedia(ctx context.Context, key string, expires time.Time, signature string, file io.Reader) error
+}
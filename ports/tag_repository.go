@@ -8,6 +8,8 @@ import (
 type TagRepository interface {
 	CreateTag(ctx context.Context, name string) (model.TagID, error)
 	ListTags(ctx context.Context) ([]*model.Tag, error)
+	// ListTagsPage lists tags ordered by ID. The bool tells whether more tags follow the page.
+	ListTagsPage(ctx context.Context, page model.PageRequest) ([]*model.Tag, bool, error)
 	DeleteTags(ctx context.Context, ids []model.TagID) error
 	AllExist(ctx context.Context, ids []model.TagID) (bool, error)
 }
@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+	"media-nexus/model"
+	"time"
+)
+
+type UploadRepository interface {
+	Create(ctx context.Context, upload *model.Upload) error
+	// Get returns ResourceNotFound for unknown uploads.
+	Get(ctx context.Context, id model.UploadID) (*model.Upload, error)
+	// AppendChunk appends the chunk only if the upload is still at offset and returns Conflict otherwise. This way
+	// concurrent appends can't corrupt the upload.
+	AppendChunk(ctx context.Context, id model.UploadID, offset int64, chunk model.UploadChunk, expiresAt time.Time) error
+	// Complete records the media the upload became and forgets its chunks.
+	Complete(ctx context.Context, id model.UploadID, mediaID model.MediaID) error
+	FindExpired(ctx context.Context, before time.Time) ([]*model.Upload, error)
+	// FindAll returns all uploads. There are only few at a time, because they expire.
+	FindAll(ctx context.Context) ([]*model.Upload, error)
+	Delete(ctx context.Context, id model.UploadID) error
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck is a checker and whether the dependency it checks is critical
+type HealthCheck struct {
+	Checker ports.HealthChecker
+	// Critical dependencies are needed to serve requests. If one fails, the app isn't ready.
+	Critical bool
+}
+
+// HealthService aggregates the health checks for the readiness and startup probes.
+type HealthService interface {
+	// Ready runs all checks at once, each with its own timeout. The report is cached, so frequent probes don't put load
+	// on the dependencies.
+	Ready(ctx context.Context) *model.HealthReport
+	// Started tells whether the storage is set up, e.g. whether the indices are created
+	Started() bool
+	// SetStarted is called once the storage is set up
+	SetStarted()
+}
+
+func NewHealthService(checks []HealthCheck, timeout time.Duration, cacheLifetime time.Duration) HealthService {
+	return &healthService{checks: checks, timeout: timeout, cacheLifetime: cacheLifetime}
+}
+
+type healthService struct {
+	checks        []HealthCheck
+	timeout       time.Duration
+	cacheLifetime time.Duration
+	started       atomic.Bool
+
+	// mutex is held while checking, so concurrent probes wait for the same report instead of checking again
+	mutex  sync.Mutex
+	report *model.HealthReport
+}
+
+func (s *healthService) Ready(ctx context.Context) *model.HealthReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.report != nil && time.Since(s.report.CheckedAt) < s.cacheLifetime {
+		return s.report
+	}
+
+	// the report is shared, so a probe giving up mustn't fail the checks for the others
+	ctx = context.WithoutCancel(ctx)
+
+	report := &model.HealthReport{
+		Healthy:   true,
+		CheckedAt: time.Now(),
+		Checks:    make([]model.HealthCheckResult, len(s.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = s.check(ctx, check)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Critical && !result.Healthy {
+			report.Healthy = false
+		}
+	}
+
+	s.report = report
+
+	return report
+}
+
+func (s *healthService) check(ctx context.Context, check HealthCheck) model.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := check.Checker.Check(ctx)
+
+	result := model.HealthCheckResult{
+		Name:     check.Checker.Name(),
+		Critical: check.Critical,
+		Healthy:  err == nil,
+		Duration: time.Since(started),
+	}
+
+	if err != nil {
+		util.Logger(ctx).Warnf("health check of %v failed: %v", result.Name, err)
+	}
+
+	return result
+}
+
+func (s *healthService) Started() bool {
+	return s.started.Load()
+}
+
+func (s *healthService) SetStarted() {
+	s.started.Store(true)
+}
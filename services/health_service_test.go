@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"media-nexus/logger"
+	"media-nexus/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type healthServiceTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func TestHealthService(t *testing.T) {
+	suite.Run(t, &healthServiceTestSuite{})
+}
+
+func (s *healthServiceTestSuite) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("test"))
+}
+
+// testHealthChecker fails with err, or blocks until ctx is done, if block is set
+type testHealthChecker struct {
+	name   string
+	err    error
+	block  bool
+	checks int
+}
+
+func (c *testHealthChecker) Name() string {
+	return c.name
+}
+
+func (c *testHealthChecker) Check(ctx context.Context) error {
+	c.checks++
+
+	if c.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	return c.err
+}
+
+func (s *healthServiceTestSuite) TestHealthy() {
+	service := NewHealthService([]HealthCheck{
+		{&testHealthChecker{name: "a"}, true},
+		{&testHealthChecker{name: "b", err: errors.New("down")}, false},
+	}, time.Second, 0)
+
+	report := service.Ready(s.ctx)
+	s.True(report.Healthy)
+	s.Require().Len(report.Checks, 2)
+	s.Equal("a", report.Checks[0].Name)
+	s.True(report.Checks[0].Healthy)
+	s.False(report.Checks[1].Healthy)
+}
+
+func (s *healthServiceTestSuite) TestUnhealthyIfCriticalCheckFails() {
+	service := NewHealthService([]HealthCheck{
+		{&testHealthChecker{name: "a", err: errors.New("down")}, true},
+	}, time.Second, 0)
+
+	s.False(service.Ready(s.ctx).Healthy)
+}
+
+func (s *healthServiceTestSuite) TestTimeout() {
+	service := NewHealthService([]HealthCheck{
+		{&testHealthChecker{name: "a", block: true}, true},
+		{&testHealthChecker{name: "b", block: true}, true},
+	}, 10*time.Millisecond, 0)
+
+	started := time.Now()
+	report := service.Ready(s.ctx)
+	s.False(report.Healthy)
+	s.False(report.Checks[0].Healthy)
+
+	// the checks run at once
+	s.Less(time.Since(started), time.Second)
+}
+
+func (s *healthServiceTestSuite) TestCache() {
+	checker := &testHealthChecker{name: "a"}
+	service := NewHealthService([]HealthCheck{{checker, true}}, time.Second, time.Hour)
+
+	first := service.Ready(s.ctx)
+	s.Equal(first, service.Ready(s.ctx))
+	s.Equal(1, checker.checks)
+
+	service.(*healthService).cacheLifetime = 0
+	service.Ready(s.ctx)
+	s.Equal(2, checker.checks)
+}
+
+func (s *healthServiceTestSuite) TestStarted() {
+	service := NewHealthService(nil, time.Second, 0)
+	s.False(service.Started())
+
+	service.SetStarted()
+	s.True(service.Started())
+
+	report := service.Ready(s.ctx)
+	s.True(report.Healthy)
+	s.Empty(report.Checks)
+}
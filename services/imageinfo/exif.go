@@ -0,0 +1,215 @@
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"media-nexus/model"
+	"strings"
+	"time"
+)
+
+// EXIF tags we read. IFD0 points to the EXIF and the GPS IFD.
+const (
+	tagCameraModel      = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+)
+
+// TIFF field types we read
+const (
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// exifEntry is an entry of an image file directory (IFD)
+type exifEntry struct {
+	fieldType uint16
+	count     int
+	value     []byte
+}
+
+// tiff is the TIFF structure EXIF metadata is stored in. All offsets are relative to its start.
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// readExif fills info with what it finds in the EXIF metadata
+func readExif(data []byte, info *model.ImageInfo) {
+	t, ok := newTIFF(data)
+	if !ok {
+		return
+	}
+
+	ifd0 := t.readIFD(int(t.order.Uint32(data[4:])))
+
+	if orientation, ok := t.uint(ifd0[tagOrientation]); ok && orientation >= 1 && orientation <= 8 {
+		info.Orientation = int(orientation)
+	}
+
+	info.CameraModel = t.string(ifd0[tagCameraModel])
+
+	captureTime := t.string(ifd0[tagDateTime])
+	if offset, ok := t.uint(ifd0[tagExifIFD]); ok {
+		if original := t.string(t.readIFD(int(offset))[tagDateTimeOriginal]); original != "" {
+			captureTime = original
+		}
+	}
+
+	if parsed, err := time.Parse(exifTimeLayout, captureTime); err == nil {
+		info.CaptureTime = &parsed
+	}
+
+	if offset, ok := t.uint(ifd0[tagGPSIFD]); ok {
+		info.GPS = t.gpsCoordinates(t.readIFD(int(offset)))
+	}
+}
+
+// ReadOrientation returns the orientation from 1 to 8 found in the EXIF metadata or 0, if there is none. data is
+// the TIFF structure without the "Exif" header.
+func ReadOrientation(data []byte) int {
+	var info model.ImageInfo
+	readExif(data, &info)
+	return info.Orientation
+}
+
+func newTIFF(data []byte) (*tiff, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, false
+	}
+
+	if order.Uint16(data[2:]) != 42 {
+		return nil, false
+	}
+
+	return &tiff{data, order}, true
+}
+
+// readIFD reads the entries of the IFD at offset. Entries pointing outside the data are left out.
+func (t *tiff) readIFD(offset int) map[uint16]exifEntry {
+	entries := map[uint16]exifEntry{}
+	if offset < 0 || offset+2 > len(t.data) {
+		return entries
+	}
+
+	count := int(t.order.Uint16(t.data[offset:]))
+	for i := 0; i < count; i++ {
+		pos := offset + 2 + i*12
+		if pos+12 > len(t.data) {
+			break
+		}
+
+		tag := t.order.Uint16(t.data[pos:])
+		fieldType := t.order.Uint16(t.data[pos+2:])
+		valueCount := int(t.order.Uint32(t.data[pos+4:]))
+
+		typeSize, ok := typeSizes[fieldType]
+		if !ok || valueCount < 0 || valueCount > len(t.data) {
+			continue
+		}
+
+		// values of up to 4 bytes are stored in the entry itself, else the entry holds their offset
+		size := typeSize * valueCount
+		valuePos := pos + 8
+		if size > 4 {
+			valuePos = int(t.order.Uint32(t.data[pos+8:]))
+		}
+
+		if valuePos < 0 || valuePos+size > len(t.data) {
+			continue
+		}
+
+		entries[tag] = exifEntry{fieldType, valueCount, t.data[valuePos : valuePos+size]}
+	}
+
+	return entries
+}
+
+func (t *tiff) uint(entry exifEntry) (uint32, bool) {
+	if entry.count < 1 {
+		return 0, false
+	}
+
+	switch entry.fieldType {
+	case typeShort:
+		return uint32(t.order.Uint16(entry.value)), true
+	case typeLong:
+		return t.order.Uint32(entry.value), true
+	}
+
+	return 0, false
+}
+
+func (t *tiff) string(entry exifEntry) string {
+	if entry.fieldType != typeASCII {
+		return ""
+	}
+
+	value, _, _ := bytes.Cut(entry.value, []byte{0})
+	return strings.TrimSpace(string(value))
+}
+
+// degrees reads degrees, minutes and seconds
+func (t *tiff) degrees(entry exifEntry) (float64, bool) {
+	if entry.fieldType != typeRational || entry.count != 3 {
+		return 0, false
+	}
+
+	degrees := 0.0
+	for i, unit := range []float64{1, 60, 3600} {
+		numerator := t.order.Uint32(entry.value[i*8:])
+		denominator := t.order.Uint32(entry.value[i*8+4:])
+		if denominator == 0 {
+			return 0, false
+		}
+
+		degrees += float64(numerator) / float64(denominator) / unit
+	}
+
+	return degrees, true
+}
+
+func (t *tiff) gpsCoordinates(gps map[uint16]exifEntry) *model.GPSCoordinates {
+	latitude, ok := t.degrees(gps[tagGPSLatitude])
+	if !ok {
+		return nil
+	}
+
+	longitude, ok := t.degrees(gps[tagGPSLongitude])
+	if !ok {
+		return nil
+	}
+
+	if t.string(gps[tagGPSLatitudeRef]) == "S" {
+		latitude = -latitude
+	}
+
+	if t.string(gps[tagGPSLongitudeRef]) == "W" {
+		longitude = -longitude
+	}
+
+	return &model.GPSCoordinates{Latitude: latitude, Longitude: longitude}
+}
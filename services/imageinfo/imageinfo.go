@@ -0,0 +1,129 @@
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"media-nexus/model"
+
+	// register the decoders for DecodeConfig
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// HeadSize is how many bytes at the start of an image Extract gets at most. The headers of nearly all images fit, even
+// with large EXIF segments or color profiles in front of the dimensions.
+const HeadSize = 256 << 10
+
+// Extract reads the dimensions and the EXIF metadata from the headers of PNG, JPEG, GIF and WebP images. head is the
+// start of the image, it doesn't need to be complete. Returns nil, if the dimensions can't be read. Broken EXIF
+// metadata is left out.
+func Extract(head []byte) *model.ImageInfo {
+	config, format, err := image.DecodeConfig(bytes.NewReader(head))
+	if err != nil {
+		return nil
+	}
+
+	info := &model.ImageInfo{Width: config.Width, Height: config.Height}
+
+	var exif []byte
+	switch format {
+	case "jpeg":
+		exif = findJPEGExif(head)
+	case "png":
+		exif = findPNGExif(head)
+	case "webp":
+		exif = findWebPExif(head)
+	}
+
+	if exif != nil {
+		readExif(exif, info)
+	}
+
+	return info
+}
+
+// ExifHeader precedes the EXIF metadata in JPEG segments
+var ExifHeader = []byte("Exif\x00\x00")
+
+// findJPEGExif looks for the APP1 segment with the EXIF metadata. It comes before the image data.
+func findJPEGExif(data []byte) []byte {
+	const (
+		markerStartOfScan = 0xda
+		markerApp1        = 0xe1
+	)
+
+	// skip the start of image marker
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xff {
+		marker := data[pos+1]
+		if marker == markerStartOfScan {
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+
+		segment := data[pos+4 : end]
+		if marker == markerApp1 && bytes.HasPrefix(segment, ExifHeader) {
+			return segment[len(ExifHeader):]
+		}
+
+		pos = end
+	}
+
+	return nil
+}
+
+// findPNGExif looks for the eXIf chunk. It has to come before the image data.
+func findPNGExif(data []byte) []byte {
+	// skip the signature
+	pos := 8
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 8 + length
+		if length < 0 || end > len(data) || chunkType == "IDAT" {
+			return nil
+		}
+
+		if chunkType == "eXIf" {
+			return data[pos+8 : end]
+		}
+
+		// skip the CRC
+		pos = end + 4
+	}
+
+	return nil
+}
+
+// findWebPExif looks for the EXIF chunk of extended WebP images.
+func findWebPExif(data []byte) []byte {
+	// skip RIFF header, size and WEBP
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkType := string(data[pos : pos+4])
+		length := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+
+		if chunkType == "EXIF" {
+			// some encoders keep the header of the JPEG segment
+			return bytes.TrimPrefix(data[pos+8:end], ExifHeader)
+		}
+
+		// chunks are padded to an even length
+		pos = end + end%2
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type imageInfoTestSuite struct {
+	suite.Suite
+}
+
+func TestImageInfo(t *testing.T) {
+	suite.Run(t, &imageInfoTestSuite{})
+}
+
+type ifdEntry struct {
+	tag       uint16
+	fieldType uint16
+	count     uint32
+	value     []byte
+}
+
+func ifdSize(entries []ifdEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, entry := range entries {
+		if len(entry.value) > 4 {
+			size += len(entry.value)
+		}
+	}
+
+	return size
+}
+
+// writeIFD writes the IFD at offset, followed by the values not fitting into the entries
+func writeIFD(buf *bytes.Buffer, offset int, entries []ifdEntry) {
+	order := binary.BigEndian
+	valuePos := offset + 2 + 12*len(entries) + 4
+
+	var values []byte
+	_ = binary.Write(buf, order, uint16(len(entries)))
+	for _, entry := range entries {
+		_ = binary.Write(buf, order, entry.tag)
+		_ = binary.Write(buf, order, entry.fieldType)
+		_ = binary.Write(buf, order, entry.count)
+
+		if len(entry.value) > 4 {
+			_ = binary.Write(buf, order, uint32(valuePos+len(values)))
+			values = append(values, entry.value...)
+		} else {
+			buf.Write(append(entry.value, make([]byte, 4-len(entry.value))...))
+		}
+	}
+
+	_ = binary.Write(buf, order, uint32(0))
+	buf.Write(values)
+}
+
+func uint32Value(value int) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(value))
+}
+
+func rationals(values ...uint32) []byte {
+	var result []byte
+	for i := 0; i < len(values); i += 2 {
+		result = binary.BigEndian.AppendUint32(result, values[i])
+		result = binary.BigEndian.AppendUint32(result, values[i+1])
+	}
+
+	return result
+}
+
+// buildExif builds big endian EXIF metadata of a Pixel 7 picture rotated by 90°, taken on 2024-05-01 in the south
+// west.
+func buildExif() []byte {
+	exifIFD := []ifdEntry{
+		{tagDateTimeOriginal, typeASCII, 20, []byte("2024:05:01 12:30:45\x00")},
+	}
+
+	gpsIFD := []ifdEntry{
+		{tagGPSLatitudeRef, typeASCII, 2, []byte("S\x00")},
+		{tagGPSLatitude, typeRational, 3, rationals(33, 1, 30, 1, 0, 1)},
+		{tagGPSLongitudeRef, typeASCII, 2, []byte("W\x00")},
+		{tagGPSLongitude, typeRational, 3, rationals(70, 1, 45, 1, 36, 1)},
+	}
+
+	ifd0 := []ifdEntry{
+		{tagCameraModel, typeASCII, 8, []byte("Pixel 7\x00")},
+		{tagOrientation, typeShort, 1, []byte{0, 6}},
+		{tagExifIFD, typeLong, 1, nil},
+		{tagGPSIFD, typeLong, 1, nil},
+	}
+
+	exifOffset := 8 + ifdSize(ifd0)
+	gpsOffset := exifOffset + ifdSize(exifIFD)
+	ifd0[2].value = uint32Value(exifOffset)
+	ifd0[3].value = uint32Value(gpsOffset)
+
+	var buf bytes.Buffer
+	buf.WriteString("MM")
+	_ = binary.Write(&buf, binary.BigEndian, uint16(42))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(8))
+	writeIFD(&buf, 8, ifd0)
+	writeIFD(&buf, exifOffset, exifIFD)
+	writeIFD(&buf, gpsOffset, gpsIFD)
+
+	return buf.Bytes()
+}
+
+func (s *imageInfoTestSuite) encodeJPEG(exif []byte) []byte {
+	var buf bytes.Buffer
+	s.Require().NoError(jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 40, 30)), nil))
+
+	if exif == nil {
+		return buf.Bytes()
+	}
+
+	encoded := buf.Bytes()
+
+	// the APP1 segment goes right after the start of image marker
+	result := append([]byte{}, encoded[:2]...)
+	result = append(result, 0xff, 0xe1)
+	result = binary.BigEndian.AppendUint16(result, uint16(len(ExifHeader)+len(exif)+2))
+	result = append(result, ExifHeader...)
+	result = append(result, exif...)
+
+	return append(result, encoded[2:]...)
+}
+
+func (s *imageInfoTestSuite) TestExtractJPEGWithExif() {
+	info := Extract(s.encodeJPEG(buildExif()))
+	s.Require().NotNil(info)
+
+	s.Equal(40, info.Width)
+	s.Equal(30, info.Height)
+	s.Equal(6, info.Orientation)
+	s.Equal("Pixel 7", info.CameraModel)
+
+	s.Require().NotNil(info.CaptureTime)
+	s.Equal(time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC), *info.CaptureTime)
+
+	s.Require().NotNil(info.GPS)
+	s.InDelta(-33.5, info.GPS.Latitude, 1e-9)
+	s.InDelta(-70.76, info.GPS.Longitude, 1e-9)
+}
+
+func (s *imageInfoTestSuite) TestExtractJPEGWithoutExif() {
+	info := Extract(s.encodeJPEG(nil))
+	s.Require().NotNil(info)
+
+	s.Equal(40, info.Width)
+	s.Equal(30, info.Height)
+	s.Zero(info.Orientation)
+	s.Nil(info.CaptureTime)
+	s.Nil(info.GPS)
+}
+
+func (s *imageInfoTestSuite) TestExtractJPEGWithBrokenExif() {
+	exif := buildExif()
+
+	info := Extract(s.encodeJPEG(exif[:20]))
+	s.Require().NotNil(info)
+	s.Equal(40, info.Width)
+}
+
+func (s *imageInfoTestSuite) TestExtractPNG() {
+	var buf bytes.Buffer
+	s.Require().NoError(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 7, 5))))
+
+	info := Extract(buf.Bytes())
+	s.Require().NotNil(info)
+	s.Equal(7, info.Width)
+	s.Equal(5, info.Height)
+}
+
+func (s *imageInfoTestSuite) TestExtractGIF() {
+	var buf bytes.Buffer
+	paletted := image.NewPaletted(image.Rect(0, 0, 3, 9), color.Palette{color.Black, color.White})
+	s.Require().NoError(gif.Encode(&buf, paletted, nil))
+
+	info := Extract(buf.Bytes())
+	s.Require().NotNil(info)
+	s.Equal(3, info.Width)
+	s.Equal(9, info.Height)
+}
+
+func (s *imageInfoTestSuite) TestExtractTruncatedImage() {
+	encoded := s.encodeJPEG(buildExif())
+
+	// the dimensions come after the EXIF metadata
+	info := Extract(encoded[:len(ExifHeader)+100])
+	s.Nil(info)
+}
+
+func (s *imageInfoTestSuite) TestExtractNoImage() {
+	s.Nil(Extract([]byte("content")))
+}
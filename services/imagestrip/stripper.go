@@ -0,0 +1,379 @@
+package imagestrip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"media-nexus/errortypes"
+	"media-nexus/services/imageinfo"
+)
+
+const (
+	mimeTypeJPEG = "image/jpeg"
+	mimeTypePNG  = "image/png"
+)
+
+// Stripper removes metadata like EXIF, XMP, IPTC and comments from JPEG and PNG images while they are streamed. The
+// image data is copied as it is, so images don't lose quality and streaming needs no more memory than a segment.
+// Anything following the image, like the secondary images of MPF or gain maps appended to JPEGs, is dropped, since it
+// carries metadata of its own.
+type Stripper struct {
+	// keepOrientation keeps the EXIF orientation, so viewers still rotate the image correctly
+	keepOrientation bool
+}
+
+func NewStripper(keepOrientation bool) *Stripper {
+	return &Stripper{keepOrientation}
+}
+
+func (s *Stripper) Applies(mimeType string) bool {
+	return mimeType == mimeTypeJPEG || mimeType == mimeTypePNG
+}
+
+// Transform writes the image read from r without its metadata to w. Returns BadUserInput if the image is broken.
+// Other media is copied as it is.
+func (s *Stripper) Transform(w io.Writer, r io.Reader, mimeType string) error {
+	switch mimeType {
+	case mimeTypeJPEG:
+		return s.stripJPEG(w, bufio.NewReader(r))
+	case mimeTypePNG:
+		return s.stripPNG(w, r)
+	}
+
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// readFull reports images ending too early as bad input
+func readFull(r io.Reader, buf []byte, format string) error {
+	_, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errortypes.NewBadUserInputf("invalid %v: truncated", format)
+	}
+
+	return err
+}
+
+func copyN(w io.Writer, r io.Reader, n int64, format string) error {
+	_, err := io.CopyN(w, r, n)
+	if errors.Is(err, io.EOF) {
+		return errortypes.NewBadUserInputf("invalid %v: truncated", format)
+	}
+
+	return err
+}
+
+// orientationExif creates EXIF metadata holding the orientation only
+func orientationExif(orientation int) []byte {
+	const tagOrientation, typeShort = 0x0112, 3
+
+	order := binary.BigEndian
+	exif := []byte("MM")
+	exif = order.AppendUint16(exif, 42)
+	// IFD0 follows right after the header
+	exif = order.AppendUint32(exif, 8)
+	exif = order.AppendUint16(exif, 1)
+	exif = order.AppendUint16(exif, tagOrientation)
+	exif = order.AppendUint16(exif, typeShort)
+	exif = order.AppendUint32(exif, 1)
+	// values are left aligned in the 4 bytes of the entry
+	exif = order.AppendUint16(exif, uint16(orientation))
+	exif = order.AppendUint16(exif, 0)
+	// no further IFD
+	return order.AppendUint32(exif, 0)
+}
+
+// JPEG markers we look at
+const (
+	markerStartOfImage = 0xd8
+	markerEndOfImage   = 0xd9
+	markerStartOfScan  = 0xda
+	markerApp0         = 0xe0
+	markerApp1         = 0xe1
+	markerApp2         = 0xe2
+	markerApp14        = 0xee
+	markerApp15        = 0xef
+	markerComment      = 0xfe
+)
+
+// mpfHeader starts APP2 segments indexing the images following the primary one, which are dropped
+var mpfHeader = []byte("MPF\x00")
+
+// keepJPEGSegment keeps everything needed to display the image: JFIF (APP0), ICC profiles (APP2 except MPF), Adobe
+// color transforms (APP14) and all segments that aren't application segments or comments. payload is the start of
+// the segment.
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	if marker == markerComment {
+		return false
+	}
+
+	if marker < markerApp0 || marker > markerApp15 {
+		return true
+	}
+
+	if marker == markerApp2 {
+		return !bytes.HasPrefix(payload, mpfHeader)
+	}
+
+	return marker == markerApp0 || marker == markerApp14
+}
+
+// isStandaloneJPEGMarker tells whether the marker has no segment following
+func isStandaloneJPEGMarker(marker byte) bool {
+	return marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7)
+}
+
+// stripJPEG goes through the segments up to the end of image. The image data of each scan is copied as it is.
+// Progressive images have segments between their scans, which are stripped as well.
+func (s *Stripper) stripJPEG(w io.Writer, r *bufio.Reader) error {
+	soi := make([]byte, 2)
+	if err := readFull(r, soi, "JPEG"); err != nil {
+		return err
+	}
+
+	if soi[0] != 0xff || soi[1] != markerStartOfImage {
+		return errortypes.NewBadUserInput("invalid JPEG: missing start of image")
+	}
+
+	if _, err := w.Write(soi); err != nil {
+		return err
+	}
+
+	marker, err := readJPEGMarker(r)
+
+	for {
+		if err != nil {
+			return err
+		}
+
+		if marker == markerEndOfImage {
+			// what follows isn't part of the image
+			_, err := w.Write([]byte{0xff, marker})
+			return err
+		}
+
+		if isStandaloneJPEGMarker(marker) {
+			if _, err := w.Write([]byte{0xff, marker}); err != nil {
+				return err
+			}
+		} else if err := s.stripJPEGSegment(w, r, marker); err != nil {
+			return err
+		}
+
+		if marker != markerStartOfScan {
+			marker, err = readJPEGMarker(r)
+			continue
+		}
+
+		marker, err = copyJPEGScan(w, r)
+		if errors.Is(err, io.EOF) {
+			// images missing the end of image are common enough to be accepted
+			return nil
+		}
+	}
+}
+
+// copyJPEGScan copies the image data following the start of scan and returns the marker ending it. Returns io.EOF if
+// the image ends first.
+func copyJPEGScan(w io.Writer, r *bufio.Reader) (byte, error) {
+	for {
+		data, err := r.ReadSlice(0xff)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			if _, err := w.Write(data); err != nil {
+				return 0, err
+			}
+
+			continue
+		} else if err != nil {
+			if _, err := w.Write(data); err != nil {
+				return 0, err
+			}
+
+			return 0, io.EOF
+		}
+
+		// the 0xff is written, once it turns out to be no marker
+		if _, err := w.Write(data[:len(data)-1]); err != nil {
+			return 0, err
+		}
+
+		next, err := r.ReadByte()
+		for err == nil && next == 0xff {
+			next, err = r.ReadByte()
+		}
+
+		if err != nil {
+			return 0, io.EOF
+		}
+
+		// stuffed zero bytes and restart markers are part of the image data
+		if next == 0x00 || (next >= 0xd0 && next <= 0xd7) {
+			if _, err := w.Write([]byte{0xff, next}); err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		return next, nil
+	}
+}
+
+// readJPEGMarker skips the fill bytes in front of the marker
+func readJPEGMarker(r *bufio.Reader) (byte, error) {
+	b := []byte{0}
+	if err := readFull(r, b, "JPEG"); err != nil {
+		return 0, err
+	}
+
+	if b[0] != 0xff {
+		return 0, errortypes.NewBadUserInputf("invalid JPEG: expected marker, got %#x", b[0])
+	}
+
+	for b[0] == 0xff {
+		if err := readFull(r, b, "JPEG"); err != nil {
+			return 0, err
+		}
+	}
+
+	return b[0], nil
+}
+
+func (s *Stripper) stripJPEGSegment(w io.Writer, r *bufio.Reader, marker byte) error {
+	header := make([]byte, 2)
+	if err := readFull(r, header, "JPEG"); err != nil {
+		return err
+	}
+
+	// the length includes its own 2 bytes
+	length := int64(binary.BigEndian.Uint16(header))
+	if length < 2 {
+		return errortypes.NewBadUserInputf("invalid JPEG: segment length %v", length)
+	}
+
+	// a short segment is reported as truncated when copying it
+	start, _ := r.Peek(min(len(mpfHeader), int(length-2)))
+
+	if keepJPEGSegment(marker, start) {
+		if _, err := w.Write(append([]byte{0xff, marker}, header...)); err != nil {
+			return err
+		}
+
+		return copyN(w, r, length-2, "JPEG")
+	}
+
+	if marker != markerApp1 || !s.keepOrientation {
+		return copyN(io.Discard, r, length-2, "JPEG")
+	}
+
+	payload := make([]byte, length-2)
+	if err := readFull(r, payload, "JPEG"); err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(payload, imageinfo.ExifHeader) {
+		return nil
+	}
+
+	orientation := imageinfo.ReadOrientation(payload[len(imageinfo.ExifHeader):])
+	if orientation == 0 {
+		return nil
+	}
+
+	exif := append(append([]byte{}, imageinfo.ExifHeader...), orientationExif(orientation)...)
+	segment := binary.BigEndian.AppendUint16([]byte{0xff, markerApp1}, uint16(len(exif)+2))
+
+	_, err := w.Write(append(segment, exif...))
+	return err
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are left out. eXIf gets replaced by one with the orientation only, if it is kept.
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNG copies the chunks up to IEND, except for the ones holding metadata. Those may come after the image data
+// as well.
+func (s *Stripper) stripPNG(w io.Writer, r io.Reader) error {
+	signature := make([]byte, len(pngSignature))
+	if err := readFull(r, signature, "PNG"); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(signature, pngSignature) {
+		return errortypes.NewBadUserInput("invalid PNG: missing signature")
+	}
+
+	if _, err := w.Write(signature); err != nil {
+		return err
+	}
+
+	for {
+		header := make([]byte, 8)
+		if err := readFull(r, header, "PNG"); err != nil {
+			return err
+		}
+
+		length := int64(binary.BigEndian.Uint32(header))
+		chunkType := string(header[4:])
+		if length > 1<<31-1 {
+			return errortypes.NewBadUserInputf("invalid PNG: chunk length %v", length)
+		}
+
+		if chunkType == "eXIf" && s.keepOrientation {
+			if err := s.stripPNGExif(w, r, length); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// the data is followed by a CRC of 4 bytes
+		if pngMetadataChunks[chunkType] {
+			if err := copyN(io.Discard, r, length+4, "PNG"); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+
+		if err := copyN(w, r, length+4, "PNG"); err != nil {
+			return err
+		}
+
+		if chunkType == "IEND" {
+			return nil
+		}
+	}
+}
+
+func (s *Stripper) stripPNGExif(w io.Writer, r io.Reader, length int64) error {
+	data := make([]byte, length+4)
+	if err := readFull(r, data, "PNG"); err != nil {
+		return err
+	}
+
+	orientation := imageinfo.ReadOrientation(data[:length])
+	if orientation == 0 {
+		return nil
+	}
+
+	return writePNGChunk(w, "eXIf", orientationExif(orientation))
+}
+
+func writePNGChunk(w io.Writer, chunkType string, data []byte) error {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	_, err := w.Write(chunk)
+	return err
+}
@@ -0,0 +1,226 @@
+package imagestrip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"media-nexus/errortypes"
+	"media-nexus/services/imageinfo"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type stripperTestSuite struct {
+	suite.Suite
+}
+
+func TestStripper(t *testing.T) {
+	suite.Run(t, &stripperTestSuite{})
+}
+
+// cameraExif builds EXIF metadata with an orientation and the camera model, which has to be stripped
+func cameraExif() []byte {
+	const (
+		tagCameraModel = 0x0110
+		tagOrientation = 0x0112
+	)
+
+	order := binary.BigEndian
+	exif := []byte("MM")
+	exif = order.AppendUint16(exif, 42)
+	exif = order.AppendUint32(exif, 8)
+	exif = order.AppendUint16(exif, 2)
+
+	// the model doesn't fit into the entry, it follows the IFD
+	exif = order.AppendUint16(exif, tagCameraModel)
+	exif = order.AppendUint16(exif, 2)
+	exif = order.AppendUint32(exif, 8)
+	exif = order.AppendUint32(exif, 8+2+2*12+4)
+
+	exif = order.AppendUint16(exif, tagOrientation)
+	exif = order.AppendUint16(exif, 3)
+	exif = order.AppendUint32(exif, 1)
+	exif = order.AppendUint32(exif, 6<<16)
+
+	exif = order.AppendUint32(exif, 0)
+
+	return append(exif, "Pixel 7\x00"...)
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := binary.BigEndian.AppendUint16([]byte{0xff, marker}, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func (s *stripperTestSuite) encodeJPEG() []byte {
+	var buf bytes.Buffer
+	s.Require().NoError(jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 40, 30)), nil))
+
+	encoded := buf.Bytes()
+
+	// the metadata goes right after the start of image marker
+	result := append([]byte{}, encoded[:2]...)
+	result = append(result, jpegSegment(markerApp1, append(append([]byte{}, imageinfo.ExifHeader...), cameraExif()...))...)
+	result = append(result, jpegSegment(markerApp1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>"))...)
+	result = append(result, jpegSegment(markerComment, []byte("secret comment"))...)
+
+	return append(result, encoded[2:]...)
+}
+
+func (s *stripperTestSuite) encodePNG() []byte {
+	var buf bytes.Buffer
+	s.Require().NoError(png.Encode(&buf, image.NewGray(image.Rect(0, 0, 7, 5))))
+
+	encoded := buf.Bytes()
+
+	// put the metadata chunks right after IHDR
+	ihdrEnd := len(pngSignature) + 8 + 13 + 4
+
+	var chunks bytes.Buffer
+	s.Require().NoError(writePNGChunk(&chunks, "eXIf", cameraExif()))
+	s.Require().NoError(writePNGChunk(&chunks, "tEXt", []byte("Comment\x00secret comment")))
+
+	result := append([]byte{}, encoded[:ihdrEnd]...)
+	result = append(result, chunks.Bytes()...)
+
+	return append(result, encoded[ihdrEnd:]...)
+}
+
+func (s *stripperTestSuite) strip(keepOrientation bool, content []byte, mimeType string) []byte {
+	var buf bytes.Buffer
+	s.Require().NoError(NewStripper(keepOrientation).Transform(&buf, bytes.NewReader(content), mimeType))
+	return buf.Bytes()
+}
+
+func (s *stripperTestSuite) TestApplies() {
+	stripper := NewStripper(false)
+	s.True(stripper.Applies("image/jpeg"))
+	s.True(stripper.Applies("image/png"))
+	s.False(stripper.Applies("image/gif"))
+	s.False(stripper.Applies("text/plain"))
+}
+
+func (s *stripperTestSuite) TestStripJPEG() {
+	stripped := s.strip(false, s.encodeJPEG(), "image/jpeg")
+
+	s.NotContains(string(stripped), "Pixel 7")
+	s.NotContains(string(stripped), "xmpmeta")
+	s.NotContains(string(stripped), "secret comment")
+
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	s.Require().NoError(err)
+	s.Equal(image.Rect(0, 0, 40, 30), decoded.Bounds())
+
+	info := imageinfo.Extract(stripped)
+	s.Require().NotNil(info)
+	s.Zero(info.Orientation)
+}
+
+func (s *stripperTestSuite) TestStripJPEGKeepingOrientation() {
+	stripped := s.strip(true, s.encodeJPEG(), "image/jpeg")
+
+	s.NotContains(string(stripped), "Pixel 7")
+	s.NotContains(string(stripped), "xmpmeta")
+
+	info := imageinfo.Extract(stripped)
+	s.Require().NotNil(info)
+	s.Equal(6, info.Orientation)
+	s.Empty(info.CameraModel)
+}
+
+func (s *stripperTestSuite) TestStripJPEGDropsTrailingImages() {
+	primary := s.encodeJPEG()
+	secondary := s.encodeJPEG()
+
+	// phones append further images like gain maps after the end of image, indexed by MPF
+	icc := jpegSegment(markerApp2, []byte("ICC_PROFILE\x00\x01\x01profile"))
+	mpf := jpegSegment(markerApp2, []byte("MPF\x00MM\x00\x2a"))
+
+	content := append([]byte{}, primary[:2]...)
+	content = append(content, icc...)
+	content = append(content, mpf...)
+	content = append(content, primary[2:]...)
+	content = append(content, secondary...)
+
+	stripped := s.strip(false, content, "image/jpeg")
+
+	s.NotContains(string(stripped), "Pixel 7")
+	s.NotContains(string(stripped), "MPF")
+	s.Contains(string(stripped), "ICC_PROFILE")
+	s.True(bytes.HasSuffix(stripped, []byte{0xff, markerEndOfImage}))
+	s.Equal(1, bytes.Count(stripped, []byte{0xff, markerStartOfImage}))
+
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	s.Require().NoError(err)
+	s.Equal(image.Rect(0, 0, 40, 30), decoded.Bounds())
+}
+
+func (s *stripperTestSuite) TestStripJPEGWithoutEndOfImage() {
+	encoded := s.encodeJPEG()
+	content := encoded[:len(encoded)-2]
+
+	stripped := s.strip(false, encoded, "image/jpeg")
+	s.Equal(stripped[:len(stripped)-2], s.strip(false, content, "image/jpeg"))
+}
+
+func (s *stripperTestSuite) TestStripPNG() {
+	stripped := s.strip(false, s.encodePNG(), "image/png")
+
+	s.NotContains(string(stripped), "Pixel 7")
+	s.NotContains(string(stripped), "secret comment")
+
+	// the PNG decoder verifies the checksums of the chunks
+	decoded, err := png.Decode(bytes.NewReader(stripped))
+	s.Require().NoError(err)
+	s.Equal(image.Rect(0, 0, 7, 5), decoded.Bounds())
+
+	info := imageinfo.Extract(stripped)
+	s.Require().NotNil(info)
+	s.Zero(info.Orientation)
+}
+
+func (s *stripperTestSuite) TestStripPNGKeepingOrientation() {
+	stripped := s.strip(true, s.encodePNG(), "image/png")
+
+	s.NotContains(string(stripped), "Pixel 7")
+
+	_, err := png.Decode(bytes.NewReader(stripped))
+	s.Require().NoError(err)
+
+	info := imageinfo.Extract(stripped)
+	s.Require().NotNil(info)
+	s.Equal(6, info.Orientation)
+}
+
+func (s *stripperTestSuite) TestStripKeepsImagesWithoutMetadata() {
+	var buf bytes.Buffer
+	s.Require().NoError(png.Encode(&buf, image.NewGray(image.Rect(0, 0, 7, 5))))
+
+	s.Equal(buf.Bytes(), s.strip(false, buf.Bytes(), "image/png"))
+}
+
+func (s *stripperTestSuite) TestCopiesOtherMedia() {
+	s.Equal([]byte("content"), s.strip(false, []byte("content"), "text/plain"))
+}
+
+func (s *stripperTestSuite) TestStripBrokenImages() {
+	encoded := s.encodeJPEG()
+
+	tests := map[string]struct {
+		content  []byte
+		mimeType string
+	}{
+		"no JPEG":       {[]byte("content"), "image/jpeg"},
+		"truncated":     {encoded[:30], "image/jpeg"},
+		"no PNG":        {[]byte("content"), "image/png"},
+		"truncated PNG": {s.encodePNG()[:40], "image/png"},
+	}
+
+	for name, test := range tests {
+		err := NewStripper(false).Transform(&bytes.Buffer{}, bytes.NewReader(test.content), test.mimeType)
+		s.True(errortypes.IsBadUserInput(err), "%v: expected bad user input, got %v", name, err)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"media-nexus/errortypes"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"slices"
+	"time"
+)
+
+// JobHandler runs a job. If it returns an error, the job is retried later. ctx is canceled, once the lease of the job
+// is lost, so the job isn't run by two workers at once for long.
+type JobHandler func(ctx context.Context, job *model.Job) error
+
+// errLeaseLost is the cause of canceling handlers, whose job may be leased by another worker
+var errLeaseLost = errors.New("lost the lease")
+
+// JobOptions configures how jobs are leased and retried.
+type JobOptions struct {
+	// the lease of a running job is extended every third of the visibility timeout. A job, whose lease couldn't be
+	// extended within the visibility timeout, e.g. because its worker crashed, is leased by another worker.
+	VisibilityTimeout time.Duration
+	// PollInterval is the time waited for new jobs, once the queue ran empty
+	PollInterval time.Duration
+	// failed jobs are retried after InitialBackoff, which doubles with every attempt up to MaxBackoff. Jobs failing
+	// MaxAttempts times are dead lettered.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// backoff returns the time to wait before retrying a job failed the given number of times
+func (o JobOptions) backoff(attempts int) time.Duration {
+	backoff := o.InitialBackoff
+	for i := 1; i < attempts && backoff < o.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, o.MaxBackoff)
+}
+
+type jobWorker struct {
+	queue    ports.JobQueue
+	handlers map[string]JobHandler
+	jobTypes []string
+	options  JobOptions
+	metrics  metrics.Metrics
+}
+
+// NewJobWorker runs the jobs of the types there are handlers for, one at a time. Runs are counted by job type.
+func NewJobWorker(
+	queue ports.JobQueue,
+	handlers map[string]JobHandler,
+	options JobOptions,
+	metrics metrics.Metrics,
+) util.Runner {
+	worker := &jobWorker{queue, handlers, slices.Sorted(maps.Keys(handlers)), options, metrics}
+	return worker.run
+}
+
+func (w *jobWorker) run(ctx context.Context) {
+	log := util.Logger(ctx)
+
+	for {
+		// drain the queue before waiting
+		leased, err := w.runNextJob(ctx)
+		if err != nil {
+			log.Errorf("failed to run job: %v", err)
+		}
+
+		if leased && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.options.PollInterval):
+		}
+	}
+}
+
+// runNextJob tells whether there was a job to run. Errors of the job itself are recorded in the queue, only errors
+// talking to the queue are returned.
+func (w *jobWorker) runNextJob(ctx context.Context) (bool, error) {
+	job, err := w.queue.Lease(ctx, w.jobTypes, w.options.VisibilityTimeout)
+	if errortypes.IsResourceNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// the workers leasing it before must have crashed running it
+	if job.Attempts > w.options.MaxAttempts {
+		return true, w.queue.DeadLetter(ctx, job.ID, job.LeaseID, "gave up after the worker crashed repeatedly")
+	}
+
+	err = w.runJob(ctx, job)
+	w.metrics.CountRun(job.Type, err)
+
+	switch {
+	case errors.Is(err, errLeaseLost):
+		// another worker owns the job now, so its outcome is theirs to record
+	case err == nil:
+		err = w.queue.Complete(ctx, job.ID, job.LeaseID)
+	case job.Attempts >= w.options.MaxAttempts:
+		util.Logger(ctx).Errorf("job %v of type %v failed for good: %v", job.ID, job.Type, err)
+		err = w.queue.DeadLetter(ctx, job.ID, job.LeaseID, err.Error())
+	default:
+		retryAt := time.Now().Add(w.options.backoff(job.Attempts))
+		err = w.queue.Fail(ctx, job.ID, job.LeaseID, err.Error(), retryAt)
+	}
+
+	if errors.Is(err, errLeaseLost) || errortypes.IsConflict(err) {
+		// the job took longer than the visibility timeout and is run by another worker now
+		util.Logger(ctx).Warnf("lost the lease of job %v of type %v: %v", job.ID, job.Type, err)
+		return true, nil
+	}
+
+	return true, err
+}
+
+// runJob runs the handler of the job and extends the lease meanwhile. The handler is canceled and errLeaseLost
+// returned, if the lease is lost or runs out, because extending it kept failing.
+func (w *jobWorker) runJob(ctx context.Context, job *model.Job) error {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	heartbeatStopped := make(chan struct{})
+
+	go func() {
+		defer close(heartbeatStopped)
+		w.extendLease(jobCtx, job, done, cancel)
+	}()
+
+	err := w.handlers[job.Type](jobCtx, job)
+	close(done)
+	<-heartbeatStopped
+
+	if errors.Is(context.Cause(jobCtx), errLeaseLost) {
+		return errLeaseLost
+	}
+
+	return err
+}
+
+// extendLease extends the lease of the job every third of the visibility timeout, until done is closed
+func (w *jobWorker) extendLease(
+	ctx context.Context,
+	job *model.Job,
+	done <-chan struct{},
+	cancel context.CancelCauseFunc,
+) {
+	ticker := time.NewTicker(w.options.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	leasedUntil := time.NewTimer(time.Until(job.VisibleAt))
+	defer leasedUntil.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-leasedUntil.C:
+			cancel(errLeaseLost)
+			return
+		case <-ticker.C:
+		}
+
+		extendedAt := time.Now()
+
+		err := w.queue.ExtendLease(ctx, job.ID, job.LeaseID, w.options.VisibilityTimeout)
+		if errortypes.IsConflict(err) {
+			cancel(errLeaseLost)
+			return
+		} else if err != nil {
+			// retried with the next tick, while the lease lasts
+			util.Logger(ctx).Warnf("failed to extend the lease of job %v of type %v: %v", job.ID, job.Type, err)
+			continue
+		}
+
+		leasedUntil.Reset(time.Until(extendedAt.Add(w.options.VisibilityTimeout)))
+	}
+}
+
+// newJob creates a job, that is visible right away
+func newJob(jobType string, payload string) (*model.Job, error) {
+	id, err := createRandomKey("")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &model.Job{ID: id, Type: jobType, Payload: payload, VisibleAt: now, CreatedAt: now}, nil
+}
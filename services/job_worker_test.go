@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/errortypes"
+	"media-nexus/logger"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type jobWorkerTestSuite struct {
+	suite.Suite
+	ctx     context.Context
+	queue   ports.JobQueue
+	options JobOptions
+	// failures is the number of runs left to fail
+	failures int
+	runs     []string
+	metrics  *countingMetrics
+}
+
+func TestJobWorker(t *testing.T) {
+	suite.Run(t, &jobWorkerTestSuite{})
+}
+
+func (s *jobWorkerTestSuite) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("test"))
+	s.queue = amemory.NewJobQueue()
+	s.options = JobOptions{
+		VisibilityTimeout: time.Minute,
+		PollInterval:      10 * time.Millisecond,
+		MaxAttempts:       3,
+		InitialBackoff:    time.Minute,
+		MaxBackoff:        time.Hour,
+	}
+	s.failures = 0
+	s.runs = nil
+	s.metrics = newCountingMetrics()
+}
+
+func (s *jobWorkerTestSuite) newWorker() *jobWorker {
+	handlers := map[string]JobHandler{
+		"test": func(ctx context.Context, job *model.Job) error {
+			s.runs = append(s.runs, job.Payload)
+
+			if s.failures > 0 {
+				s.failures--
+				return errors.New("failed")
+			}
+
+			return nil
+		},
+	}
+
+	return &jobWorker{s.queue, handlers, []string{"test"}, s.options, s.metrics}
+}
+
+func (s *jobWorkerTestSuite) enqueue(jobType string, payload string) *model.Job {
+	job, err := newJob(jobType, payload)
+	s.Require().NoError(err)
+	s.Require().NoError(s.queue.Enqueue(s.ctx, job))
+
+	return job
+}
+
+func (s *jobWorkerTestSuite) TestRunJob() {
+	s.enqueue("test", "payload")
+
+	leased, err := s.newWorker().runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.True(leased)
+	s.Equal([]string{"payload"}, s.runs)
+	s.Equal(map[string]int{"test success": 1}, s.metrics.runs)
+
+	leased, err = s.newWorker().runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.False(leased)
+	s.Len(s.runs, 1)
+}
+
+func (s *jobWorkerTestSuite) TestRunJobOfOtherType() {
+	s.enqueue("other", "payload")
+
+	leased, err := s.newWorker().runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.False(leased)
+	s.Empty(s.runs)
+}
+
+func (s *jobWorkerTestSuite) TestRetryFailedJob() {
+	s.failures = 1
+	s.enqueue("test", "payload")
+	worker := s.newWorker()
+
+	leased, err := worker.runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.True(leased)
+	s.Equal(map[string]int{"test failure": 1}, s.metrics.runs)
+
+	// the job backs off
+	leased, err = worker.runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.False(leased)
+
+	_, err = s.queue.Lease(s.ctx, []string{"test"}, time.Minute)
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *jobWorkerTestSuite) TestDeadLetterJobFailingTooOften() {
+	// retry right away
+	s.options.InitialBackoff = 0
+	s.failures = 4
+	job := s.enqueue("test", "payload")
+	worker := s.newWorker()
+
+	for range 4 {
+		_, err := worker.runNextJob(s.ctx)
+		s.Require().NoError(err)
+	}
+
+	s.Len(s.runs, 3)
+
+	deadLetters, err := s.queue.FindDeadLetters(s.ctx, 10)
+	s.Require().NoError(err)
+	s.Require().Len(deadLetters, 1)
+	s.Equal(job.ID, deadLetters[0].ID)
+	s.Equal("failed", deadLetters[0].LastError)
+}
+
+func (s *jobWorkerTestSuite) TestDeadLetterJobCrashingTooOften() {
+	job := s.enqueue("test", "payload")
+
+	// the workers leasing it crashed without failing the job
+	for range 3 {
+		_, err := s.queue.Lease(s.ctx, []string{"test"}, 0)
+		s.Require().NoError(err)
+	}
+
+	leased, err := s.newWorker().runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.True(leased)
+	s.Empty(s.runs)
+
+	deadLetters, err := s.queue.FindDeadLetters(s.ctx, 10)
+	s.Require().NoError(err)
+	s.Require().Len(deadLetters, 1)
+	s.Equal(job.ID, deadLetters[0].ID)
+}
+
+func (s *jobWorkerTestSuite) TestExtendLeaseOfLongJob() {
+	s.options.VisibilityTimeout = 60 * time.Millisecond
+	s.enqueue("test", "payload")
+
+	handlers := map[string]JobHandler{"test": func(ctx context.Context, job *model.Job) error {
+		time.Sleep(3 * s.options.VisibilityTimeout)
+
+		// no other worker took the job over meanwhile
+		_, err := s.queue.Lease(s.ctx, []string{"test"}, time.Minute)
+		s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+
+		return ctx.Err()
+	}}
+	worker := &jobWorker{s.queue, handlers, []string{"test"}, s.options, s.metrics}
+
+	leased, err := worker.runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.True(leased)
+	s.Equal(map[string]int{"test success": 1}, s.metrics.runs)
+
+	_, err = s.queue.Lease(s.ctx, []string{"test"}, time.Minute)
+	s.True(errortypes.IsResourceNotFound(err), "expected the job to be completed, got %v", err)
+}
+
+func (s *jobWorkerTestSuite) TestCancelJobWithLostLease() {
+	s.options.VisibilityTimeout = 60 * time.Millisecond
+	s.enqueue("test", "payload")
+
+	var takenOver *model.Job
+	handlers := map[string]JobHandler{"test": func(ctx context.Context, job *model.Job) error {
+		// another worker takes the job over, as if the lease ran out
+		s.Require().NoError(s.queue.Fail(s.ctx, job.ID, job.LeaseID, "released", time.Now()))
+
+		var err error
+		takenOver, err = s.queue.Lease(s.ctx, []string{"test"}, time.Minute)
+		s.Require().NoError(err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			s.Fail("job wasn't canceled")
+			return nil
+		}
+	}}
+	worker := &jobWorker{s.queue, handlers, []string{"test"}, s.options, s.metrics}
+
+	leased, err := worker.runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.True(leased)
+	s.Equal(map[string]int{"test failure": 1}, s.metrics.runs)
+
+	// the job is left to the worker leasing it now
+	s.NoError(s.queue.Complete(s.ctx, takenOver.ID, takenOver.LeaseID))
+}
+
+func (s *jobWorkerTestSuite) TestCancelJobWhoseLeaseRunsOut() {
+	s.options.VisibilityTimeout = 60 * time.Millisecond
+	job := s.enqueue("test", "payload")
+
+	handlers := map[string]JobHandler{"test": func(ctx context.Context, job *model.Job) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			s.Fail("job wasn't canceled")
+			return nil
+		}
+	}}
+	queue := &unextendableJobQueue{s.queue}
+	worker := &jobWorker{queue, handlers, []string{"test"}, s.options, s.metrics}
+
+	leased, err := worker.runNextJob(s.ctx)
+	s.Require().NoError(err)
+	s.True(leased)
+
+	// the job is visible again for other workers
+	retried, err := s.queue.Lease(s.ctx, []string{"test"}, time.Minute)
+	s.Require().NoError(err)
+	s.Equal(job.ID, retried.ID)
+	s.Equal(2, retried.Attempts)
+}
+
+func (s *jobWorkerTestSuite) TestRunner() {
+	ran := make(chan string, 1)
+	runner := NewJobWorker(s.queue, map[string]JobHandler{"test": func(ctx context.Context, job *model.Job) error {
+		ran <- job.Payload
+		return nil
+	}}, s.options, metrics.NewNopMetrics())
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	go runner(ctx)
+
+	s.enqueue("test", "payload")
+
+	select {
+	case payload := <-ran:
+		s.Equal("payload", payload)
+	case <-time.After(time.Second):
+		s.Fail("job didn't run")
+	}
+}
+
+func (s *jobWorkerTestSuite) TestBackoff() {
+	s.Equal(time.Minute, s.options.backoff(1))
+	s.Equal(2*time.Minute, s.options.backoff(2))
+	s.Equal(4*time.Minute, s.options.backoff(3))
+	s.Equal(time.Hour, s.options.backoff(10))
+}
+
+// unextendableJobQueue fails to extend leases, like a queue, that is unreachable for longer
+type unextendableJobQueue struct {
+	ports.JobQueue
+}
+
+func (q *unextendableJobQueue) ExtendLease(context.Context, model.JobID, string, time.Duration) error {
+	return errortypes.NewUpstreamUnavailablef("job queue")
+}
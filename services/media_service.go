@@ -1,22 +1,75 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"hash"
 	"io"
 	"media-nexus/errortypes"
+	"media-nexus/metrics"
 	"media-nexus/model"
 	"media-nexus/ports"
+	"media-nexus/services/imageinfo"
+	"media-nexus/services/query"
 	"media-nexus/util"
-	"mime/multipart"
+	"strings"
 	"time"
 )
 
+// stagingKeyPrefix marks media, that is still being uploaded and not yet known by its media ID
+const stagingKeyPrefix = "staging_"
+
+// directUploadKeySuffix marks media uploaded directly by clients to <media id>_direct, which is moved to the media ID
+// on completion
+const directUploadKeySuffix = "_direct"
+
 type MediaService interface {
-	CreateMedia(ctx context.Context, name string, tagIDs []model.TagID, file multipart.File) (model.MediaID, error)
-	FindByTagID(ctx context.Context, tagID model.TagID) ([]model.MediaItem, error)
+	// CreateMedia reads the file only once, so it can be streamed straight from the request. Returns
+	// UnsupportedMediaType if the type sniffed from the content is not allowed.
+	CreateMedia(ctx context.Context, name string, tagIDs []model.TagID, file io.Reader) (model.MediaID, error)
+	GetMedia(ctx context.Context, id model.MediaID) (model.MediaItem, error)
+	// OpenMediaContent returns the metadata and the content of complete media. The content is only read from the
+	// media repository for the ranges actually read, so seeking is cheap.
+	OpenMediaContent(ctx context.Context, id model.MediaID) (model.MediaMetadata, io.ReadSeekCloser, error)
+	UpdateMedia(ctx context.Context, id model.MediaID, update model.MediaMetadataUpdate) (model.MediaItem, error)
+	DeleteMedia(ctx context.Context, id model.MediaID) error
+	// CreateDirectUpload lets clients upload the media straight to the media repository with the returned URL. The
+	// URL is nil, if the media exists already. checksum is the hex encoded SHA-256 of the media.
+	CreateDirectUpload(
+		ctx context.Context,
+		name string,
+		tagIDs []model.TagID,
+		size int64,
+		checksum string,
+		mimeType string,
+	) (model.MediaID, *model.UploadURL, error)
+	// CompleteDirectUpload verifies the uploaded media matches size, checksum and MIME type given to
+	// CreateDirectUpload. Media to transform is transformed afterwards.
+	CompleteDirectUpload(ctx context.Context, id model.MediaID) (model.MediaItem, error)
+	// FindByTagID and FindByQuery return one page of media matching the filter ordered by ID together with the ID the
+	// next page starts after. It is empty on the last page.
+	FindByTagID(
+		ctx context.Context,
+		tagID model.TagID,
+		filter model.ImageFilter,
+		page model.PageRequest,
+	) ([]model.MediaItem, string, error)
+	// FindByQuery finds media matching a boolean tag query like `(tagA AND tagB) OR NOT tagC`
+	FindByQuery(
+		ctx context.Context,
+		q string,
+		filter model.ImageFilter,
+		page model.PageRequest,
+	) ([]model.MediaItem, string, error)
+	// GetVariantURL returns the URL of the variant of an image scaled down to fit into size x size pixels. Missing
+	// variants are generated first. Images not larger than size are their own variant.
+	GetVariantURL(ctx context.Context, id model.MediaID, size int) (string, error)
+	// GenerateMissingVariants runs the job enqueued after upload. Media deleted or changed meanwhile is skipped.
+	GenerateMissingVariants(ctx context.Context, id model.MediaID) error
 }
 
 func NewMediaService(
@@ -25,8 +78,28 @@ func NewMediaService(
 	media ports.MediaRepository,
 	mediaURLLifetime time.Duration,
 	incompleteMetadataLifetime time.Duration,
+	directUploadLifetime time.Duration,
+	allowedMimeTypes []string,
+	transformation MediaTransformation,
+	jobs ports.JobQueue,
+	variantSizes []int,
+	variantURL string,
+	metrics metrics.Metrics,
 ) MediaService {
-	return &mediaService{tags, mediaMetadata, media, mediaURLLifetime, incompleteMetadataLifetime}
+	return &mediaService{
+		tags,
+		mediaMetadata,
+		media,
+		mediaURLLifetime,
+		incompleteMetadataLifetime,
+		directUploadLifetime,
+		allowedMimeTypes,
+		transformation,
+		jobs,
+		variantSizes,
+		variantURL,
+		metrics,
+	}
 }
 
 type mediaService struct {
@@ -35,13 +108,27 @@ type mediaService struct {
 	media                      ports.MediaRepository
 	mediaURLLifetime           time.Duration
 	incompleteMetadataLifetime time.Duration
+	directUploadLifetime       time.Duration
+	// allowedMimeTypes may contain wildcards like `image/*`
+	allowedMimeTypes []string
+	// transformation is nil, if media is stored as uploaded
+	transformation MediaTransformation
+	jobs           ports.JobQueue
+	// variantSizes is empty, if no variants are generated
+	variantSizes []int
+	// variantURL is the base URL of the endpoint generating variants on demand
+	variantURL string
+	metrics    metrics.Metrics
 }
 
+// CreateMedia can't know the media ID before the whole file is read, because it is derived from the checksum. So the
+// file is streamed to a staging key while hashing it and only moved to the media ID, once we know the media doesn't
+// exist already.
 func (s *mediaService) CreateMedia(
 	ctx context.Context,
 	name string,
 	tagIds []model.TagID,
-	file multipart.File,
+	file io.Reader,
 ) (model.MediaID, error) {
 	if allExist, err := s.tags.AllExist(ctx, tagIds); err != nil {
 		return "", err
@@ -49,12 +136,60 @@ func (s *mediaService) CreateMedia(
 		return "", errortypes.NewBadUserInput("not all tag ids exist. Add them first.")
 	}
 
-	metadata, err := createMediaMetadata(name, tagIds, file)
+	// sniff the type before anything is stored, so media of other types doesn't even reach the media repository
+	head, err := readHead(file)
 	if err != nil {
 		return "", err
 	}
 
+	mimeType := util.DetectMimeType(head)
+	if !util.IsAllowedMimeType(mimeType, s.allowedMimeTypes) {
+		return "", errortypes.NewUnsupportedMediaType(mimeType)
+	}
+
+	stagingKey, err := createRandomKey(stagingKeyPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	staged := false
+	defer func() {
+		if !staged {
+			s.discardStagedMedia(ctx, stagingKey)
+		}
+	}()
+
+	// the media ID is derived from the checksum of the uploaded media. Only if it is transformed, it differs from the
+	// checksum of the stored media and has to be computed separately.
+	uploaded := io.MultiReader(bytes.NewReader(head), file)
+	uploadHasher := sha256.New()
+	if s.transforms(mimeType) {
+		uploaded = io.TeeReader(uploaded, uploadHasher)
+	}
+
+	stored, err := s.storeMedia(ctx, stagingKey, uploaded, mimeType)
+	if err != nil {
+		return "", err
+	}
+
+	checksum := stored.checksum
+	if s.transforms(mimeType) {
+		checksum = hex.EncodeToString(uploadHasher.Sum(nil))
+	}
+
+	metadata := createMediaMetadata(
+		name,
+		tagIds,
+		checksum,
+		stored.checksum,
+		stored.size,
+		mimeType,
+		extractImageInfo(mimeType, stored.head),
+		time.Now(),
+	)
+
 	if canProceed, existingMetadataID, err := s.canProceedCreateMedia(ctx, metadata); !canProceed {
+		s.countDedupHit(existingMetadataID, err)
 		return existingMetadataID, err
 	}
 
@@ -63,19 +198,353 @@ func (s *mediaService) CreateMedia(
 		return "", err
 	}
 
-	err = s.media.CreateMedia(ctx, metadata.ID(), file)
+	err = s.media.MoveMedia(ctx, stagingKey, metadata.ID())
 	if err != nil {
 		return "", err
 	}
 
+	staged = true
+
 	err = s.mediaMetadata.SetUploadComplete(ctx, metadata.ID(), true)
 	if err != nil {
 		return "", err
 	}
 
+	s.enqueueVariants(ctx, metadata)
+
 	return metadata.ID(), nil
 }
 
+// CreateDirectUpload locks the metadata like CreateMedia does, but the client uploads the media on its own.
+func (s *mediaService) CreateDirectUpload(
+	ctx context.Context,
+	name string,
+	tagIds []model.TagID,
+	size int64,
+	checksum string,
+	mimeType string,
+) (model.MediaID, *model.UploadURL, error) {
+	checksum = strings.ToLower(checksum)
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+		return "", nil, errortypes.NewBadUserInput("checksum must be a hex encoded SHA-256")
+	}
+
+	if size < 0 {
+		return "", nil, errortypes.NewBadUserInput("size must not be negative")
+	}
+
+	// the announced type is verified on completion, like size and checksum
+	if !util.IsAllowedMimeType(mimeType, s.allowedMimeTypes) {
+		return "", nil, errortypes.NewUnsupportedMediaType(mimeType)
+	}
+
+	if allExist, err := s.tags.AllExist(ctx, tagIds); err != nil {
+		return "", nil, err
+	} else if !allExist {
+		return "", nil, errortypes.NewBadUserInput("not all tag ids exist. Add them first.")
+	}
+
+	// the metadata stays incomplete until the client completes the upload. Dating the last update ahead to when the
+	// upload URL expires keeps it from being taken as stale or expiring meanwhile.
+	// the image info is only known once the media is uploaded
+	metadata := createMediaMetadata(
+		name,
+		tagIds,
+		checksum,
+		checksum,
+		size,
+		mimeType,
+		nil,
+		time.Now().Add(s.directUploadLifetime),
+	)
+
+	if canProceed, existingMetadataID, err := s.canProceedCreateMedia(ctx, metadata); !canProceed {
+		s.countDedupHit(existingMetadataID, err)
+		return existingMetadataID, nil, err
+	}
+
+	if err := s.mediaMetadata.Upsert(ctx, metadata); err != nil {
+		return "", nil, err
+	}
+
+	uploadURL, err := s.media.GetUploadURL(
+		ctx,
+		directUploadKey(metadata.ID()),
+		size,
+		checksum,
+		mimeType,
+		s.directUploadLifetime,
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return metadata.ID(), &uploadURL, nil
+}
+
+func (s *mediaService) CompleteDirectUpload(ctx context.Context, id model.MediaID) (model.MediaItem, error) {
+	metadata, err := s.mediaMetadata.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Deleting() {
+		return nil, errortypes.NewResourceNotFound(id)
+	}
+
+	if metadata.UploadComplete() {
+		return s.GetMedia(ctx, id)
+	}
+
+	if err := s.verifyDirectUpload(ctx, metadata); err != nil {
+		return nil, err
+	}
+
+	if err := s.mediaMetadata.SetUploadComplete(ctx, id, true); err != nil {
+		return nil, err
+	}
+
+	// the image info is only known after verifying the upload
+	item, err := s.GetMedia(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.enqueueVariants(ctx, item)
+
+	return item, nil
+}
+
+// verifyDirectUpload verifies the media at the direct upload key and moves it, or the transformed media, to the media
+// ID. Clients never upload to the media ID, so served media can't be replaced with the upload URL. Media not matching
+// the metadata is discarded, so the client can upload again while the URL is valid. Media at the media ID was moved
+// there by an earlier attempt, which crashed before completing, and is taken as it is.
+func (s *mediaService) verifyDirectUpload(ctx context.Context, metadata model.MediaMetadata) error {
+	uploadKey := directUploadKey(metadata.ID())
+
+	if _, err := s.media.GetMediaAttributes(ctx, metadata.ID()); err == nil {
+		s.consumeDirectUpload(ctx, uploadKey)
+		return nil
+	} else if !errortypes.IsResourceNotFound(err) {
+		return err
+	}
+
+	attributes, err := s.media.GetMediaAttributes(ctx, uploadKey)
+	if errortypes.IsResourceNotFound(err) {
+		return errortypes.NewConflictf("media %v hasn't been uploaded yet", metadata.ID())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// once the metadata describes the transformed media, the size of the uploaded one is only known by its checksum
+	transformed := metadata.StoredChecksum() != metadata.Checksum()
+	if !transformed && attributes.Size != metadata.Size() {
+		s.discardStagedMedia(ctx, uploadKey)
+		return errortypes.NewBadUserInputf(
+			"uploaded media has %v bytes, but %v were announced",
+			attributes.Size,
+			metadata.Size(),
+		)
+	}
+
+	checksum := attributes.Checksum
+	if checksum == "" {
+		checksum, err = s.computeChecksum(ctx, uploadKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	if checksum != metadata.Checksum() {
+		s.discardStagedMedia(ctx, uploadKey)
+		return errortypes.NewBadUserInputf("uploaded media doesn't match the checksum %v", metadata.Checksum())
+	}
+
+	head, err := s.readMediaHead(ctx, uploadKey)
+	if err != nil {
+		return err
+	}
+
+	mimeType := util.DetectMimeType(head)
+	if mimeType != metadata.MimeType() {
+		s.discardStagedMedia(ctx, uploadKey)
+		return errortypes.NewUnsupportedMediaTypef(
+			"uploaded media is of type %v, but %v was announced",
+			mimeType,
+			metadata.MimeType(),
+		)
+	}
+
+	if s.transforms(mimeType) {
+		if err := s.transformDirectUpload(ctx, metadata, uploadKey); err != nil {
+			return err
+		}
+	} else {
+		if imageInfo := extractImageInfo(mimeType, head); imageInfo != nil {
+			if err := s.mediaMetadata.Upsert(ctx, withImageInfo(metadata, imageInfo)); err != nil {
+				return err
+			}
+		}
+
+		if err := s.media.MoveMedia(ctx, uploadKey, metadata.ID()); err != nil {
+			return err
+		}
+	}
+
+	s.consumeDirectUpload(ctx, uploadKey)
+
+	return nil
+}
+
+// transformDirectUpload stores the transformed media at a staging key first. The metadata gets the stored checksum
+// before the transformed media is moved to the media ID, so completing again recognizes the uploaded media either way.
+func (s *mediaService) transformDirectUpload(
+	ctx context.Context,
+	metadata model.MediaMetadata,
+	uploadKey string,
+) error {
+	uploaded, err := s.media.OpenMedia(ctx, uploadKey, nil)
+	if err != nil {
+		return err
+	}
+	defer uploaded.Close()
+
+	stagingKey, err := createRandomKey(stagingKeyPrefix)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.storeMedia(ctx, stagingKey, uploaded, metadata.MimeType())
+	if errortypes.IsBadUserInput(err) {
+		// broken media won't get better by completing again
+		s.discardStagedMedia(ctx, uploadKey)
+	}
+
+	if err != nil {
+		s.discardStagedMedia(ctx, stagingKey)
+		return err
+	}
+
+	transformed := model.NewMediaMetadata(
+		metadata.ID(),
+		metadata.Name(),
+		metadata.TagIDs(),
+		metadata.Checksum(),
+		stored.checksum,
+		stored.size,
+		metadata.MimeType(),
+		extractImageInfo(metadata.MimeType(), stored.head),
+		metadata.Variants(),
+		false,
+		time.Time{},
+		false,
+		metadata.LastUpdate(),
+	)
+
+	if err := s.mediaMetadata.Upsert(ctx, transformed); err != nil {
+		s.discardStagedMedia(ctx, stagingKey)
+		return err
+	}
+
+	if err := s.media.MoveMedia(ctx, stagingKey, metadata.ID()); err != nil {
+		s.discardStagedMedia(ctx, stagingKey)
+		return err
+	}
+
+	return nil
+}
+
+// consumeDirectUpload replaces the uploaded media with empty media once it was moved or transformed to the media ID.
+// So the uploaded media is gone, and repositories not replacing media through upload URLs refuse the URL until it
+// expires. Best effort like discardStagedMedia, the reconciliation deletes the key eventually.
+func (s *mediaService) consumeDirectUpload(ctx context.Context, uploadKey string) {
+	if err := s.media.CreateMedia(ctx, uploadKey, bytes.NewReader(nil), ""); err != nil {
+		util.Logger(ctx).Errorf("failed to consume direct upload %v: %v", uploadKey, err)
+	}
+}
+
+// storedMedia describes media as it was stored by storeMedia
+type storedMedia struct {
+	checksum string
+	size     int64
+	// head holds the headers of images, so we don't have to read them again
+	head []byte
+}
+
+// storeMedia streams the media to key and transforms it on the way, if needed.
+func (s *mediaService) storeMedia(
+	ctx context.Context,
+	key string,
+	media io.Reader,
+	mimeType string,
+) (storedMedia, error) {
+	var transformed *transformedReader
+	if s.transforms(mimeType) {
+		transformed = startTransformation(s.transformation, media, mimeType)
+		media = transformed
+	}
+
+	hasher := sha256.New()
+	head := &headBuffer{limit: imageinfo.HeadSize}
+	counter := &countingReader{reader: io.TeeReader(media, io.MultiWriter(hasher, head))}
+
+	err := s.media.CreateMedia(ctx, key, counter, mimeType)
+	if transformed != nil {
+		err = transformed.finish(err)
+	}
+
+	if err != nil {
+		return storedMedia{}, err
+	}
+
+	return storedMedia{hex.EncodeToString(hasher.Sum(nil)), counter.count, head.data}, nil
+}
+
+func (s *mediaService) transforms(mimeType string) bool {
+	return s.transformation != nil && s.transformation.Applies(mimeType)
+}
+
+// readMediaHead reads as much of the media as needed to sniff its type and to read the headers of images.
+func (s *mediaService) readMediaHead(ctx context.Context, key string) ([]byte, error) {
+	media, err := s.media.OpenMedia(ctx, key, &model.ByteRange{Offset: 0, Length: imageinfo.HeadSize})
+	if err != nil {
+		return nil, err
+	}
+	defer media.Close()
+
+	head, err := io.ReadAll(media)
+	if err != nil {
+		return nil, errortypes.NewInputOutputErrorf("failed to read media %v: %v", key, err)
+	}
+
+	return head, nil
+}
+
+// computeChecksum reads the whole media, for repositories that don't know the checksum themselves.
+func (s *mediaService) computeChecksum(ctx context.Context, key string) (string, error) {
+	media, err := s.media.OpenMedia(ctx, key, nil)
+	if err != nil {
+		return "", err
+	}
+	defer media.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, media); err != nil {
+		return "", errortypes.NewInputOutputErrorf("failed to read media %v: %v", key, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// discardStagedMedia is best effort only. Whatever is left behind is unreferenced and can be cleaned up any time.
+func (s *mediaService) discardStagedMedia(ctx context.Context, stagingKey string) {
+	if err := s.media.DeleteAll(ctx, []string{stagingKey}); err != nil {
+		util.Logger(ctx).Errorf("failed to discard staged media %v: %v", stagingKey, err)
+	}
+}
+
 func (s *mediaService) canProceedCreateMedia(
 	ctx context.Context,
 	metadata model.MediaMetadata,
@@ -89,6 +558,22 @@ func (s *mediaService) canProceedCreateMedia(
 		return true, "", nil
 	}
 
+	if existingMetadata.Deleting() {
+		if !s.isStale(existingMetadata) {
+			return false, existingMetadata.ID(), errortypes.NewConflictf(
+				"media with id '%v' is currently being deleted",
+				existingMetadata.ID(),
+			)
+		}
+
+		// the deletion must have crashed half-way. Finish it, so we start from scratch.
+		if err := s.deleteMedia(ctx, existingMetadata); err != nil {
+			return false, "", err
+		}
+
+		return true, "", nil
+	}
+
 	if existingMetadata.UploadComplete() {
 		if metadata.Name() != existingMetadata.Name() {
 			return false, existingMetadata.ID(), errortypes.NewResourceAlreadyExistsf(
@@ -109,7 +594,7 @@ func (s *mediaService) canProceedCreateMedia(
 		return false, existingMetadata.ID(), nil
 	}
 
-	if !existingMetadata.LastUpdate().Add(s.incompleteMetadataLifetime).Before(time.Now()) {
+	if !s.isStale(existingMetadata) {
 		return false, existingMetadata.ID(), errortypes.NewResourceAlreadyExistsf(
 			"media already exists with id '%v'",
 			metadata.ID(),
@@ -119,89 +604,314 @@ func (s *mediaService) canProceedCreateMedia(
 	return true, existingMetadata.ID(), nil
 }
 
-func (s *mediaService) FindByTagID(ctx context.Context, tagID model.TagID) ([]model.MediaItem, error) {
-	log := util.Logger(ctx)
+// countDedupHit counts media found by canProceedCreateMedia to exist already, i.e. without an error
+func (s *mediaService) countDedupHit(existingMetadataID model.MediaID, err error) {
+	if err == nil && existingMetadataID != "" {
+		s.metrics.CountDedupHit()
+	}
+}
+
+// isStale tells whether the operation locking the metadata (upload or deletion) should have finished by now. If so,
+// we can assume it crashed.
+func (s *mediaService) isStale(metadata model.MediaMetadata) bool {
+	return metadata.LastUpdate().Add(s.incompleteMetadataLifetime).Before(time.Now())
+}
+
+func (s *mediaService) GetMedia(ctx context.Context, id model.MediaID) (model.MediaItem, error) {
+	metadata, err := s.mediaMetadata.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-	metadatas, err := s.mediaMetadata.FindByTagID(ctx, tagID)
+	if metadata.Deleting() {
+		return nil, errortypes.NewResourceNotFound(id)
+	}
+
+	url, err := s.media.GetMediaURL(ctx, metadata.ID(), s.mediaURLLifetime)
 	if err != nil {
 		return nil, err
 	}
 
+	return model.NewMediaItem(metadata, url, s.variantURLs(ctx, metadata, url)), nil
+}
+
+func (s *mediaService) OpenMediaContent(
+	ctx context.Context,
+	id model.MediaID,
+) (model.MediaMetadata, io.ReadSeekCloser, error) {
+	metadata, err := s.mediaMetadata.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if metadata.Deleting() {
+		return nil, nil, errortypes.NewResourceNotFound(id)
+	}
+
+	if !metadata.UploadComplete() {
+		return nil, nil, errortypes.NewConflictf("media %v is still being uploaded", id)
+	}
+
+	// metadata created before we stored sizes has none
+	size := metadata.Size()
+	if size == 0 {
+		attributes, err := s.media.GetMediaAttributes(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		size = attributes.Size
+	}
+
+	return metadata, &mediaContent{ctx: ctx, media: s.media, key: id, size: size}, nil
+}
+
+func (s *mediaService) UpdateMedia(
+	ctx context.Context,
+	id model.MediaID,
+	update model.MediaMetadataUpdate,
+) (model.MediaItem, error) {
+	if update.TagIDs != nil && (len(update.AddTagIDs) > 0 || len(update.RemoveTagIDs) > 0) {
+		return nil, errortypes.NewBadUserInput("either replace all tag ids or add and remove tag ids, not both")
+	}
+
+	if update.Name != nil && *update.Name == "" {
+		return nil, errortypes.NewBadUserInput("name must not be empty")
+	}
+
+	newTagIDs := append(append([]model.TagID{}, update.TagIDs...), update.AddTagIDs...)
+	if allExist, err := s.tags.AllExist(ctx, newTagIDs); err != nil {
+		return nil, err
+	} else if !allExist {
+		return nil, errortypes.NewBadUserInput("not all tag ids exist. Add them first.")
+	}
+
+	metadata, err := s.mediaMetadata.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Deleting() {
+		return nil, errortypes.NewResourceNotFound(id)
+	}
+
+	if !metadata.UploadComplete() {
+		return nil, errortypes.NewConflictf("media with id '%v' is currently being uploaded", id)
+	}
+
+	if err := s.mediaMetadata.Update(ctx, id, update); err != nil {
+		return nil, err
+	}
+
+	return s.GetMedia(ctx, id)
+}
+
+// DeleteMedia marks the metadata as deleting first, which locks it like an incomplete upload does. Then the blob and
+// finally the metadata is removed. If we crash in between, the media stays marked and deleting it again finishes the
+// job.
+func (s *mediaService) DeleteMedia(ctx context.Context, id model.MediaID) error {
+	metadata, err := s.mediaMetadata.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !metadata.UploadComplete() && !s.isStale(metadata) {
+		return errortypes.NewConflictf("media with id '%v' is currently being uploaded", id)
+	}
+
+	return s.deleteMedia(ctx, metadata)
+}
+
+func (s *mediaService) deleteMedia(ctx context.Context, metadata model.MediaMetadata) error {
+	if err := s.mediaMetadata.SetDeleting(ctx, metadata.ID(), true); err != nil {
+		return err
+	}
+
+	keys := append([]string{metadata.ID(), directUploadKey(metadata.ID())}, s.variantKeys(metadata)...)
+	if err := s.media.DeleteAll(ctx, keys); err != nil {
+		return err
+	}
+
+	return s.mediaMetadata.DeleteAll(ctx, []model.MediaID{metadata.ID()})
+}
+
+func (s *mediaService) FindByTagID(
+	ctx context.Context,
+	tagID model.TagID,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaItem, string, error) {
+	metadatas, hasMore, err := s.mediaMetadata.FindByTagIDPage(ctx, tagID, filter, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.createMediaItems(ctx, metadatas), nextPageAfter(metadatas, hasMore), nil
+}
+
+func (s *mediaService) FindByQuery(
+	ctx context.Context,
+	q string,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaItem, string, error) {
+	expression, err := query.Parse(q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	metadatas, hasMore, err := s.mediaMetadata.FindByQueryPage(ctx, expression, filter, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.createMediaItems(ctx, metadatas), nextPageAfter(metadatas, hasMore), nil
+}
+
+// nextPageAfter uses the metadata instead of the media items, because media being deleted is left out of the items.
+func nextPageAfter(metadatas []model.MediaMetadata, hasMore bool) string {
+	if !hasMore || len(metadatas) < 1 {
+		return ""
+	}
+
+	return metadatas[len(metadatas)-1].ID()
+}
+
+func (s *mediaService) createMediaItems(ctx context.Context, metadatas []model.MediaMetadata) []model.MediaItem {
+	log := util.Logger(ctx)
+
 	items := make([]model.MediaItem, 0, len(metadatas))
 
 	for _, metadata := range metadatas {
+		if metadata.Deleting() {
+			continue
+		}
+
 		url, err := s.media.GetMediaURL(ctx, metadata.ID(), s.mediaURLLifetime)
 		if err != nil {
 			log.Errorf("failed to get media url. Adding anyway. Details: %v", err)
 		}
 
-		item := model.NewMediaItem(metadata, url)
+		item := model.NewMediaItem(metadata, url, s.variantURLs(ctx, metadata, url))
 		items = append(items, item)
 	}
 
-	return items, nil
+	return items
 }
 
 func createMediaMetadata(
 	name string,
 	tagIds []string,
-	file multipart.File,
-) (model.MediaMetadata, error) {
-	checksum, err := computeChecksum(file, sha256.New())
-	if err != nil {
-		return nil, err
-	}
-
-	id := computeHashForMedia(sha256.New(), name, tagIds, checksum)
-
+	checksum string,
+	storedChecksum string,
+	size int64,
+	mimeType string,
+	imageInfo *model.ImageInfo,
+	lastUpdate time.Time,
+) model.MediaMetadata {
 	return model.NewMediaMetadata(
-		id,
+		computeIDForMedia(sha256.New(), checksum),
 		name,
 		tagIds,
 		checksum,
+		storedChecksum,
+		size,
+		mimeType,
+		imageInfo,
+		nil,
 		false,
-		time.Now(),
-	), nil
+		time.Time{},
+		false,
+		lastUpdate,
+	)
 }
 
-func computeChecksum(file multipart.File, hasher hash.Hash) (string, error) {
-	var err error
-	defer func() {
-		_, sErr := file.Seek(0, io.SeekStart)
-		if sErr != nil && err == nil {
-			err = sErr
-		}
-	}()
+func withImageInfo(metadata model.MediaMetadata, imageInfo *model.ImageInfo) model.MediaMetadata {
+	return model.NewMediaMetadata(
+		metadata.ID(),
+		metadata.Name(),
+		metadata.TagIDs(),
+		metadata.Checksum(),
+		metadata.StoredChecksum(),
+		metadata.Size(),
+		metadata.MimeType(),
+		imageInfo,
+		metadata.Variants(),
+		metadata.UploadComplete(),
+		metadata.UploadCompletedAt(),
+		metadata.Deleting(),
+		metadata.LastUpdate(),
+	)
+}
 
-	buffer := make([]byte, 4096)
+// extractImageInfo returns nil for media, that isn't an image
+func extractImageInfo(mimeType string, head []byte) *model.ImageInfo {
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil
+	}
 
-	for {
-		n, err := file.Read(buffer)
-		if n > 0 {
-			_, err := hasher.Write(buffer[:n])
-			if err != nil {
-				return "", errortypes.NewInputOutputErrorf("failed to update hash: %v", err)
-			}
-		}
+	return imageinfo.Extract(head)
+}
 
-		if err == io.EOF {
-			break
-		}
+// headBuffer keeps the first bytes written to it, up to its limit
+type headBuffer struct {
+	data  []byte
+	limit int
+}
 
-		if err != nil {
-			return "", errortypes.NewInputOutputErrorf("error reading file: %v", err)
-		}
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - len(b.data); remaining > 0 {
+		b.data = append(b.data, p[:min(len(p), remaining)]...)
 	}
 
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+	return len(p), nil
 }
 
-func computeHashForMedia(hasher hash.Hash, name string, tagIds []string, checksum string) string {
-	hasher.Reset()
-	hasher.Write([]byte(name))
-	for _, tag := range tagIds {
-		hasher.Write([]byte(tag))
+// readHead reads as much of the file as needed to sniff its type. Files shorter than that are read completely.
+func readHead(file io.Reader) ([]byte, error) {
+	head := make([]byte, util.MimeTypeSniffLength)
+
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errortypes.NewInputOutputErrorf("failed to read media: %v", err)
 	}
+
+	return head[:n], nil
+}
+
+// countingReader counts the bytes read
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+
+	return n, err
+}
+
+// createRandomKey creates a random key. With a prefix it can't collide with media IDs, which are hex encoded.
+func createRandomKey(prefix string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", errortypes.NewIllegalStatef("failed to create random key: %v", err)
+	}
+
+	return prefix + hex.EncodeToString(random), nil
+}
+
+// directUploadKey is where clients upload the media of a direct upload. It must not be the media ID, since the upload
+// URL is valid after completing as well.
+func directUploadKey(id model.MediaID) string {
+	return id + directUploadKeySuffix
+}
+
+// computeIDForMedia derives the ID from the checksum only. Name and tags can change later on, but the ID must stay
+// stable. Since media is deduplicated by checksum, the checksum identifies the media anyway.
+func computeIDForMedia(hasher hash.Hash, checksum string) string {
+	hasher.Reset()
 	hasher.Write([]byte(checksum))
 
 	return hex.EncodeToString(hasher.Sum(nil))
@@ -228,3 +938,67 @@ func tagIdsEqual(lhs []model.TagID, rhs []model.TagID) bool {
 
 	return true
 }
+
+// mediaContent opens the media from the current offset to the end on the first read after a seek.
+type mediaContent struct {
+	ctx    context.Context
+	media  ports.MediaRepository
+	key    string
+	size   int64
+	offset int64
+	reader io.ReadCloser
+}
+
+func (c *mediaContent) Read(p []byte) (int, error) {
+	if c.offset >= c.size {
+		return 0, io.EOF
+	}
+
+	if c.reader == nil {
+		reader, err := c.media.OpenMedia(c.ctx, c.key, &model.ByteRange{Offset: c.offset, Length: c.size - c.offset})
+		if err != nil {
+			return 0, err
+		}
+
+		c.reader = reader
+	}
+
+	n, err := c.reader.Read(p)
+	c.offset += int64(n)
+
+	return n, err
+}
+
+func (c *mediaContent) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += c.offset
+	case io.SeekEnd:
+		offset += c.size
+	}
+
+	if offset < 0 {
+		return 0, errortypes.NewInvalidArgumentf("negative offset %v", offset)
+	}
+
+	if offset != c.offset {
+		if err := c.Close(); err != nil {
+			return 0, err
+		}
+
+		c.offset = offset
+	}
+
+	return c.offset, nil
+}
+
+func (c *mediaContent) Close() error {
+	if c.reader == nil {
+		return nil
+	}
+
+	err := c.reader.Close()
+	c.reader = nil
+
+	return err
+}
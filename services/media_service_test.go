@@ -0,0 +1,1082 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"maps"
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/errortypes"
+	"media-nexus/logger"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type mediaServiceTestSuite struct {
+	suite.Suite
+	ctx             context.Context
+	tags            ports.TagRepository
+	mediaMetadata   ports.MediaMetadataRepository
+	media           *keyTrackingMediaRepository
+	mediaDownloader ports.SignedMediaDownloader
+	jobs            ports.JobQueue
+	metrics         *countingMetrics
+	service         MediaService
+}
+
+func TestMediaService(t *testing.T) {
+	suite.Run(t, &mediaServiceTestSuite{})
+}
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+func newMemoryFile(content string) *memoryFile {
+	return &memoryFile{bytes.NewReader([]byte(content))}
+}
+
+// keyTrackingMediaRepository tracks the keys of the stored media, so we see what is left behind
+type keyTrackingMediaRepository struct {
+	ports.MediaRepository
+	keys map[string]bool
+}
+
+func (r *keyTrackingMediaRepository) CreateMedia(
+	ctx context.Context,
+	key string,
+	file io.Reader,
+	mimeType string,
+) error {
+	if err := r.MediaRepository.CreateMedia(ctx, key, file, mimeType); err != nil {
+		return err
+	}
+
+	r.keys[key] = true
+
+	return nil
+}
+
+func (r *keyTrackingMediaRepository) MoveMedia(ctx context.Context, fromKey string, toKey string) error {
+	if err := r.MediaRepository.MoveMedia(ctx, fromKey, toKey); err != nil {
+		return err
+	}
+
+	delete(r.keys, fromKey)
+	r.keys[toKey] = true
+
+	return nil
+}
+
+func (r *keyTrackingMediaRepository) DeleteAll(ctx context.Context, keys []string) error {
+	if err := r.MediaRepository.DeleteAll(ctx, keys); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		delete(r.keys, key)
+	}
+
+	return nil
+}
+
+// countingMetrics counts dedup hits and runs by runner and outcome. Other metrics are dropped.
+type countingMetrics struct {
+	metrics.Metrics
+	dedupHits int
+	runs      map[string]int
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{metrics.NewNopMetrics(), 0, map[string]int{}}
+}
+
+func (m *countingMetrics) CountDedupHit() {
+	m.dedupHits++
+}
+
+func (m *countingMetrics) CountRun(runner string, err error) {
+	if err != nil {
+		m.runs[runner+" failure"]++
+	} else {
+		m.runs[runner+" success"]++
+	}
+}
+
+// allowedMimeTypes allows the plain text the tests upload
+var allowedMimeTypes = []string{"text/plain"}
+
+// variantSizes get a variant for the 7x5 images of the tests only at size 4
+var variantSizes = []int{4, 16}
+
+const variantURL = "http://localhost/api/v1/media"
+
+// pngContent starts with the magic bytes of a PNG, which isn't allowed in the tests
+const pngContent = "\x89PNG\r\n\x1a\ncontent"
+
+// upperCaseTransformation stores plain text in upper case. Text starting with "broken" fails to transform.
+type upperCaseTransformation struct{}
+
+func (t upperCaseTransformation) Applies(mimeType string) bool {
+	return mimeType == "text/plain"
+}
+
+func (t upperCaseTransformation) Transform(w io.Writer, r io.Reader, mimeType string) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(string(content), "broken") {
+		return errortypes.NewBadUserInput("broken content")
+	}
+
+	_, err = w.Write(bytes.ToUpper(content))
+	return err
+}
+
+// encodePNG encodes an empty image of the given size
+func (s *mediaServiceTestSuite) encodePNG(width, height int) string {
+	var buf bytes.Buffer
+	s.Require().NoError(png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.String()
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *mediaServiceTestSuite) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("test"))
+	s.tags = amemory.NewTagRepository()
+	s.mediaMetadata = amemory.NewMediaMetadataRepository(time.Minute)
+	media, mediaDownloader, _ := amemory.NewMediaRepository("http://localhost/api/v1/blobs", []byte("key"))
+	s.media = &keyTrackingMediaRepository{media, map[string]bool{}}
+	s.mediaDownloader = mediaDownloader
+	s.jobs = amemory.NewJobQueue()
+	s.metrics = newCountingMetrics()
+	s.service = s.newService(allowedMimeTypes, nil)
+}
+
+func (s *mediaServiceTestSuite) newService(
+	allowedMimeTypes []string,
+	transformation MediaTransformation,
+) MediaService {
+	return NewMediaService(
+		s.tags,
+		s.mediaMetadata,
+		s.media,
+		time.Minute,
+		time.Minute,
+		time.Hour,
+		allowedMimeTypes,
+		transformation,
+		s.jobs,
+		variantSizes,
+		variantURL,
+		s.metrics,
+	)
+}
+
+func (s *mediaServiceTestSuite) blobKeys() []string {
+	return slices.Sorted(maps.Keys(s.media.keys))
+}
+
+func (s *mediaServiceTestSuite) readBlob(key string) string {
+	expires := time.Now().Add(time.Minute)
+	signature := util.NewURLSigner([]byte("key")).Sign(key, expires)
+
+	blob, err := s.mediaDownloader.OpenSignedMedia(s.ctx, key, expires, signature)
+	s.Require().NoError(err)
+	defer blob.Close()
+
+	content, err := io.ReadAll(blob)
+	s.Require().NoError(err)
+
+	return string(content)
+}
+
+func (s *mediaServiceTestSuite) createTag(name string) model.TagID {
+	tagID, err := s.tags.CreateTag(s.ctx, name)
+	s.Require().NoError(err)
+	return tagID
+}
+
+func (s *mediaServiceTestSuite) TestCreateMedia() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.NotEmpty(mediaID)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.True(metadata.UploadComplete())
+	s.Equal("name", metadata.Name())
+	s.Equal([]model.TagID{tagID}, metadata.TagIDs())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaWithUnknownTag() {
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{"unknown"}, newMemoryFile("content"))
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaIdempotency() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	mediaID2, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	s.Equal(mediaID, mediaID2)
+	s.Equal(1, s.metrics.dedupHits)
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaWithSameContentDifferentName() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	mediaID2, err := s.service.CreateMedia(s.ctx, "other", []model.TagID{tagID}, newMemoryFile("content"))
+	s.True(errortypes.IsResourceAlreadyExists(err))
+	s.Equal(mediaID, mediaID2)
+	s.Zero(s.metrics.dedupHits)
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaWhileUploadIncomplete() {
+	tagID := s.createTag("tag")
+
+	metadata := createMediaMetadata(
+		"name",
+		[]model.TagID{tagID},
+		checksum("content"),
+		checksum("content"),
+		7,
+		"text/plain",
+		nil,
+		time.Now(),
+	)
+	s.Require().NoError(s.mediaMetadata.Upsert(s.ctx, metadata))
+
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.True(errortypes.IsResourceAlreadyExists(err))
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaStoresBlobAtMediaID() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Equal(computeIDForMedia(sha256.New(), checksum("content")), mediaID)
+
+	s.Equal("content", s.readBlob(mediaID))
+	s.Equal([]string{mediaID}, s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaDiscardsDuplicateUpload() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	_, err = s.service.CreateMedia(s.ctx, "other", []model.TagID{tagID}, newMemoryFile("content"))
+	s.True(errortypes.IsResourceAlreadyExists(err))
+
+	s.Equal([]string{mediaID}, s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaWithFailingFile() {
+	tagID := s.createTag("tag")
+
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, iotest.ErrReader(errors.New("broken")))
+	s.Error(err)
+
+	s.Empty(s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaStoresSizeAndMimeType() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal(int64(len("content")), metadata.Size())
+	s.Equal("text/plain", metadata.MimeType())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaOfUnsupportedType() {
+	tagID := s.createTag("tag")
+
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(pngContent))
+	s.True(errortypes.IsUnsupportedMediaType(err))
+
+	s.Empty(s.blobKeys())
+
+	_, err = s.mediaMetadata.FindByChecksum(s.ctx, checksum(pngContent))
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaAllowsWildcardTypes() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(pngContent))
+	s.Require().NoError(err)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal("image/png", metadata.MimeType())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaExtractsImageInfo() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Require().NotNil(metadata.ImageInfo())
+	s.Equal(7, metadata.ImageInfo().Width)
+	s.Equal(5, metadata.ImageInfo().Height)
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaWithoutImageInfo() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Nil(metadata.ImageInfo())
+}
+
+func (s *mediaServiceTestSuite) TestDirectUpload() {
+	tagID := s.createTag("tag")
+
+	mediaID, uploadURL, err := s.service.CreateDirectUpload(
+		s.ctx,
+		"name",
+		[]model.TagID{tagID},
+		7,
+		checksum("content"),
+		"text/plain",
+	)
+	s.Require().NoError(err)
+	s.Require().NotNil(uploadURL)
+	s.NotEmpty(uploadURL.URL)
+	s.Equal(computeIDForMedia(sha256.New(), checksum("content")), mediaID)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.False(metadata.UploadComplete())
+
+	// the client uploads on its own
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile("content"), ""))
+
+	mediaItem, err := s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.True(mediaItem.UploadComplete())
+	s.Equal(int64(7), mediaItem.Size())
+	s.Equal("content", s.readBlob(mediaID))
+
+	// the upload was moved to the media ID, only empty media is left at the upload key
+	s.Empty(s.readBlob(directUploadKey(mediaID)))
+
+	// completing again is fine
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.NoError(err)
+}
+
+func (s *mediaServiceTestSuite) TestCreateDirectUploadForExistingMedia() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	mediaID2, uploadURL, err := s.service.CreateDirectUpload(
+		s.ctx,
+		"name",
+		[]model.TagID{tagID},
+		7,
+		checksum("content"),
+		"text/plain",
+	)
+	s.Require().NoError(err)
+	s.Nil(uploadURL)
+	s.Equal(mediaID, mediaID2)
+}
+
+func (s *mediaServiceTestSuite) TestCreateDirectUploadWithInvalidChecksum() {
+	_, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, 7, "content", "text/plain")
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *mediaServiceTestSuite) TestCreateDirectUploadOfUnsupportedType() {
+	_, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, 7, checksum("content"), "image/png")
+	s.True(errortypes.IsUnsupportedMediaType(err))
+}
+
+func (s *mediaServiceTestSuite) TestCompleteDirectUploadOfOtherType() {
+	size := int64(len(pngContent))
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, size, checksum(pngContent), "text/plain")
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile(pngContent), ""))
+
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.True(errortypes.IsUnsupportedMediaType(err))
+	s.Empty(s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestCompleteDirectUploadExtractsImageInfo() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	content := s.encodePNG(7, 5)
+
+	size := int64(len(content))
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, size, checksum(content), "image/png")
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile(content), ""))
+
+	mediaItem, err := s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.True(mediaItem.UploadComplete())
+	s.Require().NotNil(mediaItem.ImageInfo())
+	s.Equal(7, mediaItem.ImageInfo().Width)
+	s.Equal(5, mediaItem.ImageInfo().Height)
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaTransformed() {
+	s.service = s.newService(allowedMimeTypes, upperCaseTransformation{})
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Equal(computeIDForMedia(sha256.New(), checksum("content")), mediaID)
+	s.Equal([]string{mediaID}, s.blobKeys())
+	s.Equal("CONTENT", s.readBlob(mediaID))
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal(checksum("content"), metadata.Checksum())
+	s.Equal(checksum("CONTENT"), metadata.StoredChecksum())
+
+	// the same upload is still recognized
+	mediaID2, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Equal(mediaID, mediaID2)
+	s.Equal([]string{mediaID}, s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaNotTransformed() {
+	s.service = s.newService([]string{"text/*"}, upperCaseTransformation{})
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("<html></html>"))
+	s.Require().NoError(err)
+	s.Equal("<html></html>", s.readBlob(mediaID))
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal(metadata.Checksum(), metadata.StoredChecksum())
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaWithFailingTransformation() {
+	s.service = s.newService(allowedMimeTypes, upperCaseTransformation{})
+	tagID := s.createTag("tag")
+
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("broken content"))
+	s.True(errortypes.IsBadUserInput(err), "expected bad user input, got %v", err)
+	s.Empty(s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestCompleteDirectUploadTransformed() {
+	s.service = s.newService(allowedMimeTypes, upperCaseTransformation{})
+
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, 7, checksum("content"), "text/plain")
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile("content"), ""))
+
+	mediaItem, err := s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.True(mediaItem.UploadComplete())
+	s.Equal(checksum("content"), mediaItem.Checksum())
+	s.Equal(checksum("CONTENT"), mediaItem.StoredChecksum())
+	s.Equal([]string{mediaID, directUploadKey(mediaID)}, s.blobKeys())
+	s.Equal("CONTENT", s.readBlob(mediaID))
+	s.Empty(s.readBlob(directUploadKey(mediaID)))
+
+	// S3 lets the upload URL replace the upload until it expires, but not the transformed media
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile("content"), ""))
+	s.Equal("CONTENT", s.readBlob(mediaID))
+}
+
+func (s *mediaServiceTestSuite) TestCompleteDirectUploadAfterCrashedTransformation() {
+	s.service = s.newService(allowedMimeTypes, upperCaseTransformation{})
+
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, 7, checksum("content"), "text/plain")
+	s.Require().NoError(err)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+
+	// the stored checksum was recorded, but the transformed media wasn't moved into place
+	transformed := model.NewMediaMetadata(
+		mediaID,
+		metadata.Name(),
+		metadata.TagIDs(),
+		metadata.Checksum(),
+		checksum("CONTENT"),
+		7,
+		metadata.MimeType(),
+		nil,
+		nil,
+		false,
+		time.Time{},
+		false,
+		metadata.LastUpdate(),
+	)
+	s.Require().NoError(s.mediaMetadata.Upsert(s.ctx, transformed))
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile("content"), ""))
+
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal("CONTENT", s.readBlob(mediaID))
+
+	// the transformed media was moved into place, but the upload wasn't completed
+	s.Require().NoError(s.mediaMetadata.SetUploadComplete(s.ctx, mediaID, false))
+
+	mediaItem, err := s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.True(mediaItem.UploadComplete())
+	s.Equal("CONTENT", s.readBlob(mediaID))
+}
+
+func (s *mediaServiceTestSuite) TestCompleteDirectUploadWithFailingTransformation() {
+	s.service = s.newService(allowedMimeTypes, upperCaseTransformation{})
+
+	size := int64(len("broken content"))
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, size, checksum("broken content"), "text/plain")
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile("broken content"), ""))
+
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.True(errortypes.IsBadUserInput(err), "expected bad user input, got %v", err)
+	s.Empty(s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestCompleteDirectUploadBeforeUpload() {
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, 7, checksum("content"), "text/plain")
+	s.Require().NoError(err)
+
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.True(errortypes.IsConflict(err))
+}
+
+func (s *mediaServiceTestSuite) TestCompleteDirectUploadWithOtherContent() {
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, 7, checksum("content"), "text/plain")
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile("CONTENT"), ""))
+
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.True(errortypes.IsBadUserInput(err))
+	s.Empty(s.blobKeys())
+
+	// the client can try again
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile("content"), ""))
+
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.NoError(err)
+}
+
+func (s *mediaServiceTestSuite) TestFindByTagID() {
+	tagID := s.createTag("tag")
+	tagID2 := s.createTag("tag2")
+
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID, tagID2}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	_, err = s.service.CreateMedia(s.ctx, "name2", []model.TagID{tagID}, newMemoryFile("content2"))
+	s.Require().NoError(err)
+
+	items, next, err := s.service.FindByTagID(s.ctx, tagID, model.ImageFilter{}, model.PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Len(items, 2)
+	s.Empty(next)
+
+	items, _, err = s.service.FindByTagID(s.ctx, tagID2, model.ImageFilter{}, model.PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(items, 1)
+	s.Equal("name", items[0].Name())
+	s.NotEmpty(items[0].FileURL())
+}
+
+func (s *mediaServiceTestSuite) TestFindByTagIDWithImageFilter() {
+	s.service = s.newService([]string{"image/*", "text/plain"}, nil)
+	tagID := s.createTag("tag")
+
+	small, err := s.service.CreateMedia(s.ctx, "small", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+
+	_, err = s.service.CreateMedia(s.ctx, "large", []model.TagID{tagID}, newMemoryFile(s.encodePNG(70, 50)))
+	s.Require().NoError(err)
+
+	_, err = s.service.CreateMedia(s.ctx, "text", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	filter := model.ImageFilter{MaxWidth: 10}
+	items, _, err := s.service.FindByTagID(s.ctx, tagID, filter, model.PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(items, 1)
+	s.Equal(small, items[0].ID())
+}
+
+func (s *mediaServiceTestSuite) TestFindByTagIDPaged() {
+	tagID := s.createTag("tag")
+
+	var mediaIDs []model.MediaID
+	for _, content := range []string{"content", "content2", "content3"} {
+		mediaID, err := s.service.CreateMedia(s.ctx, content, []model.TagID{tagID}, newMemoryFile(content))
+		s.Require().NoError(err)
+		mediaIDs = append(mediaIDs, mediaID)
+	}
+
+	items, next, err := s.service.FindByTagID(s.ctx, tagID, model.ImageFilter{}, model.PageRequest{Limit: 2})
+	s.Require().NoError(err)
+	s.Len(items, 2)
+	s.Require().NotEmpty(next)
+
+	items2, next, err := s.service.FindByTagID(s.ctx, tagID, model.ImageFilter{}, model.PageRequest{After: next, Limit: 2})
+	s.Require().NoError(err)
+	s.Len(items2, 1)
+	s.Empty(next)
+
+	var found []model.MediaID
+	for _, item := range append(items, items2...) {
+		found = append(found, item.ID())
+	}
+
+	s.ElementsMatch(mediaIDs, found)
+}
+
+func (s *mediaServiceTestSuite) TestFindByTagIDPagedSkipsDeletingMedia() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	mediaID2, err := s.service.CreateMedia(s.ctx, "name2", []model.TagID{tagID}, newMemoryFile("content2"))
+	s.Require().NoError(err)
+
+	// the first page only holds media being deleted, but the cursor still has to move on
+	first := min(mediaID, mediaID2)
+	s.Require().NoError(s.mediaMetadata.SetDeleting(s.ctx, first, true))
+
+	items, next, err := s.service.FindByTagID(s.ctx, tagID, model.ImageFilter{}, model.PageRequest{Limit: 1})
+	s.Require().NoError(err)
+	s.Empty(items)
+	s.Equal(first, next)
+
+	items, next, err = s.service.FindByTagID(s.ctx, tagID, model.ImageFilter{}, model.PageRequest{After: next, Limit: 1})
+	s.Require().NoError(err)
+	s.Require().Len(items, 1)
+	s.Equal(max(mediaID, mediaID2), items[0].ID())
+	s.Empty(next)
+}
+
+func (s *mediaServiceTestSuite) TestGetMedia() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	item, err := s.service.GetMedia(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal(mediaID, item.ID())
+	s.Equal("name", item.Name())
+	s.True(item.UploadComplete())
+	s.NotEmpty(item.FileURL())
+}
+
+func (s *mediaServiceTestSuite) TestOpenMediaContent() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	metadata, content, err := s.service.OpenMediaContent(s.ctx, mediaID)
+	s.Require().NoError(err)
+	defer content.Close()
+
+	s.Equal(checksum("content"), metadata.Checksum())
+
+	read, err := io.ReadAll(content)
+	s.Require().NoError(err)
+	s.Equal("content", string(read))
+
+	size, err := content.Seek(0, io.SeekEnd)
+	s.Require().NoError(err)
+	s.Equal(int64(7), size)
+
+	_, err = content.Seek(3, io.SeekStart)
+	s.Require().NoError(err)
+
+	read, err = io.ReadAll(content)
+	s.Require().NoError(err)
+	s.Equal("tent", string(read))
+}
+
+func (s *mediaServiceTestSuite) TestOpenMediaContentWhileUploading() {
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, 7, checksum("content"), "text/plain")
+	s.Require().NoError(err)
+
+	_, _, err = s.service.OpenMediaContent(s.ctx, mediaID)
+	s.True(errortypes.IsConflict(err))
+}
+
+func (s *mediaServiceTestSuite) TestGetMissingMedia() {
+	_, err := s.service.GetMedia(s.ctx, "unknown")
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *mediaServiceTestSuite) TestGetMediaWithVariantURLs() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+
+	item, err := s.service.GetMedia(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal(variantURL+"/"+mediaID+"/variants/4", item.VariantURLs()[4])
+	s.Equal(item.FileURL(), item.VariantURLs()[16])
+}
+
+func (s *mediaServiceTestSuite) TestGetMediaWithoutVariantURLs() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	item, err := s.service.GetMedia(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Nil(item.VariantURLs())
+}
+
+func (s *mediaServiceTestSuite) TestGetVariantURLGeneratesVariant() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+
+	url, err := s.service.GetVariantURL(s.ctx, mediaID, 4)
+	s.Require().NoError(err)
+	s.Contains(url, variantKey(mediaID, 4))
+
+	config, err := png.DecodeConfig(strings.NewReader(s.readBlob(variantKey(mediaID, 4))))
+	s.Require().NoError(err)
+	s.Equal(4, config.Width)
+	s.Equal(2, config.Height)
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal([]int{4}, metadata.Variants())
+
+	item, err := s.service.GetMedia(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Contains(item.VariantURLs()[4], variantKey(mediaID, 4))
+}
+
+func (s *mediaServiceTestSuite) TestGetVariantURLOfSmallImage() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+
+	url, err := s.service.GetVariantURL(s.ctx, mediaID, 16)
+	s.Require().NoError(err)
+	s.NotContains(url, variantKey(mediaID, 16))
+	s.Equal([]string{mediaID}, s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestGetVariantURLOfUnknownSize() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+
+	_, err = s.service.GetVariantURL(s.ctx, mediaID, 8)
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *mediaServiceTestSuite) TestGetVariantURLOfOtherType() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	_, err = s.service.GetVariantURL(s.ctx, mediaID, 4)
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *mediaServiceTestSuite) TestGenerateVariantsAfterUpload() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+
+	job, err := s.jobs.Lease(s.ctx, []string{JobTypeGenerateVariants}, time.Minute)
+	s.Require().NoError(err)
+	s.Equal(mediaID, job.Payload)
+
+	s.Require().NoError(GenerateVariantsJobHandler(s.service)(s.ctx, job))
+
+	metadata, err := s.mediaMetadata.Get(s.ctx, mediaID)
+	s.Require().NoError(err)
+	s.Equal([]int{4}, metadata.Variants())
+	s.Equal([]string{mediaID, variantKey(mediaID, 4)}, s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestGenerateVariantsAfterDirectUpload() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	content := s.encodePNG(7, 5)
+
+	size := int64(len(content))
+	mediaID, _, err := s.service.CreateDirectUpload(s.ctx, "name", nil, size, checksum(content), "image/png")
+	s.Require().NoError(err)
+	s.Require().NoError(s.media.CreateMedia(s.ctx, directUploadKey(mediaID), newMemoryFile(content), "image/png"))
+
+	_, err = s.service.CompleteDirectUpload(s.ctx, mediaID)
+	s.Require().NoError(err)
+
+	job, err := s.jobs.Lease(s.ctx, []string{JobTypeGenerateVariants}, time.Minute)
+	s.Require().NoError(err)
+	s.Equal(mediaID, job.Payload)
+}
+
+func (s *mediaServiceTestSuite) TestGenerateVariantsOnlyForImages() {
+	tagID := s.createTag("tag")
+
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	_, err = s.jobs.Lease(s.ctx, []string{JobTypeGenerateVariants}, time.Minute)
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *mediaServiceTestSuite) TestGenerateVariantsOfDeletedMedia() {
+	s.Require().NoError(s.service.GenerateMissingVariants(s.ctx, "unknown"))
+}
+
+func (s *mediaServiceTestSuite) TestDeleteMediaDeletesVariants() {
+	s.service = s.newService([]string{"image/*"}, nil)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile(s.encodePNG(7, 5)))
+	s.Require().NoError(err)
+	_, err = s.service.GetVariantURL(s.ctx, mediaID, 4)
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.service.DeleteMedia(s.ctx, mediaID))
+
+	s.Empty(s.blobKeys())
+}
+
+func (s *mediaServiceTestSuite) TestDeleteMedia() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.service.DeleteMedia(s.ctx, mediaID))
+
+	_, err = s.mediaMetadata.Get(s.ctx, mediaID)
+	s.True(errortypes.IsResourceNotFound(err))
+
+	err = s.service.DeleteMedia(s.ctx, mediaID)
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *mediaServiceTestSuite) TestDeleteMediaWhileUploading() {
+	tagID := s.createTag("tag")
+
+	metadata := createMediaMetadata(
+		"name",
+		[]model.TagID{tagID},
+		checksum("content"),
+		checksum("content"),
+		7,
+		"text/plain",
+		nil,
+		time.Now(),
+	)
+	s.Require().NoError(s.mediaMetadata.Upsert(s.ctx, metadata))
+
+	err := s.service.DeleteMedia(s.ctx, metadata.ID())
+	s.True(errortypes.IsConflict(err))
+}
+
+func (s *mediaServiceTestSuite) TestDeleteMediaAfterCrash() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Require().NoError(s.mediaMetadata.SetDeleting(s.ctx, mediaID, true))
+
+	_, err = s.service.GetMedia(s.ctx, mediaID)
+	s.True(errortypes.IsResourceNotFound(err))
+
+	s.Require().NoError(s.service.DeleteMedia(s.ctx, mediaID))
+
+	_, err = s.mediaMetadata.Get(s.ctx, mediaID)
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaWhileDeleting() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Require().NoError(s.mediaMetadata.SetDeleting(s.ctx, mediaID, true))
+
+	_, err = s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.True(errortypes.IsConflict(err))
+}
+
+func (s *mediaServiceTestSuite) TestCreateMediaAfterCrashedDeletion() {
+	s.service = NewMediaService(
+		s.tags,
+		s.mediaMetadata,
+		s.media,
+		time.Minute,
+		0,
+		time.Hour,
+		allowedMimeTypes,
+		nil,
+		s.jobs,
+		variantSizes,
+		variantURL,
+		s.metrics,
+	)
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Require().NoError(s.mediaMetadata.SetDeleting(s.ctx, mediaID, true))
+
+	mediaID2, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Equal(mediaID, mediaID2)
+
+	item, err := s.service.GetMedia(s.ctx, mediaID2)
+	s.Require().NoError(err)
+	s.False(item.Deleting())
+}
+
+func (s *mediaServiceTestSuite) TestUpdateMedia() {
+	tagID := s.createTag("tag")
+	tagID2 := s.createTag("tag2")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	name := "renamed"
+	item, err := s.service.UpdateMedia(s.ctx, mediaID, model.MediaMetadataUpdate{
+		Name:         &name,
+		AddTagIDs:    []model.TagID{tagID2},
+		RemoveTagIDs: []model.TagID{tagID},
+	})
+	s.Require().NoError(err)
+	s.Equal(mediaID, item.ID())
+	s.Equal("renamed", item.Name())
+	s.Equal([]model.TagID{tagID2}, item.TagIDs())
+
+	mediaID2, err := s.service.CreateMedia(s.ctx, "renamed", []model.TagID{tagID2}, newMemoryFile("content"))
+	s.Require().NoError(err)
+	s.Equal(mediaID, mediaID2)
+}
+
+func (s *mediaServiceTestSuite) TestUpdateMediaWithUnknownTag() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	_, err = s.service.UpdateMedia(s.ctx, mediaID, model.MediaMetadataUpdate{TagIDs: []model.TagID{"unknown"}})
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *mediaServiceTestSuite) TestUpdateMediaReplacingAndAddingTags() {
+	tagID := s.createTag("tag")
+
+	mediaID, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	_, err = s.service.UpdateMedia(s.ctx, mediaID, model.MediaMetadataUpdate{
+		TagIDs:    []model.TagID{tagID},
+		AddTagIDs: []model.TagID{tagID},
+	})
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *mediaServiceTestSuite) TestUpdateMissingMedia() {
+	name := "renamed"
+	_, err := s.service.UpdateMedia(s.ctx, "unknown", model.MediaMetadataUpdate{Name: &name})
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *mediaServiceTestSuite) TestFindByQuery() {
+	tagID := s.createTag("tag")
+	tagID2 := s.createTag("tag2")
+
+	_, err := s.service.CreateMedia(s.ctx, "name", []model.TagID{tagID, tagID2}, newMemoryFile("content"))
+	s.Require().NoError(err)
+
+	_, err = s.service.CreateMedia(s.ctx, "name2", []model.TagID{tagID}, newMemoryFile("content2"))
+	s.Require().NoError(err)
+
+	q := tagID + " AND NOT " + tagID2
+	items, _, err := s.service.FindByQuery(s.ctx, q, model.ImageFilter{}, model.PageRequest{Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Len(items, 1)
+	s.Equal("name2", items[0].Name())
+}
+
+func (s *mediaServiceTestSuite) TestFindByInvalidQuery() {
+	_, _, err := s.service.FindByQuery(s.ctx, "tag AND", model.ImageFilter{}, model.PageRequest{Limit: 10})
+	s.True(errortypes.IsBadUserInput(err))
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"io"
+)
+
+// MediaTransformation rewrites media while it is uploaded, before it is stored. Transform reads the media from r and
+// writes the transformed media to w. It is only called for media Applies returns true for.
+type MediaTransformation interface {
+	Applies(mimeType string) bool
+	Transform(w io.Writer, r io.Reader, mimeType string) error
+}
+
+// transformedReader reads the output of a transformation running in the background.
+type transformedReader struct {
+	*io.PipeReader
+	done chan error
+}
+
+// startTransformation reads all of r, even what the transformation leaves out, so checksums computed while reading r
+// cover the whole media.
+func startTransformation(transformation MediaTransformation, r io.Reader, mimeType string) *transformedReader {
+	pipeReader, pipeWriter := io.Pipe()
+	reader := &transformedReader{pipeReader, make(chan error, 1)}
+
+	go func() {
+		err := transformation.Transform(pipeWriter, r, mimeType)
+		if err == nil {
+			_, err = io.Copy(io.Discard, r)
+		}
+
+		pipeWriter.CloseWithError(err)
+		reader.done <- err
+	}()
+
+	return reader
+}
+
+// finish stops the transformation and waits for it. The error of the transformation takes precedence over err of
+// the consumer, because it is the cause, if both failed. That the consumer stopped reading is no error of the
+// transformation, though.
+func (r *transformedReader) finish(err error) error {
+	_ = r.PipeReader.Close()
+
+	if transformErr := <-r.done; transformErr != nil && !errors.Is(transformErr, io.ErrClosedPipe) {
+		return transformErr
+	}
+
+	return err
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"media-nexus/services/variants"
+	"media-nexus/util"
+	"slices"
+)
+
+// JobTypeGenerateVariants generates the variants of the media with the ID in the payload
+const JobTypeGenerateVariants = "generate_variants"
+
+// variantKey derives the key a variant is stored at in the media repository from the media ID and the variant size
+func variantKey(id model.MediaID, size int) string {
+	return fmt.Sprintf("%v_%v", id, size)
+}
+
+// variantKeys returns the keys of all variants the media might have, including those of sizes not configured anymore
+func (s *mediaService) variantKeys(metadata model.MediaMetadata) []string {
+	sizes := append(slices.Clone(s.variantSizes), metadata.Variants()...)
+	slices.Sort(sizes)
+
+	keys := make([]string, 0, len(sizes))
+	for _, size := range slices.Compact(sizes) {
+		keys = append(keys, variantKey(metadata.ID(), size))
+	}
+
+	return keys
+}
+
+// hasVariants tells whether variants are generated for the media. Images too large to decode safely have none.
+func (s *mediaService) hasVariants(metadata model.MediaMetadata) bool {
+	imageInfo := metadata.ImageInfo()
+	if len(s.variantSizes) < 1 || imageInfo == nil || !variants.Supports(metadata.MimeType()) {
+		return false
+	}
+
+	return int64(imageInfo.Width)*int64(imageInfo.Height) <= variants.MaxPixels
+}
+
+// needsVariant tells whether the image is larger than the variant size. Smaller images serve as their own variant.
+func needsVariant(metadata model.MediaMetadata, size int) bool {
+	imageInfo := metadata.ImageInfo()
+	return imageInfo.Width > size || imageInfo.Height > size
+}
+
+// missingVariants returns the configured sizes, that need a variant, which wasn't generated yet
+func (s *mediaService) missingVariants(metadata model.MediaMetadata) []int {
+	var missing []int
+	for _, size := range s.variantSizes {
+		if needsVariant(metadata, size) && !slices.Contains(metadata.Variants(), size) {
+			missing = append(missing, size)
+		}
+	}
+
+	return missing
+}
+
+// variantURLs maps the configured sizes to the URLs of the variants. Variants not generated yet point to the endpoint
+// generating them on demand. Returns nil for media without variants.
+func (s *mediaService) variantURLs(
+	ctx context.Context,
+	metadata model.MediaMetadata,
+	fileURL string,
+) map[int]string {
+	if !s.hasVariants(metadata) {
+		return nil
+	}
+
+	urls := make(map[int]string, len(s.variantSizes))
+
+	for _, size := range s.variantSizes {
+		switch {
+		case !needsVariant(metadata, size):
+			urls[size] = fileURL
+		case slices.Contains(metadata.Variants(), size):
+			url, err := s.media.GetMediaURL(ctx, variantKey(metadata.ID(), size), s.mediaURLLifetime)
+			if err != nil {
+				util.Logger(ctx).Errorf("failed to get variant url. Leaving it out. Details: %v", err)
+				continue
+			}
+
+			urls[size] = url
+		default:
+			urls[size] = fmt.Sprintf("%v/%v/variants/%v", s.variantURL, metadata.ID(), size)
+		}
+	}
+
+	return urls
+}
+
+func (s *mediaService) GetVariantURL(ctx context.Context, id model.MediaID, size int) (string, error) {
+	if !slices.Contains(s.variantSizes, size) {
+		return "", errortypes.NewBadUserInputf("no variants of size %v. Available sizes are %v", size, s.variantSizes)
+	}
+
+	metadata, err := s.mediaMetadata.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if metadata.Deleting() {
+		return "", errortypes.NewResourceNotFound(id)
+	}
+
+	if !metadata.UploadComplete() {
+		return "", errortypes.NewConflictf("media with id '%v' is currently being uploaded", id)
+	}
+
+	if !s.hasVariants(metadata) {
+		return "", errortypes.NewResourceNotFoundf("media with id '%v' has no variants", id)
+	}
+
+	if !needsVariant(metadata, size) {
+		return s.media.GetMediaURL(ctx, id, s.mediaURLLifetime)
+	}
+
+	if !slices.Contains(metadata.Variants(), size) {
+		if err := s.generateVariants(ctx, metadata, []int{size}); err != nil {
+			return "", err
+		}
+	}
+
+	return s.media.GetMediaURL(ctx, variantKey(id, size), s.mediaURLLifetime)
+}
+
+// generateVariants decodes the image once and stores a variant for each of the sizes. Generating a variant again
+// just overwrites it, so concurrent generation of the same variant is harmless.
+func (s *mediaService) generateVariants(ctx context.Context, metadata model.MediaMetadata, sizes []int) error {
+	media, err := s.media.OpenMedia(ctx, metadata.ID(), nil)
+	if err != nil {
+		return err
+	}
+	defer media.Close()
+
+	img, format, err := variants.Decode(media)
+	if err != nil {
+		return err
+	}
+
+	for _, size := range sizes {
+		var buf bytes.Buffer
+
+		resized := variants.Resize(img, size, metadata.ImageInfo().Orientation)
+		mimeType, err := variants.Encode(&buf, resized, format)
+		if err != nil {
+			return err
+		}
+
+		if err := s.media.CreateMedia(ctx, variantKey(metadata.ID(), size), &buf, mimeType); err != nil {
+			return err
+		}
+
+		if err := s.mediaMetadata.AddVariant(ctx, metadata.ID(), size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueueVariants doesn't fail the upload. If the job can't be enqueued, the variants are generated on demand instead.
+func (s *mediaService) enqueueVariants(ctx context.Context, metadata model.MediaMetadata) {
+	if !s.hasVariants(metadata) {
+		return
+	}
+
+	job, err := newJob(JobTypeGenerateVariants, metadata.ID())
+	if err == nil {
+		err = s.jobs.Enqueue(ctx, job)
+	}
+
+	if err != nil {
+		util.Logger(ctx).Errorf("failed to enqueue variants of media %v. Leaving them on demand: %v", metadata.ID(), err)
+	}
+}
+
+// GenerateVariantsJobHandler runs the jobs enqueued by the media service to generate variants
+func GenerateVariantsJobHandler(service MediaService) JobHandler {
+	return func(ctx context.Context, job *model.Job) error {
+		return service.GenerateMissingVariants(ctx, job.Payload)
+	}
+}
+
+func (s *mediaService) GenerateMissingVariants(ctx context.Context, id model.MediaID) error {
+	metadata, err := s.mediaMetadata.Get(ctx, id)
+	if errortypes.IsResourceNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if metadata.Deleting() || !metadata.UploadComplete() || !s.hasVariants(metadata) {
+		return nil
+	}
+
+	missing := s.missingVariants(metadata)
+	if len(missing) < 1 {
+		return nil
+	}
+
+	return s.generateVariants(ctx, metadata, missing)
+}
@@ -0,0 +1,64 @@
+// Package query parses boolean tag queries like `(tagA AND tagB) OR NOT tagC` into an expression tree.
+package query
+
+import "media-nexus/model"
+
+// Expression is a node of the parsed query. It's one of Tag, And, Or or Not.
+type Expression interface {
+	// Matches tells whether media with the given tags is matched by the expression
+	Matches(tagIDs []model.TagID) bool
+}
+
+// Tag matches media having the tag.
+type Tag struct {
+	ID model.TagID
+}
+
+// And matches media matched by all operands.
+type And struct {
+	Operands []Expression
+}
+
+// Or matches media matched by any operand.
+type Or struct {
+	Operands []Expression
+}
+
+// Not matches media not matched by the operand.
+type Not struct {
+	Operand Expression
+}
+
+func (e *Tag) Matches(tagIDs []model.TagID) bool {
+	for _, tagID := range tagIDs {
+		if tagID == e.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *And) Matches(tagIDs []model.TagID) bool {
+	for _, operand := range e.Operands {
+		if !operand.Matches(tagIDs) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *Or) Matches(tagIDs []model.TagID) bool {
+	for _, operand := range e.Operands {
+		if operand.Matches(tagIDs) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *Not) Matches(tagIDs []model.TagID) bool {
+	return !e.Operand.Matches(tagIDs)
+}
@@ -0,0 +1,247 @@
+package query
+
+import (
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"strings"
+)
+
+const maxDepth = 32
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenLeftParen
+	tokenRightParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenTag
+)
+
+type token struct {
+	typ   tokenType
+	value string
+	// pos is the byte offset of the token in the query
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "end of query"
+	}
+
+	return "'" + t.value + "'"
+}
+
+// Parse parses a query of tag IDs combined with AND, OR, NOT and parentheses. NOT binds strongest, then AND, then
+// OR. Operators are case-insensitive. Tag IDs with other characters than letters, digits, '_' and '-', or named like
+// an operator, are put in double quotes, with '"' and '\' escaped by a backslash. Errors are BadUserInput and contain
+// the (1-based) position of the problem.
+func Parse(query string) (Expression, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	expression, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if next := p.peek(); next.typ != tokenEOF {
+		return nil, newParseError(next.pos, "unexpected %v", next)
+	}
+
+	return expression, nil
+}
+
+func newParseError(pos int, format string, a ...interface{}) error {
+	return errortypes.NewBadUserInputf("invalid query at position %v: "+format, append([]interface{}{pos + 1}, a...)...)
+}
+
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+
+	for pos := 0; pos < len(query); {
+		c := query[pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{tokenLeftParen, "(", pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{tokenRightParen, ")", pos})
+			pos++
+		case c == '"':
+			tagID, end, err := readQuotedTagID(query, pos)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{tokenTag, tagID, pos})
+			pos = end
+		case isTagIDChar(c):
+			start := pos
+			for pos < len(query) && isTagIDChar(query[pos]) {
+				pos++
+			}
+
+			tokens = append(tokens, newWordToken(query[start:pos], start))
+		default:
+			return nil, newParseError(pos, "unexpected character '%c'", c)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(query)}), nil
+}
+
+func isTagIDChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-'
+}
+
+// readQuotedTagID reads the tag ID in double quotes starting at pos. Returns the position after the closing quote.
+func readQuotedTagID(query string, pos int) (string, int, error) {
+	var tagID strings.Builder
+
+	for i := pos + 1; i < len(query); i++ {
+		switch query[i] {
+		case '"':
+			if tagID.Len() == 0 {
+				return "", 0, newParseError(pos, "empty tag ID")
+			}
+
+			return tagID.String(), i + 1, nil
+		case '\\':
+			i++
+			if i < len(query) && (query[i] == '"' || query[i] == '\\') {
+				tagID.WriteByte(query[i])
+				continue
+			}
+
+			return "", 0, newParseError(i-1, "only '\"' and '\\' can be escaped")
+		default:
+			tagID.WriteByte(query[i])
+		}
+	}
+
+	return "", 0, newParseError(pos, "missing closing '\"'")
+}
+
+func newWordToken(word string, pos int) token {
+	switch strings.ToUpper(word) {
+	case "AND":
+		return token{tokenAnd, word, pos}
+	case "OR":
+		return token{tokenOr, word, pos}
+	case "NOT":
+		return token{tokenNot, word, pos}
+	}
+
+	return token{tokenTag, word, pos}
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) consume() token {
+	t := p.tokens[p.next]
+	if t.typ != tokenEOF {
+		p.next++
+	}
+
+	return t
+}
+
+func (p *parser) parseOr(depth int) (Expression, error) {
+	operands, err := p.parseOperands(tokenOr, depth, p.parseAnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+
+	return &Or{operands}, nil
+}
+
+func (p *parser) parseAnd(depth int) (Expression, error) {
+	operands, err := p.parseOperands(tokenAnd, depth, p.parseUnary)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+
+	return &And{operands}, nil
+}
+
+func (p *parser) parseOperands(
+	operator tokenType,
+	depth int,
+	parseOperand func(depth int) (Expression, error),
+) ([]Expression, error) {
+	var operands []Expression
+
+	for {
+		operand, err := parseOperand(depth)
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, operand)
+
+		if p.peek().typ != operator {
+			return operands, nil
+		}
+
+		p.consume()
+	}
+}
+
+func (p *parser) parseUnary(depth int) (Expression, error) {
+	if depth > maxDepth {
+		return nil, newParseError(p.peek().pos, "query nested too deeply. Maximum is %v", maxDepth)
+	}
+
+	t := p.consume()
+
+	switch t.typ {
+	case tokenNot:
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Not{operand}, nil
+	case tokenLeftParen:
+		expression, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.consume(); closing.typ != tokenRightParen {
+			return nil, newParseError(closing.pos, "expected ')' but got %v", closing)
+		}
+
+		return expression, nil
+	case tokenTag:
+		return &Tag{model.TagID(t.value)}, nil
+	}
+
+	return nil, newParseError(t.pos, "expected tag ID, NOT or '(' but got %v", t)
+}
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"media-nexus/errortypes"
+	"media-nexus/model"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type parserTestSuite struct {
+	suite.Suite
+}
+
+func TestParser(t *testing.T) {
+	suite.Run(t, &parserTestSuite{})
+}
+
+func (s *parserTestSuite) TestParseTag() {
+	expression, err := Parse("tagA")
+	s.Require().NoError(err)
+	s.Equal(&Tag{"tagA"}, expression)
+}
+
+func (s *parserTestSuite) TestParsePrecedence() {
+	expression, err := Parse("a OR b and not c")
+	s.Require().NoError(err)
+	s.Equal(&Or{[]Expression{
+		&Tag{"a"},
+		&And{[]Expression{&Tag{"b"}, &Not{&Tag{"c"}}}},
+	}}, expression)
+}
+
+func (s *parserTestSuite) TestParseParentheses() {
+	expression, err := Parse("(tagA AND tagB) OR NOT tagC")
+	s.Require().NoError(err)
+	s.Equal(&Or{[]Expression{
+		&And{[]Expression{&Tag{"tagA"}, &Tag{"tagB"}}},
+		&Not{&Tag{"tagC"}},
+	}}, expression)
+
+	expression, err = Parse("tagA AND (tagB OR tagC)")
+	s.Require().NoError(err)
+	s.Equal(&And{[]Expression{
+		&Tag{"tagA"},
+		&Or{[]Expression{&Tag{"tagB"}, &Tag{"tagC"}}},
+	}}, expression)
+}
+
+func (s *parserTestSuite) TestParseQuotedTag() {
+	expression, err := Parse(`"and" OR "tag with spaces" AND NOT "a\"b\\c"`)
+	s.Require().NoError(err)
+	s.Equal(&Or{[]Expression{
+		&Tag{"and"},
+		&And{[]Expression{&Tag{"tag with spaces"}, &Not{&Tag{`a"b\c`}}}},
+	}}, expression)
+}
+
+func (s *parserTestSuite) TestParseErrors() {
+	tests := map[string]string{
+		"":              "position 1",
+		"tagA AND":      "position 9",
+		"tagA tagB":     "position 6",
+		"(tagA OR tagB": "position 14",
+		"tagA)":         "position 5",
+		"tagA AND $b":   "position 10",
+		"NOT":           "position 4",
+		`tagA OR "b`:    "position 9",
+		`""`:            "position 1",
+		`"a\b"`:         "position 3",
+	}
+
+	for query, position := range tests {
+		_, err := Parse(query)
+		s.True(errortypes.IsBadUserInput(err), "query %q: expected bad user input, got %v", query, err)
+		s.ErrorContains(err, position, "query %q", query)
+	}
+}
+
+func (s *parserTestSuite) TestParseTooDeeplyNested() {
+	_, err := Parse(strings.Repeat("(", 100) + "a" + strings.Repeat(")", 100))
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *parserTestSuite) TestMatches() {
+	expression, err := Parse("(tagA AND tagB) OR NOT tagC")
+	s.Require().NoError(err)
+
+	s.True(expression.Matches([]model.TagID{"tagA", "tagB", "tagC"}))
+	s.True(expression.Matches([]model.TagID{}))
+	s.False(expression.Matches([]model.TagID{"tagA", "tagC"}))
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"media-nexus/errortypes"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobTypeReconcile reconciles the media repository with the metadata. The payload is empty.
+const JobTypeReconcile = "reconcile"
+
+// reconcilePageSize is the number of keys listed and deleted at once. S3 deletes at most 1000 objects per request.
+const reconcilePageSize = 1000
+
+// mediaIDPattern matches media IDs, which are hex encoded. Keys not starting with one are left alone.
+var mediaIDPattern = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// Reconciler finds media without metadata and metadata without media. Media loses its metadata e.g. when the
+// metadata of an incomplete upload expires after the media was written, or when an instance crashes in between.
+type Reconciler interface {
+	// Reconcile deletes media without metadata, that is older than the grace period, and reports complete metadata
+	// without media.
+	Reconcile(ctx context.Context) (*model.ReconcileReport, error)
+	// LastReport returns the report of the last reconciliation run by this instance. Returns ResourceNotFound if there
+	// was none yet.
+	LastReport(ctx context.Context) (*model.ReconcileReport, error)
+}
+
+// NewReconciler returns a runner, that schedules a reconciliation every interval through the job queue. This way only
+// one instance reconciles at a time, however many are running.
+func NewReconciler(
+	mediaMetadata ports.MediaMetadataRepository,
+	media ports.MediaRepository,
+	uploads ports.UploadRepository,
+	jobs ports.JobQueue,
+	interval time.Duration,
+	gracePeriod time.Duration,
+	metrics metrics.Metrics,
+) (Reconciler, util.Runner) {
+	reconciler := &reconciler{
+		mediaMetadata: mediaMetadata,
+		media:         media,
+		uploads:       uploads,
+		jobs:          jobs,
+		interval:      interval,
+		gracePeriod:   gracePeriod,
+		metrics:       metrics,
+	}
+
+	return reconciler, reconciler.runSchedule
+}
+
+type reconciler struct {
+	mediaMetadata ports.MediaMetadataRepository
+	media         ports.MediaRepository
+	uploads       ports.UploadRepository
+	jobs          ports.JobQueue
+	interval      time.Duration
+	gracePeriod   time.Duration
+	metrics       metrics.Metrics
+
+	mutex      sync.Mutex
+	lastReport *model.ReconcileReport
+}
+
+func (r *reconciler) Reconcile(ctx context.Context) (*model.ReconcileReport, error) {
+	log := util.Logger(ctx)
+	report := &model.ReconcileReport{StartedAt: time.Now()}
+
+	// media stored while reconciling has no metadata loaded yet. The grace period keeps it from being deleted.
+	cutoff := report.StartedAt.Add(-r.gracePeriod)
+
+	expectsMedia, err := r.loadMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ScannedMetadata = len(expectsMedia)
+
+	chunkKeys, err := r.loadChunkKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := map[model.MediaID]bool{}
+	page := model.PageRequest{Limit: reconcilePageSize}
+
+	for {
+		media, hasMore, err := r.media.ListMediaPage(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+
+		report.ScannedMedia += len(media)
+
+		var orphans []string
+		for _, stored := range media {
+			if mediaIDPattern.MatchString(stored.Key) {
+				listed[stored.Key] = true
+			}
+
+			if stored.LastModified.After(cutoff) || !isOrphan(stored.Key, expectsMedia, chunkKeys) {
+				continue
+			}
+
+			log.WithFields(map[string]interface{}{
+				"key":          stored.Key,
+				"size":         stored.Size,
+				"lastModified": stored.LastModified,
+			}).Warn("deleting orphaned media")
+
+			orphans = append(orphans, stored.Key)
+		}
+
+		if err := r.media.DeleteAll(ctx, orphans); err != nil {
+			return nil, err
+		}
+
+		report.DeletedOrphans = append(report.DeletedOrphans, orphans...)
+
+		if !hasMore {
+			break
+		}
+
+		page.After = media[len(media)-1].Key
+	}
+
+	for id, expected := range expectsMedia {
+		if !expected || listed[id] {
+			continue
+		}
+
+		missing, err := r.isMissing(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if missing {
+			log.WithFields(map[string]interface{}{"mediaId": id}).Error("media of complete metadata is missing")
+			report.MissingMedia = append(report.MissingMedia, id)
+		}
+	}
+
+	slices.Sort(report.MissingMedia)
+	report.FinishedAt = time.Now()
+
+	log.WithFields(map[string]interface{}{
+		"scannedMedia":    report.ScannedMedia,
+		"scannedMetadata": report.ScannedMetadata,
+		"deletedOrphans":  len(report.DeletedOrphans),
+		"missingMedia":    len(report.MissingMedia),
+		"duration":        report.FinishedAt.Sub(report.StartedAt),
+	}).Info("reconciled media with metadata")
+
+	r.mutex.Lock()
+	r.lastReport = report
+	r.mutex.Unlock()
+
+	return report, nil
+}
+
+// loadMetadata maps the IDs of all metadata to whether its media must exist, which is the case for complete metadata
+// not being deleted.
+func (r *reconciler) loadMetadata(ctx context.Context) (map[model.MediaID]bool, error) {
+	expectsMedia := map[model.MediaID]bool{}
+	page := model.PageRequest{Limit: reconcilePageSize}
+
+	for {
+		metadatas, hasMore, err := r.mediaMetadata.FindAllPage(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, metadata := range metadatas {
+			expectsMedia[metadata.ID()] = metadata.UploadComplete() && !metadata.Deleting()
+		}
+
+		if !hasMore {
+			return expectsMedia, nil
+		}
+
+		page.After = metadatas[len(metadatas)-1].ID()
+	}
+}
+
+func (r *reconciler) loadChunkKeys(ctx context.Context) (map[string]bool, error) {
+	uploads, err := r.uploads.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for _, upload := range uploads {
+		for _, chunk := range upload.Chunks {
+			keys[chunk.Key] = true
+		}
+	}
+
+	return keys, nil
+}
+
+// isOrphan tells whether nothing refers to the media at key anymore
+func isOrphan(key string, expectsMedia map[model.MediaID]bool, chunkKeys map[string]bool) bool {
+	switch {
+	case strings.HasPrefix(key, stagingKeyPrefix):
+		// staged media is moved or discarded right after it was stored
+		return true
+	case strings.HasPrefix(key, uploadChunkKeyPrefix):
+		return !chunkKeys[key]
+	}
+
+	// variants are stored at <media id>_<size>
+	id, _, _ := strings.Cut(key, "_")
+	if !mediaIDPattern.MatchString(id) {
+		// not stored by us
+		return false
+	}
+
+	expected, ok := expectsMedia[id]
+	if strings.HasSuffix(key, directUploadKeySuffix) {
+		// direct uploads are moved to the media ID on completion
+		return !ok || expected
+	}
+
+	return !ok
+}
+
+// isMissing checks the media again, which might have been deleted or uploaded again since the metadata was loaded.
+func (r *reconciler) isMissing(ctx context.Context, id model.MediaID) (bool, error) {
+	metadata, err := r.mediaMetadata.Get(ctx, id)
+	if errortypes.IsResourceNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !metadata.UploadComplete() || metadata.Deleting() {
+		return false, nil
+	}
+
+	_, err = r.media.GetMediaAttributes(ctx, id)
+	if errortypes.IsResourceNotFound(err) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+func (r *reconciler) LastReport(ctx context.Context) (*model.ReconcileReport, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.lastReport == nil {
+		return nil, errortypes.NewResourceNotFound("reconcile report")
+	}
+
+	return r.lastReport, nil
+}
+
+func (r *reconciler) runSchedule(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.scheduleNext(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleNext enqueues the reconciliation of the next slot. All instances derive the same job ID from the slot, so
+// only the first one to enqueue it succeeds.
+func (r *reconciler) scheduleNext(ctx context.Context) {
+	now := time.Now()
+	slot := now.Truncate(r.interval).Add(r.interval)
+
+	job := &model.Job{
+		ID:        fmt.Sprintf("%v_%v", JobTypeReconcile, slot.Unix()),
+		Type:      JobTypeReconcile,
+		VisibleAt: slot,
+		CreatedAt: now,
+	}
+
+	err := r.jobs.Enqueue(ctx, job)
+	if errortypes.IsResourceAlreadyExists(err) {
+		err = nil
+	} else if err != nil {
+		util.Logger(ctx).Errorf("failed to schedule the reconciliation at %v: %v", slot, err)
+	}
+
+	r.metrics.CountRun("schedule_reconciliation", err)
+}
+
+// ReconcileJobHandler runs the reconciliations scheduled by the reconciler
+func ReconcileJobHandler(reconciler Reconciler) JobHandler {
+	return func(ctx context.Context, job *model.Job) error {
+		_, err := reconciler.Reconcile(ctx)
+		return err
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/errortypes"
+	"media-nexus/logger"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type reconcilerTestSuite struct {
+	suite.Suite
+	ctx           context.Context
+	mediaMetadata ports.MediaMetadataRepository
+	media         ports.MediaRepository
+	uploads       ports.UploadRepository
+	jobs          ports.JobQueue
+	reconciler    *reconciler
+}
+
+func TestReconciler(t *testing.T) {
+	suite.Run(t, &reconcilerTestSuite{})
+}
+
+func (s *reconcilerTestSuite) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("test"))
+	s.mediaMetadata = amemory.NewMediaMetadataRepository(time.Minute)
+	s.media, _, _ = amemory.NewMediaRepository("http://localhost/api/v1/blobs", []byte("key"))
+	s.uploads = amemory.NewUploadRepository()
+	s.jobs = amemory.NewJobQueue()
+
+	// without grace period, all media stored so far is old enough to be deleted
+	service, _ := NewReconciler(s.mediaMetadata, s.media, s.uploads, s.jobs, time.Hour, 0, metrics.NewNopMetrics())
+	s.reconciler = service.(*reconciler)
+}
+
+func (s *reconcilerTestSuite) createMetadata(id model.MediaID, uploadComplete bool, deleting bool) {
+	metadata := model.NewMediaMetadata(
+		id,
+		"name",
+		nil,
+		checksum(id),
+		checksum(id),
+		7,
+		"text/plain",
+		nil,
+		nil,
+		uploadComplete,
+		time.Time{},
+		deleting,
+		time.Now(),
+	)
+
+	s.Require().NoError(s.mediaMetadata.Upsert(s.ctx, metadata))
+}
+
+func (s *reconcilerTestSuite) createBlobs(keys ...string) {
+	for _, key := range keys {
+		s.Require().NoError(s.media.CreateMedia(s.ctx, key, strings.NewReader("content"), ""))
+	}
+}
+
+func (s *reconcilerTestSuite) requireBlobs(keys ...string) {
+	for _, key := range keys {
+		_, err := s.media.GetMediaAttributes(s.ctx, key)
+		s.Require().NoError(err, "expected media at %v", key)
+	}
+}
+
+func (s *reconcilerTestSuite) reconcile() *model.ReconcileReport {
+	report, err := s.reconciler.Reconcile(s.ctx)
+	s.Require().NoError(err)
+
+	return report
+}
+
+func (s *reconcilerTestSuite) TestDeleteOrphans() {
+	s.createMetadata("aa", true, false)
+	s.createBlobs("aa", "aa_128", "aa_direct", "bb", "bb_128", "staging_cc", "upload_dd", "upload_ee", "not-ours")
+
+	upload := &model.Upload{ID: "ff", Name: "name", Length: 100, ExpiresAt: time.Now().Add(time.Hour)}
+	s.Require().NoError(s.uploads.Create(s.ctx, upload))
+	chunk := model.UploadChunk{Key: "upload_dd", Size: 7}
+	s.Require().NoError(s.uploads.AppendChunk(s.ctx, upload.ID, 0, chunk, upload.ExpiresAt))
+
+	report := s.reconcile()
+	s.Equal([]string{"aa_direct", "bb", "bb_128", "staging_cc", "upload_ee"}, report.DeletedOrphans)
+	s.Empty(report.MissingMedia)
+	s.Equal(9, report.ScannedMedia)
+	s.Equal(1, report.ScannedMetadata)
+
+	s.requireBlobs("aa", "aa_128", "upload_dd", "not-ours")
+
+	_, err := s.media.GetMediaAttributes(s.ctx, "bb")
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+}
+
+func (s *reconcilerTestSuite) TestKeepOrphansWithinGracePeriod() {
+	s.reconciler.gracePeriod = time.Hour
+	s.createBlobs("bb", "staging_cc")
+
+	report := s.reconcile()
+	s.Empty(report.DeletedOrphans)
+	s.requireBlobs("bb", "staging_cc")
+}
+
+func (s *reconcilerTestSuite) TestKeepMediaOfIncompleteAndDeletingMetadata() {
+	s.createMetadata("aa", false, false)
+	s.createMetadata("bb", true, true)
+	s.createBlobs("aa", "aa_direct", "bb", "bb_128")
+
+	report := s.reconcile()
+	s.Empty(report.DeletedOrphans)
+	s.requireBlobs("aa", "aa_direct", "bb", "bb_128")
+}
+
+func (s *reconcilerTestSuite) TestReportMissingMedia() {
+	s.createMetadata("aa", true, false)
+	s.createMetadata("bb", true, false)
+	// incomplete and deleting metadata is expected to have no media
+	s.createMetadata("cc", false, false)
+	s.createMetadata("dd", true, true)
+	s.createBlobs("bb")
+
+	report := s.reconcile()
+	s.Equal([]model.MediaID{"aa"}, report.MissingMedia)
+	s.Empty(report.DeletedOrphans)
+}
+
+func (s *reconcilerTestSuite) TestReconcilePages() {
+	var keys []string
+	for i := 0; i < reconcilePageSize+1; i++ {
+		keys = append(keys, checksum(fmt.Sprint(i)))
+	}
+
+	s.createBlobs(keys...)
+
+	report := s.reconcile()
+	s.Equal(reconcilePageSize+1, report.ScannedMedia)
+	s.Len(report.DeletedOrphans, reconcilePageSize+1)
+}
+
+func (s *reconcilerTestSuite) TestLastReport() {
+	_, err := s.reconciler.LastReport(s.ctx)
+	s.True(errortypes.IsResourceNotFound(err), "expected resource not found, got %v", err)
+
+	report := s.reconcile()
+
+	last, err := s.reconciler.LastReport(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(report, last)
+}
+
+func (s *reconcilerTestSuite) TestScheduleNextOnce() {
+	s.reconciler.scheduleNext(s.ctx)
+	s.reconciler.scheduleNext(s.ctx)
+
+	_, err := s.jobs.Lease(s.ctx, []string{JobTypeReconcile}, time.Minute)
+	s.True(errortypes.IsResourceNotFound(err), "expected the job to be scheduled in the future, got %v", err)
+
+	// another instance scheduling the same slot
+	slot := time.Now().Truncate(time.Hour).Add(time.Hour)
+	job := &model.Job{ID: fmt.Sprintf("reconcile_%v", slot.Unix()), Type: JobTypeReconcile, VisibleAt: slot}
+	err = s.jobs.Enqueue(s.ctx, job)
+	s.True(errortypes.IsResourceAlreadyExists(err), "expected resource already exists, got %v", err)
+}
+
+func (s *reconcilerTestSuite) TestReconcileJobHandler() {
+	s.createBlobs("bb")
+
+	s.Require().NoError(ReconcileJobHandler(s.reconciler)(s.ctx, &model.Job{Type: JobTypeReconcile}))
+
+	_, err := s.reconciler.LastReport(s.ctx)
+	s.NoError(err)
+}
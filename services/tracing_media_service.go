@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"io"
+	"media-nexus/model"
+	"media-nexus/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingMediaService struct {
+	next   MediaService
+	tracer trace.Tracer
+}
+
+// NewTracingMediaService wraps every call to next in a span
+func NewTracingMediaService(next MediaService, tracerProvider trace.TracerProvider) MediaService {
+	return &tracingMediaService{next, tracerProvider.Tracer(tracing.TracerName)}
+}
+
+func (s *tracingMediaService) start(
+	ctx context.Context,
+	operation string,
+	attributes ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "MediaService."+operation, trace.WithAttributes(attributes...))
+}
+
+func mediaIDAttribute(id model.MediaID) attribute.KeyValue {
+	return attribute.String(tracing.AttributeMediaID, string(id))
+}
+
+func (s *tracingMediaService) CreateMedia(
+	ctx context.Context,
+	name string,
+	tagIDs []model.TagID,
+	file io.Reader,
+) (model.MediaID, error) {
+	ctx, span := s.start(ctx, "CreateMedia")
+	id, err := s.next.CreateMedia(ctx, name, tagIDs, file)
+	span.SetAttributes(mediaIDAttribute(id))
+	tracing.End(span, err)
+
+	return id, err
+}
+
+func (s *tracingMediaService) GetMedia(ctx context.Context, id model.MediaID) (model.MediaItem, error) {
+	ctx, span := s.start(ctx, "GetMedia", mediaIDAttribute(id))
+	item, err := s.next.GetMedia(ctx, id)
+	tracing.End(span, err)
+
+	return item, err
+}
+
+func (s *tracingMediaService) OpenMediaContent(
+	ctx context.Context,
+	id model.MediaID,
+) (model.MediaMetadata, io.ReadSeekCloser, error) {
+	ctx, span := s.start(ctx, "OpenMediaContent", mediaIDAttribute(id))
+	metadata, content, err := s.next.OpenMediaContent(ctx, id)
+	tracing.End(span, err)
+
+	return metadata, content, err
+}
+
+func (s *tracingMediaService) UpdateMedia(
+	ctx context.Context,
+	id model.MediaID,
+	update model.MediaMetadataUpdate,
+) (model.MediaItem, error) {
+	ctx, span := s.start(ctx, "UpdateMedia", mediaIDAttribute(id))
+	item, err := s.next.UpdateMedia(ctx, id, update)
+	tracing.End(span, err)
+
+	return item, err
+}
+
+func (s *tracingMediaService) DeleteMedia(ctx context.Context, id model.MediaID) error {
+	ctx, span := s.start(ctx, "DeleteMedia", mediaIDAttribute(id))
+	err := s.next.DeleteMedia(ctx, id)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (s *tracingMediaService) CreateDirectUpload(
+	ctx context.Context,
+	name string,
+	tagIDs []model.TagID,
+	size int64,
+	checksum string,
+	mimeType string,
+) (model.MediaID, *model.UploadURL, error) {
+	ctx, span := s.start(ctx, "CreateDirectUpload")
+	id, uploadURL, err := s.next.CreateDirectUpload(ctx, name, tagIDs, size, checksum, mimeType)
+	span.SetAttributes(mediaIDAttribute(id))
+	tracing.End(span, err)
+
+	return id, uploadURL, err
+}
+
+func (s *tracingMediaService) CompleteDirectUpload(ctx context.Context, id model.MediaID) (model.MediaItem, error) {
+	ctx, span := s.start(ctx, "CompleteDirectUpload", mediaIDAttribute(id))
+	item, err := s.next.CompleteDirectUpload(ctx, id)
+	tracing.End(span, err)
+
+	return item, err
+}
+
+func (s *tracingMediaService) FindByTagID(
+	ctx context.Context,
+	tagID model.TagID,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaItem, string, error) {
+	ctx, span := s.start(ctx, "FindByTagID", attribute.String(tracing.AttributeTagID, string(tagID)))
+	items, cursor, err := s.next.FindByTagID(ctx, tagID, filter, page)
+	tracing.End(span, err)
+
+	return items, cursor, err
+}
+
+func (s *tracingMediaService) FindByQuery(
+	ctx context.Context,
+	q string,
+	filter model.ImageFilter,
+	page model.PageRequest,
+) ([]model.MediaItem, string, error) {
+	ctx, span := s.start(ctx, "FindByQuery")
+	items, cursor, err := s.next.FindByQuery(ctx, q, filter, page)
+	tracing.End(span, err)
+
+	return items, cursor, err
+}
+
+func (s *tracingMediaService) GetVariantURL(ctx context.Context, id model.MediaID, size int) (string, error) {
+	ctx, span := s.start(ctx, "GetVariantURL", mediaIDAttribute(id), attribute.Int(tracing.AttributeVariantSize, size))
+	url, err := s.next.GetVariantURL(ctx, id, size)
+	tracing.End(span, err)
+
+	return url, err
+}
+
+func (s *tracingMediaService) GenerateMissingVariants(ctx context.Context, id model.MediaID) error {
+	ctx, span := s.start(ctx, "GenerateMissingVariants", mediaIDAttribute(id))
+	err := s.next.GenerateMissingVariants(ctx, id)
+	tracing.End(span, err)
+
+	return err
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"media-nexus/errortypes"
+	"media-nexus/logger"
+	"media-nexus/model"
+	"media-nexus/tracing"
+	"media-nexus/util"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// stubMediaService knows no media and fails deleting it
+type stubMediaService struct {
+	MediaService
+}
+
+func (s stubMediaService) GetMedia(ctx context.Context, id model.MediaID) (model.MediaItem, error) {
+	return nil, errortypes.NewResourceNotFound(id)
+}
+
+func (s stubMediaService) DeleteMedia(ctx context.Context, id model.MediaID) error {
+	// the span of the service is the parent of the ones started by the services it calls
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return errortypes.NewIllegalStatef("no span in context")
+	}
+
+	return errortypes.NewInputOutputError("disk full")
+}
+
+type tracingMediaServiceTestSuite struct {
+	suite.Suite
+	ctx      context.Context
+	recorder *tracetest.SpanRecorder
+	service  MediaService
+}
+
+func TestTracingMediaService(t *testing.T) {
+	suite.Run(t, &tracingMediaServiceTestSuite{})
+}
+
+func (s *tracingMediaServiceTestSuite) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("test"))
+	s.recorder = tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder))
+	s.service = NewTracingMediaService(stubMediaService{}, tracerProvider)
+}
+
+func (s *tracingMediaServiceTestSuite) TestSpanOfMissingMedia() {
+	_, err := s.service.GetMedia(s.ctx, "id")
+	s.True(errortypes.IsResourceNotFound(err))
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("MediaService.GetMedia", spans[0].Name())
+	s.Contains(spans[0].Attributes(), attribute.String(tracing.AttributeMediaID, "id"))
+	s.Equal(codes.Unset, spans[0].Status().Code)
+}
+
+func (s *tracingMediaServiceTestSuite) TestSpanOfFailure() {
+	err := s.service.DeleteMedia(s.ctx, "id")
+	s.True(errortypes.IsInputOutputError(err))
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("MediaService.DeleteMedia", spans[0].Name())
+	s.Equal(codes.Error, spans[0].Status().Code)
+}
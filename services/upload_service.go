@@ -0,0 +1,411 @@
+package services
+
+import (
+	"context"
+	"io"
+	"media-nexus/errortypes"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"time"
+)
+
+// uploadChunkKeyPrefix marks chunks of resumable uploads in the media repository
+const uploadChunkKeyPrefix = "upload_"
+
+// UploadService receives media in chunks, so clients can resume an upload after losing their connection. Once all
+// chunks are there, they are put together and created as media like MediaService.CreateMedia does.
+type UploadService interface {
+	CreateUpload(ctx context.Context, name string, tagIDs []model.TagID, length int64) (*model.Upload, error)
+	// GetUpload returns ResourceNotFound for expired uploads as well.
+	GetUpload(ctx context.Context, id model.UploadID) (*model.Upload, error)
+	// AppendChunk returns Conflict if the upload isn't at offset.
+	AppendChunk(ctx context.Context, id model.UploadID, offset int64, chunk io.Reader) (*model.Upload, error)
+	DeleteUpload(ctx context.Context, id model.UploadID) error
+}
+
+// NewUploadService returns a runner, that deletes expired uploads every expiredUploadCheckInterval.
+func NewUploadService(
+	tags ports.TagRepository,
+	uploads ports.UploadRepository,
+	media ports.MediaRepository,
+	mediaService MediaService,
+	uploadLifetime time.Duration,
+	expiredUploadCheckInterval time.Duration,
+	metrics metrics.Metrics,
+) (UploadService, util.Runner) {
+	service := &uploadService{
+		tags,
+		uploads,
+		media,
+		mediaService,
+		uploadLifetime,
+		expiredUploadCheckInterval,
+		metrics,
+	}
+
+	return service, service.runDeleteExpiredUploads
+}
+
+type uploadService struct {
+	tags                       ports.TagRepository
+	uploads                    ports.UploadRepository
+	media                      ports.MediaRepository
+	mediaService               MediaService
+	uploadLifetime             time.Duration
+	expiredUploadCheckInterval time.Duration
+	metrics                    metrics.Metrics
+}
+
+func (s *uploadService) CreateUpload(
+	ctx context.Context,
+	name string,
+	tagIDs []model.TagID,
+	length int64,
+) (*model.Upload, error) {
+	if name == "" {
+		return nil, errortypes.NewBadUserInput("name must not be empty")
+	}
+
+	if length < 0 {
+		return nil, errortypes.NewBadUserInput("length must not be negative")
+	}
+
+	// checked again when the upload completes, but it's nicer to fail before uploading everything
+	if allExist, err := s.tags.AllExist(ctx, tagIDs); err != nil {
+		return nil, err
+	} else if !allExist {
+		return nil, errortypes.NewBadUserInput("not all tag ids exist. Add them first.")
+	}
+
+	id, err := createRandomKey("")
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &model.Upload{
+		ID:        id,
+		Name:      name,
+		TagIDs:    tagIDs,
+		Length:    length,
+		ExpiresAt: time.Now().Add(s.uploadLifetime),
+	}
+
+	if err := s.uploads.Create(ctx, upload); err != nil {
+		return nil, err
+	}
+
+	// there is nothing to wait for with empty media
+	if length == 0 {
+		return s.completeUpload(ctx, upload)
+	}
+
+	return upload, nil
+}
+
+func (s *uploadService) GetUpload(ctx context.Context, id model.UploadID) (*model.Upload, error) {
+	upload, err := s.uploads.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// expired uploads are deleted only every now and then, so hide them until then
+	if upload.Expired() {
+		return nil, errortypes.NewResourceNotFound(id)
+	}
+
+	return upload, nil
+}
+
+func (s *uploadService) AppendChunk(
+	ctx context.Context,
+	id model.UploadID,
+	offset int64,
+	chunk io.Reader,
+) (*model.Upload, error) {
+	upload, err := s.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.Offset != offset {
+		return nil, errortypes.NewConflictf("upload %v is at offset %v, not %v", id, upload.Offset, offset)
+	}
+
+	if upload.Complete() {
+		return upload, nil
+	}
+
+	// the bytes received are kept, when the client breaks off the request. Then its context is canceled already.
+	storeCtx := context.WithoutCancel(ctx)
+
+	storedChunk, readErr := s.storeChunk(storeCtx, upload, chunk)
+	if readErr != nil && storedChunk.Size == 0 {
+		return nil, readErr
+	}
+
+	// an empty chunk still retries completing the upload, in case that failed before
+	if storedChunk.Size > 0 {
+		expiresAt := time.Now().Add(s.uploadLifetime)
+		if err := s.uploads.AppendChunk(storeCtx, id, offset, storedChunk, expiresAt); err != nil {
+			s.deleteChunks(storeCtx, []model.UploadChunk{storedChunk})
+			return nil, err
+		}
+
+		upload.Chunks = append(upload.Chunks, storedChunk)
+		upload.Offset += storedChunk.Size
+		upload.ExpiresAt = expiresAt
+	}
+
+	// the client resumes at the new offset
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if upload.Offset < upload.Length {
+		return upload, nil
+	}
+
+	return s.completeUpload(ctx, upload)
+}
+
+// storeChunk stores at most the remaining bytes of the upload. Returns an empty chunk without a key, if there was no
+// data. If reading the chunk fails midway, the bytes read so far are stored anyway and returned with the error.
+func (s *uploadService) storeChunk(
+	ctx context.Context,
+	upload *model.Upload,
+	chunk io.Reader,
+) (model.UploadChunk, error) {
+	key, err := createRandomKey(uploadChunkKeyPrefix)
+	if err != nil {
+		return model.UploadChunk{}, err
+	}
+
+	reader := &chunkReader{reader: chunk, remaining: upload.Length - upload.Offset}
+	// chunks are no media on their own, so their type is unknown
+	if err := s.media.CreateMedia(ctx, key, reader, ""); err != nil {
+		s.deleteChunks(ctx, []model.UploadChunk{{Key: key}})
+
+		if reader.exceeded {
+			return model.UploadChunk{}, errortypes.NewBadUserInputf(
+				"chunk exceeds the upload length of %v",
+				upload.Length,
+			)
+		}
+
+		return model.UploadChunk{}, err
+	}
+
+	if reader.size == 0 {
+		s.deleteChunks(ctx, []model.UploadChunk{{Key: key}})
+		return model.UploadChunk{}, reader.readErr(upload.ID)
+	}
+
+	return model.UploadChunk{Key: key, Size: reader.size}, reader.readErr(upload.ID)
+}
+
+// completeUpload puts the chunks together and creates the media. If that fails, the upload stays at its full length,
+// so the client can retry by sending an empty chunk. Media of an unsupported type won't get better by retrying, so
+// that upload is deleted right away.
+func (s *uploadService) completeUpload(ctx context.Context, upload *model.Upload) (*model.Upload, error) {
+	chunks := &chunksReader{ctx: ctx, media: s.media, chunks: upload.Chunks}
+	defer chunks.Close()
+
+	mediaID, err := s.mediaService.CreateMedia(ctx, upload.Name, upload.TagIDs, chunks)
+	if errortypes.IsUnsupportedMediaType(err) {
+		if deleteErr := s.deleteUpload(ctx, upload); deleteErr != nil {
+			util.Logger(ctx).Errorf("failed to delete upload %v of unsupported media: %v", upload.ID, deleteErr)
+		}
+
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uploads.Complete(ctx, upload.ID, mediaID); err != nil {
+		return nil, err
+	}
+
+	s.deleteChunks(ctx, upload.Chunks)
+
+	upload.MediaID = mediaID
+	upload.Chunks = nil
+
+	return upload, nil
+}
+
+func (s *uploadService) DeleteUpload(ctx context.Context, id model.UploadID) error {
+	upload, err := s.uploads.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.deleteUpload(ctx, upload)
+}
+
+// deleteUpload deletes the chunks first. If we crash in between, the upload is still there and gets deleted again
+// at the latest when it expires.
+func (s *uploadService) deleteUpload(ctx context.Context, upload *model.Upload) error {
+	if err := s.media.DeleteAll(ctx, chunkKeys(upload.Chunks)); err != nil {
+		return err
+	}
+
+	return s.uploads.Delete(ctx, upload.ID)
+}
+
+func (s *uploadService) deleteExpiredUploads(ctx context.Context) error {
+	uploads, err := s.uploads.FindExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, upload := range uploads {
+		if err := s.deleteUpload(ctx, upload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *uploadService) runDeleteExpiredUploads(ctx context.Context) {
+	log := util.Logger(ctx)
+
+	ticker := time.NewTicker(s.expiredUploadCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.deleteExpiredUploads(ctx)
+		if err != nil {
+			log.Errorf("failed to delete expired uploads: %v", err)
+		}
+
+		s.metrics.CountRun("delete_expired_uploads", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteChunks is best effort only. Chunks left behind are unreferenced and can be cleaned up any time.
+func (s *uploadService) deleteChunks(ctx context.Context, chunks []model.UploadChunk) {
+	if err := s.media.DeleteAll(ctx, chunkKeys(chunks)); err != nil {
+		util.Logger(ctx).Errorf("failed to delete upload chunks: %v", err)
+	}
+}
+
+func chunkKeys(chunks []model.UploadChunk) []string {
+	keys := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		keys = append(keys, chunk.Key)
+	}
+
+	return keys
+}
+
+// chunkReader reads at most remaining bytes and fails, if there is more.
+// chunkReader ends the chunk early, if reading fails. The error is kept in err instead, so the media repository stores
+// the bytes read so far.
+type chunkReader struct {
+	reader    io.Reader
+	remaining int64
+	size      int64
+	exceeded  bool
+	err       error
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, io.EOF
+	}
+
+	if r.remaining <= 0 {
+		n, err := io.ReadFull(r.reader, make([]byte, 1))
+		if n > 0 {
+			r.exceeded = true
+			return 0, errortypes.NewBadUserInput("chunk exceeds the upload length")
+		} else if err != io.EOF {
+			r.err = err
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	r.size += int64(n)
+
+	if err != nil && err != io.EOF {
+		r.err = err
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+func (r *chunkReader) readErr(id model.UploadID) error {
+	if r.err == nil {
+		return nil
+	}
+
+	return errortypes.NewInputOutputErrorf("failed to read chunk of upload %v: %v", id, r.err)
+}
+
+// chunksReader reads the chunks one after another, opening each only when it's needed.
+type chunksReader struct {
+	ctx     context.Context
+	media   ports.MediaRepository
+	chunks  []model.UploadChunk
+	current io.ReadCloser
+}
+
+func (r *chunksReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+
+			current, err := r.media.OpenMedia(r.ctx, r.chunks[0].Key, nil)
+			if err != nil {
+				return 0, err
+			}
+
+			r.current = current
+			r.chunks = r.chunks[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			err = r.Close()
+			if n > 0 || err != nil {
+				return n, err
+			}
+
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (r *chunksReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+
+	err := r.current.Close()
+	r.current = nil
+
+	return err
+}
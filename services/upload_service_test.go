@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"media-nexus/adapters/secondary/amemory"
+	"media-nexus/errortypes"
+	"media-nexus/logger"
+	"media-nexus/metrics"
+	"media-nexus/model"
+	"media-nexus/ports"
+	"media-nexus/util"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type uploadServiceTestSuite struct {
+	suite.Suite
+	ctx           context.Context
+	tags          ports.TagRepository
+	mediaMetadata ports.MediaMetadataRepository
+	uploads       ports.UploadRepository
+	media         *keyTrackingMediaRepository
+	mediaService  MediaService
+	service       *uploadService
+}
+
+func TestUploadService(t *testing.T) {
+	suite.Run(t, &uploadServiceTestSuite{})
+}
+
+func (s *uploadServiceTestSuite) SetupTest() {
+	s.ctx = util.WithLogger(context.Background(), logger.NewLogger("test"))
+	s.tags = amemory.NewTagRepository()
+	s.mediaMetadata = amemory.NewMediaMetadataRepository(time.Minute)
+	s.uploads = amemory.NewUploadRepository()
+	media, _, _ := amemory.NewMediaRepository("http://localhost/api/v1/blobs", []byte("key"))
+	s.media = &keyTrackingMediaRepository{media, map[string]bool{}}
+	s.mediaService = NewMediaService(
+		s.tags,
+		s.mediaMetadata,
+		s.media,
+		time.Minute,
+		time.Minute,
+		time.Hour,
+		[]string{"text/*"},
+		nil,
+		amemory.NewJobQueue(),
+		nil,
+		"",
+		metrics.NewNopMetrics(),
+	)
+
+	service, _ := NewUploadService(
+		s.tags,
+		s.uploads,
+		s.media,
+		s.mediaService,
+		time.Hour,
+		time.Hour,
+		metrics.NewNopMetrics(),
+	)
+	s.service = service.(*uploadService)
+}
+
+func (s *uploadServiceTestSuite) blobKeys() []string {
+	return slices.Sorted(maps.Keys(s.media.keys))
+}
+
+func (s *uploadServiceTestSuite) createUpload(length int64) *model.Upload {
+	tagID, err := s.tags.CreateTag(s.ctx, "tag")
+	s.Require().NoError(err)
+
+	upload, err := s.service.CreateUpload(s.ctx, "name", []model.TagID{tagID}, length)
+	s.Require().NoError(err)
+
+	return upload
+}
+
+func (s *uploadServiceTestSuite) TestUploadInChunks() {
+	upload := s.createUpload(int64(len("content")))
+	s.Empty(upload.MediaID)
+
+	upload, err := s.service.AppendChunk(s.ctx, upload.ID, 0, strings.NewReader("con"))
+	s.Require().NoError(err)
+	s.Equal(int64(3), upload.Offset)
+	s.False(upload.Complete())
+
+	upload, err = s.service.AppendChunk(s.ctx, upload.ID, 3, strings.NewReader("tent"))
+	s.Require().NoError(err)
+	s.Equal(int64(7), upload.Offset)
+	s.True(upload.Complete())
+
+	mediaID, err := s.mediaService.CreateMedia(s.ctx, "name", upload.TagIDs, strings.NewReader("content"))
+	s.Require().NoError(err)
+	s.Equal(mediaID, upload.MediaID)
+
+	// the chunks are gone, only the media is left
+	s.Equal([]string{mediaID}, s.blobKeys())
+
+	upload, err = s.service.GetUpload(s.ctx, upload.ID)
+	s.Require().NoError(err)
+	s.Equal(mediaID, upload.MediaID)
+}
+
+func (s *uploadServiceTestSuite) TestCreateEmptyUpload() {
+	upload := s.createUpload(0)
+	s.True(upload.Complete())
+
+	_, err := s.mediaMetadata.Get(s.ctx, upload.MediaID)
+	s.NoError(err)
+}
+
+func (s *uploadServiceTestSuite) TestCreateUploadWithUnknownTag() {
+	_, err := s.service.CreateUpload(s.ctx, "name", []model.TagID{"unknown"}, 10)
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *uploadServiceTestSuite) TestCreateUploadWithoutName() {
+	_, err := s.service.CreateUpload(s.ctx, "", nil, 10)
+	s.True(errortypes.IsBadUserInput(err))
+}
+
+func (s *uploadServiceTestSuite) TestAppendChunkAtWrongOffset() {
+	upload := s.createUpload(10)
+
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 5, strings.NewReader("data"))
+	s.True(errortypes.IsConflict(err))
+	s.Empty(s.blobKeys())
+}
+
+func (s *uploadServiceTestSuite) TestAppendChunkExceedingLength() {
+	upload := s.createUpload(3)
+
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 0, strings.NewReader("content"))
+	s.True(errortypes.IsBadUserInput(err))
+	s.Empty(s.blobKeys())
+
+	upload, err = s.service.GetUpload(s.ctx, upload.ID)
+	s.Require().NoError(err)
+	s.Equal(int64(0), upload.Offset)
+}
+
+func (s *uploadServiceTestSuite) TestAppendFailingChunk() {
+	upload := s.createUpload(10)
+
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 0, iotest.ErrReader(errors.New("connection reset")))
+	s.Error(err)
+	s.Empty(s.blobKeys())
+}
+
+func (s *uploadServiceTestSuite) TestAppendChunkFailingMidway() {
+	upload := s.createUpload(int64(len("content")))
+
+	chunk := io.MultiReader(strings.NewReader("con"), iotest.ErrReader(errors.New("connection reset")))
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 0, chunk)
+	s.Error(err)
+
+	// the bytes received so far are kept, so the client resumes after them
+	upload, err = s.service.GetUpload(s.ctx, upload.ID)
+	s.Require().NoError(err)
+	s.Equal(int64(3), upload.Offset)
+	s.Len(s.blobKeys(), 1)
+
+	upload, err = s.service.AppendChunk(s.ctx, upload.ID, 3, strings.NewReader("tent"))
+	s.Require().NoError(err)
+	s.True(upload.Complete())
+
+	mediaID, err := s.mediaService.CreateMedia(s.ctx, "name", upload.TagIDs, strings.NewReader("content"))
+	s.Require().NoError(err)
+	s.Equal(mediaID, upload.MediaID)
+}
+
+func (s *uploadServiceTestSuite) TestAppendEmptyChunk() {
+	upload := s.createUpload(10)
+
+	upload, err := s.service.AppendChunk(s.ctx, upload.ID, 0, strings.NewReader(""))
+	s.Require().NoError(err)
+	s.Equal(int64(0), upload.Offset)
+	s.Empty(s.blobKeys())
+}
+
+func (s *uploadServiceTestSuite) TestRetryCompletion() {
+	upload := s.createUpload(int64(len("content")))
+
+	// the tag is gone, so the media can't be created
+	s.Require().NoError(s.tags.DeleteTags(s.ctx, upload.TagIDs))
+
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 0, strings.NewReader("content"))
+	s.True(errortypes.IsBadUserInput(err))
+
+	_, err = s.tags.CreateTag(s.ctx, "tag")
+	s.Require().NoError(err)
+
+	upload, err = s.service.AppendChunk(s.ctx, upload.ID, 7, strings.NewReader(""))
+	s.Require().NoError(err)
+	s.True(upload.Complete())
+	s.Equal([]string{upload.MediaID}, s.blobKeys())
+}
+
+func (s *uploadServiceTestSuite) TestUploadOfUnsupportedType() {
+	content := "\x89PNG\r\n\x1a\ncontent"
+	upload := s.createUpload(int64(len(content)))
+
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 0, strings.NewReader(content))
+	s.True(errortypes.IsUnsupportedMediaType(err))
+	s.Empty(s.blobKeys())
+
+	// retrying won't help, so the upload is gone
+	_, err = s.service.GetUpload(s.ctx, upload.ID)
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *uploadServiceTestSuite) TestDeleteUpload() {
+	upload := s.createUpload(10)
+
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 0, strings.NewReader("data"))
+	s.Require().NoError(err)
+	s.NotEmpty(s.blobKeys())
+
+	s.Require().NoError(s.service.DeleteUpload(s.ctx, upload.ID))
+	s.Empty(s.blobKeys())
+
+	_, err = s.service.GetUpload(s.ctx, upload.ID)
+	s.True(errortypes.IsResourceNotFound(err))
+}
+
+func (s *uploadServiceTestSuite) TestDeleteExpiredUploads() {
+	upload := s.createUpload(10)
+
+	_, err := s.service.AppendChunk(s.ctx, upload.ID, 0, strings.NewReader("data"))
+	s.Require().NoError(err)
+
+	s.service.uploadLifetime = -time.Minute
+	expired := s.createUpload(10)
+
+	_, err = s.service.GetUpload(s.ctx, expired.ID)
+	s.True(errortypes.IsResourceNotFound(err))
+
+	s.Require().NoError(s.service.deleteExpiredUploads(s.ctx))
+
+	_, err = s.uploads.Get(s.ctx, expired.ID)
+	s.True(errortypes.IsResourceNotFound(err))
+
+	_, err = s.service.GetUpload(s.ctx, upload.ID)
+	s.NoError(err)
+	s.Len(s.blobKeys(), 1)
+}
@@ -0,0 +1,126 @@
+package variants
+
+import (
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"media-nexus/errortypes"
+
+	"golang.org/x/image/draw"
+
+	// register the decoders for Decode
+	_ "image/gif"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxPixels limits the size of images we decode. Decoding needs about 4 bytes per pixel, so larger images could
+// exhaust the memory.
+const MaxPixels = 100_000_000
+
+const jpegQuality = 85
+
+// Supports tells whether variants can be generated for media of the MIME type
+func Supports(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp":
+		return true
+	}
+
+	return false
+}
+
+// Decode decodes a whole image. Returns the format, like `jpeg`, as well. Returns UnsupportedMediaType, if the image
+// can't be decoded.
+func Decode(r io.Reader) (image.Image, string, error) {
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return nil, "", errortypes.NewUnsupportedMediaTypef("failed to decode image: %v", err)
+	}
+
+	return img, format, nil
+}
+
+// Resize scales the image down, so its longer edge is size pixels long, and turns it upright according to the EXIF
+// orientation. Images are never scaled up.
+func Resize(img image.Image, size int, orientation int) image.Image {
+	bounds := img.Bounds()
+	width, height := scaledSize(bounds.Dx(), bounds.Dy(), size)
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+
+	return orient(scaled, orientation)
+}
+
+func scaledSize(width int, height int, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+
+	return max(1, width*size/height), size
+}
+
+// Encode writes JPEGs as JPEG again and everything else as PNG, which keeps the transparency. Returns the MIME type
+// written.
+func Encode(w io.Writer, img image.Image, format string) (string, error) {
+	if format == "jpeg" {
+		return "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	}
+
+	return "image/png", png.Encode(w, img)
+}
+
+// orient applies the EXIF orientation from 2 to 8, which tells how the stored image has to be mirrored and rotated to
+// be displayed upright.
+func orient(img *image.NRGBA, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	// orientations from 5 on swap width and height
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			srcX, srcY := sourcePixel(x, y, width, height, orientation)
+			dst.SetNRGBA(x, y, img.NRGBAAt(srcX, srcY))
+		}
+	}
+
+	return dst
+}
+
+// sourcePixel maps a pixel of the upright image to the pixel of the stored one
+func sourcePixel(x int, y int, width int, height int, orientation int) (int, int) {
+	switch orientation {
+	case 2:
+		return width - 1 - x, y
+	case 3:
+		return width - 1 - x, height - 1 - y
+	case 4:
+		return x, height - 1 - y
+	case 5:
+		return y, x
+	case 6:
+		return y, height - 1 - x
+	case 7:
+		return width - 1 - y, height - 1 - x
+	case 8:
+		return width - 1 - y, x
+	}
+
+	return x, y
+}
@@ -0,0 +1,118 @@
+package variants
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"media-nexus/errortypes"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type variantsTestSuite struct {
+	suite.Suite
+}
+
+func TestVariants(t *testing.T) {
+	suite.Run(t, &variantsTestSuite{})
+}
+
+var red = color.NRGBA{R: 255, A: 255}
+
+// newImage creates a white image with a red pixel in the top left corner
+func newImage(width int, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+
+	img.SetNRGBA(0, 0, red)
+
+	return img
+}
+
+func (s *variantsTestSuite) TestResize() {
+	tests := map[string]struct {
+		width, height  int
+		size           int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		"landscape":    {400, 300, 100, 100, 75},
+		"portrait":     {300, 400, 100, 75, 100},
+		"square":       {200, 200, 50, 50, 50},
+		"small":        {40, 30, 100, 40, 30},
+		"thin":         {1000, 2, 100, 100, 1},
+		"exactly size": {100, 50, 100, 100, 50},
+	}
+
+	for name, test := range tests {
+		resized := Resize(newImage(test.width, test.height), test.size, 1)
+		s.Equal(test.expectedWidth, resized.Bounds().Dx(), name)
+		s.Equal(test.expectedHeight, resized.Bounds().Dy(), name)
+	}
+}
+
+func (s *variantsTestSuite) TestResizeOrients() {
+	// where the top left pixel of the stored image ends up in the upright one
+	tests := map[int]image.Point{
+		1: {0, 0},
+		2: {3, 0},
+		3: {3, 1},
+		4: {0, 1},
+		5: {0, 0},
+		6: {1, 0},
+		7: {1, 3},
+		8: {0, 3},
+	}
+
+	for orientation, expected := range tests {
+		resized := Resize(newImage(4, 2), 10, orientation)
+
+		if orientation >= 5 {
+			s.Equal(image.Rect(0, 0, 2, 4), resized.Bounds(), "orientation %v", orientation)
+		} else {
+			s.Equal(image.Rect(0, 0, 4, 2), resized.Bounds(), "orientation %v", orientation)
+		}
+
+		s.Equal(red, resized.At(expected.X, expected.Y), "orientation %v", orientation)
+	}
+}
+
+func (s *variantsTestSuite) TestEncode() {
+	img := Resize(newImage(40, 30), 20, 1)
+
+	var buf bytes.Buffer
+	mimeType, err := Encode(&buf, img, "jpeg")
+	s.Require().NoError(err)
+	s.Equal("image/jpeg", mimeType)
+
+	decoded, format, err := Decode(&buf)
+	s.Require().NoError(err)
+	s.Equal("jpeg", format)
+	s.Equal(image.Rect(0, 0, 20, 15), decoded.Bounds())
+
+	buf.Reset()
+	mimeType, err = Encode(&buf, img, "gif")
+	s.Require().NoError(err)
+	s.Equal("image/png", mimeType)
+
+	_, err = png.Decode(&buf)
+	s.NoError(err)
+}
+
+func (s *variantsTestSuite) TestDecodeNoImage() {
+	_, _, err := Decode(bytes.NewReader([]byte("content")))
+	s.True(errortypes.IsUnsupportedMediaType(err))
+}
+
+func (s *variantsTestSuite) TestSupports() {
+	s.True(Supports("image/jpeg"))
+	s.True(Supports("image/webp"))
+	s.False(Supports("image/svg+xml"))
+	s.False(Supports("text/plain"))
+}
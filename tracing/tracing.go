@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"media-nexus/errortypes"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName names the tracer of the spans of this service, as opposed to the ones of instrumented libraries
+const TracerName = "media-nexus"
+
+// attribute keys of the spans
+const (
+	AttributeMediaID    = "media.id"
+	AttributeMediaKey   = "media.key"
+	AttributeMediaToKey = "media.to_key"
+	AttributeTagID      = "tag.id"
+	// AttributeVariantSize is the size of the variant of an image in pixels
+	AttributeVariantSize = "media.variant_size"
+	// AttributeCount is the number of IDs or keys of calls taking many
+	AttributeCount = "count"
+)
+
+// End ends the span and marks it as failed, if there is an error. Resources not found are only recorded, they are
+// expected, e.g. when looking up whether media exists already.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+
+		if !errortypes.IsResourceNotFound(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
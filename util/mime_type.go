@@ -0,0 +1,36 @@
+package util
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MimeTypeSniffLength is how many bytes at the start of the media DetectMimeType looks at at most
+const MimeTypeSniffLength = 512
+
+// DetectMimeType sniffs the MIME type from the magic bytes at the start of the media, like `image/png`. Parameters like
+// the charset of text are dropped.
+func DetectMimeType(head []byte) string {
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	return mimeType
+}
+
+// IsAllowedMimeType tells whether the MIME type is in allowed. Entries like `image/*` allow all subtypes.
+func IsAllowedMimeType(mimeType string, allowed []string) bool {
+	for _, entry := range allowed {
+		if entry == mimeType {
+			return true
+		}
+
+		if strings.HasSuffix(entry, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(entry, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -39,3 +39,13 @@ func IsValidStringProperty(containerType string, propertyName string, value stri
 
 	return nil
 }
+
+func IsValidEnumProperty(containerType string, propertyName string, value string, allowed []string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+
+	return errortypes.NewBadUserInputf("%v in %v must be one of %v, but is '%v'", propertyName, containerType, allowed, value)
+}